@@ -0,0 +1,150 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"strconv"
+	"syscall"
+	"text/tabwriter"
+	"time"
+
+	"github.com/ieasydevops/demo-scrapy/internal/backfill"
+	"github.com/ieasydevops/demo-scrapy/internal/database"
+	"github.com/ieasydevops/demo-scrapy/internal/models"
+	"github.com/ieasydevops/demo-scrapy/internal/scheduler"
+)
+
+const backfillUsage = `用法: server [-config config.yaml] backfill <命令>
+
+命令:
+  start -monitor-config <ID> | -web-page <ID> -from <日期> [-to <日期>] [-keyword <表达式>]...
+                     创建回填任务并在前台执行，日期格式为 2006-01-02，-to 默认为定时采集时间窗口开始的前一天
+  resume <ID>        在前台继续失败、已取消或中断的回填任务
+  status [ID]        查看回填任务进度
+
+前台执行时按 Ctrl+C 中断，任务保持执行中状态，可用 resume 或重启服务继续`
+
+type keywordFlags []string
+
+func (k *keywordFlags) String() string     { return fmt.Sprint(*k) }
+func (k *keywordFlags) Set(v string) error { *k = append(*k, v); return nil }
+
+// runBackfill 执行 backfill 子命令
+func runBackfill(dbPath string, args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("缺少回填命令\n%s", backfillUsage)
+	}
+
+	if err := database.InitDB(dbPath); err != nil {
+		return fmt.Errorf("数据库初始化失败: %v", err)
+	}
+	defer database.Close()
+
+	switch args[0] {
+	case "start":
+		fs := flag.NewFlagSet("backfill start", flag.ContinueOnError)
+		configID := fs.Int("monitor-config", 0, "监控配置ID")
+		webPageID := fs.Int("web-page", 0, "网页ID")
+		from := fs.String("from", "", "开始日期")
+		to := fs.String("to", "", "结束日期，默认为定时采集时间窗口开始的前一天")
+		var keywords keywordFlags
+		fs.Var(&keywords, "keyword", "关键词表达式，可重复，默认使用监控配置的关键词")
+		if err := fs.Parse(args[1:]); err != nil {
+			return err
+		}
+		if (*configID > 0) == (*webPageID > 0) {
+			return fmt.Errorf("-monitor-config 和 -web-page 需且只能指定一个\n%s", backfillUsage)
+		}
+		start, end, err := backfill.ParseRange(*from, *to)
+		if err != nil {
+			return err
+		}
+		id, err := scheduler.CreateBackfill(scheduler.BackfillRequest{
+			MonitorConfigID: *configID,
+			WebPageID:       *webPageID,
+			Keywords:        keywords,
+			StartDate:       start,
+			EndDate:         end,
+		})
+		if err != nil {
+			return fmt.Errorf("创建回填任务失败: %v", err)
+		}
+		fmt.Printf("已创建回填任务 %d\n", id)
+		return runBackfillForeground(id)
+
+	case "resume":
+		if len(args) != 2 {
+			return fmt.Errorf("resume 需要回填任务ID\n%s", backfillUsage)
+		}
+		id, err := strconv.Atoi(args[1])
+		if err != nil {
+			return fmt.Errorf("回填任务ID无效: %s", args[1])
+		}
+		return runBackfillForeground(id)
+
+	case "status":
+		var jobs []models.BackfillJob
+		if len(args) > 1 {
+			id, err := strconv.Atoi(args[1])
+			if err != nil {
+				return fmt.Errorf("回填任务ID无效: %s", args[1])
+			}
+			job, err := backfill.Get(id)
+			if err != nil {
+				return fmt.Errorf("读取回填任务 %d 失败: %v", id, err)
+			}
+			jobs = append(jobs, job)
+		} else {
+			var err error
+			if jobs, _, err = backfill.List(1, 50); err != nil {
+				return err
+			}
+		}
+		w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+		fmt.Fprintln(w, "ID\t网页\t日期范围\t状态\t进度\t新增\t跳过\t错误")
+		for _, job := range jobs {
+			fmt.Fprintf(w, "%d\t%s\t%s ~ %s\t%s\t%d/%d\t%d\t%d\t%s\n", job.ID, job.WebPageName, job.StartDate, job.EndDate,
+				job.Status, job.DaysDone, job.DaysTotal, job.Inserted, job.Skipped, job.Error)
+		}
+		return w.Flush()
+
+	default:
+		return fmt.Errorf("未知的回填命令: %s\n%s", args[0], backfillUsage)
+	}
+}
+
+// runBackfillForeground 在前台执行回填任务并逐日输出进度，收到中断信号后停止
+func runBackfillForeground(id int) error {
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	scheduler.Start()
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), time.Second)
+		defer cancel()
+		scheduler.Stop(shutdownCtx)
+	}()
+
+	err := scheduler.RunBackfill(id, func(job models.BackfillJob, day string) {
+		fmt.Printf("[%d/%d] %s 完成，累计新增 %d 条，跳过 %d 条\n",
+			job.DaysDone, job.DaysTotal, day, job.Inserted, job.Skipped)
+	})
+	if ctx.Err() != nil {
+		fmt.Printf("已中断，回填任务 %d 可用 resume 继续\n", id)
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	job, err := backfill.Get(id)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("回填任务 %d %s: 新增 %d 条，跳过 %d 条\n", id, job.Status, job.Inserted, job.Skipped)
+	return nil
+}
@@ -1,15 +1,42 @@
 package main
 
 import (
+	"context"
+	"encoding/json"
+	"errors"
 	"flag"
+	"fmt"
 	"log"
+	"net/http"
+	"os/signal"
+	"strings"
+	"syscall"
 	"time"
 
+	_ "github.com/ieasydevops/demo-scrapy/docs"
+	"github.com/ieasydevops/demo-scrapy/internal/api"
 	"github.com/ieasydevops/demo-scrapy/internal/config"
+	"github.com/ieasydevops/demo-scrapy/internal/crawler"
+	"github.com/ieasydevops/demo-scrapy/internal/crawlrun"
 	"github.com/ieasydevops/demo-scrapy/internal/database"
+	"github.com/ieasydevops/demo-scrapy/internal/digestrun"
+	"github.com/ieasydevops/demo-scrapy/internal/email"
+	"github.com/ieasydevops/demo-scrapy/internal/extract"
+	"github.com/ieasydevops/demo-scrapy/internal/fetch"
 	"github.com/ieasydevops/demo-scrapy/internal/scheduler"
+	"github.com/ieasydevops/demo-scrapy/internal/webhook"
 )
 
+// @title           政府采购网监控系统 API
+// @version         1.0
+// @description     政府采购网公告监控系统的 API 文档
+// @termsOfService  http://swagger.io/terms/
+
+// @contact.name   API Support
+// @contact.email  403608355@qq.com
+
+// @host      localhost:5080
+// @BasePath  /api
 func main() {
 	configPath := flag.String("config", "config.yaml", "配置文件路径")
 	flag.Parse()
@@ -23,32 +50,235 @@ func main() {
 		log.Fatalf("加载配置文件失败: %v", err)
 	}
 
+	if flag.Arg(0) == "migrate" {
+		if err := runMigrate(cfg.Server.DBPath, flag.Args()[1:]); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+	if flag.Arg(0) == "reindex" {
+		if err := runReindex(cfg.Server.DBPath); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+
+	if err := extract.LoadRules(cfg.Crawler.ExtractRules); err != nil {
+		log.Fatalf("加载字段抽取规则失败: %v", err)
+	}
+	for typ, rule := range cfg.Crawler.TypeRules {
+		if err := extract.LoadTypeRules(typ, rule.Title, rule.Body); err != nil {
+			log.Fatalf("加载公告分类规则失败: %v", err)
+		}
+	}
+
+	if err := email.Configure(email.Config{
+		Host:        cfg.Email.SMTPHost,
+		Port:        cfg.Email.SMTPPort,
+		Username:    cfg.Email.SMTPUser,
+		Password:    cfg.Email.SMTPPass,
+		Security:    cfg.Email.Security,
+		From:        cfg.Email.From,
+		FromName:    cfg.Email.FromName,
+		ReplyTo:     cfg.Email.ReplyTo,
+		Timeout:     time.Duration(cfg.Email.Timeout) * time.Second,
+		IdleTimeout: time.Duration(cfg.Email.KeepAlive) * time.Second,
+		MaxAttempts: cfg.Email.MaxAttempts,
+		RetryDelay:  time.Duration(cfg.Email.RetryDelay) * time.Second,
+		MaxDelay:    time.Duration(cfg.Email.MaxDelay) * time.Second,
+	}); err != nil {
+		log.Fatalf("邮件配置无效: %v", err)
+	}
+	if err := email.LoadTemplateDir(cfg.Email.TemplateDir); err != nil {
+		log.Fatalf("加载邮件模板失败: %v", err)
+	}
+
+	if err := configureFetch(cfg.Crawler.Fetch); err != nil {
+		log.Fatalf("初始化采集客户端失败: %v", err)
+	}
+
+	if flag.Arg(0) == "backfill" {
+		if err := runBackfill(cfg.Server.DBPath, flag.Args()[1:]); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+
 	if err := database.InitDB(cfg.Server.DBPath); err != nil {
 		log.Fatal("数据库初始化失败:", err)
 	}
 
-	database.DB.Exec("INSERT OR IGNORE INTO web_pages (url, name) VALUES (?, ?)",
-		"http://zfcg.szggzy.com:8081/gsgg/secondPage.html", "深圳政府采购网")
+	if n, err := crawler.ClassifyUnclassified(); err != nil {
+		log.Printf("补充历史公告类型失败: %v", err)
+	} else if n > 0 {
+		log.Printf("已为 %d 条历史公告补充类型", n)
+	}
+
+	if n, err := crawlrun.AbandonRunning(); err != nil {
+		log.Printf("清理未完成的采集记录失败: %v", err)
+	} else if n > 0 {
+		log.Printf("已将 %d 条未完成的采集记录标记为失败", n)
+	}
+	if n, err := digestrun.AbandonRunning(); err != nil {
+		log.Printf("清理未完成的推送记录失败: %v", err)
+	} else if n > 0 {
+		log.Printf("已将 %d 条未完成的推送记录标记为失败", n)
+	}
+
+	for _, page := range cfg.WebPages {
+		database.DB.Exec("INSERT INTO web_pages (url, name) SELECT ?, ? WHERE NOT EXISTS (SELECT 1 FROM web_pages WHERE url = ?)",
+			page.URL, page.Name, page.URL)
+	}
 
 	for _, keyword := range cfg.Keywords {
 		database.DB.Exec("INSERT OR IGNORE INTO keywords (keyword) VALUES (?)", keyword)
 		log.Printf("初始化关键词: %s", keyword)
 	}
 
-	database.DB.Exec("INSERT OR IGNORE INTO push_config (email, push_time) VALUES (?, ?)",
-		cfg.Email.SMTPUser, "17")
+	if cfg.Email.SMTPUser != "" {
+		database.DB.Exec("INSERT INTO subscribe_config (email, push_time) SELECT ?, ? WHERE NOT EXISTS (SELECT 1 FROM subscribe_config)",
+			cfg.Email.SMTPUser, "17")
+	}
+
+	seedMonitorConfigs(cfg.MonitorConfigs)
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	webhook.Configure(webhook.Options{
+		Timeout:     time.Duration(cfg.Webhook.Timeout) * time.Second,
+		MaxAttempts: cfg.Webhook.MaxAttempts,
+		BaseDelay:   time.Duration(cfg.Webhook.RetryDelay) * time.Second,
+		MaxDelay:    time.Duration(cfg.Webhook.MaxDelay) * time.Second,
+	})
+	webhookCtx, stopWebhooks := context.WithCancel(context.Background())
+	webhookDone := make(chan struct{})
+	go func() {
+		defer close(webhookDone)
+		webhook.Run(webhookCtx)
+	}()
+	mailCtx, stopMail := context.WithCancel(context.Background())
+	mailDone := make(chan struct{})
+	go func() {
+		defer close(mailDone)
+		email.Run(mailCtx)
+	}()
 
 	scheduler.Start()
 	if err := scheduler.ReloadTasks(); err != nil {
 		log.Printf("加载定时任务失败: %v", err)
 	}
+	scheduler.ResumeBackfills()
 
 	log.Println("启动后立即执行一次采集任务...")
 	go func() {
-		time.Sleep(2 * time.Second)
-		scheduler.ExecuteCrawlTask()
+		select {
+		case <-time.After(2 * time.Second):
+			scheduler.ExecuteCrawlTask()
+		case <-ctx.Done():
+		}
+	}()
+
+	srv := &http.Server{
+		Addr:    fmt.Sprintf(":%d", cfg.Server.Port),
+		Handler: api.SetupRouter(),
+	}
+
+	serverErr := make(chan error, 1)
+	go func() {
+		log.Printf("API 服务监听端口 %d，Swagger: http://localhost:%d/swagger/index.html", cfg.Server.Port, cfg.Server.Port)
+		if err := srv.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			serverErr <- err
+		}
 	}()
 
 	log.Println("服务运行中，按 Ctrl+C 停止服务")
-	select {}
+	select {
+	case <-ctx.Done():
+		log.Println("收到退出信号，开始优雅退出...")
+	case err := <-serverErr:
+		log.Printf("API 服务异常退出: %v", err)
+	}
+	stop()
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), time.Duration(cfg.Server.ShutdownTimeout)*time.Second)
+	defer cancel()
+
+	if err := srv.Shutdown(shutdownCtx); err != nil {
+		log.Printf("关闭 API 服务失败: %v", err)
+	}
+	if err := scheduler.Stop(shutdownCtx); err != nil {
+		log.Printf("停止定时任务失败: %v", err)
+	}
+	// 中断的 webhook 投递不计入投递次数，下次启动后继续
+	stopWebhooks()
+	<-webhookDone
+	// 未发送的邮件保留在发件箱中，下次启动后继续发送
+	stopMail()
+	<-mailDone
+	email.Close()
+	if err := database.Close(); err != nil {
+		log.Printf("关闭数据库失败: %v", err)
+	}
+
+	log.Println("服务已停止")
+}
+
+// seedMonitorConfigs 监控配置表为空时按配置文件初始化
+func seedMonitorConfigs(items []config.MonitorConfigItem) {
+	var count int
+	if err := database.DB.QueryRow("SELECT COUNT(*) FROM monitor_config").Scan(&count); err != nil || count > 0 {
+		return
+	}
+
+	for _, item := range items {
+		var webPageID int
+		err := database.DB.QueryRow("SELECT id FROM web_pages WHERE name = ? ORDER BY id LIMIT 1", item.WebPageName).Scan(&webPageID)
+		if err != nil {
+			log.Printf("初始化监控配置失败，网页不存在: %s", item.WebPageName)
+			continue
+		}
+		params := "{}"
+		if len(item.SourceParams) > 0 {
+			data, _ := json.Marshal(item.SourceParams)
+			params = string(data)
+		}
+		database.DB.Exec("INSERT INTO monitor_config (web_page_id, crawl_time, crawl_freq, keywords, source_params) VALUES (?, ?, ?, ?, ?)",
+			webPageID, item.CrawlTime, item.CrawlFreq, strings.Join(item.Keywords, ","), params)
+		log.Printf("初始化监控配置: %s %s %s", item.WebPageName, item.CrawlFreq, item.CrawlTime)
+	}
+}
+
+// configureFetch 按配置创建采集客户端，sources 中列出的数据源使用各自的传输设置
+func configureFetch(fc config.FetchConfig) error {
+	opts := fetch.Options{
+		Timeout:    time.Duration(fc.Timeout) * time.Second,
+		MaxRetries: fc.MaxRetries,
+		BaseDelay:  time.Duration(fc.RetryDelay) * time.Millisecond,
+		MaxDelay:   time.Duration(fc.MaxDelay) * time.Second,
+		Rate:       fc.RateLimit,
+		Burst:      fc.Burst,
+		Transport:  transportOptions(fc.Transport),
+		Fixtures:   fetch.FixtureOptions{Mode: fc.Fixtures.Mode, Dir: fc.Fixtures.Dir},
+	}
+	sources := make(map[string]fetch.TransportOptions, len(fc.Sources))
+	for name := range fc.Sources {
+		sources[name] = transportOptions(fc.SourceTransport(name))
+	}
+	return fetch.Configure(opts, sources)
+}
+
+func transportOptions(t config.TransportConfig) fetch.TransportOptions {
+	return fetch.TransportOptions{
+		Proxies:            t.Proxies,
+		ProxyMaxFailures:   t.ProxyMaxFailures,
+		ProxyCooldown:      time.Duration(t.ProxyCooldown) * time.Second,
+		UserAgent:          t.UserAgent,
+		Headers:            t.Headers,
+		InsecureSkipVerify: t.TLS.InsecureSkipVerify,
+		CAFile:             t.TLS.CAFile,
+		MinTLSVersion:      t.TLS.MinVersion,
+		Cookies:            t.Cookies,
+		CookieFile:         t.CookieFile,
+	}
 }
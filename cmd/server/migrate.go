@@ -0,0 +1,90 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"text/tabwriter"
+
+	"github.com/ieasydevops/demo-scrapy/internal/database"
+)
+
+const migrateUsage = `用法: server [-config config.yaml] migrate <命令>
+
+命令:
+  status             查看迁移执行状态
+  up                 执行所有未执行的迁移
+  down-to <版本>     回滚到指定版本（不含更高版本），0 表示全部回滚`
+
+// runMigrate 执行 migrate 子命令
+func runMigrate(dbPath string, args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("缺少迁移命令\n%s", migrateUsage)
+	}
+
+	if err := database.Open(dbPath); err != nil {
+		return fmt.Errorf("打开数据库失败: %v", err)
+	}
+	defer database.Close()
+
+	switch args[0] {
+	case "status":
+		states, err := database.MigrationStatus()
+		if err != nil {
+			return err
+		}
+		w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+		fmt.Fprintln(w, "版本\t名称\t状态\t执行时间")
+		for _, s := range states {
+			status := "未执行"
+			if s.Applied {
+				status = "已执行"
+			}
+			if s.Modified {
+				status += "（文件已修改）"
+			}
+			fmt.Fprintf(w, "%04d\t%s\t%s\t%s\n", s.Version, s.Name, status, s.AppliedAt)
+		}
+		return w.Flush()
+
+	case "up":
+		n, err := database.MigrateUp()
+		if err != nil {
+			return err
+		}
+		fmt.Printf("已执行 %d 个迁移\n", n)
+		return nil
+
+	case "down-to":
+		if len(args) != 2 {
+			return fmt.Errorf("down-to 需要目标版本\n%s", migrateUsage)
+		}
+		version, err := strconv.Atoi(args[1])
+		if err != nil || version < 0 {
+			return fmt.Errorf("目标版本无效: %s", args[1])
+		}
+		n, err := database.MigrateDownTo(version)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("已回滚 %d 个迁移\n", n)
+		return nil
+
+	default:
+		return fmt.Errorf("未知的迁移命令: %s\n%s", args[0], migrateUsage)
+	}
+}
+
+// runReindex 执行 reindex 子命令，重建公告全文索引
+func runReindex(dbPath string) error {
+	if err := database.InitDB(dbPath); err != nil {
+		return fmt.Errorf("数据库初始化失败: %v", err)
+	}
+	defer database.Close()
+
+	if err := database.RebuildSearchIndex(); err != nil {
+		return fmt.Errorf("重建全文索引失败: %v", err)
+	}
+	fmt.Println("全文索引已重建")
+	return nil
+}
@@ -19,9 +19,32 @@ const docTemplate = `{
     "host": "{{.Host}}",
     "basePath": "{{.BasePath}}",
     "paths": {
+        "/announcement-types": {
+            "get": {
+                "description": "获取公告类型枚举及中文名称，用于列表筛选和订阅配置",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "采购信息动态"
+                ],
+                "summary": "获取公告类型列表",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/extract.TypeInfo"
+                            }
+                        }
+                    }
+                }
+            }
+        },
         "/announcements": {
             "get": {
-                "description": "获取采购信息动态，支持时间排序和搜索",
+                "description": "获取采购信息动态，支持全文检索、按抽取字段筛选和排序。keyword 支持关键词表达式（空格/AND、OR、-/NOT、\"短语\"、括号、title:/content:），检索时默认按相关度排序，结果附带高亮的 title_highlight 和 snippet",
                 "consumes": [
                     "application/json"
                 ],
@@ -35,10 +58,52 @@ const docTemplate = `{
                 "parameters": [
                     {
                         "type": "string",
-                        "description": "搜索关键字",
+                        "description": "搜索关键字表达式",
                         "name": "keyword",
                         "in": "query"
                     },
+                    {
+                        "type": "string",
+                        "description": "公告类型，多个用逗号分隔: tender、award、correction、cancellation、intention、contract、other",
+                        "name": "type",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "采购人（模糊匹配）",
+                        "name": "purchaser",
+                        "in": "query"
+                    },
+                    {
+                        "type": "number",
+                        "description": "最低预算金额（元）",
+                        "name": "budget_min",
+                        "in": "query"
+                    },
+                    {
+                        "type": "number",
+                        "description": "最高预算金额（元）",
+                        "name": "budget_max",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "投标截止时间不早于，格式 2006-01-02",
+                        "name": "deadline_after",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "投标截止时间早于，格式 2006-01-02",
+                        "name": "deadline_before",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "排序字段: relevance（仅检索时）、created_at、publish_date、budget、deadline，检索时默认 relevance，否则默认 created_at",
+                        "name": "sort",
+                        "in": "query"
+                    },
                     {
                         "type": "string",
                         "default": "desc",
@@ -69,40 +134,14 @@ const docTemplate = `{
                             "additionalProperties": true
                         }
                     },
-                    "500": {
-                        "description": "Internal Server Error",
+                    "400": {
+                        "description": "Bad Request",
                         "schema": {
                             "type": "object",
                             "additionalProperties": {
                                 "type": "string"
                             }
                         }
-                    }
-                }
-            }
-        },
-        "/keywords": {
-            "get": {
-                "description": "获取所有监控关键字",
-                "consumes": [
-                    "application/json"
-                ],
-                "produces": [
-                    "application/json"
-                ],
-                "tags": [
-                    "关键字管理"
-                ],
-                "summary": "获取关键字列表",
-                "responses": {
-                    "200": {
-                        "description": "OK",
-                        "schema": {
-                            "type": "array",
-                            "items": {
-                                "$ref": "#/definitions/models.Keyword"
-                            }
-                        }
                     },
                     "500": {
                         "description": "Internal Server Error",
@@ -114,9 +153,11 @@ const docTemplate = `{
                         }
                     }
                 }
-            },
-            "post": {
-                "description": "添加一个新的监控关键字，并立即重新加载任务",
+            }
+        },
+        "/announcements/{id}": {
+            "get": {
+                "description": "获取单条公告，包含详情页正文、抽取字段和附件列表",
                 "consumes": [
                     "application/json"
                 ],
@@ -124,29 +165,27 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "关键字管理"
+                    "采购信息动态"
                 ],
-                "summary": "创建关键字",
+                "summary": "获取公告详情",
                 "parameters": [
                     {
-                        "description": "关键字信息",
-                        "name": "keyword",
-                        "in": "body",
-                        "required": true,
-                        "schema": {
-                            "$ref": "#/definitions/models.Keyword"
-                        }
+                        "type": "integer",
+                        "description": "公告ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
                     }
                 ],
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/models.Keyword"
+                            "$ref": "#/definitions/models.Announcement"
                         }
                     },
-                    "400": {
-                        "description": "Bad Request",
+                    "404": {
+                        "description": "Not Found",
                         "schema": {
                             "type": "object",
                             "additionalProperties": {
@@ -166,23 +205,20 @@ const docTemplate = `{
                 }
             }
         },
-        "/keywords/{id}": {
-            "delete": {
-                "description": "删除指定ID的关键字，并立即重新加载任务",
-                "consumes": [
-                    "application/json"
-                ],
+        "/attachments/{id}/download": {
+            "get": {
+                "description": "返回本地保存的附件文件，未下载到本地时重定向到原始地址",
                 "produces": [
-                    "application/json"
+                    "application/octet-stream"
                 ],
                 "tags": [
-                    "关键字管理"
+                    "采购信息动态"
                 ],
-                "summary": "删除关键字",
+                "summary": "下载附件",
                 "parameters": [
                     {
                         "type": "integer",
-                        "description": "关键字ID",
+                        "description": "附件ID",
                         "name": "id",
                         "in": "path",
                         "required": true
@@ -192,14 +228,11 @@ const docTemplate = `{
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "type": "object",
-                            "additionalProperties": {
-                                "type": "string"
-                            }
+                            "type": "file"
                         }
                     },
-                    "500": {
-                        "description": "Internal Server Error",
+                    "404": {
+                        "description": "Not Found",
                         "schema": {
                             "type": "object",
                             "additionalProperties": {
@@ -210,27 +243,38 @@ const docTemplate = `{
                 }
             }
         },
-        "/monitor-config": {
+        "/backfill-jobs": {
             "get": {
-                "description": "获取所有监控配置列表",
-                "consumes": [
-                    "application/json"
-                ],
+                "description": "分页获取回填任务及其进度（cursor 为下一个待采集的日期），按创建时间倒序",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "监控配置管理"
+                    "历史回填"
+                ],
+                "summary": "获取历史回填任务",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "default": 1,
+                        "description": "页码",
+                        "name": "page",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "default": 20,
+                        "description": "每页数量",
+                        "name": "pageSize",
+                        "in": "query"
+                    }
                 ],
-                "summary": "获取监控配置",
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "type": "array",
-                            "items": {
-                                "$ref": "#/definitions/models.MonitorConfig"
-                            }
+                            "type": "object",
+                            "additionalProperties": true
                         }
                     },
                     "500": {
@@ -245,7 +289,7 @@ const docTemplate = `{
                 }
             },
             "post": {
-                "description": "创建新的监控配置",
+                "description": "按天回溯采集 start_date 到 end_date（含）的历史公告并立即在后台执行，进度按天记录，服务重启后自动继续。\nmonitor_config_id 和 web_page_id 二选一；keywords 为空时使用监控配置的关键词（按网页回填时使用关键词表）。回填入库的公告不会出现在订阅摘要中。\nend_date 不能晚于网页上定时采集的时间窗口开始的前一天（daily 为前天或更早，weekly 等执行间隔较长时更早），为空时取这一天",
                 "consumes": [
                     "application/json"
                 ],
@@ -253,13 +297,13 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "监控配置管理"
+                    "历史回填"
                 ],
-                "summary": "创建监控配置",
+                "summary": "创建历史回填任务",
                 "parameters": [
                     {
-                        "description": "监控配置",
-                        "name": "config",
+                        "description": "回填目标、关键词和日期范围",
+                        "name": "request",
                         "in": "body",
                         "required": true,
                         "schema": {
@@ -268,8 +312,8 @@ const docTemplate = `{
                     }
                 ],
                 "responses": {
-                    "200": {
-                        "description": "OK",
+                    "202": {
+                        "description": "Accepted",
                         "schema": {
                             "type": "object",
                             "additionalProperties": true
@@ -284,8 +328,17 @@ const docTemplate = `{
                             }
                         }
                     },
-                    "500": {
-                        "description": "Internal Server Error",
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "503": {
+                        "description": "Service Unavailable",
                         "schema": {
                             "type": "object",
                             "additionalProperties": {
@@ -296,49 +349,34 @@ const docTemplate = `{
                 }
             }
         },
-        "/monitor-config/{id}": {
-            "put": {
-                "description": "更新指定ID的监控配置",
-                "consumes": [
-                    "application/json"
-                ],
+        "/backfill-jobs/{id}": {
+            "get": {
+                "description": "获取单个回填任务的状态和进度，每天的采集结果可通过 GET /crawl-runs?backfill_job_id= 查看",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "监控配置管理"
+                    "历史回填"
                 ],
-                "summary": "更新监控配置",
+                "summary": "获取历史回填任务详情",
                 "parameters": [
                     {
                         "type": "integer",
-                        "description": "配置ID",
+                        "description": "回填任务ID",
                         "name": "id",
                         "in": "path",
                         "required": true
-                    },
-                    {
-                        "description": "监控配置",
-                        "name": "config",
-                        "in": "body",
-                        "required": true,
-                        "schema": {
-                            "type": "object"
-                        }
                     }
                 ],
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "type": "object",
-                            "additionalProperties": {
-                                "type": "string"
-                            }
+                            "$ref": "#/definitions/models.BackfillJob"
                         }
                     },
-                    "400": {
-                        "description": "Bad Request",
+                    "404": {
+                        "description": "Not Found",
                         "schema": {
                             "type": "object",
                             "additionalProperties": {
@@ -356,23 +394,22 @@ const docTemplate = `{
                         }
                     }
                 }
-            },
-            "delete": {
-                "description": "删除指定ID的监控配置",
-                "consumes": [
-                    "application/json"
-                ],
+            }
+        },
+        "/backfill-jobs/{id}/cancel": {
+            "post": {
+                "description": "取消回填任务，执行中的任务在当前一天采集完成后停止，之后可以继续",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "监控配置管理"
+                    "历史回填"
                 ],
-                "summary": "删除监控配置",
+                "summary": "取消历史回填任务",
                 "parameters": [
                     {
                         "type": "integer",
-                        "description": "配置ID",
+                        "description": "回填任务ID",
                         "name": "id",
                         "in": "path",
                         "required": true
@@ -388,72 +425,56 @@ const docTemplate = `{
                             }
                         }
                     },
-                    "500": {
-                        "description": "Internal Server Error",
+                    "404": {
+                        "description": "Not Found",
                         "schema": {
                             "type": "object",
                             "additionalProperties": {
                                 "type": "string"
                             }
                         }
-                    }
-                }
-            }
-        },
-        "/push-config": {
-            "get": {
-                "description": "获取邮件推送配置信息",
-                "consumes": [
-                    "application/json"
-                ],
-                "produces": [
-                    "application/json"
-                ],
-                "tags": [
-                    "推送配置"
-                ],
-                "summary": "获取推送配置",
-                "responses": {
-                    "200": {
-                        "description": "OK",
+                    },
+                    "409": {
+                        "description": "Conflict",
                         "schema": {
-                            "$ref": "#/definitions/models.PushConfig"
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
                         }
                     }
                 }
-            },
-            "put": {
-                "description": "更新邮件推送配置（邮箱和推送时间）",
-                "consumes": [
-                    "application/json"
-                ],
+            }
+        },
+        "/backfill-jobs/{id}/resume": {
+            "post": {
+                "description": "从进度处继续失败或已取消的回填任务",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "推送配置"
+                    "历史回填"
                 ],
-                "summary": "更新推送配置",
+                "summary": "继续历史回填任务",
                 "parameters": [
                     {
-                        "description": "推送配置",
-                        "name": "config",
-                        "in": "body",
-                        "required": true,
-                        "schema": {
-                            "$ref": "#/definitions/models.PushConfig"
-                        }
+                        "type": "integer",
+                        "description": "回填任务ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
                     }
                 ],
                 "responses": {
-                    "200": {
-                        "description": "OK",
+                    "202": {
+                        "description": "Accepted",
                         "schema": {
-                            "$ref": "#/definitions/models.PushConfig"
+                            "type": "object",
+                            "additionalProperties": true
                         }
                     },
-                    "400": {
-                        "description": "Bad Request",
+                    "404": {
+                        "description": "Not Found",
                         "schema": {
                             "type": "object",
                             "additionalProperties": {
@@ -461,21 +482,30 @@ const docTemplate = `{
                             }
                         }
                     },
-                    "500": {
-                        "description": "Internal Server Error",
+                    "409": {
+                        "description": "Conflict",
                         "schema": {
                             "type": "object",
                             "additionalProperties": {
                                 "type": "string"
                             }
                         }
-                    }
-                }
-            }
+                    },
+                    "503": {
+                        "description": "Service Unavailable",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
         },
-        "/subscribe-config": {
+        "/crawl-runs": {
             "get": {
-                "description": "获取所有订阅用户邮箱列表",
+                "description": "分页获取采集执行记录，包含触发方式、时间窗口、耗时、各阶段计数和错误信息，按开始时间倒序",
                 "consumes": [
                     "application/json"
                 ],
@@ -483,16 +513,63 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "订阅配置管理"
+                    "采集记录"
+                ],
+                "summary": "获取采集记录",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "监控配置ID",
+                        "name": "monitor_config_id",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "状态 running/success/failed",
+                        "name": "status",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "触发方式 schedule/startup/manual/backfill",
+                        "name": "trigger",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "回填任务ID",
+                        "name": "backfill_job_id",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "default": 1,
+                        "description": "页码",
+                        "name": "page",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "default": 20,
+                        "description": "每页数量",
+                        "name": "pageSize",
+                        "in": "query"
+                    }
                 ],
-                "summary": "获取订阅配置列表",
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "type": "array",
-                            "items": {
-                                "$ref": "#/definitions/models.SubscribeConfig"
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
                             }
                         }
                     },
@@ -508,7 +585,7 @@ const docTemplate = `{
                 }
             },
             "post": {
-                "description": "添加新的订阅用户邮箱",
+                "description": "立即在后台按监控配置或网页执行一次采集，返回可轮询的采集记录 ID。monitor_config_id 和 web_page_id 二选一，按网页采集时使用关键词表。\nstart_time/end_time 格式为 2006-01-02 或 2006-01-02 15:04:05，只写日期的 end_time 包含当天；未设置时按采集频率回溯。\n同一监控配置（或网页）已有采集在执行时不会重复执行，返回执行中的记录 ID，coalesced 为 true",
                 "consumes": [
                     "application/json"
                 ],
@@ -516,25 +593,26 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "订阅配置管理"
+                    "采集记录"
                 ],
-                "summary": "创建订阅配置",
+                "summary": "手动触发采集",
                 "parameters": [
                     {
-                        "description": "订阅配置",
-                        "name": "config",
+                        "description": "采集目标和时间窗口",
+                        "name": "request",
                         "in": "body",
                         "required": true,
                         "schema": {
-                            "$ref": "#/definitions/models.SubscribeConfig"
+                            "type": "object"
                         }
                     }
                 ],
                 "responses": {
-                    "200": {
-                        "description": "OK",
+                    "202": {
+                        "description": "Accepted",
                         "schema": {
-                            "$ref": "#/definitions/models.SubscribeConfig"
+                            "type": "object",
+                            "additionalProperties": true
                         }
                     },
                     "400": {
@@ -546,8 +624,17 @@ const docTemplate = `{
                             }
                         }
                     },
-                    "500": {
-                        "description": "Internal Server Error",
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "503": {
+                        "description": "Service Unavailable",
                         "schema": {
                             "type": "object",
                             "additionalProperties": {
@@ -558,9 +645,9 @@ const docTemplate = `{
                 }
             }
         },
-        "/subscribe-config/{id}": {
-            "put": {
-                "description": "更新指定ID的订阅配置",
+        "/crawl-runs/{id}": {
+            "get": {
+                "description": "获取单次采集的执行记录及本次新增的公告",
                 "consumes": [
                     "application/json"
                 ],
@@ -568,36 +655,27 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "订阅配置管理"
+                    "采集记录"
                 ],
-                "summary": "更新订阅配置",
+                "summary": "获取采集记录详情",
                 "parameters": [
                     {
                         "type": "integer",
-                        "description": "配置ID",
+                        "description": "采集记录ID",
                         "name": "id",
                         "in": "path",
                         "required": true
-                    },
-                    {
-                        "description": "订阅配置",
-                        "name": "config",
-                        "in": "body",
-                        "required": true,
-                        "schema": {
-                            "$ref": "#/definitions/models.SubscribeConfig"
-                        }
                     }
                 ],
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/models.SubscribeConfig"
+                            "$ref": "#/definitions/models.CrawlRun"
                         }
                     },
-                    "400": {
-                        "description": "Bad Request",
+                    "404": {
+                        "description": "Not Found",
                         "schema": {
                             "type": "object",
                             "additionalProperties": {
@@ -615,23 +693,74 @@ const docTemplate = `{
                         }
                     }
                 }
-            },
-            "delete": {
-                "description": "删除指定ID的订阅配置",
-                "consumes": [
+            }
+        },
+        "/digest-runs": {
+            "get": {
+                "description": "分页获取摘要推送的执行记录，包含触发方式、状态、待推送和实际发送条数、错误信息，按开始时间倒序",
+                "produces": [
                     "application/json"
                 ],
+                "tags": [
+                    "订阅配置管理"
+                ],
+                "summary": "获取摘要推送记录",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "订阅配置ID",
+                        "name": "subscriber_id",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "default": 1,
+                        "description": "页码",
+                        "name": "page",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "default": 20,
+                        "description": "每页数量",
+                        "name": "pageSize",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/digest-runs/{id}": {
+            "get": {
+                "description": "获取单次摘要推送的执行记录，用于轮询手动触发的推送",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
                     "订阅配置管理"
                 ],
-                "summary": "删除订阅配置",
+                "summary": "获取摘要推送记录详情",
                 "parameters": [
                     {
                         "type": "integer",
-                        "description": "配置ID",
+                        "description": "推送记录ID",
                         "name": "id",
                         "in": "path",
                         "required": true
@@ -640,6 +769,12 @@ const docTemplate = `{
                 "responses": {
                     "200": {
                         "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.DigestRun"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
                         "schema": {
                             "type": "object",
                             "additionalProperties": {
@@ -659,27 +794,22 @@ const docTemplate = `{
                 }
             }
         },
-        "/web-pages": {
+        "/email-templates/digest": {
             "get": {
-                "description": "获取所有监控网页的列表",
-                "consumes": [
-                    "application/json"
-                ],
+                "description": "stored 为数据库中保存的模板（为空的部分不覆盖），default 为模板目录（email.template_dir）与内置模板合并的结果。\n主题和纯文本正文使用 text/template，HTML 正文使用 html/template（标题、链接等自动转义），数据字段见 email.Digest",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "网页管理"
+                    "邮件模板"
                 ],
-                "summary": "获取网页列表",
+                "summary": "获取摘要邮件模板",
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "type": "array",
-                            "items": {
-                                "$ref": "#/definitions/models.WebPage"
-                            }
+                            "type": "object",
+                            "additionalProperties": true
                         }
                     },
                     "500": {
@@ -693,8 +823,8 @@ const docTemplate = `{
                     }
                 }
             },
-            "post": {
-                "description": "添加一个新的监控网页",
+            "put": {
+                "description": "保存到数据库，之后发送的摘要立即使用。subject、text、html 为空的部分使用模板目录或内置模板。保存前以示例数据试渲染，模板有误时返回 400",
                 "consumes": [
                     "application/json"
                 ],
@@ -702,17 +832,17 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "网页管理"
+                    "邮件模板"
                 ],
-                "summary": "创建网页",
+                "summary": "保存摘要邮件模板",
                 "parameters": [
                     {
-                        "description": "网页信息",
-                        "name": "page",
+                        "description": "摘要邮件模板",
+                        "name": "template",
                         "in": "body",
                         "required": true,
                         "schema": {
-                            "$ref": "#/definitions/models.WebPage"
+                            "$ref": "#/definitions/email.Template"
                         }
                     }
                 ],
@@ -720,7 +850,7 @@ const docTemplate = `{
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/models.WebPage"
+                            "$ref": "#/definitions/email.Template"
                         }
                     },
                     "400": {
@@ -742,54 +872,57 @@ const docTemplate = `{
                         }
                     }
                 }
-            }
-        },
-        "/web-pages/{id}": {
-            "put": {
-                "description": "更新指定ID的网页信息",
-                "consumes": [
-                    "application/json"
-                ],
+            },
+            "delete": {
+                "description": "删除数据库中保存的模板，恢复使用模板目录或内置模板",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "网页管理"
-                ],
-                "summary": "更新网页",
-                "parameters": [
-                    {
-                        "type": "integer",
-                        "description": "网页ID",
-                        "name": "id",
-                        "in": "path",
-                        "required": true
-                    },
-                    {
-                        "description": "网页信息",
-                        "name": "page",
-                        "in": "body",
-                        "required": true,
-                        "schema": {
-                            "$ref": "#/definitions/models.WebPage"
-                        }
-                    }
+                    "邮件模板"
                 ],
+                "summary": "恢复默认摘要邮件模板",
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/models.WebPage"
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
                         }
                     },
-                    "400": {
-                        "description": "Bad Request",
+                    "500": {
+                        "description": "Internal Server Error",
                         "schema": {
                             "type": "object",
                             "additionalProperties": {
                                 "type": "string"
                             }
                         }
+                    }
+                }
+            }
+        },
+        "/email/flagged-recipients": {
+            "get": {
+                "description": "SMTP 服务器对收件人返回 5xx（如邮箱不存在）时标记该收件人，之后发往该地址的邮件直接标记为失败而不发送",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "邮件"
+                ],
+                "summary": "获取被拒收的收件人",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/models.FlaggedRecipient"
+                            }
+                        }
                     },
                     "500": {
                         "description": "Internal Server Error",
@@ -801,24 +934,23 @@ const docTemplate = `{
                         }
                     }
                 }
-            },
+            }
+        },
+        "/email/flagged-recipients/{recipient}": {
             "delete": {
-                "description": "删除指定ID的网页",
-                "consumes": [
-                    "application/json"
-                ],
+                "description": "之后写入发件箱的邮件正常发送，已失败的邮件需要重新发送",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "网页管理"
+                    "邮件"
                 ],
-                "summary": "删除网页",
+                "summary": "解除收件人的拒收标记",
                 "parameters": [
                     {
-                        "type": "integer",
-                        "description": "网页ID",
-                        "name": "id",
+                        "type": "string",
+                        "description": "收件人邮箱",
+                        "name": "recipient",
                         "in": "path",
                         "required": true
                     }
@@ -833,6 +965,15 @@ const docTemplate = `{
                             }
                         }
                     },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
                     "500": {
                         "description": "Internal Server Error",
                         "schema": {
@@ -844,9 +985,2544 @@ const docTemplate = `{
                     }
                 }
             }
-        }
-    },
-    "definitions": {
+        },
+        "/email/outbox": {
+            "get": {
+                "description": "分页获取摘要和告警邮件的发送状态，包含发送次数、下次重试时间、最近一次失败的 SMTP 应答码和错误，按创建时间倒序。\nstatus 为 pending（待发送或等待重试）、sent 或 failed（重试用尽或被永久拒收），kind 为 digest 或 alert",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "邮件"
+                ],
+                "summary": "获取发件箱",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "发送状态",
+                        "name": "status",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "收件人",
+                        "name": "recipient",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "邮件类型",
+                        "name": "kind",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "default": 1,
+                        "description": "页码",
+                        "name": "page",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "default": 20,
+                        "description": "每页数量",
+                        "name": "pageSize",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/email/outbox/resend": {
+            "post": {
+                "description": "把所有失败的邮件（指定 recipient 时只处理该收件人）重新放入发件箱，用于 SMTP 服务恢复后补发，返回重新发送的封数",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "邮件"
+                ],
+                "summary": "重新发送所有失败的邮件",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "收件人",
+                        "name": "recipient",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "202": {
+                        "description": "Accepted",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "integer"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/email/outbox/{id}": {
+            "get": {
+                "description": "获取邮件的发送状态和正文",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "邮件"
+                ],
+                "summary": "获取发件箱中的邮件",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "邮件ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.EmailMessage"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/email/outbox/{id}/resend": {
+            "post": {
+                "description": "把失败的邮件重新放入发件箱并立即发送，发送次数清零，同时解除收件人的拒收标记",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "邮件"
+                ],
+                "summary": "重新发送失败的邮件",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "邮件ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "202": {
+                        "description": "Accepted",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/email/test": {
+            "post": {
+                "description": "按当前 SMTP 配置建立新连接发送一封测试邮件，返回 SMTP 会话记录（发送的命令和服务器的错误应答，不含密码），用于检查服务器、端口、加密方式和账号。\n失败时返回 502，code 为服务器的应答码（连接、TLS 等错误为 0）",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "邮件"
+                ],
+                "summary": "发送测试邮件",
+                "parameters": [
+                    {
+                        "description": "收件人",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/api.emailTestRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "502": {
+                        "description": "Bad Gateway",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    }
+                }
+            }
+        },
+        "/keywords": {
+            "get": {
+                "description": "获取所有监控关键字",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "关键字管理"
+                ],
+                "summary": "获取关键字列表",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/models.Keyword"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            },
+            "post": {
+                "description": "添加一个新的监控关键字，并立即重新加载任务。关键字支持表达式：空格/AND、OR、-/NOT、\"短语\"、括号和 title:/content: 字段限定，语法错误时返回出错位置",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "关键字管理"
+                ],
+                "summary": "创建关键字",
+                "parameters": [
+                    {
+                        "description": "关键字信息",
+                        "name": "keyword",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/models.Keyword"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.Keyword"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/keywords/{id}": {
+            "delete": {
+                "description": "删除指定ID的关键字，并立即重新加载任务",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "关键字管理"
+                ],
+                "summary": "删除关键字",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "关键字ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/monitor-config": {
+            "get": {
+                "description": "获取所有监控配置列表",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "监控配置管理"
+                ],
+                "summary": "获取监控配置",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/models.MonitorConfig"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            },
+            "post": {
+                "description": "创建新的监控配置。crawl_freq 可选 hourly/daily/weekly/custom，\ncrawl_time 分别为分钟、\"H:MM\"、\"[W] H:MM\"（W 为 0-6）或 5 段 cron 表达式。\nsource_params 覆盖网页的同名数据源参数，例如 szggzy 的 cnum、categories、participle",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "监控配置管理"
+                ],
+                "summary": "创建监控配置",
+                "parameters": [
+                    {
+                        "description": "监控配置",
+                        "name": "config",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "type": "object"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/monitor-config/{id}": {
+            "put": {
+                "description": "更新指定ID的监控配置",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "监控配置管理"
+                ],
+                "summary": "更新监控配置",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "配置ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "监控配置",
+                        "name": "config",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "type": "object"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "description": "删除指定ID的监控配置",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "监控配置管理"
+                ],
+                "summary": "删除监控配置",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "配置ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/notify-channels": {
+            "get": {
+                "description": "获取钉钉、企业微信、飞书机器人和通用 webhook 推送渠道，不返回密钥，has_secret 表示是否设置了签名密钥。邮件渠道固定名为 email，不在列表中",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "推送渠道"
+                ],
+                "summary": "获取推送渠道列表",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/models.NotifyChannel"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            },
+            "post": {
+                "description": "type 为 dingtalk、wecom、feishu 或 webhook，url 为机器人的 webhook 地址。\nsecret 为钉钉加签密钥、飞书签名校验密钥，或通用 webhook 的 HMAC-SHA256 签名密钥（X-Signature: sha256=hex(HMAC(secret, X-Timestamp + \".\" + body))），企业微信不支持",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "推送渠道"
+                ],
+                "summary": "创建推送渠道",
+                "parameters": [
+                    {
+                        "description": "name、type、url、secret",
+                        "name": "channel",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "type": "object"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.NotifyChannel"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/notify-channels/{id}": {
+            "put": {
+                "description": "更新推送渠道的名称、类型、地址和密钥，不传 secret 时保留原密钥。已被订阅或告警配置使用的渠道不能改名",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "推送渠道"
+                ],
+                "summary": "更新推送渠道",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "渠道ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "name、type、url、secret",
+                        "name": "channel",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "type": "object"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.NotifyChannel"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "description": "删除指定ID的推送渠道，已被订阅或告警配置使用的渠道需要先从中移除",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "推送渠道"
+                ],
+                "summary": "删除推送渠道",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "渠道ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/notify-channels/{id}/test": {
+            "post": {
+                "description": "通过推送渠道立即发送一条测试消息，返回机器人或 webhook 的错误信息，用于检查地址和密钥",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "推送渠道"
+                ],
+                "summary": "发送测试消息",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "渠道ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "502": {
+                        "description": "Bad Gateway",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/push-config": {
+            "get": {
+                "description": "兼容旧版接口，返回最早创建的订阅配置，请改用 /subscribe-config",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "推送配置"
+                ],
+                "summary": "获取推送配置",
+                "deprecated": true,
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.PushConfig"
+                        }
+                    }
+                }
+            },
+            "put": {
+                "description": "兼容旧版接口，按邮箱创建或更新订阅配置的推送时间，请改用 /subscribe-config",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "推送配置"
+                ],
+                "summary": "更新推送配置",
+                "deprecated": true,
+                "parameters": [
+                    {
+                        "description": "推送配置",
+                        "name": "config",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/models.PushConfig"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.PushConfig"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/sources": {
+            "get": {
+                "description": "获取已注册的数据源适配器名称，用于配置网页的 source 字段",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "网页管理"
+                ],
+                "summary": "获取数据源列表",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/sources/health": {
+            "get": {
+                "description": "获取各网页数据源的健康状态，异常（degraded）的排在前面。响应结构异常（字段缺失、计数矛盾、返回错误页面）\n或连续多次采集没有结果时标记为异常，之后拉取到记录时恢复正常。列表不包含原始响应样本",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "网页管理"
+                ],
+                "summary": "获取数据源健康状态",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/models.SourceHealth"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/sources/health/{id}": {
+            "get": {
+                "description": "获取指定网页的数据源健康状态，包含触发最近一次异常的原始响应样本",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "网页管理"
+                ],
+                "summary": "获取网页的数据源健康状态",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "网页ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.SourceHealth"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/sources/html/test": {
+            "post": {
+                "description": "按 html 数据源参数抓取列表页第一页并返回解析结果，不保存任何数据。解析不出任何条目时返回 502 和原因",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "网页管理"
+                ],
+                "summary": "测试列表页选择器",
+                "parameters": [
+                    {
+                        "description": "url 和 source_params",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "type": "object"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "502": {
+                        "description": "Bad Gateway",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/sources/proxies": {
+            "get": {
+                "description": "获取各数据源代理的可用状态、连续失败次数、成功和失败计数及暂停截止时间",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "网页管理"
+                ],
+                "summary": "获取代理池状态",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/fetch.ProxyState"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/subscribe-config": {
+            "get": {
+                "description": "获取所有订阅用户邮箱列表",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "订阅配置管理"
+                ],
+                "summary": "获取订阅配置列表",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/models.SubscribeConfig"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            },
+            "post": {
+                "description": "添加新的订阅用户邮箱。push_time 为 \"H\" 或 \"H:MM\"，keywords、web_page_ids 和 types 为空时不过滤。\nchannels 为推送渠道名称，email 表示发送到订阅邮箱，其余为 /notify-channels 中配置的渠道，为空时只发邮件。\ngroup_by 为邮件摘要的分组方式：source（来源网页）、keyword（匹配的关键词）、type（公告类型），为空时不分组",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "订阅配置管理"
+                ],
+                "summary": "创建订阅配置",
+                "parameters": [
+                    {
+                        "description": "订阅配置",
+                        "name": "config",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/models.SubscribeConfig"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.SubscribeConfig"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/subscribe-config/{id}": {
+            "put": {
+                "description": "更新指定ID的订阅配置",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "订阅配置管理"
+                ],
+                "summary": "更新订阅配置",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "配置ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "订阅配置",
+                        "name": "config",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/models.SubscribeConfig"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.SubscribeConfig"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "description": "删除指定ID的订阅配置",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "订阅配置管理"
+                ],
+                "summary": "删除订阅配置",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "配置ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/subscribe-config/{id}/deliveries": {
+            "get": {
+                "description": "分页获取指定订阅者已推送的公告记录，按推送时间倒序，可按推送渠道过滤\n邮件渠道的记录包含发件箱中邮件的 ID 和发送状态（outbox_status），failed 的公告会在下次推送时重发",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "订阅配置管理"
+                ],
+                "summary": "获取订阅推送记录",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "配置ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "推送渠道名称",
+                        "name": "channel",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "default": 1,
+                        "description": "页码",
+                        "name": "page",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "default": 20,
+                        "description": "每页数量",
+                        "name": "pageSize",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/subscribe-config/{id}/digest": {
+            "post": {
+                "description": "立即在后台为订阅者生成并发送一次摘要，返回可轮询的推送记录 ID。该订阅者已有推送在执行时不会重复发送，返回执行中的记录 ID，coalesced 为 true",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "订阅配置管理"
+                ],
+                "summary": "手动触发摘要推送",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "配置ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "202": {
+                        "description": "Accepted",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "503": {
+                        "description": "Service Unavailable",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/subscribe-config/{id}/digest/preview": {
+            "get": {
+                "description": "按订阅的过滤条件和分组方式渲染下一次邮件摘要，不发送也不记录推送。包含尚未通过邮件推送给该订阅者的公告，没有待推送公告时渲染空摘要。\nformat 为 html 或 text 时直接返回对应正文，否则返回 JSON。POST 时可在请求体中传入尚未保存的模板（为空的部分使用当前模板）",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "邮件模板"
+                ],
+                "summary": "预览摘要邮件",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "订阅配置ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "default": "json",
+                        "description": "html、text 或 json",
+                        "name": "format",
+                        "in": "query"
+                    },
+                    {
+                        "description": "预览使用的模板（仅 POST）",
+                        "name": "template",
+                        "in": "body",
+                        "schema": {
+                            "$ref": "#/definitions/email.Template"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            },
+            "post": {
+                "description": "按订阅的过滤条件和分组方式渲染下一次邮件摘要，不发送也不记录推送。包含尚未通过邮件推送给该订阅者的公告，没有待推送公告时渲染空摘要。\nformat 为 html 或 text 时直接返回对应正文，否则返回 JSON。POST 时可在请求体中传入尚未保存的模板（为空的部分使用当前模板）",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "邮件模板"
+                ],
+                "summary": "预览摘要邮件",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "订阅配置ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "default": "json",
+                        "description": "html、text 或 json",
+                        "name": "format",
+                        "in": "query"
+                    },
+                    {
+                        "description": "预览使用的模板（仅 POST）",
+                        "name": "template",
+                        "in": "body",
+                        "schema": {
+                            "$ref": "#/definitions/email.Template"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/web-pages": {
+            "get": {
+                "description": "获取所有监控网页的列表",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "网页管理"
+                ],
+                "summary": "获取网页列表",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/models.WebPage"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            },
+            "post": {
+                "description": "添加一个新的监控网页，source 为数据源适配器名称（见 /sources），source_params 为适配器参数",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "网页管理"
+                ],
+                "summary": "创建网页",
+                "parameters": [
+                    {
+                        "description": "网页信息",
+                        "name": "page",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/models.WebPage"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.WebPage"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/web-pages/{id}": {
+            "put": {
+                "description": "更新指定ID的网页信息",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "网页管理"
+                ],
+                "summary": "更新网页",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "网页ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "网页信息",
+                        "name": "page",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/models.WebPage"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.WebPage"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "description": "删除指定ID的网页",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "网页管理"
+                ],
+                "summary": "删除网页",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "网页ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/webhook-deliveries": {
+            "get": {
+                "description": "分页获取 webhook 的投递记录，包含投递次数、下次重试时间、最近一次的响应状态码和错误，按创建时间倒序。\nstatus 为 pending（待投递或等待重试）、success 或 failed（重试用尽）",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Webhook"
+                ],
+                "summary": "获取 webhook 投递记录",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Webhook ID",
+                        "name": "webhook_id",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "投递状态",
+                        "name": "status",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "事件类型",
+                        "name": "event",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "default": 1,
+                        "description": "页码",
+                        "name": "page",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "default": 20,
+                        "description": "每页数量",
+                        "name": "pageSize",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/webhook-deliveries/{id}": {
+            "get": {
+                "description": "获取投递记录及其请求体",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Webhook"
+                ],
+                "summary": "获取 webhook 投递记录详情",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "投递记录ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.WebhookDelivery"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/webhook-deliveries/{id}/replay": {
+            "post": {
+                "description": "以原请求体新建一条待投递记录并立即投递，用于接收方修复后补发失败的事件，返回新记录的 ID",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Webhook"
+                ],
+                "summary": "重放 webhook 投递",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "投递记录ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "202": {
+                        "description": "Accepted",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/webhooks": {
+            "get": {
+                "description": "获取所有出站 webhook，不返回签名密钥",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Webhook"
+                ],
+                "summary": "获取 webhook 列表",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/models.Webhook"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            },
+            "post": {
+                "description": "events 可选 announcement.created（新公告入库）、announcement.classified（新公告属于 types 中的类型，types 为空时为任一类型，详情页改变类型后再次推送）、\nannouncement.updated（详情页抓取后公告类型或抽取字段发生变化）、keyword.matched（新公告匹配 keywords 中的关键词表达式）、crawl.failed（采集失败）。\n每次投递带 X-Webhook-Event、X-Webhook-Delivery、X-Timestamp 和 X-Signature: sha256=hex(HMAC-SHA256(secret, X-Timestamp + \".\" + body)) 请求头。\nsecret 为空时自动生成，只在本接口的响应中返回",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Webhook"
+                ],
+                "summary": "创建 webhook",
+                "parameters": [
+                    {
+                        "description": "name、url、events、types、keywords、secret、enabled",
+                        "name": "webhook",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "type": "object"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.Webhook"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/webhooks/{id}": {
+            "put": {
+                "description": "更新 webhook 的地址、事件和过滤条件，不传 secret 时保留原密钥。停用期间产生的事件在重新启用后投递",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Webhook"
+                ],
+                "summary": "更新 webhook",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Webhook ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "name、url、events、types、keywords、secret、enabled",
+                        "name": "webhook",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "type": "object"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.Webhook"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "description": "删除 webhook 及其全部投递记录",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Webhook"
+                ],
+                "summary": "删除 webhook",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Webhook ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        }
+    },
+    "definitions": {
+        "api.emailTestRequest": {
+            "type": "object",
+            "required": [
+                "to"
+            ],
+            "properties": {
+                "to": {
+                    "type": "string"
+                }
+            }
+        },
+        "email.Template": {
+            "type": "object",
+            "properties": {
+                "html": {
+                    "type": "string"
+                },
+                "subject": {
+                    "type": "string"
+                },
+                "text": {
+                    "type": "string"
+                }
+            }
+        },
+        "extract.TypeInfo": {
+            "type": "object",
+            "properties": {
+                "label": {
+                    "type": "string"
+                },
+                "type": {
+                    "type": "string"
+                }
+            }
+        },
+        "fetch.ProxyState": {
+            "type": "object",
+            "properties": {
+                "available": {
+                    "type": "boolean"
+                },
+                "consecutive_failures": {
+                    "description": "ConsecutiveFailures 连续失败次数，成功一次后清零",
+                    "type": "integer"
+                },
+                "evicted_until": {
+                    "type": "string"
+                },
+                "evictions": {
+                    "type": "integer"
+                },
+                "failures": {
+                    "type": "integer"
+                },
+                "last_error": {
+                    "type": "string"
+                },
+                "last_used_at": {
+                    "type": "string"
+                },
+                "proxy": {
+                    "type": "string"
+                },
+                "source": {
+                    "type": "string"
+                },
+                "successes": {
+                    "type": "integer"
+                }
+            }
+        },
+        "models.Announcement": {
+            "type": "object",
+            "properties": {
+                "agency": {
+                    "type": "string"
+                },
+                "attachments": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.Attachment"
+                    }
+                },
+                "bid_deadline": {
+                    "type": "string"
+                },
+                "body": {
+                    "type": "string"
+                },
+                "budget_amount": {
+                    "type": "number"
+                },
+                "contact": {
+                    "type": "string"
+                },
+                "content": {
+                    "type": "string"
+                },
+                "created_at": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "opening_time": {
+                    "type": "string"
+                },
+                "project_number": {
+                    "type": "string"
+                },
+                "publish_date": {
+                    "type": "string"
+                },
+                "publisher": {
+                    "type": "string"
+                },
+                "purchaser": {
+                    "type": "string"
+                },
+                "snippet": {
+                    "type": "string"
+                },
+                "title": {
+                    "type": "string"
+                },
+                "title_highlight": {
+                    "description": "全文检索时返回，检索词以 \u003cmark\u003e 标记，其余文本按 HTML 转义",
+                    "type": "string"
+                },
+                "type": {
+                    "type": "string"
+                },
+                "url": {
+                    "type": "string"
+                },
+                "web_page_id": {
+                    "type": "integer"
+                },
+                "web_page_name": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.Attachment": {
+            "type": "object",
+            "properties": {
+                "announcement_id": {
+                    "type": "integer"
+                },
+                "downloaded": {
+                    "type": "boolean"
+                },
+                "error": {
+                    "type": "string"
+                },
+                "ext": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "name": {
+                    "type": "string"
+                },
+                "sha256": {
+                    "type": "string"
+                },
+                "size": {
+                    "type": "integer"
+                },
+                "url": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.BackfillJob": {
+            "type": "object",
+            "properties": {
+                "created_at": {
+                    "type": "string"
+                },
+                "cursor": {
+                    "type": "string"
+                },
+                "days_done": {
+                    "type": "integer"
+                },
+                "days_total": {
+                    "type": "integer"
+                },
+                "end_date": {
+                    "type": "string"
+                },
+                "error": {
+                    "type": "string"
+                },
+                "finished_at": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "inserted": {
+                    "type": "integer"
+                },
+                "keywords": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "monitor_config_id": {
+                    "type": "integer"
+                },
+                "skipped": {
+                    "type": "integer"
+                },
+                "start_date": {
+                    "type": "string"
+                },
+                "status": {
+                    "type": "string"
+                },
+                "updated_at": {
+                    "type": "string"
+                },
+                "web_page_id": {
+                    "type": "integer"
+                },
+                "web_page_name": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.ChannelResult": {
+            "type": "object",
+            "properties": {
+                "channel": {
+                    "type": "string"
+                },
+                "error": {
+                    "type": "string"
+                },
+                "pending": {
+                    "type": "integer"
+                },
+                "sent": {
+                    "type": "integer"
+                }
+            }
+        },
+        "models.CrawlRun": {
+            "type": "object",
+            "properties": {
+                "announcements": {
+                    "description": "Announcements 本次采集新增的公告，仅详情接口返回",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.Announcement"
+                    }
+                },
+                "backfill_job_id": {
+                    "type": "integer"
+                },
+                "duration_ms": {
+                    "type": "integer"
+                },
+                "error": {
+                    "type": "string"
+                },
+                "finished_at": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "inserted": {
+                    "type": "integer"
+                },
+                "keywords": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "matched": {
+                    "type": "integer"
+                },
+                "monitor_config_id": {
+                    "type": "integer"
+                },
+                "pages_fetched": {
+                    "type": "integer"
+                },
+                "records_fetched": {
+                    "type": "integer"
+                },
+                "skipped": {
+                    "type": "integer"
+                },
+                "source": {
+                    "type": "string"
+                },
+                "started_at": {
+                    "type": "string"
+                },
+                "status": {
+                    "type": "string"
+                },
+                "trigger": {
+                    "type": "string"
+                },
+                "web_page_id": {
+                    "type": "integer"
+                },
+                "web_page_name": {
+                    "type": "string"
+                },
+                "window_end": {
+                    "type": "string"
+                },
+                "window_start": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.DigestRun": {
+            "type": "object",
+            "properties": {
+                "channels": {
+                    "description": "Channels 每个推送渠道的结果",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.ChannelResult"
+                    }
+                },
+                "duration_ms": {
+                    "type": "integer"
+                },
+                "error": {
+                    "type": "string"
+                },
+                "finished_at": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "pending": {
+                    "type": "integer"
+                },
+                "recipient": {
+                    "type": "string"
+                },
+                "sent": {
+                    "type": "integer"
+                },
+                "started_at": {
+                    "type": "string"
+                },
+                "status": {
+                    "type": "string"
+                },
+                "subscriber_id": {
+                    "type": "integer"
+                },
+                "trigger": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.EmailMessage": {
+            "type": "object",
+            "properties": {
+                "attempts": {
+                    "type": "integer"
+                },
+                "created_at": {
+                    "type": "string"
+                },
+                "error": {
+                    "type": "string"
+                },
+                "html": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "kind": {
+                    "type": "string"
+                },
+                "next_attempt_at": {
+                    "type": "string"
+                },
+                "recipient": {
+                    "type": "string"
+                },
+                "sent_at": {
+                    "type": "string"
+                },
+                "smtp_code": {
+                    "description": "SMTPCode 最近一次失败时服务器的应答码，连接错误等为 0",
+                    "type": "integer"
+                },
+                "status": {
+                    "type": "string"
+                },
+                "subject": {
+                    "type": "string"
+                },
+                "text": {
+                    "description": "Text、HTML 邮件正文，仅详情接口返回",
+                    "type": "string"
+                }
+            }
+        },
+        "models.FlaggedRecipient": {
+            "type": "object",
+            "properties": {
+                "created_at": {
+                    "type": "string"
+                },
+                "outbox_id": {
+                    "description": "OutboxID 被拒收的邮件",
+                    "type": "integer"
+                },
+                "reason": {
+                    "type": "string"
+                },
+                "recipient": {
+                    "type": "string"
+                },
+                "smtp_code": {
+                    "type": "integer"
+                }
+            }
+        },
         "models.Keyword": {
             "type": "object",
             "properties": {
@@ -876,6 +3552,13 @@ const docTemplate = `{
                 "keywords": {
                     "type": "string"
                 },
+                "source_params": {
+                    "description": "SourceParams 覆盖网页记录中的同名数据源参数",
+                    "type": "object",
+                    "additionalProperties": {
+                        "type": "string"
+                    }
+                },
                 "updated_at": {
                     "type": "string"
                 },
@@ -884,6 +3567,33 @@ const docTemplate = `{
                 }
             }
         },
+        "models.NotifyChannel": {
+            "type": "object",
+            "properties": {
+                "created_at": {
+                    "type": "string"
+                },
+                "has_secret": {
+                    "type": "boolean"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "name": {
+                    "type": "string"
+                },
+                "secret": {
+                    "description": "Secret 签名密钥，接口返回时不包含，HasSecret 表示是否已设置",
+                    "type": "string"
+                },
+                "type": {
+                    "type": "string"
+                },
+                "url": {
+                    "type": "string"
+                }
+            }
+        },
         "models.PushConfig": {
             "type": "object",
             "properties": {
@@ -898,20 +3608,82 @@ const docTemplate = `{
                 }
             }
         },
+        "models.SourceHealth": {
+            "type": "object",
+            "properties": {
+                "checked_at": {
+                    "type": "string"
+                },
+                "crawl_run_id": {
+                    "type": "integer"
+                },
+                "degraded_at": {
+                    "type": "string"
+                },
+                "reason": {
+                    "type": "string"
+                },
+                "sample": {
+                    "description": "Sample 触发异常的原始响应开头部分，仅详情接口返回",
+                    "type": "string"
+                },
+                "source": {
+                    "type": "string"
+                },
+                "status": {
+                    "type": "string"
+                },
+                "web_page_id": {
+                    "type": "integer"
+                },
+                "web_page_name": {
+                    "type": "string"
+                }
+            }
+        },
         "models.SubscribeConfig": {
             "type": "object",
             "properties": {
+                "channels": {
+                    "description": "Channels 推送渠道名称，email 为订阅邮箱，其余为推送渠道表中的名称；为空时只发邮件",
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
                 "created_at": {
                     "type": "string"
                 },
                 "email": {
                     "type": "string"
                 },
+                "group_by": {
+                    "description": "GroupBy 邮件摘要的分组方式：source 按来源网页，keyword 按匹配的关键词，type 按公告类型，为空时不分组",
+                    "type": "string"
+                },
                 "id": {
                     "type": "integer"
                 },
+                "keywords": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
                 "push_time": {
                     "type": "string"
+                },
+                "types": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "web_page_ids": {
+                    "type": "array",
+                    "items": {
+                        "type": "integer"
+                    }
                 }
             }
         },
@@ -924,10 +3696,116 @@ const docTemplate = `{
                 "name": {
                     "type": "string"
                 },
+                "source": {
+                    "type": "string"
+                },
+                "source_params": {
+                    "type": "object",
+                    "additionalProperties": {
+                        "type": "string"
+                    }
+                },
+                "url": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.Webhook": {
+            "type": "object",
+            "properties": {
+                "created_at": {
+                    "type": "string"
+                },
+                "enabled": {
+                    "type": "boolean"
+                },
+                "events": {
+                    "description": "Events 订阅的事件：announcement.created、announcement.classified、announcement.updated、keyword.matched、crawl.failed",
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "has_secret": {
+                    "type": "boolean"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "keywords": {
+                    "description": "Keywords keyword.matched 事件的关键词表达式",
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "name": {
+                    "type": "string"
+                },
+                "secret": {
+                    "description": "Secret 签名密钥，只在创建时返回，HasSecret 表示是否已设置",
+                    "type": "string"
+                },
+                "types": {
+                    "description": "Types announcement.classified 事件只推送这些类型的公告，为空时推送所有已分类的公告",
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
                 "url": {
                     "type": "string"
                 }
             }
+        },
+        "models.WebhookDelivery": {
+            "type": "object",
+            "properties": {
+                "attempts": {
+                    "type": "integer"
+                },
+                "created_at": {
+                    "type": "string"
+                },
+                "delivered_at": {
+                    "type": "string"
+                },
+                "error": {
+                    "type": "string"
+                },
+                "event": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "next_attempt_at": {
+                    "type": "string"
+                },
+                "payload": {
+                    "description": "Payload 请求体，仅详情接口返回",
+                    "type": "object"
+                },
+                "replay_of": {
+                    "description": "ReplayOf 重放时为原投递记录的 ID",
+                    "type": "integer"
+                },
+                "response_body": {
+                    "type": "string"
+                },
+                "response_status": {
+                    "type": "integer"
+                },
+                "status": {
+                    "type": "string"
+                },
+                "webhook_id": {
+                    "type": "integer"
+                },
+                "webhook_name": {
+                    "type": "string"
+                }
+            }
         }
     }
 }`
@@ -935,7 +3813,7 @@ const docTemplate = `{
 // SwaggerInfo holds exported Swagger Info so clients can modify it
 var SwaggerInfo = &swag.Spec{
 	Version:          "1.0",
-	Host:             "localhost:8080",
+	Host:             "localhost:5080",
 	BasePath:         "/api",
 	Schemes:          []string{},
 	Title:            "政府采购网监控系统 API",
@@ -6,27 +6,109 @@ import (
 	"net/http"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/ieasydevops/demo-scrapy/internal/crawler"
 	"github.com/ieasydevops/demo-scrapy/internal/database"
+	"github.com/ieasydevops/demo-scrapy/internal/extract"
+	"github.com/ieasydevops/demo-scrapy/internal/keyword"
 	"github.com/ieasydevops/demo-scrapy/internal/models"
+	"github.com/ieasydevops/demo-scrapy/internal/scheduler"
 )
 
+// announcementColumns 列表和详情共用的查询列，与 scanAnnouncement 的顺序一致
+const announcementColumns = `
+	a.id, a.title, a.url, a.publish_date, a.content, a.created_at,
+	a.web_page_id, wp.name as web_page_name, a.publisher, a.type,
+	a.project_number, a.purchaser, a.agency, a.budget_amount, a.bid_deadline, a.opening_time, a.contact`
+
+// announcementSortColumns 允许的排序键
+var announcementSortColumns = map[string]string{
+	"created_at":   "a.created_at",
+	"publish_date": "a.publish_date",
+	"budget":       "a.budget_amount",
+	"deadline":     "a.bid_deadline",
+}
+
+// 检索结果的高亮标记和摘要长度
+const (
+	highlightOpen  = "<mark>"
+	highlightClose = "</mark>"
+	snippetRadius  = 40
+)
+
+// ftsColumns 关键词字段对应的全文索引列过滤器
+func ftsColumns(field string) string {
+	switch field {
+	case keyword.FieldTitle:
+		return "title"
+	case keyword.FieldContent:
+		return "{content body}"
+	}
+	return "{title content body}"
+}
+
+// likeColumns 关键词字段对应的公告列
+func likeColumns(field string) []string {
+	switch field {
+	case keyword.FieldTitle:
+		return []string{"a.title"}
+	case keyword.FieldContent:
+		return []string{"a.content", "a.body"}
+	}
+	return []string{"a.title", "a.content", "a.body"}
+}
+
+func scanAnnouncement(row interface{ Scan(...interface{}) error }, extra ...interface{}) (models.Announcement, error) {
+	var ann models.Announcement
+	var content, webPageName, publisher sql.NullString
+	var projectNumber, purchaser, agency, bidDeadline, openingTime, contact sql.NullString
+	var webPageID sql.NullInt64
+	var budget sql.NullFloat64
+	dest := []interface{}{&ann.ID, &ann.Title, &ann.URL, &ann.PublishDate, &content, &ann.CreatedAt,
+		&webPageID, &webPageName, &publisher, &ann.Type,
+		&projectNumber, &purchaser, &agency, &budget, &bidDeadline, &openingTime, &contact}
+	if err := row.Scan(append(dest, extra...)...); err != nil {
+		return ann, err
+	}
+	ann.Content = content.String
+	ann.WebPageID = int(webPageID.Int64)
+	ann.WebPageName = webPageName.String
+	ann.Publisher = publisher.String
+	ann.ProjectNumber = projectNumber.String
+	ann.Purchaser = purchaser.String
+	ann.Agency = agency.String
+	ann.BudgetAmount = budget.Float64
+	ann.BidDeadline = bidDeadline.String
+	ann.OpeningTime = openingTime.String
+	ann.Contact = contact.String
+	return ann, nil
+}
+
 // GetAnnouncements 获取公告列表
 // @Summary      获取公告列表
-// @Description  获取采购信息动态，支持时间排序和搜索
+// @Description  获取采购信息动态，支持全文检索、按抽取字段筛选和排序。keyword 支持关键词表达式（空格/AND、OR、-/NOT、"短语"、括号、title:/content:），检索时默认按相关度排序，结果附带高亮的 title_highlight 和 snippet
 // @Tags         采购信息动态
 // @Accept       json
 // @Produce      json
-// @Param        keyword  query     string  false  "搜索关键字"
-// @Param        order    query     string  false  "排序方式: desc(降序) 或 asc(升序)" default(desc)
-// @Param        page     query     int     false  "页码" default(1)
-// @Param        pageSize query     int     false  "每页数量" default(20)
+// @Param        keyword         query     string  false  "搜索关键字表达式"
+// @Param        type            query     string  false  "公告类型，多个用逗号分隔: tender、award、correction、cancellation、intention、contract、other"
+// @Param        purchaser       query     string  false  "采购人（模糊匹配）"
+// @Param        budget_min      query     number  false  "最低预算金额（元）"
+// @Param        budget_max      query     number  false  "最高预算金额（元）"
+// @Param        deadline_after  query     string  false  "投标截止时间不早于，格式 2006-01-02"
+// @Param        deadline_before query     string  false  "投标截止时间早于，格式 2006-01-02"
+// @Param        sort            query     string  false  "排序字段: relevance（仅检索时）、created_at、publish_date、budget、deadline，检索时默认 relevance，否则默认 created_at"
+// @Param        order           query     string  false  "排序方式: desc(降序) 或 asc(升序)" default(desc)
+// @Param        page            query     int     false  "页码" default(1)
+// @Param        pageSize        query     int     false  "每页数量" default(20)
 // @Success      200      {object}  map[string]interface{}
+// @Failure      400      {object}  map[string]string
 // @Failure      500      {object}  map[string]string
 // @Router       /announcements [get]
 func GetAnnouncements(c *gin.Context) {
-	keyword := c.Query("keyword")
+	search := strings.TrimSpace(c.Query("keyword"))
 	order := c.DefaultQuery("order", "desc")
 	page := c.DefaultQuery("page", "1")
 	pageSize := c.DefaultQuery("pageSize", "20")
@@ -34,40 +116,94 @@ func GetAnnouncements(c *gin.Context) {
 	if order != "asc" && order != "desc" {
 		order = "desc"
 	}
+	sortKey := c.Query("sort")
+	if sortKey == "" {
+		sortKey = "created_at"
+		if search != "" {
+			sortKey = "relevance"
+		}
+	}
+	sortColumn, ok := announcementSortColumns[sortKey]
+	if !ok && sortKey != "relevance" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "不支持的排序字段: " + sortKey})
+		return
+	}
 
-	var query strings.Builder
-	query.WriteString(`
-		SELECT a.id, a.title, a.url, a.publish_date, a.content, a.created_at,
-		       a.web_page_id, wp.name as web_page_name, a.publisher
-		FROM announcements a
-		LEFT JOIN web_pages wp ON a.web_page_id = wp.id
-		WHERE 1=1
-	`)
-
+	from := " FROM announcements a"
+	var where strings.Builder
+	where.WriteString(" WHERE 1=1")
 	args := []interface{}{}
-	if keyword != "" {
-		query.WriteString(" AND (a.title LIKE ? OR a.content LIKE ?)")
-		keywordPattern := "%" + keyword + "%"
-		args = append(args, keywordPattern, keywordPattern)
+	var terms []string
+	ranked := false
+	if search != "" {
+		expr, err := keyword.Parse(search)
+		if err != nil {
+			badRequest(c, err)
+			return
+		}
+		terms = keyword.Terms(expr)
+		// trigram 索引只能检索 3 个字符以上的词，其余表达式按 LIKE 逐行匹配
+		if match, ok := keyword.FTSQuery(expr, ftsColumns); ok {
+			from += " JOIN announcements_fts ON announcements_fts.rowid = a.id"
+			where.WriteString(" AND announcements_fts MATCH ?")
+			args = append(args, match)
+			ranked = true
+		} else {
+			cond, condArgs := keyword.SQLCondition(expr, likeColumns)
+			where.WriteString(" AND " + cond)
+			args = append(args, condArgs...)
+		}
+	}
+	if sortKey == "relevance" && !ranked {
+		sortColumn = announcementSortColumns["created_at"]
+	}
+	if types := scheduler.SplitKeywords(c.Query("type")); len(types) > 0 {
+		for _, t := range types {
+			if !extract.ValidType(t) {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "未知的公告类型: " + t})
+				return
+			}
+		}
+		where.WriteString(" AND a.type IN (?" + strings.Repeat(", ?", len(types)-1) + ")")
+		for _, t := range types {
+			args = append(args, t)
+		}
+	}
+	if purchaser := c.Query("purchaser"); purchaser != "" {
+		where.WriteString(" AND a.purchaser LIKE ? ESCAPE '\\'")
+		args = append(args, keyword.ContainsPattern(purchaser))
+	}
+	for _, f := range []struct{ param, cond string }{
+		{"budget_min", " AND a.budget_amount >= ?"},
+		{"budget_max", " AND a.budget_amount <= ?"},
+	} {
+		if v := c.Query(f.param); v != "" {
+			amount, err := strconv.ParseFloat(v, 64)
+			if err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": f.param + " 必须是数字"})
+				return
+			}
+			where.WriteString(f.cond)
+			args = append(args, amount)
+		}
+	}
+	for _, f := range []struct{ param, cond string }{
+		{"deadline_after", " AND a.bid_deadline >= ?"},
+		{"deadline_before", " AND a.bid_deadline != '' AND a.bid_deadline < ?"},
+	} {
+		if v := c.Query(f.param); v != "" {
+			if _, err := time.Parse("2006-01-02", v); err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": f.param + " 格式应为 2006-01-02"})
+				return
+			}
+			where.WriteString(f.cond)
+			args = append(args, v)
+		}
 	}
-
-	query.WriteString(" ORDER BY a.created_at " + strings.ToUpper(order))
 
 	var total int
-	countQuery := `
-		SELECT COUNT(*)
-		FROM announcements a
-		LEFT JOIN web_pages wp ON a.web_page_id = wp.id
-		WHERE 1=1
-	`
-	countArgs := []interface{}{}
-	if keyword != "" {
-		countQuery += " AND (a.title LIKE ? OR a.content LIKE ?)"
-		keywordPattern := "%" + keyword + "%"
-		countArgs = append(countArgs, keywordPattern, keywordPattern)
-	}
-	err := database.DB.QueryRow(countQuery, countArgs...).Scan(&total)
-	if err != nil {
+	countQuery := "SELECT COUNT(*)" + from + where.String()
+	if err := database.DB.QueryRow(countQuery, args...).Scan(&total); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
@@ -82,10 +218,23 @@ func GetAnnouncements(c *gin.Context) {
 	}
 
 	offset := (pageInt - 1) * pageSizeInt
-	query.WriteString(" LIMIT ? OFFSET ?")
-	args = append(args, pageSizeInt, offset)
-
-	rows, err := database.DB.Query(query.String(), args...)
+	var orderBy string
+	if sortKey == "relevance" && ranked {
+		// bm25 越小越相关，标题权重高于摘要和正文
+		direction := "ASC"
+		if order == "asc" {
+			direction = "DESC"
+		}
+		orderBy = " ORDER BY bm25(announcements_fts, 10.0, 2.0, 1.0) " + direction + ", a.id DESC"
+	} else {
+		// 未抽取到值的行始终排在最后
+		orderBy = " ORDER BY (" + sortColumn + " IS NULL OR " + sortColumn + " = ''), " +
+			sortColumn + " " + strings.ToUpper(order) + ", a.id " + strings.ToUpper(order)
+	}
+	query := "SELECT " + announcementColumns + ", COALESCE(a.body, '')" + from + `
+		LEFT JOIN web_pages wp ON a.web_page_id = wp.id` + where.String() + orderBy +
+		" LIMIT ? OFFSET ?"
+	rows, err := database.DB.Query(query, append(args, pageSizeInt, offset)...)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
@@ -94,24 +243,19 @@ func GetAnnouncements(c *gin.Context) {
 
 	var announcements []models.Announcement
 	for rows.Next() {
-		var ann models.Announcement
-		var webPageID sql.NullInt64
-		var webPageName sql.NullString
-		var publisher sql.NullString
-		err := rows.Scan(&ann.ID, &ann.Title, &ann.URL, &ann.PublishDate, &ann.Content,
-			&ann.CreatedAt, &webPageID, &webPageName, &publisher)
+		var body string
+		ann, err := scanAnnouncement(rows, &body)
 		if err != nil {
 			c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("扫描数据失败: %v", err)})
 			return
 		}
-		if webPageID.Valid {
-			ann.WebPageID = int(webPageID.Int64)
-		}
-		if webPageName.Valid {
-			ann.WebPageName = webPageName.String
-		}
-		if publisher.Valid {
-			ann.Publisher = publisher.String
+		if len(terms) > 0 {
+			text := body
+			if text == "" {
+				text = ann.Content
+			}
+			ann.TitleHighlight = keyword.Highlight(ann.Title, terms, highlightOpen, highlightClose)
+			ann.Snippet = keyword.Snippet(text, terms, snippetRadius, highlightOpen, highlightClose, "…")
 		}
 		announcements = append(announcements, ann)
 	}
@@ -129,3 +273,82 @@ func GetAnnouncements(c *gin.Context) {
 		"total_page": (total + pageSizeInt - 1) / pageSizeInt,
 	})
 }
+
+// GetAnnouncementTypes 获取公告类型列表
+// @Summary      获取公告类型列表
+// @Description  获取公告类型枚举及中文名称，用于列表筛选和订阅配置
+// @Tags         采购信息动态
+// @Produce      json
+// @Success      200 {array} extract.TypeInfo
+// @Router       /announcement-types [get]
+func GetAnnouncementTypes(c *gin.Context) {
+	c.JSON(http.StatusOK, extract.Types())
+}
+
+// GetAnnouncement 获取公告详情
+// @Summary      获取公告详情
+// @Description  获取单条公告，包含详情页正文、抽取字段和附件列表
+// @Tags         采购信息动态
+// @Accept       json
+// @Produce      json
+// @Param        id  path      int  true  "公告ID"
+// @Success      200 {object}  models.Announcement
+// @Failure      404 {object}  map[string]string
+// @Failure      500 {object}  map[string]string
+// @Router       /announcements/{id} [get]
+func GetAnnouncement(c *gin.Context) {
+	id, _ := strconv.Atoi(c.Param("id"))
+
+	var body sql.NullString
+	row := database.DB.QueryRow("SELECT "+announcementColumns+`, a.body
+		FROM announcements a
+		LEFT JOIN web_pages wp ON a.web_page_id = wp.id
+		WHERE a.id = ?`, id)
+	ann, err := scanAnnouncement(row, &body)
+	if err == sql.ErrNoRows {
+		c.JSON(http.StatusNotFound, gin.H{"error": "公告不存在"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	ann.Body = body.String
+
+	ann.Attachments, err = crawler.GetAttachments(ann.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, ann)
+}
+
+// DownloadAttachment 下载附件
+// @Summary      下载附件
+// @Description  返回本地保存的附件文件，未下载到本地时重定向到原始地址
+// @Tags         采购信息动态
+// @Produce      octet-stream
+// @Param        id  path      int  true  "附件ID"
+// @Success      200 {file}    file
+// @Failure      404 {object}  map[string]string
+// @Router       /attachments/{id}/download [get]
+func DownloadAttachment(c *gin.Context) {
+	id, _ := strconv.Atoi(c.Param("id"))
+	att, err := crawler.GetAttachment(id)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "附件不存在"})
+		return
+	}
+
+	if !att.Downloaded {
+		c.Redirect(http.StatusFound, att.URL)
+		return
+	}
+
+	name := att.Name
+	if !strings.HasSuffix(strings.ToLower(name), att.Ext) {
+		name += att.Ext
+	}
+	c.FileAttachment(att.LocalPath, name)
+}
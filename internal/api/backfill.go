@@ -0,0 +1,195 @@
+package api
+
+import (
+	"database/sql"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/ieasydevops/demo-scrapy/internal/backfill"
+	"github.com/ieasydevops/demo-scrapy/internal/scheduler"
+)
+
+// CreateBackfillJob 创建历史回填任务
+// @Summary      创建历史回填任务
+// @Description  按天回溯采集 start_date 到 end_date（含）的历史公告并立即在后台执行，进度按天记录，服务重启后自动继续。
+// @Description  monitor_config_id 和 web_page_id 二选一；keywords 为空时使用监控配置的关键词（按网页回填时使用关键词表）。回填入库的公告不会出现在订阅摘要中。
+// @Description  end_date 不能晚于网页上定时采集的时间窗口开始的前一天（daily 为前天或更早，weekly 等执行间隔较长时更早），为空时取这一天
+// @Tags         历史回填
+// @Accept       json
+// @Produce      json
+// @Param        request  body      object  true  "回填目标、关键词和日期范围"
+// @Success      202      {object}  map[string]interface{}
+// @Failure      400      {object}  map[string]string
+// @Failure      404      {object}  map[string]string
+// @Failure      503      {object}  map[string]string
+// @Router       /backfill-jobs [post]
+func CreateBackfillJob(c *gin.Context) {
+	var req struct {
+		MonitorConfigID int      `json:"monitor_config_id"`
+		WebPageID       int      `json:"web_page_id"`
+		Keywords        []string `json:"keywords"`
+		StartDate       string   `json:"start_date" binding:"required"`
+		EndDate         string   `json:"end_date"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if (req.MonitorConfigID > 0) == (req.WebPageID > 0) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "monitor_config_id 和 web_page_id 需且只能指定一个"})
+		return
+	}
+	if err := validateKeywords(req.Keywords...); err != nil {
+		badRequest(c, err)
+		return
+	}
+	start, end, err := backfill.ParseRange(req.StartDate, req.EndDate)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	id, err := scheduler.CreateBackfill(scheduler.BackfillRequest{
+		MonitorConfigID: req.MonitorConfigID,
+		WebPageID:       req.WebPageID,
+		Keywords:        req.Keywords,
+		StartDate:       start,
+		EndDate:         end,
+	})
+	if errors.Is(err, sql.ErrNoRows) {
+		if req.MonitorConfigID > 0 {
+			c.JSON(http.StatusNotFound, gin.H{"error": "监控配置不存在"})
+		} else {
+			c.JSON(http.StatusNotFound, gin.H{"error": "网页不存在"})
+		}
+		return
+	}
+	if errors.Is(err, scheduler.ErrBackfillRange) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	if _, err := scheduler.StartBackfill(id); err != nil {
+		backfillError(c, err)
+		return
+	}
+	c.JSON(http.StatusAccepted, gin.H{"id": id})
+}
+
+// GetBackfillJobs 获取历史回填任务
+// @Summary      获取历史回填任务
+// @Description  分页获取回填任务及其进度（cursor 为下一个待采集的日期），按创建时间倒序
+// @Tags         历史回填
+// @Produce      json
+// @Param        page     query     int  false  "页码" default(1)
+// @Param        pageSize query     int  false  "每页数量" default(20)
+// @Success      200      {object}  map[string]interface{}
+// @Failure      500      {object}  map[string]string
+// @Router       /backfill-jobs [get]
+func GetBackfillJobs(c *gin.Context) {
+	pageInt := 1
+	pageSizeInt := 20
+	if p, err := strconv.Atoi(c.DefaultQuery("page", "1")); err == nil && p > 0 {
+		pageInt = p
+	}
+	if ps, err := strconv.Atoi(c.DefaultQuery("pageSize", "20")); err == nil && ps > 0 {
+		pageSizeInt = ps
+	}
+
+	jobs, total, err := backfill.List(pageInt, pageSizeInt)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data":       jobs,
+		"total":      total,
+		"page":       pageInt,
+		"page_size":  pageSizeInt,
+		"total_page": (total + pageSizeInt - 1) / pageSizeInt,
+	})
+}
+
+// GetBackfillJob 获取历史回填任务详情
+// @Summary      获取历史回填任务详情
+// @Description  获取单个回填任务的状态和进度，每天的采集结果可通过 GET /crawl-runs?backfill_job_id= 查看
+// @Tags         历史回填
+// @Produce      json
+// @Param        id  path      int  true  "回填任务ID"
+// @Success      200 {object}  models.BackfillJob
+// @Failure      404 {object}  map[string]string
+// @Failure      500 {object}  map[string]string
+// @Router       /backfill-jobs/{id} [get]
+func GetBackfillJob(c *gin.Context) {
+	id, _ := strconv.Atoi(c.Param("id"))
+	job, err := backfill.Get(id)
+	if err == sql.ErrNoRows {
+		c.JSON(http.StatusNotFound, gin.H{"error": "回填任务不存在"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, job)
+}
+
+// CancelBackfillJob 取消历史回填任务
+// @Summary      取消历史回填任务
+// @Description  取消回填任务，执行中的任务在当前一天采集完成后停止，之后可以继续
+// @Tags         历史回填
+// @Produce      json
+// @Param        id  path      int  true  "回填任务ID"
+// @Success      200 {object}  map[string]string
+// @Failure      404 {object}  map[string]string
+// @Failure      409 {object}  map[string]string
+// @Router       /backfill-jobs/{id}/cancel [post]
+func CancelBackfillJob(c *gin.Context) {
+	id, _ := strconv.Atoi(c.Param("id"))
+	if err := scheduler.CancelBackfill(id); err != nil {
+		backfillError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "cancelled"})
+}
+
+// ResumeBackfillJob 继续历史回填任务
+// @Summary      继续历史回填任务
+// @Description  从进度处继续失败或已取消的回填任务
+// @Tags         历史回填
+// @Produce      json
+// @Param        id  path      int  true  "回填任务ID"
+// @Success      202 {object}  map[string]interface{}
+// @Failure      404 {object}  map[string]string
+// @Failure      409 {object}  map[string]string
+// @Failure      503 {object}  map[string]string
+// @Router       /backfill-jobs/{id}/resume [post]
+func ResumeBackfillJob(c *gin.Context) {
+	id, _ := strconv.Atoi(c.Param("id"))
+	started, err := scheduler.StartBackfill(id)
+	if err != nil {
+		backfillError(c, err)
+		return
+	}
+	c.JSON(http.StatusAccepted, gin.H{"id": id, "already_running": !started})
+}
+
+func backfillError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		c.JSON(http.StatusNotFound, gin.H{"error": "回填任务不存在"})
+	case errors.Is(err, scheduler.ErrBackfillDone):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	case errors.Is(err, scheduler.ErrNotRunning):
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	}
+}
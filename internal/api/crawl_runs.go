@@ -0,0 +1,221 @@
+package api
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/ieasydevops/demo-scrapy/internal/crawlrun"
+	"github.com/ieasydevops/demo-scrapy/internal/database"
+	"github.com/ieasydevops/demo-scrapy/internal/scheduler"
+)
+
+// TriggerCrawl 手动触发采集
+// @Summary      手动触发采集
+// @Description  立即在后台按监控配置或网页执行一次采集，返回可轮询的采集记录 ID。monitor_config_id 和 web_page_id 二选一，按网页采集时使用关键词表。
+// @Description  start_time/end_time 格式为 2006-01-02 或 2006-01-02 15:04:05，只写日期的 end_time 包含当天；未设置时按采集频率回溯。
+// @Description  同一监控配置（或网页）已有采集在执行时不会重复执行，返回执行中的记录 ID，coalesced 为 true
+// @Tags         采集记录
+// @Accept       json
+// @Produce      json
+// @Param        request  body      object  true  "采集目标和时间窗口"
+// @Success      202      {object}  map[string]interface{}
+// @Failure      400      {object}  map[string]string
+// @Failure      404      {object}  map[string]string
+// @Failure      503      {object}  map[string]string
+// @Router       /crawl-runs [post]
+func TriggerCrawl(c *gin.Context) {
+	var req struct {
+		MonitorConfigID int    `json:"monitor_config_id"`
+		WebPageID       int    `json:"web_page_id"`
+		StartTime       string `json:"start_time"`
+		EndTime         string `json:"end_time"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if (req.MonitorConfigID > 0) == (req.WebPageID > 0) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "monitor_config_id 和 web_page_id 需且只能指定一个"})
+		return
+	}
+
+	crawlReq := scheduler.CrawlRequest{MonitorConfigID: req.MonitorConfigID, WebPageID: req.WebPageID}
+	var err error
+	if crawlReq.StartTime, err = parseWindowTime(req.StartTime, false); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "start_time " + err.Error()})
+		return
+	}
+	if crawlReq.EndTime, err = parseWindowTime(req.EndTime, true); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "end_time " + err.Error()})
+		return
+	}
+	if !crawlReq.StartTime.IsZero() && !crawlReq.EndTime.IsZero() && !crawlReq.StartTime.Before(crawlReq.EndTime) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "start_time 应早于 end_time"})
+		return
+	}
+	if !crawlReq.StartTime.IsZero() && crawlReq.EndTime.IsZero() && crawlReq.StartTime.After(time.Now()) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "start_time 不能晚于当前时间"})
+		return
+	}
+
+	runID, coalesced, err := scheduler.TriggerCrawl(crawlReq)
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		if req.MonitorConfigID > 0 {
+			c.JSON(http.StatusNotFound, gin.H{"error": "监控配置不存在"})
+		} else {
+			c.JSON(http.StatusNotFound, gin.H{"error": "网页不存在"})
+		}
+		return
+	case errors.Is(err, scheduler.ErrNotRunning):
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": err.Error()})
+		return
+	case err != nil:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusAccepted, gin.H{"run_id": runID, "coalesced": coalesced})
+}
+
+// parseWindowTime 解析采集窗口时间，endOfDay 为 true 时只写日期的值取次日零点
+func parseWindowTime(s string, endOfDay bool) (time.Time, error) {
+	if s == "" {
+		return time.Time{}, nil
+	}
+	if t, err := time.ParseInLocation("2006-01-02 15:04:05", s, time.Local); err == nil {
+		return t, nil
+	}
+	t, err := time.ParseInLocation("2006-01-02", s, time.Local)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("格式应为 2006-01-02 或 2006-01-02 15:04:05")
+	}
+	if endOfDay {
+		t = t.AddDate(0, 0, 1)
+	}
+	return t, nil
+}
+
+// GetCrawlRuns 获取采集记录
+// @Summary      获取采集记录
+// @Description  分页获取采集执行记录，包含触发方式、时间窗口、耗时、各阶段计数和错误信息，按开始时间倒序
+// @Tags         采集记录
+// @Accept       json
+// @Produce      json
+// @Param        monitor_config_id query     int     false  "监控配置ID"
+// @Param        status            query     string  false  "状态 running/success/failed"
+// @Param        trigger           query     string  false  "触发方式 schedule/startup/manual/backfill"
+// @Param        backfill_job_id   query     int     false  "回填任务ID"
+// @Param        page              query     int     false  "页码" default(1)
+// @Param        pageSize          query     int     false  "每页数量" default(20)
+// @Success      200               {object}  map[string]interface{}
+// @Failure      400               {object}  map[string]string
+// @Failure      500               {object}  map[string]string
+// @Router       /crawl-runs [get]
+func GetCrawlRuns(c *gin.Context) {
+	pageInt := 1
+	pageSizeInt := 20
+	if p, err := strconv.Atoi(c.DefaultQuery("page", "1")); err == nil && p > 0 {
+		pageInt = p
+	}
+	if ps, err := strconv.Atoi(c.DefaultQuery("pageSize", "20")); err == nil && ps > 0 {
+		pageSizeInt = ps
+	}
+
+	var filter crawlrun.Filter
+	if v := c.Query("monitor_config_id"); v != "" {
+		id, err := strconv.Atoi(v)
+		if err != nil || id <= 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "monitor_config_id 无效"})
+			return
+		}
+		filter.MonitorConfigID = id
+	}
+	if v := c.Query("backfill_job_id"); v != "" {
+		id, err := strconv.Atoi(v)
+		if err != nil || id <= 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "backfill_job_id 无效"})
+			return
+		}
+		filter.BackfillJobID = id
+	}
+	switch trigger := c.Query("trigger"); trigger {
+	case "", crawlrun.TriggerSchedule, crawlrun.TriggerStartup, crawlrun.TriggerManual, crawlrun.TriggerBackfill:
+		filter.Trigger = trigger
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "trigger 应为 schedule、startup、manual 或 backfill"})
+		return
+	}
+	switch status := c.Query("status"); status {
+	case "", crawlrun.StatusRunning, crawlrun.StatusSuccess, crawlrun.StatusFailed:
+		filter.Status = status
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "status 应为 running、success 或 failed"})
+		return
+	}
+
+	runs, total, err := crawlrun.List(filter, pageInt, pageSizeInt)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data":       runs,
+		"total":      total,
+		"page":       pageInt,
+		"page_size":  pageSizeInt,
+		"total_page": (total + pageSizeInt - 1) / pageSizeInt,
+	})
+}
+
+// GetCrawlRun 获取采集记录详情
+// @Summary      获取采集记录详情
+// @Description  获取单次采集的执行记录及本次新增的公告
+// @Tags         采集记录
+// @Accept       json
+// @Produce      json
+// @Param        id  path      int  true  "采集记录ID"
+// @Success      200 {object}  models.CrawlRun
+// @Failure      404 {object}  map[string]string
+// @Failure      500 {object}  map[string]string
+// @Router       /crawl-runs/{id} [get]
+func GetCrawlRun(c *gin.Context) {
+	id, _ := strconv.Atoi(c.Param("id"))
+	run, err := crawlrun.Get(id)
+	if err == sql.ErrNoRows {
+		c.JSON(http.StatusNotFound, gin.H{"error": "采集记录不存在"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	rows, err := database.DB.Query("SELECT "+announcementColumns+`
+		FROM announcements a
+		LEFT JOIN web_pages wp ON a.web_page_id = wp.id
+		WHERE a.crawl_run_id = ?
+		ORDER BY a.id`, id)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		ann, err := scanAnnouncement(rows)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		run.Announcements = append(run.Announcements, ann)
+	}
+
+	c.JSON(http.StatusOK, run)
+}
@@ -0,0 +1,101 @@
+package api
+
+import (
+	"database/sql"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/ieasydevops/demo-scrapy/internal/digestrun"
+	"github.com/ieasydevops/demo-scrapy/internal/scheduler"
+)
+
+// TriggerDigest 手动触发摘要推送
+// @Summary      手动触发摘要推送
+// @Description  立即在后台为订阅者生成并发送一次摘要，返回可轮询的推送记录 ID。该订阅者已有推送在执行时不会重复发送，返回执行中的记录 ID，coalesced 为 true
+// @Tags         订阅配置管理
+// @Produce      json
+// @Param        id   path      int  true  "配置ID"
+// @Success      202  {object}  map[string]interface{}
+// @Failure      404  {object}  map[string]string
+// @Failure      503  {object}  map[string]string
+// @Router       /subscribe-config/{id}/digest [post]
+func TriggerDigest(c *gin.Context) {
+	id, _ := strconv.Atoi(c.Param("id"))
+	runID, coalesced, err := scheduler.TriggerDigest(id)
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		c.JSON(http.StatusNotFound, gin.H{"error": "订阅配置不存在"})
+		return
+	case errors.Is(err, scheduler.ErrNotRunning):
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": err.Error()})
+		return
+	case err != nil:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusAccepted, gin.H{"run_id": runID, "coalesced": coalesced})
+}
+
+// GetDigestRuns 获取摘要推送记录
+// @Summary      获取摘要推送记录
+// @Description  分页获取摘要推送的执行记录，包含触发方式、状态、待推送和实际发送条数、错误信息，按开始时间倒序
+// @Tags         订阅配置管理
+// @Produce      json
+// @Param        subscriber_id query     int  false  "订阅配置ID"
+// @Param        page          query     int  false  "页码" default(1)
+// @Param        pageSize      query     int  false  "每页数量" default(20)
+// @Success      200           {object}  map[string]interface{}
+// @Failure      500           {object}  map[string]string
+// @Router       /digest-runs [get]
+func GetDigestRuns(c *gin.Context) {
+	pageInt := 1
+	pageSizeInt := 20
+	if p, err := strconv.Atoi(c.DefaultQuery("page", "1")); err == nil && p > 0 {
+		pageInt = p
+	}
+	if ps, err := strconv.Atoi(c.DefaultQuery("pageSize", "20")); err == nil && ps > 0 {
+		pageSizeInt = ps
+	}
+	subscriberID, _ := strconv.Atoi(c.Query("subscriber_id"))
+
+	runs, total, err := digestrun.List(subscriberID, pageInt, pageSizeInt)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data":       runs,
+		"total":      total,
+		"page":       pageInt,
+		"page_size":  pageSizeInt,
+		"total_page": (total + pageSizeInt - 1) / pageSizeInt,
+	})
+}
+
+// GetDigestRun 获取摘要推送记录详情
+// @Summary      获取摘要推送记录详情
+// @Description  获取单次摘要推送的执行记录，用于轮询手动触发的推送
+// @Tags         订阅配置管理
+// @Produce      json
+// @Param        id  path      int  true  "推送记录ID"
+// @Success      200 {object}  models.DigestRun
+// @Failure      404 {object}  map[string]string
+// @Failure      500 {object}  map[string]string
+// @Router       /digest-runs/{id} [get]
+func GetDigestRun(c *gin.Context) {
+	id, _ := strconv.Atoi(c.Param("id"))
+	run, err := digestrun.Get(id)
+	if err == sql.ErrNoRows {
+		c.JSON(http.StatusNotFound, gin.H{"error": "推送记录不存在"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, run)
+}
@@ -0,0 +1,215 @@
+package api
+
+import (
+	"database/sql"
+	"errors"
+	"net/http"
+	"net/mail"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/ieasydevops/demo-scrapy/internal/email"
+	"github.com/ieasydevops/demo-scrapy/internal/models"
+)
+
+type emailTestRequest struct {
+	To string `json:"to" binding:"required"`
+}
+
+// TestEmail 发送测试邮件
+// @Summary      发送测试邮件
+// @Description  按当前 SMTP 配置建立新连接发送一封测试邮件，返回 SMTP 会话记录（发送的命令和服务器的错误应答，不含密码），用于检查服务器、端口、加密方式和账号。
+// @Description  失败时返回 502，code 为服务器的应答码（连接、TLS 等错误为 0）
+// @Tags         邮件
+// @Accept       json
+// @Produce      json
+// @Param        request  body      emailTestRequest  true  "收件人"
+// @Success      200      {object}  map[string]interface{}
+// @Failure      400      {object}  map[string]string
+// @Failure      502      {object}  map[string]interface{}
+// @Router       /email/test [post]
+func TestEmail(c *gin.Context) {
+	var req emailTestRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if _, err := mail.ParseAddress(req.To); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "收件人邮箱无效: " + req.To})
+		return
+	}
+
+	transcript, err := email.Test(req.To)
+	if err != nil {
+		var smtpErr *email.Error
+		if errors.As(err, &smtpErr) {
+			c.JSON(http.StatusBadGateway, gin.H{
+				"error":      err.Error(),
+				"command":    smtpErr.Command,
+				"code":       smtpErr.Code,
+				"transcript": smtpErr.Transcript,
+			})
+			return
+		}
+		c.JSON(http.StatusBadGateway, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "sent", "transcript": transcript})
+}
+
+// GetEmailOutbox 获取发件箱
+// @Summary      获取发件箱
+// @Description  分页获取摘要和告警邮件的发送状态，包含发送次数、下次重试时间、最近一次失败的 SMTP 应答码和错误，按创建时间倒序。
+// @Description  status 为 pending（待发送或等待重试）、sent 或 failed（重试用尽或被永久拒收），kind 为 digest 或 alert
+// @Tags         邮件
+// @Produce      json
+// @Param        status     query     string  false  "发送状态"
+// @Param        recipient  query     string  false  "收件人"
+// @Param        kind       query     string  false  "邮件类型"
+// @Param        page       query     int     false  "页码" default(1)
+// @Param        pageSize   query     int     false  "每页数量" default(20)
+// @Success      200        {object}  map[string]interface{}
+// @Failure      500        {object}  map[string]string
+// @Router       /email/outbox [get]
+func GetEmailOutbox(c *gin.Context) {
+	pageInt := 1
+	pageSizeInt := 20
+	if p, err := strconv.Atoi(c.DefaultQuery("page", "1")); err == nil && p > 0 {
+		pageInt = p
+	}
+	if ps, err := strconv.Atoi(c.DefaultQuery("pageSize", "20")); err == nil && ps > 0 {
+		pageSizeInt = ps
+	}
+	filter := email.OutboxFilter{Status: c.Query("status"), Recipient: c.Query("recipient"), Kind: c.Query("kind")}
+
+	messages, total, err := email.Outbox(filter, pageInt, pageSizeInt)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if messages == nil {
+		messages = []models.EmailMessage{}
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data":       messages,
+		"total":      total,
+		"page":       pageInt,
+		"page_size":  pageSizeInt,
+		"total_page": (total + pageSizeInt - 1) / pageSizeInt,
+	})
+}
+
+// GetEmailMessage 获取发件箱中的邮件
+// @Summary      获取发件箱中的邮件
+// @Description  获取邮件的发送状态和正文
+// @Tags         邮件
+// @Produce      json
+// @Param        id  path      int  true  "邮件ID"
+// @Success      200 {object}  models.EmailMessage
+// @Failure      404 {object}  map[string]string
+// @Failure      500 {object}  map[string]string
+// @Router       /email/outbox/{id} [get]
+func GetEmailMessage(c *gin.Context) {
+	id, _ := strconv.Atoi(c.Param("id"))
+	m, err := email.GetMessage(id)
+	if err == sql.ErrNoRows {
+		c.JSON(http.StatusNotFound, gin.H{"error": "邮件不存在"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, m)
+}
+
+// ResendEmail 重新发送失败的邮件
+// @Summary      重新发送失败的邮件
+// @Description  把失败的邮件重新放入发件箱并立即发送，发送次数清零，同时解除收件人的拒收标记
+// @Tags         邮件
+// @Produce      json
+// @Param        id  path      int  true  "邮件ID"
+// @Success      202 {object}  map[string]string
+// @Failure      404 {object}  map[string]string
+// @Failure      409 {object}  map[string]string
+// @Failure      500 {object}  map[string]string
+// @Router       /email/outbox/{id}/resend [post]
+func ResendEmail(c *gin.Context) {
+	id, _ := strconv.Atoi(c.Param("id"))
+	err := email.Resend(id)
+	if err == sql.ErrNoRows {
+		c.JSON(http.StatusNotFound, gin.H{"error": "邮件不存在"})
+		return
+	}
+	if err == email.ErrNotFailed {
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusAccepted, gin.H{"message": "queued"})
+}
+
+// ResendFailedEmails 重新发送所有失败的邮件
+// @Summary      重新发送所有失败的邮件
+// @Description  把所有失败的邮件（指定 recipient 时只处理该收件人）重新放入发件箱，用于 SMTP 服务恢复后补发，返回重新发送的封数
+// @Tags         邮件
+// @Produce      json
+// @Param        recipient  query     string  false  "收件人"
+// @Success      202        {object}  map[string]int
+// @Failure      500        {object}  map[string]string
+// @Router       /email/outbox/resend [post]
+func ResendFailedEmails(c *gin.Context) {
+	n, err := email.ResendFailed(c.Query("recipient"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusAccepted, gin.H{"count": n})
+}
+
+// GetFlaggedRecipients 获取被拒收的收件人
+// @Summary      获取被拒收的收件人
+// @Description  SMTP 服务器对收件人返回 5xx（如邮箱不存在）时标记该收件人，之后发往该地址的邮件直接标记为失败而不发送
+// @Tags         邮件
+// @Produce      json
+// @Success      200 {array}   models.FlaggedRecipient
+// @Failure      500 {object}  map[string]string
+// @Router       /email/flagged-recipients [get]
+func GetFlaggedRecipients(c *gin.Context) {
+	list, err := email.FlaggedRecipients()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if list == nil {
+		list = []models.FlaggedRecipient{}
+	}
+	c.JSON(http.StatusOK, list)
+}
+
+// UnflagRecipient 解除收件人的拒收标记
+// @Summary      解除收件人的拒收标记
+// @Description  之后写入发件箱的邮件正常发送，已失败的邮件需要重新发送
+// @Tags         邮件
+// @Produce      json
+// @Param        recipient  path      string  true  "收件人邮箱"
+// @Success      200        {object}  map[string]string
+// @Failure      404        {object}  map[string]string
+// @Failure      500        {object}  map[string]string
+// @Router       /email/flagged-recipients/{recipient} [delete]
+func UnflagRecipient(c *gin.Context) {
+	err := email.Unflag(c.Param("recipient"))
+	if err == sql.ErrNoRows {
+		c.JSON(http.StatusNotFound, gin.H{"error": "收件人未被标记"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "deleted"})
+}
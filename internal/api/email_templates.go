@@ -0,0 +1,144 @@
+package api
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/ieasydevops/demo-scrapy/internal/email"
+	"github.com/ieasydevops/demo-scrapy/internal/notify"
+	"github.com/ieasydevops/demo-scrapy/internal/scheduler"
+)
+
+// GetEmailTemplate 获取摘要邮件模板
+// @Summary      获取摘要邮件模板
+// @Description  stored 为数据库中保存的模板（为空的部分不覆盖），default 为模板目录（email.template_dir）与内置模板合并的结果。
+// @Description  主题和纯文本正文使用 text/template，HTML 正文使用 html/template（标题、链接等自动转义），数据字段见 email.Digest
+// @Tags         邮件模板
+// @Produce      json
+// @Success      200 {object}  map[string]interface{}
+// @Failure      500 {object}  map[string]string
+// @Router       /email-templates/digest [get]
+func GetEmailTemplate(c *gin.Context) {
+	stored, updatedAt, err := email.StoredTemplate()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"stored":     stored,
+		"default":    email.DefaultTemplate(),
+		"updated_at": updatedAt,
+	})
+}
+
+// UpdateEmailTemplate 保存摘要邮件模板
+// @Summary      保存摘要邮件模板
+// @Description  保存到数据库，之后发送的摘要立即使用。subject、text、html 为空的部分使用模板目录或内置模板。保存前以示例数据试渲染，模板有误时返回 400
+// @Tags         邮件模板
+// @Accept       json
+// @Produce      json
+// @Param        template  body      email.Template  true  "摘要邮件模板"
+// @Success      200       {object}  email.Template
+// @Failure      400       {object}  map[string]string
+// @Failure      500       {object}  map[string]string
+// @Router       /email-templates/digest [put]
+func UpdateEmailTemplate(c *gin.Context) {
+	var t email.Template
+	if err := c.ShouldBindJSON(&t); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err := t.Validate(); err != nil {
+		badRequest(c, err)
+		return
+	}
+	if err := email.SaveTemplate(t); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, t)
+}
+
+// DeleteEmailTemplate 恢复默认摘要邮件模板
+// @Summary      恢复默认摘要邮件模板
+// @Description  删除数据库中保存的模板，恢复使用模板目录或内置模板
+// @Tags         邮件模板
+// @Produce      json
+// @Success      200 {object}  map[string]string
+// @Failure      500 {object}  map[string]string
+// @Router       /email-templates/digest [delete]
+func DeleteEmailTemplate(c *gin.Context) {
+	if err := email.DeleteTemplate(); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "deleted"})
+}
+
+// PreviewDigest 预览摘要邮件
+// @Summary      预览摘要邮件
+// @Description  按订阅的过滤条件和分组方式渲染下一次邮件摘要，不发送也不记录推送。包含尚未通过邮件推送给该订阅者的公告，没有待推送公告时渲染空摘要。
+// @Description  format 为 html 或 text 时直接返回对应正文，否则返回 JSON。POST 时可在请求体中传入尚未保存的模板（为空的部分使用当前模板）
+// @Tags         邮件模板
+// @Accept       json
+// @Produce      json
+// @Param        id        path      int             true   "订阅配置ID"
+// @Param        format    query     string          false  "html、text 或 json" default(json)
+// @Param        template  body      email.Template  false  "预览使用的模板（仅 POST）"
+// @Success      200       {object}  map[string]interface{}
+// @Failure      400       {object}  map[string]string
+// @Failure      404       {object}  map[string]string
+// @Failure      500       {object}  map[string]string
+// @Router       /subscribe-config/{id}/digest/preview [get]
+// @Router       /subscribe-config/{id}/digest/preview [post]
+func PreviewDigest(c *gin.Context) {
+	id, _ := strconv.Atoi(c.Param("id"))
+	sub, err := scheduler.LoadSubscriber(id)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "订阅配置不存在"})
+		return
+	}
+
+	t, err := email.CurrentTemplate()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if c.Request.Method == http.MethodPost && c.Request.ContentLength != 0 {
+		var draft email.Template
+		if err := c.ShouldBindJSON(&draft); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		t = draft.Merge(t)
+	}
+
+	announcements, err := scheduler.PendingForSubscriber(sub, notify.ChannelEmail)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	rendered, err := t.Render(email.NewDigest(email.DigestRequest{
+		To: sub.Email, Announcements: announcements, Keywords: sub.Keywords, GroupBy: sub.GroupBy,
+	}))
+	if err != nil {
+		badRequest(c, err)
+		return
+	}
+
+	switch c.Query("format") {
+	case "html":
+		c.Data(http.StatusOK, "text/html; charset=utf-8", []byte(rendered.HTML))
+	case "text":
+		c.Data(http.StatusOK, "text/plain; charset=utf-8", []byte(rendered.Text))
+	default:
+		c.JSON(http.StatusOK, gin.H{
+			"to":      sub.Email,
+			"count":   len(announcements),
+			"subject": rendered.Subject,
+			"text":    rendered.Text,
+			"html":    rendered.HTML,
+		})
+	}
+}
@@ -1,11 +1,18 @@
 package api
 
 import (
+	"context"
+	"encoding/json"
+	"errors"
 	"net/http"
 	"strconv"
+	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/ieasydevops/demo-scrapy/internal/crawler"
 	"github.com/ieasydevops/demo-scrapy/internal/database"
+	"github.com/ieasydevops/demo-scrapy/internal/fetch"
+	"github.com/ieasydevops/demo-scrapy/internal/keyword"
 	"github.com/ieasydevops/demo-scrapy/internal/models"
 	"github.com/ieasydevops/demo-scrapy/internal/scheduler"
 )
@@ -20,28 +27,18 @@ import (
 // @Failure      500  {object}  map[string]string
 // @Router       /web-pages [get]
 func GetWebPages(c *gin.Context) {
-	rows, err := database.DB.Query("SELECT id, url, name FROM web_pages")
+	pages, err := crawler.GetWebPages()
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
-	defer rows.Close()
-
-	var pages []models.WebPage
-	for rows.Next() {
-		var page models.WebPage
-		if err := rows.Scan(&page.ID, &page.URL, &page.Name); err != nil {
-			continue
-		}
-		pages = append(pages, page)
-	}
 
 	c.JSON(http.StatusOK, pages)
 }
 
 // CreateWebPage 创建网页
 // @Summary      创建网页
-// @Description  添加一个新的监控网页
+// @Description  添加一个新的监控网页，source 为数据源适配器名称（见 /sources），source_params 为适配器参数
 // @Tags         网页管理
 // @Accept       json
 // @Produce      json
@@ -57,7 +54,14 @@ func CreateWebPage(c *gin.Context) {
 		return
 	}
 
-	result, err := database.DB.Exec("INSERT INTO web_pages (url, name) VALUES (?, ?)", page.URL, page.Name)
+	params, err := validateWebPageSource(&page)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	result, err := database.DB.Exec("INSERT INTO web_pages (url, name, source, source_params) VALUES (?, ?, ?, ?)",
+		page.URL, page.Name, page.Source, params)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
@@ -89,7 +93,14 @@ func UpdateWebPage(c *gin.Context) {
 		return
 	}
 
-	_, err := database.DB.Exec("UPDATE web_pages SET url = ?, name = ? WHERE id = ?", page.URL, page.Name, id)
+	params, err := validateWebPageSource(&page)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	_, err = database.DB.Exec("UPDATE web_pages SET url = ?, name = ?, source = ?, source_params = ? WHERE id = ?",
+		page.URL, page.Name, page.Source, params, id)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
@@ -120,6 +131,111 @@ func DeleteWebPage(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{"message": "deleted"})
 }
 
+// GetSources 获取数据源列表
+// @Summary      获取数据源列表
+// @Description  获取已注册的数据源适配器名称，用于配置网页的 source 字段
+// @Tags         网页管理
+// @Accept       json
+// @Produce      json
+// @Success      200 {array}   string
+// @Router       /sources [get]
+func GetSources(c *gin.Context) {
+	c.JSON(http.StatusOK, crawler.SourceNames())
+}
+
+// GetProxies 获取代理池状态
+// @Summary      获取代理池状态
+// @Description  获取各数据源代理的可用状态、连续失败次数、成功和失败计数及暂停截止时间
+// @Tags         网页管理
+// @Accept       json
+// @Produce      json
+// @Success      200 {array}   fetch.ProxyState
+// @Router       /sources/proxies [get]
+func GetProxies(c *gin.Context) {
+	c.JSON(http.StatusOK, fetch.ProxyStates())
+}
+
+// TestHTMLSelectors 测试列表页选择器
+// @Summary      测试列表页选择器
+// @Description  按 html 数据源参数抓取列表页第一页并返回解析结果，不保存任何数据。解析不出任何条目时返回 502 和原因
+// @Tags         网页管理
+// @Accept       json
+// @Produce      json
+// @Param        request  body      object  true  "url 和 source_params"
+// @Success      200      {object}  map[string]interface{}
+// @Failure      400      {object}  map[string]string
+// @Failure      502      {object}  map[string]string
+// @Router       /sources/html/test [post]
+func TestHTMLSelectors(c *gin.Context) {
+	var req struct {
+		URL          string            `json:"url" binding:"required"`
+		SourceParams map[string]string `json:"source_params"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	params := map[string]string{}
+	for k, v := range req.SourceParams {
+		params[k] = v
+	}
+	if params["url"] == "" {
+		params["url"] = req.URL
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 30*time.Second)
+	defer cancel()
+
+	rows, nextURL, err := crawler.TestHTMLSelectors(ctx, params)
+	if err != nil {
+		c.JSON(http.StatusBadGateway, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data":     rows,
+		"count":    len(rows),
+		"next_url": nextURL,
+	})
+}
+
+// validateWebPageSource 校验网页的数据源配置，返回序列化后的参数
+func validateWebPageSource(page *models.WebPage) (string, error) {
+	if page.Source == "" {
+		page.Source = crawler.DefaultSource
+	}
+	if page.SourceParams == nil {
+		page.SourceParams = map[string]string{}
+	}
+	if _, err := crawler.SourceForWebPage(*page); err != nil {
+		return "", err
+	}
+
+	data, err := json.Marshal(page.SourceParams)
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
+
+// badRequest 返回 400 响应，关键词表达式语法错误时附带表达式和出错位置
+func badRequest(c *gin.Context, err error) {
+	body := gin.H{"error": err.Error()}
+	var syntaxErr *keyword.SyntaxError
+	if errors.As(err, &syntaxErr) {
+		body["expression"] = syntaxErr.Expr
+		body["position"] = syntaxErr.Pos
+	}
+	c.JSON(http.StatusBadRequest, body)
+}
+
+// validateKeywords 校验关键词表达式语法
+func validateKeywords(keywords ...string) error {
+	_, err := keyword.ParseAll(keywords)
+	return err
+}
+
 // GetKeywords 获取关键字列表
 // @Summary      获取关键字列表
 // @Description  获取所有监控关键字
@@ -151,7 +267,7 @@ func GetKeywords(c *gin.Context) {
 
 // CreateKeyword 创建关键字
 // @Summary      创建关键字
-// @Description  添加一个新的监控关键字，并立即重新加载任务
+// @Description  添加一个新的监控关键字，并立即重新加载任务。关键字支持表达式：空格/AND、OR、-/NOT、"短语"、括号和 title:/content: 字段限定，语法错误时返回出错位置
 // @Tags         关键字管理
 // @Accept       json
 // @Produce      json
@@ -167,6 +283,11 @@ func CreateKeyword(c *gin.Context) {
 		return
 	}
 
+	if err := validateKeywords(keyword.Keyword); err != nil {
+		badRequest(c, err)
+		return
+	}
+
 	result, err := database.DB.Exec("INSERT INTO keywords (keyword) VALUES (?)", keyword.Keyword)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
@@ -206,17 +327,18 @@ func DeleteKeyword(c *gin.Context) {
 
 // GetPushConfig 获取推送配置
 // @Summary      获取推送配置
-// @Description  获取邮件推送配置信息
+// @Description  兼容旧版接口，返回最早创建的订阅配置，请改用 /subscribe-config
 // @Tags         推送配置
 // @Accept       json
 // @Produce      json
 // @Success      200 {object} models.PushConfig
+// @Deprecated
 // @Router       /push-config [get]
 func GetPushConfig(c *gin.Context) {
 	var config models.PushConfig
-	err := database.DB.QueryRow("SELECT id, email, push_time FROM push_config LIMIT 1").Scan(&config.ID, &config.Email, &config.PushTime)
+	err := database.DB.QueryRow("SELECT id, email, push_time FROM subscribe_config ORDER BY id LIMIT 1").Scan(&config.ID, &config.Email, &config.PushTime)
 	if err != nil {
-		c.JSON(http.StatusOK, models.PushConfig{Email: "403608355@qq.com", PushTime: "17"})
+		c.JSON(http.StatusOK, models.PushConfig{PushTime: "17"})
 		return
 	}
 
@@ -225,7 +347,7 @@ func GetPushConfig(c *gin.Context) {
 
 // UpdatePushConfig 更新推送配置
 // @Summary      更新推送配置
-// @Description  更新邮件推送配置（邮箱和推送时间）
+// @Description  兼容旧版接口，按邮箱创建或更新订阅配置的推送时间，请改用 /subscribe-config
 // @Tags         推送配置
 // @Accept       json
 // @Produce      json
@@ -233,6 +355,7 @@ func GetPushConfig(c *gin.Context) {
 // @Success      200     {object}  models.PushConfig
 // @Failure      400     {object}  map[string]string
 // @Failure      500     {object}  map[string]string
+// @Deprecated
 // @Router       /push-config [put]
 func UpdatePushConfig(c *gin.Context) {
 	var config models.PushConfig
@@ -241,22 +364,18 @@ func UpdatePushConfig(c *gin.Context) {
 		return
 	}
 
-	var exists int
-	database.DB.QueryRow("SELECT COUNT(*) FROM push_config").Scan(&exists)
+	if _, err := scheduler.PushSpec(config.PushTime); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
 
-	if exists == 0 {
-		_, err := database.DB.Exec("INSERT INTO push_config (email, push_time) VALUES (?, ?)", config.Email, config.PushTime)
-		if err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
-			return
-		}
-	} else {
-		_, err := database.DB.Exec("UPDATE push_config SET email = ?, push_time = ?", config.Email, config.PushTime)
-		if err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
-			return
-		}
+	_, err := database.DB.Exec(`INSERT INTO subscribe_config (email, push_time) VALUES (?, ?)
+		ON CONFLICT(email) DO UPDATE SET push_time = excluded.push_time`, config.Email, config.PushTime)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
 	}
+	database.DB.QueryRow("SELECT id FROM subscribe_config WHERE email = ?", config.Email).Scan(&config.ID)
 
 	scheduler.ReloadTasks()
 
@@ -1,11 +1,15 @@
 package api
 
 import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
 	"net/http"
 	"strconv"
 	"strings"
 
 	"github.com/gin-gonic/gin"
+	"github.com/ieasydevops/demo-scrapy/internal/crawler"
 	"github.com/ieasydevops/demo-scrapy/internal/database"
 	"github.com/ieasydevops/demo-scrapy/internal/models"
 	"github.com/ieasydevops/demo-scrapy/internal/scheduler"
@@ -22,7 +26,7 @@ import (
 // @Router       /monitor-config [get]
 func GetMonitorConfig(c *gin.Context) {
 	rows, err := database.DB.Query(`
-		SELECT mc.id, mc.web_page_id, mc.crawl_time, mc.crawl_freq, mc.keywords, 
+		SELECT mc.id, mc.web_page_id, mc.crawl_time, mc.crawl_freq, mc.keywords, mc.source_params,
 		       mc.created_at, mc.updated_at, wp.name as web_page_name
 		FROM monitor_config mc
 		LEFT JOIN web_pages wp ON mc.web_page_id = wp.id
@@ -32,27 +36,56 @@ func GetMonitorConfig(c *gin.Context) {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
-	defer rows.Close()
 
-	var configs []map[string]interface{}
+	type row struct {
+		config      models.MonitorConfig
+		webPageName string
+	}
+	var list []row
 	for rows.Next() {
-		var config models.MonitorConfig
-		var webPageName string
-		err := rows.Scan(&config.ID, &config.WebPageID, &config.CrawlTime, &config.CrawlFreq,
-			&config.Keywords, &config.CreatedAt, &config.UpdatedAt, &webPageName)
+		var r row
+		var params string
+		err := rows.Scan(&r.config.ID, &r.config.WebPageID, &r.config.CrawlTime, &r.config.CrawlFreq,
+			&r.config.Keywords, &params, &r.config.CreatedAt, &r.config.UpdatedAt, &r.webPageName)
 		if err != nil {
 			continue
 		}
-		configs = append(configs, map[string]interface{}{
+		json.Unmarshal([]byte(params), &r.config.SourceParams)
+		if r.config.SourceParams == nil {
+			r.config.SourceParams = map[string]string{}
+		}
+		list = append(list, r)
+	}
+	rows.Close()
+
+	var configs []map[string]interface{}
+	for _, r := range list {
+		config := r.config
+		spec, _ := scheduler.CrawlSpec(config.CrawlFreq, config.CrawlTime)
+		item := map[string]interface{}{
 			"id":            config.ID,
 			"web_page_id":   config.WebPageID,
-			"web_page_name": webPageName,
+			"web_page_name": r.webPageName,
 			"crawl_time":    config.CrawlTime,
 			"crawl_freq":    config.CrawlFreq,
-			"keywords":      strings.Split(config.Keywords, ","),
+			"cron_spec":     spec,
+			"keywords":      scheduler.SplitKeywords(config.Keywords),
+			"source_params": config.SourceParams,
 			"created_at":    config.CreatedAt,
 			"updated_at":    config.UpdatedAt,
-		})
+		}
+		// 合并网页参数和默认值后实际使用的检索参数，参数无效时返回 source_error
+		if page, err := crawler.GetWebPage(config.WebPageID); err == nil {
+			page = crawler.WithSourceParams(page, config.SourceParams)
+			if src, err := crawler.SourceForWebPage(page); err != nil {
+				item["source_error"] = err.Error()
+			} else if reporter, ok := src.(crawler.ParamsReporter); ok {
+				item["effective_source_params"] = reporter.EffectiveParams()
+			} else {
+				item["effective_source_params"] = page.SourceParams
+			}
+		}
+		configs = append(configs, item)
 	}
 
 	c.JSON(http.StatusOK, configs)
@@ -60,7 +93,9 @@ func GetMonitorConfig(c *gin.Context) {
 
 // CreateMonitorConfig 创建监控配置
 // @Summary      创建监控配置
-// @Description  创建新的监控配置
+// @Description  创建新的监控配置。crawl_freq 可选 hourly/daily/weekly/custom，
+// @Description  crawl_time 分别为分钟、"H:MM"、"[W] H:MM"（W 为 0-6）或 5 段 cron 表达式。
+// @Description  source_params 覆盖网页的同名数据源参数，例如 szggzy 的 cnum、categories、participle
 // @Tags         监控配置管理
 // @Accept       json
 // @Produce      json
@@ -75,6 +110,8 @@ func CreateMonitorConfig(c *gin.Context) {
 		CrawlTime string   `json:"crawl_time" binding:"required"`
 		CrawlFreq string   `json:"crawl_freq" binding:"required"`
 		Keywords  []string `json:"keywords" binding:"required"`
+		// SourceParams 覆盖网页的同名数据源参数
+		SourceParams map[string]string `json:"source_params"`
 	}
 
 	if err := c.ShouldBindJSON(&req); err != nil {
@@ -82,10 +119,16 @@ func CreateMonitorConfig(c *gin.Context) {
 		return
 	}
 
+	params, err := validateMonitorConfig(req.WebPageID, req.CrawlFreq, req.CrawlTime, req.Keywords, req.SourceParams)
+	if err != nil {
+		badRequest(c, err)
+		return
+	}
+
 	keywordsStr := strings.Join(req.Keywords, ",")
 	result, err := database.DB.Exec(
-		"INSERT INTO monitor_config (web_page_id, crawl_time, crawl_freq, keywords) VALUES (?, ?, ?, ?)",
-		req.WebPageID, req.CrawlTime, req.CrawlFreq, keywordsStr,
+		"INSERT INTO monitor_config (web_page_id, crawl_time, crawl_freq, keywords, source_params) VALUES (?, ?, ?, ?, ?)",
+		req.WebPageID, req.CrawlTime, req.CrawlFreq, keywordsStr, params,
 	)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
@@ -118,6 +161,8 @@ func UpdateMonitorConfig(c *gin.Context) {
 		CrawlTime string   `json:"crawl_time"`
 		CrawlFreq string   `json:"crawl_freq"`
 		Keywords  []string `json:"keywords"`
+		// SourceParams 覆盖网页的同名数据源参数
+		SourceParams map[string]string `json:"source_params"`
 	}
 
 	if err := c.ShouldBindJSON(&req); err != nil {
@@ -125,10 +170,16 @@ func UpdateMonitorConfig(c *gin.Context) {
 		return
 	}
 
+	params, err := validateMonitorConfig(req.WebPageID, req.CrawlFreq, req.CrawlTime, req.Keywords, req.SourceParams)
+	if err != nil {
+		badRequest(c, err)
+		return
+	}
+
 	keywordsStr := strings.Join(req.Keywords, ",")
-	_, err := database.DB.Exec(
-		"UPDATE monitor_config SET web_page_id = ?, crawl_time = ?, crawl_freq = ?, keywords = ?, updated_at = CURRENT_TIMESTAMP WHERE id = ?",
-		req.WebPageID, req.CrawlTime, req.CrawlFreq, keywordsStr, id,
+	_, err = database.DB.Exec(
+		"UPDATE monitor_config SET web_page_id = ?, crawl_time = ?, crawl_freq = ?, keywords = ?, source_params = ?, updated_at = CURRENT_TIMESTAMP WHERE id = ?",
+		req.WebPageID, req.CrawlTime, req.CrawlFreq, keywordsStr, params, id,
 	)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
@@ -161,3 +212,33 @@ func DeleteMonitorConfig(c *gin.Context) {
 
 	c.JSON(http.StatusOK, gin.H{"message": "deleted"})
 }
+
+// validateMonitorConfig 校验目标网页存在、调度规则、关键词表达式和合并后的数据源参数有效，
+// 返回序列化后的数据源参数
+func validateMonitorConfig(webPageID int, crawlFreq, crawlTime string, keywords []string, sourceParams map[string]string) (string, error) {
+	page, err := crawler.GetWebPage(webPageID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return "", fmt.Errorf("网页 %d 不存在", webPageID)
+		}
+		return "", err
+	}
+	if _, err := scheduler.CrawlSpec(crawlFreq, crawlTime); err != nil {
+		return "", err
+	}
+	if err := validateKeywords(keywords...); err != nil {
+		return "", err
+	}
+	if _, err := crawler.SourceForWebPage(crawler.WithSourceParams(page, sourceParams)); err != nil {
+		return "", fmt.Errorf("数据源参数无效: %v", err)
+	}
+
+	if sourceParams == nil {
+		sourceParams = map[string]string{}
+	}
+	data, err := json.Marshal(sourceParams)
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
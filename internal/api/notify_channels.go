@@ -0,0 +1,264 @@
+package api
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/ieasydevops/demo-scrapy/internal/config"
+	"github.com/ieasydevops/demo-scrapy/internal/models"
+	"github.com/ieasydevops/demo-scrapy/internal/notify"
+)
+
+// notifyChannelRequest 创建或更新推送渠道的请求体。更新时不传 secret 保留原密钥，传空字符串清除密钥
+type notifyChannelRequest struct {
+	Name   string  `json:"name"`
+	Type   string  `json:"type"`
+	URL    string  `json:"url"`
+	Secret *string `json:"secret"`
+}
+
+// GetNotifyChannels 获取推送渠道列表
+// @Summary      获取推送渠道列表
+// @Description  获取钉钉、企业微信、飞书机器人和通用 webhook 推送渠道，不返回密钥，has_secret 表示是否设置了签名密钥。邮件渠道固定名为 email，不在列表中
+// @Tags         推送渠道
+// @Produce      json
+// @Success      200 {array}   models.NotifyChannel
+// @Failure      500 {object}  map[string]string
+// @Router       /notify-channels [get]
+func GetNotifyChannels(c *gin.Context) {
+	channels, err := notify.List()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if channels == nil {
+		channels = []models.NotifyChannel{}
+	}
+	c.JSON(http.StatusOK, channels)
+}
+
+// CreateNotifyChannel 创建推送渠道
+// @Summary      创建推送渠道
+// @Description  type 为 dingtalk、wecom、feishu 或 webhook，url 为机器人的 webhook 地址。
+// @Description  secret 为钉钉加签密钥、飞书签名校验密钥，或通用 webhook 的 HMAC-SHA256 签名密钥（X-Signature: sha256=hex(HMAC(secret, X-Timestamp + "." + body))），企业微信不支持
+// @Tags         推送渠道
+// @Accept       json
+// @Produce      json
+// @Param        channel  body      object  true  "name、type、url、secret"
+// @Success      200      {object}  models.NotifyChannel
+// @Failure      400      {object}  map[string]string
+// @Failure      500      {object}  map[string]string
+// @Router       /notify-channels [post]
+func CreateNotifyChannel(c *gin.Context) {
+	var req notifyChannelRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	ch := models.NotifyChannel{Name: strings.TrimSpace(req.Name), Type: req.Type, URL: strings.TrimSpace(req.URL)}
+	if req.Secret != nil {
+		ch.Secret = *req.Secret
+	}
+	if err := validateNotifyChannel(ch); err != nil {
+		badRequest(c, err)
+		return
+	}
+
+	id, err := notify.Create(ch)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	ch.ID = id
+	c.JSON(http.StatusOK, notify.Masked(ch))
+}
+
+// UpdateNotifyChannel 更新推送渠道
+// @Summary      更新推送渠道
+// @Description  更新推送渠道的名称、类型、地址和密钥，不传 secret 时保留原密钥。已被订阅或告警配置使用的渠道不能改名
+// @Tags         推送渠道
+// @Accept       json
+// @Produce      json
+// @Param        id       path      int     true  "渠道ID"
+// @Param        channel  body      object  true  "name、type、url、secret"
+// @Success      200      {object}  models.NotifyChannel
+// @Failure      400      {object}  map[string]string
+// @Failure      404      {object}  map[string]string
+// @Failure      500      {object}  map[string]string
+// @Router       /notify-channels/{id} [put]
+func UpdateNotifyChannel(c *gin.Context) {
+	id, _ := strconv.Atoi(c.Param("id"))
+	existing, err := notify.Get(id)
+	if err == sql.ErrNoRows {
+		c.JSON(http.StatusNotFound, gin.H{"error": "推送渠道不存在"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	var req notifyChannelRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	ch := models.NotifyChannel{ID: id, Name: strings.TrimSpace(req.Name), Type: req.Type, URL: strings.TrimSpace(req.URL),
+		Secret: existing.Secret, CreatedAt: existing.CreatedAt}
+	if req.Secret != nil {
+		ch.Secret = *req.Secret
+	}
+	if ch.Name != existing.Name {
+		if err := validateNotifyChannel(ch); err != nil {
+			badRequest(c, err)
+			return
+		}
+		if users, err := notifyChannelUsers(existing.Name); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		} else if len(users) > 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "渠道正在使用，不能改名", "used_by": users})
+			return
+		}
+	} else if err := notify.Validate(ch); err != nil {
+		badRequest(c, err)
+		return
+	}
+
+	if err := notify.Update(ch); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, notify.Masked(ch))
+}
+
+// DeleteNotifyChannel 删除推送渠道
+// @Summary      删除推送渠道
+// @Description  删除指定ID的推送渠道，已被订阅或告警配置使用的渠道需要先从中移除
+// @Tags         推送渠道
+// @Produce      json
+// @Param        id  path      int  true  "渠道ID"
+// @Success      200 {object}  map[string]string
+// @Failure      404 {object}  map[string]string
+// @Failure      409 {object}  map[string]interface{}
+// @Failure      500 {object}  map[string]string
+// @Router       /notify-channels/{id} [delete]
+func DeleteNotifyChannel(c *gin.Context) {
+	id, _ := strconv.Atoi(c.Param("id"))
+	ch, err := notify.Get(id)
+	if err == sql.ErrNoRows {
+		c.JSON(http.StatusNotFound, gin.H{"error": "推送渠道不存在"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	users, err := notifyChannelUsers(ch.Name)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if len(users) > 0 {
+		c.JSON(http.StatusConflict, gin.H{"error": "渠道正在使用，不能删除", "used_by": users})
+		return
+	}
+
+	if err := notify.Delete(id); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "deleted"})
+}
+
+// TestNotifyChannel 发送测试消息
+// @Summary      发送测试消息
+// @Description  通过推送渠道立即发送一条测试消息，返回机器人或 webhook 的错误信息，用于检查地址和密钥
+// @Tags         推送渠道
+// @Produce      json
+// @Param        id  path      int  true  "渠道ID"
+// @Success      200 {object}  map[string]string
+// @Failure      404 {object}  map[string]string
+// @Failure      502 {object}  map[string]string
+// @Router       /notify-channels/{id}/test [post]
+func TestNotifyChannel(c *gin.Context) {
+	id, _ := strconv.Atoi(c.Param("id"))
+	ch, err := notify.Get(id)
+	if err == sql.ErrNoRows {
+		c.JSON(http.StatusNotFound, gin.H{"error": "推送渠道不存在"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	n, err := notify.New(ch)
+	if err != nil {
+		badRequest(c, err)
+		return
+	}
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 20*time.Second)
+	defer cancel()
+	msg := notify.Message{
+		Subject: "政府采购网公告监控测试消息",
+		Text:    fmt.Sprintf("推送渠道 %s 配置正确，发送时间 %s。", ch.Name, time.Now().Format("2006-01-02 15:04:05")),
+	}
+	if err := n.Send(ctx, msg); err != nil {
+		c.JSON(http.StatusBadGateway, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "sent"})
+}
+
+// validateNotifyChannel 校验新渠道的配置，名称不能与已有渠道重复
+func validateNotifyChannel(ch models.NotifyChannel) error {
+	if err := notify.Validate(ch); err != nil {
+		return err
+	}
+	if _, err := notify.GetByName(ch.Name); err == nil {
+		return fmt.Errorf("渠道名称 %s 已存在", ch.Name)
+	} else if err != sql.ErrNoRows {
+		return err
+	}
+	return nil
+}
+
+// notifyChannelUsers 返回使用渠道的订阅邮箱，告警配置使用时包含 "alert"
+func notifyChannelUsers(name string) ([]string, error) {
+	users, err := notify.Subscribers(name)
+	if err != nil {
+		return nil, err
+	}
+	if cfg := config.GlobalConfig; cfg != nil {
+		for _, c := range cfg.Alert.Channels {
+			if c == name {
+				users = append(users, "alert")
+				break
+			}
+		}
+	}
+	return users, nil
+}
+
+// validateChannels 校验订阅使用的渠道都已配置
+func validateChannels(channels []string) error {
+	for _, name := range channels {
+		if name == notify.ChannelEmail {
+			continue
+		}
+		if _, err := notify.GetByName(name); err == sql.ErrNoRows {
+			return fmt.Errorf("推送渠道 %s 不存在", name)
+		} else if err != nil {
+			return err
+		}
+	}
+	return nil
+}
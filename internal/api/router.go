@@ -31,6 +31,11 @@ func SetupRouter() *gin.Engine {
 		api.POST("/web-pages", CreateWebPage)
 		api.PUT("/web-pages/:id", UpdateWebPage)
 		api.DELETE("/web-pages/:id", DeleteWebPage)
+		api.GET("/sources", GetSources)
+		api.GET("/sources/proxies", GetProxies)
+		api.GET("/sources/health", GetSourceHealth)
+		api.GET("/sources/health/:id", GetWebPageSourceHealth)
+		api.POST("/sources/html/test", TestHTMLSelectors)
 
 		api.GET("/keywords", GetKeywords)
 		api.POST("/keywords", CreateKeyword)
@@ -45,8 +50,51 @@ func SetupRouter() *gin.Engine {
 		api.POST("/subscribe-config", CreateSubscribeConfig)
 		api.PUT("/subscribe-config/:id", UpdateSubscribeConfig)
 		api.DELETE("/subscribe-config/:id", DeleteSubscribeConfig)
+		api.GET("/subscribe-config/:id/deliveries", GetSubscriberDeliveries)
+		api.POST("/subscribe-config/:id/digest", TriggerDigest)
+		api.GET("/subscribe-config/:id/digest/preview", PreviewDigest)
+		api.POST("/subscribe-config/:id/digest/preview", PreviewDigest)
+		api.GET("/digest-runs", GetDigestRuns)
+		api.GET("/digest-runs/:id", GetDigestRun)
+		api.POST("/email/test", TestEmail)
+		api.GET("/email/outbox", GetEmailOutbox)
+		api.POST("/email/outbox/resend", ResendFailedEmails)
+		api.GET("/email/outbox/:id", GetEmailMessage)
+		api.POST("/email/outbox/:id/resend", ResendEmail)
+		api.GET("/email/flagged-recipients", GetFlaggedRecipients)
+		api.DELETE("/email/flagged-recipients/:recipient", UnflagRecipient)
+		api.GET("/email-templates/digest", GetEmailTemplate)
+		api.PUT("/email-templates/digest", UpdateEmailTemplate)
+		api.DELETE("/email-templates/digest", DeleteEmailTemplate)
+
+		api.GET("/notify-channels", GetNotifyChannels)
+		api.POST("/notify-channels", CreateNotifyChannel)
+		api.PUT("/notify-channels/:id", UpdateNotifyChannel)
+		api.DELETE("/notify-channels/:id", DeleteNotifyChannel)
+		api.POST("/notify-channels/:id/test", TestNotifyChannel)
+
+		api.GET("/webhooks", GetWebhooks)
+		api.POST("/webhooks", CreateWebhook)
+		api.PUT("/webhooks/:id", UpdateWebhook)
+		api.DELETE("/webhooks/:id", DeleteWebhook)
+		api.GET("/webhook-deliveries", GetWebhookDeliveries)
+		api.GET("/webhook-deliveries/:id", GetWebhookDelivery)
+		api.POST("/webhook-deliveries/:id/replay", ReplayWebhookDelivery)
 
 		api.GET("/announcements", GetAnnouncements)
+		api.GET("/announcements/:id", GetAnnouncement)
+		api.GET("/announcement-types", GetAnnouncementTypes)
+		api.GET("/attachments/:id/download", DownloadAttachment)
+
+		api.GET("/crawl-runs", GetCrawlRuns)
+		api.POST("/crawl-runs", TriggerCrawl)
+		api.GET("/crawl-runs/:id", GetCrawlRun)
+
+		api.GET("/backfill-jobs", GetBackfillJobs)
+		api.POST("/backfill-jobs", CreateBackfillJob)
+		api.GET("/backfill-jobs/:id", GetBackfillJob)
+		api.POST("/backfill-jobs/:id/cancel", CancelBackfillJob)
+		api.POST("/backfill-jobs/:id/resume", ResumeBackfillJob)
 
 		api.GET("/push-config", GetPushConfig)
 		api.PUT("/push-config", UpdatePushConfig)
@@ -0,0 +1,58 @@
+package api
+
+import (
+	"database/sql"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/ieasydevops/demo-scrapy/internal/health"
+	"github.com/ieasydevops/demo-scrapy/internal/models"
+)
+
+// GetSourceHealth 获取数据源健康状态
+// @Summary      获取数据源健康状态
+// @Description  获取各网页数据源的健康状态，异常（degraded）的排在前面。响应结构异常（字段缺失、计数矛盾、返回错误页面）
+// @Description  或连续多次采集没有结果时标记为异常，之后拉取到记录时恢复正常。列表不包含原始响应样本
+// @Tags         网页管理
+// @Accept       json
+// @Produce      json
+// @Success      200 {array}   models.SourceHealth
+// @Failure      500 {object}  map[string]string
+// @Router       /sources/health [get]
+func GetSourceHealth(c *gin.Context) {
+	list, err := health.List()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if list == nil {
+		list = []models.SourceHealth{}
+	}
+	c.JSON(http.StatusOK, list)
+}
+
+// GetWebPageSourceHealth 获取网页的数据源健康状态
+// @Summary      获取网页的数据源健康状态
+// @Description  获取指定网页的数据源健康状态，包含触发最近一次异常的原始响应样本
+// @Tags         网页管理
+// @Accept       json
+// @Produce      json
+// @Param        id  path      int  true  "网页ID"
+// @Success      200 {object}  models.SourceHealth
+// @Failure      404 {object}  map[string]string
+// @Failure      500 {object}  map[string]string
+// @Router       /sources/health/{id} [get]
+func GetWebPageSourceHealth(c *gin.Context) {
+	id, _ := strconv.Atoi(c.Param("id"))
+	h, err := health.Get(id)
+	if err == sql.ErrNoRows {
+		c.JSON(http.StatusNotFound, gin.H{"error": "该网页尚未检查过数据源状态"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, h)
+}
@@ -1,12 +1,18 @@
 package api
 
 import (
+	"fmt"
 	"net/http"
 	"strconv"
+	"strings"
 
 	"github.com/gin-gonic/gin"
 	"github.com/ieasydevops/demo-scrapy/internal/database"
+	"github.com/ieasydevops/demo-scrapy/internal/delivery"
+	"github.com/ieasydevops/demo-scrapy/internal/email"
+	"github.com/ieasydevops/demo-scrapy/internal/extract"
 	"github.com/ieasydevops/demo-scrapy/internal/models"
+	"github.com/ieasydevops/demo-scrapy/internal/notify"
 	"github.com/ieasydevops/demo-scrapy/internal/scheduler"
 )
 
@@ -20,7 +26,7 @@ import (
 // @Failure      500 {object} map[string]string
 // @Router       /subscribe-config [get]
 func GetSubscribeConfig(c *gin.Context) {
-	rows, err := database.DB.Query("SELECT id, email, push_time, created_at FROM subscribe_config ORDER BY created_at DESC")
+	rows, err := database.DB.Query("SELECT id, email, push_time, keywords, web_page_ids, types, channels, group_by, created_at FROM subscribe_config ORDER BY created_at DESC")
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
@@ -30,9 +36,14 @@ func GetSubscribeConfig(c *gin.Context) {
 	var configs []models.SubscribeConfig
 	for rows.Next() {
 		var config models.SubscribeConfig
-		if err := rows.Scan(&config.ID, &config.Email, &config.PushTime, &config.CreatedAt); err != nil {
+		var keywords, webPageIDs, types, channels string
+		if err := rows.Scan(&config.ID, &config.Email, &config.PushTime, &keywords, &webPageIDs, &types, &channels, &config.GroupBy, &config.CreatedAt); err != nil {
 			continue
 		}
+		config.Keywords = scheduler.SplitKeywords(keywords)
+		config.WebPageIDs = scheduler.SplitWebPageIDs(webPageIDs)
+		config.Types = scheduler.SplitKeywords(types)
+		config.Channels = scheduler.SplitKeywords(channels)
 		configs = append(configs, config)
 	}
 
@@ -41,7 +52,9 @@ func GetSubscribeConfig(c *gin.Context) {
 
 // CreateSubscribeConfig 创建订阅配置
 // @Summary      创建订阅配置
-// @Description  添加新的订阅用户邮箱
+// @Description  添加新的订阅用户邮箱。push_time 为 "H" 或 "H:MM"，keywords、web_page_ids 和 types 为空时不过滤。
+// @Description  channels 为推送渠道名称，email 表示发送到订阅邮箱，其余为 /notify-channels 中配置的渠道，为空时只发邮件。
+// @Description  group_by 为邮件摘要的分组方式：source（来源网页）、keyword（匹配的关键词）、type（公告类型），为空时不分组
 // @Tags         订阅配置管理
 // @Accept       json
 // @Produce      json
@@ -57,9 +70,16 @@ func CreateSubscribeConfig(c *gin.Context) {
 		return
 	}
 
+	config.Channels = normalizeChannels(config.Channels)
+	if err := validateSubscribeConfig(config); err != nil {
+		badRequest(c, err)
+		return
+	}
+
 	result, err := database.DB.Exec(
-		"INSERT INTO subscribe_config (email, push_time) VALUES (?, ?)",
-		config.Email, config.PushTime,
+		"INSERT INTO subscribe_config (email, push_time, keywords, web_page_ids, types, channels, group_by) VALUES (?, ?, ?, ?, ?, ?, ?)",
+		config.Email, config.PushTime, strings.Join(config.Keywords, ","), scheduler.JoinWebPageIDs(config.WebPageIDs),
+		strings.Join(config.Types, ","), strings.Join(config.Channels, ","), config.GroupBy,
 	)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
@@ -94,9 +114,16 @@ func UpdateSubscribeConfig(c *gin.Context) {
 		return
 	}
 
+	config.Channels = normalizeChannels(config.Channels)
+	if err := validateSubscribeConfig(config); err != nil {
+		badRequest(c, err)
+		return
+	}
+
 	_, err := database.DB.Exec(
-		"UPDATE subscribe_config SET email = ?, push_time = ? WHERE id = ?",
-		config.Email, config.PushTime, id,
+		"UPDATE subscribe_config SET email = ?, push_time = ?, keywords = ?, web_page_ids = ?, types = ?, channels = ?, group_by = ? WHERE id = ?",
+		config.Email, config.PushTime, strings.Join(config.Keywords, ","), scheduler.JoinWebPageIDs(config.WebPageIDs),
+		strings.Join(config.Types, ","), strings.Join(config.Channels, ","), config.GroupBy, id,
 	)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
@@ -110,6 +137,49 @@ func UpdateSubscribeConfig(c *gin.Context) {
 	c.JSON(http.StatusOK, config)
 }
 
+// validateSubscribeConfig 校验推送时间、推送渠道、分组方式、公告类型和关键词表达式
+func validateSubscribeConfig(config models.SubscribeConfig) error {
+	if _, err := scheduler.PushSpec(config.PushTime); err != nil {
+		return err
+	}
+	if !email.ValidGroupBy(config.GroupBy) {
+		return fmt.Errorf("未知的分组方式 %q，可选 source、keyword、type", config.GroupBy)
+	}
+	if err := validateChannels(config.Channels); err != nil {
+		return err
+	}
+	for _, t := range config.Types {
+		if !extract.ValidType(t) {
+			return fmt.Errorf("未知的公告类型: %s", t)
+		}
+	}
+	return validateKeywords(config.Keywords...)
+}
+
+// normalizeChannels 去掉空白和重复的渠道名称，为空时只发邮件
+func normalizeChannels(channels []string) []string {
+	var result []string
+	for _, name := range channels {
+		name = strings.TrimSpace(name)
+		if name != "" && !containsChannel(result, name) {
+			result = append(result, name)
+		}
+	}
+	if len(result) == 0 {
+		return []string{notify.ChannelEmail}
+	}
+	return result
+}
+
+func containsChannel(channels []string, name string) bool {
+	for _, c := range channels {
+		if c == name {
+			return true
+		}
+	}
+	return false
+}
+
 // DeleteSubscribeConfig 删除订阅配置
 // @Summary      删除订阅配置
 // @Description  删除指定ID的订阅配置
@@ -132,3 +202,49 @@ func DeleteSubscribeConfig(c *gin.Context) {
 
 	c.JSON(http.StatusOK, gin.H{"message": "deleted"})
 }
+
+// GetSubscriberDeliveries 获取订阅推送记录
+// @Summary      获取订阅推送记录
+// @Description  分页获取指定订阅者已推送的公告记录，按推送时间倒序，可按推送渠道过滤
+// @Description  邮件渠道的记录包含发件箱中邮件的 ID 和发送状态（outbox_status），failed 的公告会在下次推送时重发
+// @Tags         订阅配置管理
+// @Accept       json
+// @Produce      json
+// @Param        id       path      int  true   "配置ID"
+// @Param        channel  query     string  false  "推送渠道名称"
+// @Param        page     query     int  false  "页码" default(1)
+// @Param        pageSize query     int  false  "每页数量" default(20)
+// @Success      200      {object}  map[string]interface{}
+// @Failure      404      {object}  map[string]string
+// @Failure      500      {object}  map[string]string
+// @Router       /subscribe-config/{id}/deliveries [get]
+func GetSubscriberDeliveries(c *gin.Context) {
+	id, _ := strconv.Atoi(c.Param("id"))
+	if _, err := scheduler.LoadSubscriber(id); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "订阅配置不存在"})
+		return
+	}
+
+	pageInt := 1
+	pageSizeInt := 20
+	if p, err := strconv.Atoi(c.DefaultQuery("page", "1")); err == nil && p > 0 {
+		pageInt = p
+	}
+	if ps, err := strconv.Atoi(c.DefaultQuery("pageSize", "20")); err == nil && ps > 0 {
+		pageSizeInt = ps
+	}
+
+	deliveries, total, err := delivery.History(id, c.Query("channel"), pageInt, pageSizeInt)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data":       deliveries,
+		"total":      total,
+		"page":       pageInt,
+		"page_size":  pageSizeInt,
+		"total_page": (total + pageSizeInt - 1) / pageSizeInt,
+	})
+}
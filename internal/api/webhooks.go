@@ -0,0 +1,262 @@
+package api
+
+import (
+	"database/sql"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/ieasydevops/demo-scrapy/internal/models"
+	"github.com/ieasydevops/demo-scrapy/internal/webhook"
+)
+
+// webhookRequest 创建或更新 webhook 的请求体。enabled 默认为 true；更新时不传 secret 保留原密钥
+type webhookRequest struct {
+	Name     string   `json:"name"`
+	URL      string   `json:"url"`
+	Secret   string   `json:"secret"`
+	Events   []string `json:"events"`
+	Types    []string `json:"types"`
+	Keywords []string `json:"keywords"`
+	Enabled  *bool    `json:"enabled"`
+}
+
+func (r webhookRequest) webhook() models.Webhook {
+	w := models.Webhook{
+		Name:     strings.TrimSpace(r.Name),
+		URL:      strings.TrimSpace(r.URL),
+		Secret:   r.Secret,
+		Events:   r.Events,
+		Types:    r.Types,
+		Keywords: r.Keywords,
+		Enabled:  true,
+	}
+	if r.Enabled != nil {
+		w.Enabled = *r.Enabled
+	}
+	return w
+}
+
+// GetWebhooks 获取 webhook 列表
+// @Summary      获取 webhook 列表
+// @Description  获取所有出站 webhook，不返回签名密钥
+// @Tags         Webhook
+// @Produce      json
+// @Success      200 {array}   models.Webhook
+// @Failure      500 {object}  map[string]string
+// @Router       /webhooks [get]
+func GetWebhooks(c *gin.Context) {
+	list, err := webhook.List()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	hooks := make([]models.Webhook, 0, len(list))
+	for _, w := range list {
+		hooks = append(hooks, webhook.Masked(w))
+	}
+	c.JSON(http.StatusOK, hooks)
+}
+
+// CreateWebhook 创建 webhook
+// @Summary      创建 webhook
+// @Description  events 可选 announcement.created（新公告入库）、announcement.classified（新公告属于 types 中的类型，types 为空时为任一类型，详情页改变类型后再次推送）、
+// @Description  announcement.updated（详情页抓取后公告类型或抽取字段发生变化）、keyword.matched（新公告匹配 keywords 中的关键词表达式）、crawl.failed（采集失败）。
+// @Description  每次投递带 X-Webhook-Event、X-Webhook-Delivery、X-Timestamp 和 X-Signature: sha256=hex(HMAC-SHA256(secret, X-Timestamp + "." + body)) 请求头。
+// @Description  secret 为空时自动生成，只在本接口的响应中返回
+// @Tags         Webhook
+// @Accept       json
+// @Produce      json
+// @Param        webhook  body      object  true  "name、url、events、types、keywords、secret、enabled"
+// @Success      200      {object}  models.Webhook
+// @Failure      400      {object}  map[string]string
+// @Failure      500      {object}  map[string]string
+// @Router       /webhooks [post]
+func CreateWebhook(c *gin.Context) {
+	var req webhookRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	w := req.webhook()
+	if err := webhook.Validate(w); err != nil {
+		badRequest(c, err)
+		return
+	}
+
+	id, secret, err := webhook.Create(w)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	created, err := webhook.Get(id)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	created.HasSecret = true
+	created.Secret = secret
+	c.JSON(http.StatusOK, created)
+}
+
+// UpdateWebhook 更新 webhook
+// @Summary      更新 webhook
+// @Description  更新 webhook 的地址、事件和过滤条件，不传 secret 时保留原密钥。停用期间产生的事件在重新启用后投递
+// @Tags         Webhook
+// @Accept       json
+// @Produce      json
+// @Param        id       path      int     true  "Webhook ID"
+// @Param        webhook  body      object  true  "name、url、events、types、keywords、secret、enabled"
+// @Success      200      {object}  models.Webhook
+// @Failure      400      {object}  map[string]string
+// @Failure      404      {object}  map[string]string
+// @Failure      500      {object}  map[string]string
+// @Router       /webhooks/{id} [put]
+func UpdateWebhook(c *gin.Context) {
+	id, _ := strconv.Atoi(c.Param("id"))
+	var req webhookRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	w := req.webhook()
+	w.ID = id
+	if err := webhook.Validate(w); err != nil {
+		badRequest(c, err)
+		return
+	}
+
+	err := webhook.Update(w)
+	if err == sql.ErrNoRows {
+		c.JSON(http.StatusNotFound, gin.H{"error": "webhook 不存在"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	updated, err := webhook.Get(id)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	webhook.Wake()
+	c.JSON(http.StatusOK, webhook.Masked(updated))
+}
+
+// DeleteWebhook 删除 webhook
+// @Summary      删除 webhook
+// @Description  删除 webhook 及其全部投递记录
+// @Tags         Webhook
+// @Produce      json
+// @Param        id  path      int  true  "Webhook ID"
+// @Success      200 {object}  map[string]string
+// @Failure      404 {object}  map[string]string
+// @Failure      500 {object}  map[string]string
+// @Router       /webhooks/{id} [delete]
+func DeleteWebhook(c *gin.Context) {
+	id, _ := strconv.Atoi(c.Param("id"))
+	err := webhook.Delete(id)
+	if err == sql.ErrNoRows {
+		c.JSON(http.StatusNotFound, gin.H{"error": "webhook 不存在"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "deleted"})
+}
+
+// GetWebhookDeliveries 获取 webhook 投递记录
+// @Summary      获取 webhook 投递记录
+// @Description  分页获取 webhook 的投递记录，包含投递次数、下次重试时间、最近一次的响应状态码和错误，按创建时间倒序。
+// @Description  status 为 pending（待投递或等待重试）、success 或 failed（重试用尽）
+// @Tags         Webhook
+// @Produce      json
+// @Param        webhook_id query     int     false  "Webhook ID"
+// @Param        status     query     string  false  "投递状态"
+// @Param        event      query     string  false  "事件类型"
+// @Param        page       query     int     false  "页码" default(1)
+// @Param        pageSize   query     int     false  "每页数量" default(20)
+// @Success      200        {object}  map[string]interface{}
+// @Failure      500        {object}  map[string]string
+// @Router       /webhook-deliveries [get]
+func GetWebhookDeliveries(c *gin.Context) {
+	pageInt := 1
+	pageSizeInt := 20
+	if p, err := strconv.Atoi(c.DefaultQuery("page", "1")); err == nil && p > 0 {
+		pageInt = p
+	}
+	if ps, err := strconv.Atoi(c.DefaultQuery("pageSize", "20")); err == nil && ps > 0 {
+		pageSizeInt = ps
+	}
+	filter := webhook.DeliveryFilter{Status: c.Query("status"), Event: c.Query("event")}
+	filter.WebhookID, _ = strconv.Atoi(c.Query("webhook_id"))
+
+	deliveries, total, err := webhook.Deliveries(filter, pageInt, pageSizeInt)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if deliveries == nil {
+		deliveries = []models.WebhookDelivery{}
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data":       deliveries,
+		"total":      total,
+		"page":       pageInt,
+		"page_size":  pageSizeInt,
+		"total_page": (total + pageSizeInt - 1) / pageSizeInt,
+	})
+}
+
+// GetWebhookDelivery 获取 webhook 投递记录详情
+// @Summary      获取 webhook 投递记录详情
+// @Description  获取投递记录及其请求体
+// @Tags         Webhook
+// @Produce      json
+// @Param        id  path      int  true  "投递记录ID"
+// @Success      200 {object}  models.WebhookDelivery
+// @Failure      404 {object}  map[string]string
+// @Failure      500 {object}  map[string]string
+// @Router       /webhook-deliveries/{id} [get]
+func GetWebhookDelivery(c *gin.Context) {
+	id, _ := strconv.Atoi(c.Param("id"))
+	d, err := webhook.GetDelivery(id)
+	if err == sql.ErrNoRows {
+		c.JSON(http.StatusNotFound, gin.H{"error": "投递记录不存在"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, d)
+}
+
+// ReplayWebhookDelivery 重放 webhook 投递
+// @Summary      重放 webhook 投递
+// @Description  以原请求体新建一条待投递记录并立即投递，用于接收方修复后补发失败的事件，返回新记录的 ID
+// @Tags         Webhook
+// @Produce      json
+// @Param        id  path      int  true  "投递记录ID"
+// @Success      202 {object}  map[string]interface{}
+// @Failure      404 {object}  map[string]string
+// @Failure      500 {object}  map[string]string
+// @Router       /webhook-deliveries/{id}/replay [post]
+func ReplayWebhookDelivery(c *gin.Context) {
+	id, _ := strconv.Atoi(c.Param("id"))
+	newID, err := webhook.Replay(id)
+	if err == sql.ErrNoRows {
+		c.JSON(http.StatusNotFound, gin.H{"error": "投递记录不存在"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusAccepted, gin.H{"id": newID})
+}
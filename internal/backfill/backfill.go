@@ -0,0 +1,165 @@
+package backfill
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/ieasydevops/demo-scrapy/internal/database"
+	"github.com/ieasydevops/demo-scrapy/internal/models"
+)
+
+// DateLayout 回填日期范围和进度使用的日期格式
+const DateLayout = "2006-01-02"
+
+// 回填任务状态，running 的任务在服务启动时自动继续
+const (
+	StatusRunning   = "running"
+	StatusCompleted = "completed"
+	StatusFailed    = "failed"
+	StatusCancelled = "cancelled"
+)
+
+// ParseRange 解析回填日期范围（含两端）。to 为空时 end 为零值，由调用方取可以回填的最后一天；
+// 今天及之后的公告由定时采集负责，范围不能包含今天
+func ParseRange(from, to string) (time.Time, time.Time, error) {
+	start, err := time.ParseInLocation(DateLayout, from, time.Local)
+	if err != nil {
+		return time.Time{}, time.Time{}, fmt.Errorf("开始日期格式应为 %s: %q", DateLayout, from)
+	}
+	today := time.Now().In(time.Local).Format(DateLayout)
+	if from >= today {
+		return time.Time{}, time.Time{}, fmt.Errorf("开始日期 %s 不早于今天，今天的公告由定时采集负责", from)
+	}
+	if to == "" {
+		return start, time.Time{}, nil
+	}
+	end, err := time.ParseInLocation(DateLayout, to, time.Local)
+	if err != nil {
+		return time.Time{}, time.Time{}, fmt.Errorf("结束日期格式应为 %s: %q", DateLayout, to)
+	}
+	if start.After(end) {
+		return time.Time{}, time.Time{}, fmt.Errorf("开始日期 %s 晚于结束日期 %s", from, to)
+	}
+	if to >= today {
+		return time.Time{}, time.Time{}, fmt.Errorf("结束日期 %s 不早于今天，今天的公告由定时采集负责", to)
+	}
+	return start, end, nil
+}
+
+// Create 创建回填任务，返回任务 ID
+func Create(job models.BackfillJob) (int, error) {
+	result, err := database.DB.Exec(`
+		INSERT INTO backfill_jobs (monitor_config_id, web_page_id, keywords, start_date, end_date, cursor, status, days_total)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?)
+	`, database.NullInt(job.MonitorConfigID), job.WebPageID, strings.Join(job.Keywords, ","), job.StartDate, job.EndDate,
+		job.StartDate, StatusRunning, job.DaysTotal)
+	if err != nil {
+		return 0, err
+	}
+	id, err := result.LastInsertId()
+	return int(id), err
+}
+
+// Advance 记录一天采集完成，cursor 推进到下一天
+func Advance(id int, cursor string, inserted, skipped int) error {
+	_, err := database.DB.Exec(`
+		UPDATE backfill_jobs SET cursor = ?, days_done = days_done + 1, inserted = inserted + ?, skipped = skipped + ?,
+			updated_at = CURRENT_TIMESTAMP
+		WHERE id = ?
+	`, cursor, inserted, skipped, id)
+	return err
+}
+
+// SetStatus 更新任务状态，结束状态同时记录完成时间，message 为失败原因
+func SetStatus(id int, status, message string) error {
+	finished := "NULL"
+	if status != StatusRunning {
+		finished = "CURRENT_TIMESTAMP"
+	}
+	_, err := database.DB.Exec(`
+		UPDATE backfill_jobs SET status = ?, error = ?, updated_at = CURRENT_TIMESTAMP, finished_at = `+finished+`
+		WHERE id = ?
+	`, status, message, id)
+	return err
+}
+
+// Unfinished 返回状态为 running 的任务 ID，用于重启后继续
+func Unfinished() ([]int, error) {
+	rows, err := database.DB.Query("SELECT id FROM backfill_jobs WHERE status = ? ORDER BY id", StatusRunning)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var ids []int
+	for rows.Next() {
+		var id int
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	return ids, rows.Err()
+}
+
+const jobColumns = `
+	j.id, j.monitor_config_id, j.web_page_id, wp.name, j.keywords, j.start_date, j.end_date, j.cursor,
+	j.status, j.days_total, j.days_done, j.inserted, j.skipped, j.error, j.created_at, j.updated_at, j.finished_at`
+
+// List 分页查询回填任务，按创建时间倒序
+func List(page, pageSize int) ([]models.BackfillJob, int, error) {
+	var total int
+	if err := database.DB.QueryRow("SELECT COUNT(*) FROM backfill_jobs").Scan(&total); err != nil {
+		return nil, 0, err
+	}
+
+	rows, err := database.DB.Query("SELECT "+jobColumns+`
+		FROM backfill_jobs j
+		LEFT JOIN web_pages wp ON j.web_page_id = wp.id
+		ORDER BY j.id DESC
+		LIMIT ? OFFSET ?`, pageSize, (page-1)*pageSize)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	var jobs []models.BackfillJob
+	for rows.Next() {
+		job, err := scanJob(rows)
+		if err != nil {
+			return nil, 0, err
+		}
+		jobs = append(jobs, job)
+	}
+	return jobs, total, rows.Err()
+}
+
+// Get 按 ID 读取回填任务
+func Get(id int) (models.BackfillJob, error) {
+	row := database.DB.QueryRow("SELECT "+jobColumns+`
+		FROM backfill_jobs j
+		LEFT JOIN web_pages wp ON j.web_page_id = wp.id
+		WHERE j.id = ?`, id)
+	return scanJob(row)
+}
+
+func scanJob(row interface{ Scan(...interface{}) error }) (models.BackfillJob, error) {
+	var job models.BackfillJob
+	var configID sql.NullInt64
+	var webPageName, finishedAt sql.NullString
+	var keywords string
+	if err := row.Scan(&job.ID, &configID, &job.WebPageID, &webPageName, &keywords, &job.StartDate, &job.EndDate,
+		&job.Cursor, &job.Status, &job.DaysTotal, &job.DaysDone, &job.Inserted, &job.Skipped, &job.Error,
+		&job.CreatedAt, &job.UpdatedAt, &finishedAt); err != nil {
+		return job, err
+	}
+	job.MonitorConfigID = int(configID.Int64)
+	job.WebPageName = webPageName.String
+	job.FinishedAt = finishedAt.String
+	if keywords != "" {
+		job.Keywords = strings.Split(keywords, ",")
+	}
+	return job, nil
+}
@@ -3,6 +3,7 @@ package config
 import (
 	"fmt"
 	"os"
+	"strconv"
 
 	"gopkg.in/yaml.v3"
 )
@@ -11,7 +12,10 @@ type Config struct {
 	WebPages       []WebPageConfig     `yaml:"web_pages"`
 	Keywords       []string            `yaml:"keywords"`
 	MonitorConfigs []MonitorConfigItem `yaml:"monitor_configs"`
+	Crawler        CrawlerConfig       `yaml:"crawler"`
 	Email          EmailConfig         `yaml:"email"`
+	Alert          AlertConfig         `yaml:"alert"`
+	Webhook        WebhookConfig       `yaml:"webhook"`
 	Server         ServerConfig        `yaml:"server"`
 }
 
@@ -25,17 +29,160 @@ type MonitorConfigItem struct {
 	CrawlTime   string   `yaml:"crawl_time"`
 	CrawlFreq   string   `yaml:"crawl_freq"`
 	Keywords    []string `yaml:"keywords"`
+	// SourceParams 覆盖网页的同名数据源参数，如 szggzy 的 cnum、categories
+	SourceParams map[string]string `yaml:"source_params"`
 }
 
+type CrawlerConfig struct {
+	FetchDetail         bool   `yaml:"fetch_detail"`
+	DownloadAttachments bool   `yaml:"download_attachments"`
+	AttachmentDir       string `yaml:"attachment_dir"`
+	MaxAttachmentMB     int    `yaml:"max_attachment_mb"`
+	// BackfillInterval 历史回填每采集完一天后的等待时间（秒），避免对门户造成压力
+	BackfillInterval int `yaml:"backfill_interval"`
+	// Fetch 采集请求的超时、重试和限速
+	Fetch FetchConfig `yaml:"fetch"`
+	// EmptyRuns 同一监控配置连续多少次采集没有拉取到任何记录（此前有过记录）时标记数据源异常
+	EmptyRuns int `yaml:"empty_runs"`
+	// ExtractRules 按数据源追加的字段抽取规则：数据源 -> 字段 -> 正则列表
+	ExtractRules map[string]map[string][]string `yaml:"extract_rules,omitempty"`
+	// TypeRules 按公告类型追加的分类规则，优先于内置规则
+	TypeRules map[string]TypeRuleConfig `yaml:"type_rules,omitempty"`
+}
+
+// FetchConfig 采集请求参数，列表页、详情页和附件下载共用
+type FetchConfig struct {
+	Timeout    int     `yaml:"timeout"`     // 单次请求超时（秒）
+	MaxRetries int     `yaml:"max_retries"` // 超时、连接错误、429 和 5xx 的最大重试次数
+	RetryDelay int     `yaml:"retry_delay"` // 第一次重试前的等待（毫秒），之后每次翻倍并加随机抖动
+	MaxDelay   int     `yaml:"max_delay"`   // 单次重试等待的上限（秒）
+	RateLimit  float64 `yaml:"rate_limit"`  // 每个主机每秒请求数
+	Burst      int     `yaml:"burst"`       // 每个主机允许的突发请求数
+	// Transport 默认的代理、请求头、TLS 和 Cookie 设置
+	Transport TransportConfig `yaml:"transport"`
+	// Sources 按数据源覆盖的传输设置，未设置的字段沿用 Transport
+	Sources map[string]TransportConfig `yaml:"sources,omitempty"`
+	// Fixtures 把门户的真实响应录制为测试用的录制文件
+	Fixtures FixtureConfig `yaml:"fixtures,omitempty"`
+}
+
+type FixtureConfig struct {
+	Mode string `yaml:"mode,omitempty"` // record 录制，replay 回放，为空时不启用
+	Dir  string `yaml:"dir,omitempty"`  // 录制文件目录，每个数据源一个子目录
+}
+
+// TransportConfig 采集请求的代理、请求头、TLS 和 Cookie 设置
+type TransportConfig struct {
+	Proxies          []string          `yaml:"proxies,omitempty"`    // http://、https://、socks5:// 代理，多个时轮换使用
+	ProxyMaxFailures int               `yaml:"proxy_max_failures"`   // 代理连续失败多少次后暂停使用
+	ProxyCooldown    int               `yaml:"proxy_cooldown"`       // 代理暂停使用的时间（秒）
+	UserAgent        string            `yaml:"user_agent,omitempty"` // 为空时使用内置的浏览器标识
+	Headers          map[string]string `yaml:"headers,omitempty"`
+	TLS              TLSConfig         `yaml:"tls,omitempty"`
+	Cookies          bool              `yaml:"cookies,omitempty"`     // 在请求之间保存 Cookie
+	CookieFile       string            `yaml:"cookie_file,omitempty"` // Cookie 持久化文件，设置后自动启用 Cookie
+}
+
+type TLSConfig struct {
+	InsecureSkipVerify bool   `yaml:"insecure_skip_verify,omitempty"`
+	CAFile             string `yaml:"ca_file,omitempty"`     // 额外信任的 CA 证书（PEM）
+	MinVersion         string `yaml:"min_version,omitempty"` // 最低 TLS 版本，如 "1.2"
+}
+
+// SourceTransport 返回数据源的传输设置：Sources 中未设置的字段沿用 Transport，请求头合并
+func (f FetchConfig) SourceTransport(source string) TransportConfig {
+	t := f.Transport
+	o, ok := f.Sources[source]
+	if !ok {
+		return t
+	}
+	if len(o.Proxies) > 0 {
+		t.Proxies = o.Proxies
+	}
+	if o.ProxyMaxFailures > 0 {
+		t.ProxyMaxFailures = o.ProxyMaxFailures
+	}
+	if o.ProxyCooldown > 0 {
+		t.ProxyCooldown = o.ProxyCooldown
+	}
+	if o.UserAgent != "" {
+		t.UserAgent = o.UserAgent
+	}
+	if len(o.Headers) > 0 {
+		headers := make(map[string]string, len(t.Headers)+len(o.Headers))
+		for k, v := range t.Headers {
+			headers[k] = v
+		}
+		for k, v := range o.Headers {
+			headers[k] = v
+		}
+		t.Headers = headers
+	}
+	if o.TLS.InsecureSkipVerify {
+		t.TLS.InsecureSkipVerify = true
+	}
+	if o.TLS.CAFile != "" {
+		t.TLS.CAFile = o.TLS.CAFile
+	}
+	if o.TLS.MinVersion != "" {
+		t.TLS.MinVersion = o.TLS.MinVersion
+	}
+	if o.Cookies {
+		t.Cookies = true
+	}
+	if o.CookieFile != "" {
+		t.CookieFile = o.CookieFile
+	}
+	return t
+}
+
+// TypeRuleConfig 公告类型的标题和正文匹配正则
+type TypeRuleConfig struct {
+	Title []string `yaml:"title"`
+	Body  []string `yaml:"body"`
+}
+
+// EmailConfig SMTP 服务器和发件人，可被环境变量 SMTP_HOST、SMTP_PORT、SMTP_USER、SMTP_PASS、
+// SMTP_SECURITY、SMTP_FROM、SMTP_FROM_NAME、SMTP_REPLY_TO 覆盖
 type EmailConfig struct {
 	SMTPHost string `yaml:"smtp_host"`
+	SMTPPort int    `yaml:"smtp_port"` // 为 0 时按加密方式取 587、465 或 25
 	SMTPUser string `yaml:"smtp_user"`
 	SMTPPass string `yaml:"smtp_pass"`
+	// Security 连接加密方式：starttls（默认）、tls（隐式 TLS）或 none
+	Security  string `yaml:"security"`
+	From      string `yaml:"from,omitempty"`      // 发件地址，为空时使用 smtp_user
+	FromName  string `yaml:"from_name,omitempty"` // 发件人显示名称
+	ReplyTo   string `yaml:"reply_to,omitempty"`
+	Timeout   int    `yaml:"timeout"`    // 连接和单封邮件的超时（秒）
+	KeepAlive int    `yaml:"keep_alive"` // 发送后保持连接的时间（秒），同一批摘要复用连接
+	// MaxAttempts 发件箱中每封邮件的最大发送次数，用尽后标记为 failed，可通过接口重新发送
+	MaxAttempts int `yaml:"max_attempts"`
+	RetryDelay  int `yaml:"retry_delay"` // 第一次重试前的等待（秒），之后每次翻倍
+	MaxDelay    int `yaml:"max_delay"`   // 重试等待的上限（秒）
+	// TemplateDir 摘要邮件模板目录，其中的 digest.subject.tmpl、digest.txt.tmpl、digest.html.tmpl 覆盖内置模板
+	TemplateDir string `yaml:"template_dir,omitempty"`
+}
+
+// AlertConfig 数据源异常等运维告警的接收人
+type AlertConfig struct {
+	Emails []string `yaml:"emails,omitempty"`
+	// Channels 同时接收告警的推送渠道名称（见 /api/notify-channels）
+	Channels []string `yaml:"channels,omitempty"`
+}
+
+// WebhookConfig 出站 webhook 的投递超时和重试
+type WebhookConfig struct {
+	Timeout     int `yaml:"timeout"`      // 单次请求超时（秒）
+	MaxAttempts int `yaml:"max_attempts"` // 最大投递次数，用尽后标记为 failed，可通过接口重放
+	RetryDelay  int `yaml:"retry_delay"`  // 第一次重试前的等待（秒），之后每次翻倍
+	MaxDelay    int `yaml:"max_delay"`    // 重试等待的上限（秒）
 }
 
 type ServerConfig struct {
-	Port   int    `yaml:"port"`
-	DBPath string `yaml:"db_path"`
+	Port            int    `yaml:"port"`
+	DBPath          string `yaml:"db_path"`
+	ShutdownTimeout int    `yaml:"shutdown_timeout"`
 }
 
 var GlobalConfig *Config
@@ -51,10 +198,112 @@ func LoadConfig(configPath string) (*Config, error) {
 		return nil, fmt.Errorf("解析配置文件失败: %v", err)
 	}
 
+	if err := applyEmailEnv(&config.Email); err != nil {
+		return nil, err
+	}
+	if config.Email.Timeout <= 0 {
+		config.Email.Timeout = 30
+	}
+	if config.Email.KeepAlive <= 0 {
+		config.Email.KeepAlive = 30
+	}
+	if config.Email.MaxAttempts <= 0 {
+		config.Email.MaxAttempts = 6
+	}
+	if config.Email.RetryDelay <= 0 {
+		config.Email.RetryDelay = 60
+	}
+	if config.Email.MaxDelay <= 0 {
+		config.Email.MaxDelay = 3600
+	}
+
+	if config.Server.Port == 0 {
+		config.Server.Port = 5080
+	}
+	if config.Server.ShutdownTimeout <= 0 {
+		config.Server.ShutdownTimeout = 30
+	}
+	if config.Crawler.AttachmentDir == "" {
+		config.Crawler.AttachmentDir = "./attachments"
+	}
+	if config.Crawler.MaxAttachmentMB <= 0 {
+		config.Crawler.MaxAttachmentMB = 20
+	}
+	if config.Crawler.BackfillInterval <= 0 {
+		config.Crawler.BackfillInterval = 5
+	}
+	if config.Crawler.EmptyRuns <= 0 {
+		config.Crawler.EmptyRuns = 3
+	}
+	fetchCfg := &config.Crawler.Fetch
+	if fetchCfg.Timeout <= 0 {
+		fetchCfg.Timeout = 30
+	}
+	if fetchCfg.MaxRetries <= 0 {
+		fetchCfg.MaxRetries = 3
+	}
+	if fetchCfg.RetryDelay <= 0 {
+		fetchCfg.RetryDelay = 1000
+	}
+	if fetchCfg.MaxDelay <= 0 {
+		fetchCfg.MaxDelay = 30
+	}
+	if fetchCfg.RateLimit <= 0 {
+		fetchCfg.RateLimit = 2
+	}
+	if fetchCfg.Burst <= 0 {
+		fetchCfg.Burst = 2
+	}
+	if fetchCfg.Transport.ProxyMaxFailures <= 0 {
+		fetchCfg.Transport.ProxyMaxFailures = 3
+	}
+	if fetchCfg.Transport.ProxyCooldown <= 0 {
+		fetchCfg.Transport.ProxyCooldown = 300
+	}
+
+	hookCfg := &config.Webhook
+	if hookCfg.Timeout <= 0 {
+		hookCfg.Timeout = 10
+	}
+	if hookCfg.MaxAttempts <= 0 {
+		hookCfg.MaxAttempts = 8
+	}
+	if hookCfg.RetryDelay <= 0 {
+		hookCfg.RetryDelay = 30
+	}
+	if hookCfg.MaxDelay <= 0 {
+		hookCfg.MaxDelay = 3600
+	}
+
 	GlobalConfig = &config
 	return &config, nil
 }
 
+// applyEmailEnv 用 SMTP_* 环境变量覆盖邮件配置
+func applyEmailEnv(e *EmailConfig) error {
+	for env, field := range map[string]*string{
+		"SMTP_HOST":      &e.SMTPHost,
+		"SMTP_USER":      &e.SMTPUser,
+		"SMTP_PASS":      &e.SMTPPass,
+		"SMTP_SECURITY":  &e.Security,
+		"SMTP_FROM":      &e.From,
+		"SMTP_FROM_NAME": &e.FromName,
+		"SMTP_REPLY_TO":  &e.ReplyTo,
+	} {
+		if v := os.Getenv(env); v != "" {
+			*field = v
+		}
+	}
+	if v := os.Getenv("SMTP_PORT"); v != "" {
+		port, err := strconv.Atoi(v)
+		if err != nil {
+			return fmt.Errorf("环境变量 SMTP_PORT 无效: %s", v)
+		}
+		e.SMTPPort = port
+	}
+	return nil
+}
+
 func InitDefaultConfig(configPath string) error {
 	if _, err := os.Stat(configPath); err == nil {
 		return nil
@@ -76,14 +325,37 @@ func InitDefaultConfig(configPath string) error {
 				Keywords:    []string{"生态环境局"},
 			},
 		},
+		Crawler: CrawlerConfig{
+			FetchDetail:         true,
+			DownloadAttachments: false,
+			AttachmentDir:       "./attachments",
+			MaxAttachmentMB:     20,
+			BackfillInterval:    5,
+			EmptyRuns:           3,
+			Fetch: FetchConfig{
+				Timeout:    30,
+				MaxRetries: 3,
+				RetryDelay: 1000,
+				MaxDelay:   30,
+				RateLimit:  2,
+				Burst:      2,
+				Transport: TransportConfig{
+					ProxyMaxFailures: 3,
+					ProxyCooldown:    300,
+				},
+			},
+		},
 		Email: EmailConfig{
 			SMTPHost: "smtp.qq.com",
-			SMTPUser: "403608355@qq.com",
+			SMTPPort: 587,
+			SMTPUser: "your_email@qq.com",
 			SMTPPass: "your_smtp_password",
+			Security: "starttls",
 		},
 		Server: ServerConfig{
-			Port:   5080,
-			DBPath: "./monitor.db",
+			Port:            5080,
+			DBPath:          "./monitor.db",
+			ShutdownTimeout: 30,
 		},
 	}
 
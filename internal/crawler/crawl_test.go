@@ -0,0 +1,234 @@
+package crawler_test
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ieasydevops/demo-scrapy/internal/crawler"
+	"github.com/ieasydevops/demo-scrapy/internal/crawler/crawlertest"
+	"github.com/ieasydevops/demo-scrapy/internal/fetch"
+)
+
+var day = time.Date(2025, 6, 2, 0, 0, 0, 0, time.Local)
+
+func newSzggzy(t *testing.T, srv *crawlertest.SzggzyServer) crawler.Source {
+	t.Helper()
+	src, err := crawler.NewSource("szggzy", map[string]string{"base_url": srv.URL})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return src
+}
+
+// records 生成 n 条当天发布、标题包含 title 的公告，链接为 /gsgg/<slug>-<序号>.html
+func records(n int, title, slug string) []crawlertest.Record {
+	recs := make([]crawlertest.Record, n)
+	for i := range recs {
+		recs[i] = crawlertest.Record{
+			Title:   fmt.Sprintf("%s%03d", title, i),
+			Content: "项目概况",
+			Linkurl: fmt.Sprintf("/gsgg/%s-%03d.html", slug, i),
+			Webdate: day.Add(time.Duration(i) * time.Minute),
+		}
+	}
+	return recs
+}
+
+func TestMain(m *testing.M) {
+	flag.Parse()
+	if !testing.Verbose() {
+		log.SetOutput(io.Discard)
+	}
+	os.Exit(m.Run())
+}
+
+func TestCrawlPagesThroughWindow(t *testing.T) {
+	crawlertest.ConfigureFetch(t, fetch.FixtureOptions{})
+	srv := crawlertest.NewSzggzyServer(records(120, "深圳市生态环境局监测服务采购", "jc")...)
+	defer srv.Close()
+	// 窗口外和不含检索词的公告不应返回
+	srv.Add(crawlertest.Record{Title: "深圳市生态环境局前一天的公告", Linkurl: "/gsgg/old.html", Webdate: day.Add(-time.Hour)})
+	srv.Add(crawlertest.Record{Title: "深圳市交通运输局公告", Linkurl: "/gsgg/other.html", Webdate: day.Add(time.Hour)})
+	srv.Add(crawlertest.Record{Title: "深圳市生态环境局更正公告", Linkurl: "/gsgg/fix.html", Webdate: day.Add(time.Hour)})
+
+	anns, stats, err := crawler.Crawl(context.Background(), newSzggzy(t, srv),
+		[]string{"生态环境局 -更正"}, day, day.Add(24*time.Hour))
+	if err != nil {
+		t.Fatalf("Crawl: %v", err)
+	}
+
+	if len(anns) != 120 {
+		t.Errorf("匹配 %d 条，期望 120", len(anns))
+	}
+	if stats.Pages != 3 || stats.Fetched != 121 || stats.Matched != 120 {
+		t.Errorf("统计 %+v，期望 3 页、拉取 121 条、匹配 120 条", stats)
+	}
+	var offsets []int
+	for _, req := range srv.Requests() {
+		offsets = append(offsets, req.Pn)
+		if req.Wd != "生态环境局" {
+			t.Errorf("上游检索词 %q，期望 生态环境局", req.Wd)
+		}
+	}
+	if fmt.Sprint(offsets) != "[0 50 100]" {
+		t.Errorf("翻页偏移 %v，期望 [0 50 100]", offsets)
+	}
+	if got := anns[0].URL; got != srv.URL+"/gsgg/jc-119.html" {
+		t.Errorf("第一条 URL %s，期望按发布时间倒序并补全门户地址", got)
+	}
+	if anns[0].PublishDate != "2025-06-02" {
+		t.Errorf("发布日期 %s，期望 2025-06-02", anns[0].PublishDate)
+	}
+}
+
+// 只有排除条件或 OR 中含排除条件时无法推导上游检索词，应拉取窗口内的全部公告后在本地过滤
+func TestCrawlWithoutUpstreamTerms(t *testing.T) {
+	crawlertest.ConfigureFetch(t, fetch.FixtureOptions{})
+	srv := crawlertest.NewSzggzyServer(
+		crawlertest.Record{Title: "深圳市交通运输局采购公告", Linkurl: "/gsgg/jt.html", Webdate: day.Add(time.Hour)},
+		crawlertest.Record{Title: "深圳市生态环境局更正公告", Linkurl: "/gsgg/fix.html", Webdate: day.Add(time.Hour)},
+		crawlertest.Record{Title: "深圳市水务局监测服务更正公告", Linkurl: "/gsgg/sw.html", Webdate: day.Add(time.Hour)},
+	)
+	defer srv.Close()
+
+	for _, tc := range []struct {
+		keywords []string
+		want     int
+	}{
+		{[]string{"-更正"}, 1},
+		{[]string{"NOT 更正"}, 1},
+		{[]string{"监测 OR -更正"}, 2},
+	} {
+		before := len(srv.Requests())
+		anns, _, err := crawler.Crawl(context.Background(), newSzggzy(t, srv), tc.keywords, day, day.Add(24*time.Hour))
+		if err != nil {
+			t.Fatalf("%v: %v", tc.keywords, err)
+		}
+		if len(anns) != tc.want {
+			t.Errorf("%v: 匹配 %d 条，期望 %d", tc.keywords, len(anns), tc.want)
+		}
+		for _, req := range srv.Requests()[before:] {
+			if req.Wd != "" {
+				t.Errorf("%v: 上游检索词 %q，期望为空", tc.keywords, req.Wd)
+			}
+		}
+	}
+}
+
+func TestCrawlRetriesTransientErrors(t *testing.T) {
+	crawlertest.ConfigureFetch(t, fetch.FixtureOptions{})
+	srv := crawlertest.NewSzggzyServer(records(3, "深圳市生态环境局", "a")...)
+	defer srv.Close()
+	srv.Fail(http.StatusServiceUnavailable, http.StatusTooManyRequests)
+
+	anns, _, err := crawler.Crawl(context.Background(), newSzggzy(t, srv),
+		[]string{"生态环境局"}, day, day.Add(24*time.Hour))
+	if err != nil {
+		t.Fatalf("Crawl: %v", err)
+	}
+	if len(anns) != 3 || len(srv.Requests()) != 3 {
+		t.Errorf("得到 %d 条、请求 %d 次，期望重试两次后得到 3 条", len(anns), len(srv.Requests()))
+	}
+}
+
+func TestCrawlKeepsEarlierPagesOnFailure(t *testing.T) {
+	crawlertest.ConfigureFetch(t, fetch.FixtureOptions{})
+	srv := crawlertest.NewSzggzyServer(records(80, "深圳市生态环境局", "b")...)
+	defer srv.Close()
+	srv.FailPage(50, http.StatusInternalServerError)
+
+	anns, stats, err := crawler.Crawl(context.Background(), newSzggzy(t, srv),
+		[]string{"生态环境局"}, day, day.Add(24*time.Hour))
+	if err == nil || !strings.Contains(err.Error(), "第 2 页") {
+		t.Fatalf("错误 %v，期望第 2 页失败", err)
+	}
+	if len(anns) != 50 || stats.Pages != 1 {
+		t.Errorf("得到 %d 条、%d 页，期望保留第 1 页的 50 条", len(anns), stats.Pages)
+	}
+}
+
+func TestCrawlUsesConfiguredSearchParams(t *testing.T) {
+	crawlertest.ConfigureFetch(t, fetch.FixtureOptions{})
+	srv := crawlertest.NewSzggzyServer(
+		crawlertest.Record{Title: "深圳市生态环境局采购意向", Linkurl: "/gsgg/yx.html", Webdate: day, Category: "002001001"},
+		crawlertest.Record{Title: "深圳市生态环境局采购公告", Linkurl: "/gsgg/gg.html", Webdate: day, Category: "002002001"},
+		crawlertest.Record{Title: "深圳市生态环境局结果公告", Linkurl: "/gsgg/jg.html", Webdate: day, Category: "002003"},
+	)
+	defer srv.Close()
+
+	src, err := crawler.NewSource("szggzy", map[string]string{
+		"base_url":   srv.URL,
+		"cnum":       "003",
+		"categories": "002001, 002003",
+		"participle": "false",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	anns, _, err := crawler.Crawl(context.Background(), src, []string{"生态环境局"}, day, day.Add(24*time.Hour))
+	if err != nil {
+		t.Fatalf("Crawl: %v", err)
+	}
+	if len(anns) != 2 {
+		t.Errorf("得到 %d 条，期望只返回 002001 和 002003 分类下的 2 条", len(anns))
+	}
+	req := srv.Requests()[0]
+	if req.Cnum != "003" || req.NoParticiple != "1" || fmt.Sprint(req.Categories()) != "[002001 002003]" {
+		t.Errorf("请求 cnum=%s noParticiple=%s 分类 %v", req.Cnum, req.NoParticiple, req.Categories())
+	}
+
+	for _, params := range []map[string]string{
+		{"categories": "招标公告"},
+		{"cl": "0"},
+		{"participle": "maybe"},
+		{"sort": "webdate"},
+		{"base_url": "zfcg.szggzy.com"},
+	} {
+		if _, err := crawler.NewSource("szggzy", params); err == nil {
+			t.Errorf("参数 %v 应校验失败", params)
+		}
+	}
+}
+
+func TestCrawlDetectsSchemaDrift(t *testing.T) {
+	crawlertest.ConfigureFetch(t, fetch.FixtureOptions{})
+	srv := crawlertest.NewSzggzyServer(records(3, "深圳市生态环境局", "d")...)
+	defer srv.Close()
+
+	cases := []struct {
+		contentType, body, reason string
+	}{
+		{"text/html", "<html><body>系统维护中</body></html>", "HTML"},
+		{"application/json", `{"data":{"totalcount":3,"records":[]}}`, "缺少 result"},
+		{"application/json", `{"result":{"total":3,"list":[]}}`, "totalcount"},
+		{"application/json", `{"result":{"totalcount":3,"records":[{"name":"a","url":"/a.html","date":"2025-06-02"}]}}`, "缺少 title"},
+		{"application/json", `{"result":{"totalcount":3,"records":[]}}`, "没有返回 records"},
+	}
+	for _, tc := range cases {
+		srv.Respond(tc.contentType, tc.body)
+		_, _, err := crawler.Crawl(context.Background(), newSzggzy(t, srv), []string{"生态环境局"}, day, day.Add(24*time.Hour))
+		var drift *crawler.DriftError
+		if !errors.As(err, &drift) {
+			t.Errorf("%s: 错误 %v，期望 DriftError", tc.body, err)
+			continue
+		}
+		if !strings.Contains(drift.Reason, tc.reason) || drift.Sample != tc.body {
+			t.Errorf("%s: 原因 %q、样本 %q", tc.body, drift.Reason, drift.Sample)
+		}
+	}
+
+	// 没有结果本身不是结构异常
+	srv.Respond("", "")
+	if _, _, err := crawler.Crawl(context.Background(), newSzggzy(t, srv), []string{"生态环境局"}, day.Add(-48*time.Hour), day.Add(-24*time.Hour)); err != nil {
+		t.Errorf("窗口内没有公告时返回错误: %v", err)
+	}
+}
@@ -1,233 +1,196 @@
 package crawler
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
-	"io"
 	"log"
-	"net/http"
 	"regexp"
 	"strings"
 	"time"
 
 	"github.com/ieasydevops/demo-scrapy/internal/database"
+	"github.com/ieasydevops/demo-scrapy/internal/extract"
 	"github.com/ieasydevops/demo-scrapy/internal/models"
+	"github.com/ieasydevops/demo-scrapy/internal/webhook"
 )
 
-type APISearchRequest struct {
-	Pn           int    `json:"pn"`
-	Rn           int    `json:"rn"`
-	Sdt          string `json:"sdt"`
-	Edt          string `json:"edt"`
-	Wd           string `json:"wd"`
-	Fields       string `json:"fields"`
-	Cnum         string `json:"cnum"`
-	Sort         string `json:"sort"`
-	Ssort        string `json:"ssort"`
-	Cl           int    `json:"cl"`
-	Highlights   string `json:"highlights"`
-	NoParticiple string `json:"noParticiple"`
-}
-
-type APISearchResponse struct {
-	Result struct {
-		Totalcount int `json:"totalcount"`
-		Records    []struct {
-			Title   string `json:"title"`
-			Content string `json:"content"`
-			Webdate string `json:"webdate"`
-			Linkurl string `json:"linkurl"`
-		} `json:"records"`
-	} `json:"result"`
-}
-
-func CrawlByAPISearch(keywords []string, days int) ([]models.Announcement, error) {
-	client := &http.Client{
-		Timeout: 30 * time.Second,
+// CrawlByAPISearch 使用默认数据源采集最近 days 天内匹配关键词的公告
+func CrawlByAPISearch(ctx context.Context, keywords []string, days int) ([]models.Announcement, error) {
+	src, err := NewSource(DefaultSource, nil)
+	if err != nil {
+		return nil, err
 	}
 
 	endTime := time.Now()
 	startTime := endTime.AddDate(0, 0, -days)
+	announcements, _, err := Crawl(ctx, src, keywords, startTime, endTime)
+	return announcements, err
+}
 
-	keywordStr := strings.Join(keywords, " ")
-	if keywordStr == "" {
-		keywordStr = "生态环境局"
+// SaveAnnouncements 按 URL 去重保存公告，返回本次新增的公告（已填充 ID）和已存在而跳过的条数。
+// crawlRunID 为产生这些公告的采集记录，为 0 时不关联。每条新公告与其 webhook 事件在同一事务中写入，
+// backfill 为 true（历史回填）时不写入 webhook 事件，以免回填的大量旧公告涌向外部系统
+func SaveAnnouncements(announcements []models.Announcement, webPageID, crawlRunID int, backfill bool) ([]models.Announcement, int, error) {
+	if len(announcements) == 0 {
+		return nil, 0, nil
 	}
 
-	var allAnnouncements []models.Announcement
-	pageNum := 0
-	pageSize := 50
-
-	for {
-		searchReq := APISearchRequest{
-			Pn:           pageNum * pageSize,
-			Rn:           pageSize,
-			Sdt:          startTime.Format("2006-01-02 15:04:05"),
-			Edt:          endTime.Format("2006-01-02 15:04:05"),
-			Wd:           keywordStr,
-			Fields:       "title;content",
-			Cnum:         "002",
-			Sort:         "{\"webdate\":\"0\"}",
-			Ssort:        "title",
-			Cl:           500,
-			Highlights:   "title;content",
-			NoParticiple: "0",
-		}
-
-		apiResponse, err := sendAPISearchRequest(client, searchReq)
-		if err != nil {
-			return nil, fmt.Errorf("API请求失败: %v", err)
-		}
-
-		if apiResponse.Result.Totalcount == 0 {
-			break
+	var source, pageName string
+	if page, err := GetWebPage(webPageID); err == nil {
+		source, pageName = page.Source, page.Name
+	}
+	var hooks *webhook.Set
+	if !backfill {
+		var err error
+		if hooks, err = webhook.Load(); err != nil {
+			return nil, 0, fmt.Errorf("读取 webhook 失败: %v", err)
 		}
+	}
 
-		for _, record := range apiResponse.Result.Records {
-			cleanTitle := cleanHTMLTags(record.Title)
-			fullURL := buildFullURLFromAPI(record.Linkurl)
-
-			matched := false
-			if len(keywords) > 0 {
-				for _, keyword := range keywords {
-					if strings.Contains(cleanTitle, keyword) || strings.Contains(cleanHTMLTags(record.Content), keyword) {
-						matched = true
-						break
-					}
-				}
-			} else {
-				matched = strings.Contains(cleanTitle, "生态环境局")
-			}
-
-			if matched {
-				announcement := models.Announcement{
-					Title:       cleanTitle,
-					URL:         fullURL,
-					PublishDate: formatDateString(record.Webdate),
-					Content:     cleanHTMLTags(record.Content),
-				}
-				allAnnouncements = append(allAnnouncements, announcement)
-				log.Printf("采集公告: %s", cleanTitle)
-			}
+	var saved []models.Announcement
+	skippedCount, events := 0, 0
+	defer func() {
+		if events > 0 {
+			webhook.Wake()
 		}
+	}()
 
-		if pageNum*pageSize+len(apiResponse.Result.Records) >= apiResponse.Result.Totalcount {
-			break
+	for _, ann := range announcements {
+		extract.Apply(&ann, source)
+		ann.WebPageName = pageName
+		inserted, n, err := saveAnnouncement(&ann, webPageID, crawlRunID, hooks)
+		if err != nil {
+			return saved, skippedCount, fmt.Errorf("插入公告失败: %v, URL: %s", err, ann.URL)
 		}
-
-		if len(apiResponse.Result.Records) == 0 {
-			break
+		if !inserted {
+			skippedCount++
+			continue
 		}
-
-		pageNum++
-		time.Sleep(500 * time.Millisecond)
+		events += n
+		saved = append(saved, ann)
 	}
 
-	return allAnnouncements, nil
+	log.Printf("保存公告完成: 新增 %d 条, 跳过 %d 条(已存在)", len(saved), skippedCount)
+	return saved, skippedCount, nil
 }
 
-func sendAPISearchRequest(client *http.Client, reqData APISearchRequest) (*APISearchResponse, error) {
-	apiURL := "http://zfcg.szggzy.com:8081/inteligentsearch/rest/esinteligentsearch/getFullTextDataNew"
-
-	jsonData, err := json.Marshal(reqData)
+// saveAnnouncement 插入一条公告并写入 webhook 事件（hooks 为 nil 时不写入），公告已存在时返回 false
+func saveAnnouncement(ann *models.Announcement, webPageID, crawlRunID int, hooks *webhook.Set) (bool, int, error) {
+	tx, err := database.DB.Begin()
 	if err != nil {
-		return nil, fmt.Errorf("JSON编码失败: %v", err)
-	}
-
-	req, err := http.NewRequest("POST", apiURL, strings.NewReader(string(jsonData)))
+		return false, 0, err
+	}
+	defer tx.Rollback()
+
+	// 并发的采集可能同时保存同一条公告，以 url 唯一约束去重
+	result, err := tx.Exec(
+		`INSERT INTO announcements (title, url, publish_date, content, web_page_id, publisher, type,
+			project_number, purchaser, agency, budget_amount, bid_deadline, opening_time, contact, crawl_run_id)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT(url) DO NOTHING`,
+		ann.Title, ann.URL, ann.PublishDate, ann.Content, webPageID, ann.Publisher, ann.Type,
+		ann.ProjectNumber, ann.Purchaser, ann.Agency, database.NullFloat(ann.BudgetAmount),
+		ann.BidDeadline, ann.OpeningTime, ann.Contact, database.NullInt(crawlRunID),
+	)
 	if err != nil {
-		return nil, fmt.Errorf("创建请求失败: %v", err)
+		return false, 0, err
 	}
-
-	req.Header.Set("Content-Type", "application/json")
-	req.Header.Set("User-Agent", "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/120.0.0.0 Safari/537.36")
-	req.Header.Set("Accept", "application/json, text/plain, */*")
-	req.Header.Set("Origin", "http://zfcg.szggzy.com:8081")
-	req.Header.Set("Referer", "http://zfcg.szggzy.com:8081/")
-
-	resp, err := client.Do(req)
-	if err != nil {
-		return nil, fmt.Errorf("请求失败: %v", err)
+	if n, _ := result.RowsAffected(); n == 0 {
+		return false, 0, nil
 	}
-	defer resp.Body.Close()
+	id, _ := result.LastInsertId()
+	ann.ID = int(id)
+	ann.WebPageID = webPageID
 
-	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("HTTP状态码错误: %d", resp.StatusCode)
+	events := 0
+	if hooks != nil {
+		if events, err = hooks.EnqueueAnnouncement(tx, *ann, AnnouncementDocument(*ann)); err != nil {
+			return false, 0, err
+		}
 	}
+	return true, events, tx.Commit()
+}
 
-	body, err := io.ReadAll(resp.Body)
+// ClassifyUnclassified 为尚未判断类型的历史公告补充类型，返回处理的条数
+func ClassifyUnclassified() (int, error) {
+	rows, err := database.DB.Query("SELECT id, title, COALESCE(body, ''), COALESCE(content, '') FROM announcements WHERE type = ''")
 	if err != nil {
-		return nil, fmt.Errorf("读取响应失败: %v", err)
+		return 0, err
 	}
-
-	var apiResp APISearchResponse
-	if err := json.Unmarshal(body, &apiResp); err != nil {
-		return nil, fmt.Errorf("JSON解析失败: %v", err)
+	type pending struct {
+		id  int
+		typ string
 	}
-
-	return &apiResp, nil
-}
-
-func SaveAnnouncements(announcements []models.Announcement, webPageID int) error {
-	if len(announcements) == 0 {
-		return nil
+	var updates []pending
+	for rows.Next() {
+		var id int
+		var title, body, content string
+		if err := rows.Scan(&id, &title, &body, &content); err != nil {
+			rows.Close()
+			return 0, err
+		}
+		if body == "" {
+			body = content
+		}
+		updates = append(updates, pending{id, extract.Classify(title, body)})
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, err
 	}
 
-	savedCount := 0
-	skippedCount := 0
-
-	for _, ann := range announcements {
-		var existingID int
-		err := database.DB.QueryRow("SELECT id FROM announcements WHERE url = ?", ann.URL).Scan(&existingID)
-
-		if err != nil {
-			if err.Error() == "sql: no rows in result set" {
-				_, err = database.DB.Exec(
-					"INSERT INTO announcements (title, url, publish_date, content, web_page_id) VALUES (?, ?, ?, ?, ?)",
-					ann.Title, ann.URL, ann.PublishDate, ann.Content, webPageID,
-				)
-				if err != nil {
-					return fmt.Errorf("插入公告失败: %v, URL: %s", err, ann.URL)
-				}
-				savedCount++
-			} else {
-				return fmt.Errorf("检查公告是否存在失败: %v", err)
-			}
-		} else {
-			skippedCount++
+	tx, err := database.DB.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+	for _, u := range updates {
+		if _, err := tx.Exec("UPDATE announcements SET type = ? WHERE id = ?", u.typ, u.id); err != nil {
+			return 0, err
 		}
 	}
-
-	log.Printf("保存公告完成: 新增 %d 条, 跳过 %d 条(已存在)", savedCount, skippedCount)
-	return nil
+	return len(updates), tx.Commit()
 }
 
-func GetNewAnnouncements() ([]models.Announcement, error) {
-	rows, err := database.DB.Query(`
-		SELECT a.id, a.title, a.url, a.publish_date, a.content, a.created_at,
-		       a.web_page_id, wp.name as web_page_name
-		FROM announcements a
-		LEFT JOIN web_pages wp ON a.web_page_id = wp.id
-		WHERE DATE(a.created_at) = DATE('now')
-		ORDER BY a.created_at DESC
-	`)
+// GetWebPages 读取所有网页及其数据源配置
+func GetWebPages() ([]models.WebPage, error) {
+	rows, err := database.DB.Query("SELECT id, url, name, source, source_params FROM web_pages")
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
 
-	var announcements []models.Announcement
+	var pages []models.WebPage
 	for rows.Next() {
-		var ann models.Announcement
-		err := rows.Scan(&ann.ID, &ann.Title, &ann.URL, &ann.PublishDate, &ann.Content,
-			&ann.CreatedAt, &ann.WebPageID, &ann.WebPageName)
+		page, err := scanWebPage(rows)
 		if err != nil {
 			return nil, err
 		}
-		announcements = append(announcements, ann)
+		pages = append(pages, page)
 	}
 
-	return announcements, nil
+	return pages, rows.Err()
+}
+
+// GetWebPage 按 ID 读取网页及其数据源配置
+func GetWebPage(id int) (models.WebPage, error) {
+	row := database.DB.QueryRow("SELECT id, url, name, source, source_params FROM web_pages WHERE id = ?", id)
+	return scanWebPage(row)
+}
+
+func scanWebPage(row interface{ Scan(...interface{}) error }) (models.WebPage, error) {
+	var page models.WebPage
+	var params string
+	if err := row.Scan(&page.ID, &page.URL, &page.Name, &page.Source, &params); err != nil {
+		return page, err
+	}
+	if params != "" {
+		if err := json.Unmarshal([]byte(params), &page.SourceParams); err != nil {
+			return page, fmt.Errorf("解析网页 %d 数据源参数失败: %v", page.ID, err)
+		}
+	}
+	return page, nil
 }
 
 func cleanHTMLTags(html string) string {
@@ -255,13 +218,3 @@ func formatDateString(dateStr string) string {
 	}
 	return dateStr
 }
-
-func buildFullURLFromAPI(href string) string {
-	if strings.HasPrefix(href, "http://") || strings.HasPrefix(href, "https://") {
-		return href
-	}
-	if strings.HasPrefix(href, "/") {
-		return "http://zfcg.szggzy.com:8081" + href
-	}
-	return "http://zfcg.szggzy.com:8081/gsgg/" + href
-}
@@ -0,0 +1,53 @@
+package crawlertest
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/ieasydevops/demo-scrapy/internal/database"
+	"github.com/ieasydevops/demo-scrapy/internal/fetch"
+)
+
+// OpenDB 在临时目录创建数据库并执行全部迁移，测试结束时关闭
+func OpenDB(t testing.TB) {
+	t.Helper()
+	if err := database.InitDB(filepath.Join(t.TempDir(), "test.db")); err != nil {
+		t.Fatalf("初始化测试数据库失败: %v", err)
+	}
+	t.Cleanup(func() { database.Close() })
+}
+
+// ConfigureFetch 让采集客户端不限速、快速重试，fixtures 不为空时录制或回放，测试结束时恢复默认设置。
+// 数据源在创建时取得客户端，需在创建数据源之前调用
+func ConfigureFetch(t testing.TB, fixtures fetch.FixtureOptions, sources ...string) {
+	t.Helper()
+	opts := fetch.Options{
+		Timeout:    10 * time.Second,
+		MaxRetries: 2,
+		BaseDelay:  time.Millisecond,
+		MaxDelay:   10 * time.Millisecond,
+		Fixtures:   fixtures,
+	}
+	transports := map[string]fetch.TransportOptions{}
+	for _, name := range sources {
+		transports[name] = fetch.TransportOptions{}
+	}
+	if err := fetch.Configure(opts, transports); err != nil {
+		t.Fatalf("初始化采集客户端失败: %v", err)
+	}
+	t.Cleanup(func() { fetch.Configure(fetch.DefaultOptions(), nil) })
+}
+
+// InsertWebPage 添加网页记录并返回 ID
+func InsertWebPage(t testing.TB, name, url, source, sourceParams string) int {
+	t.Helper()
+	result, err := database.DB.Exec(
+		"INSERT INTO web_pages (name, url, source, source_params) VALUES (?, ?, ?, ?)",
+		name, url, source, sourceParams)
+	if err != nil {
+		t.Fatalf("添加网页失败: %v", err)
+	}
+	id, _ := result.LastInsertId()
+	return int(id)
+}
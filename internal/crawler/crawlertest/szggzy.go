@@ -0,0 +1,258 @@
+// Package crawlertest 提供离线测试采集流程用的假门户。
+package crawlertest
+
+import (
+	"encoding/json"
+	"fmt"
+	"html"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// SzggzyPath 深圳政府采购网全文检索接口的路径
+const SzggzyPath = "/inteligentsearch/rest/esinteligentsearch/getFullTextDataNew"
+
+const timeLayout = "2006-01-02 15:04:05"
+
+// Record 假接口中的一条公告，Linkurl 为相对门户根目录的链接
+type Record struct {
+	Title   string
+	Content string
+	Linkurl string
+	Webdate time.Time
+	// Category 分类编号，请求带 categorynum 条件时按前缀过滤
+	Category string
+	// Body 详情页正文，不为空时 GET Linkurl 返回包含正文的详情页
+	Body string
+}
+
+// SzggzyRequest 假接口收到的检索请求，只包含用到的字段
+type SzggzyRequest struct {
+	Pn           int    `json:"pn"`
+	Rn           int    `json:"rn"`
+	Sdt          string `json:"sdt"`
+	Edt          string `json:"edt"`
+	Wd           string `json:"wd"`
+	Cnum         string `json:"cnum"`
+	NoParticiple string `json:"noParticiple"`
+	Condition    []struct {
+		FieldName string   `json:"fieldName"`
+		EqualList []string `json:"equalList"`
+	} `json:"condition"`
+}
+
+// Categories 返回请求中 categorynum 条件的分类编号
+func (r SzggzyRequest) Categories() []string {
+	var categories []string
+	for _, cond := range r.Condition {
+		if cond.FieldName == "categorynum" {
+			categories = append(categories, cond.EqualList...)
+		}
+	}
+	return categories
+}
+
+// SzggzyServer 模拟 getFullTextDataNew 检索接口：按 sdt/edt 过滤发布时间，
+// wd 中的词任一出现在标题或内容中即命中，带分类条件时只返回前缀匹配的分类，
+// 结果按发布时间倒序并按 pn/rn 分页
+type SzggzyServer struct {
+	*httptest.Server
+
+	mu       sync.Mutex
+	records  []Record
+	requests []SzggzyRequest
+	failures []int
+	failPage map[int]int
+	override *cannedResponse
+	// detailDelay 详情页的响应延迟
+	detailDelay time.Duration
+}
+
+type cannedResponse struct {
+	contentType string
+	body        string
+}
+
+// NewSzggzyServer 启动假接口，测试结束时调用 Close
+func NewSzggzyServer(records ...Record) *SzggzyServer {
+	s := &SzggzyServer{records: records, failPage: map[int]int{}}
+	s.Server = httptest.NewServer(http.HandlerFunc(s.handle))
+	return s
+}
+
+// Add 追加公告
+func (s *SzggzyServer) Add(records ...Record) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.records = append(s.records, records...)
+}
+
+// Fail 让接下来的请求依次返回给定的 HTTP 状态码，用完后恢复正常
+func (s *SzggzyServer) Fail(statuses ...int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.failures = append(s.failures, statuses...)
+}
+
+// FailPage 让 pn 为给定偏移的请求始终返回 status，用于模拟翻页中途失败
+func (s *SzggzyServer) FailPage(pn, status int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.failPage[pn] = status
+}
+
+// Respond 让之后的请求都返回状态码 200 和给定的内容，用于模拟门户改版或返回错误页面；
+// body 为空时恢复正常
+func (s *SzggzyServer) Respond(contentType, body string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.override = nil
+	if body != "" {
+		s.override = &cannedResponse{contentType: contentType, body: body}
+	}
+}
+
+// DelayDetails 让详情页延迟 d 后再响应，用于观察详情页抓取期间的状态
+func (s *SzggzyServer) DelayDetails(d time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.detailDelay = d
+}
+
+// Requests 返回收到的检索请求（包括返回失败状态码的请求）
+func (s *SzggzyServer) Requests() []SzggzyRequest {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]SzggzyRequest(nil), s.requests...)
+}
+
+func (s *SzggzyServer) handle(w http.ResponseWriter, r *http.Request) {
+	if r.Method == http.MethodGet {
+		s.serveDetail(w, r)
+		return
+	}
+	if r.Method != http.MethodPost || r.URL.Path != SzggzyPath {
+		http.NotFound(w, r)
+		return
+	}
+	var req SzggzyRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	s.mu.Lock()
+	s.requests = append(s.requests, req)
+	if len(s.failures) > 0 {
+		status := s.failures[0]
+		s.failures = s.failures[1:]
+		s.mu.Unlock()
+		w.WriteHeader(status)
+		return
+	}
+	if status, ok := s.failPage[req.Pn]; ok {
+		s.mu.Unlock()
+		w.WriteHeader(status)
+		return
+	}
+	if canned := s.override; canned != nil {
+		s.mu.Unlock()
+		w.Header().Set("Content-Type", canned.contentType)
+		fmt.Fprint(w, canned.body)
+		return
+	}
+	records := append([]Record(nil), s.records...)
+	s.mu.Unlock()
+
+	sdt, err1 := time.ParseInLocation(timeLayout, req.Sdt, time.Local)
+	edt, err2 := time.ParseInLocation(timeLayout, req.Edt, time.Local)
+	if err1 != nil || err2 != nil {
+		http.Error(w, fmt.Sprintf("sdt/edt 格式错误: %q %q", req.Sdt, req.Edt), http.StatusBadRequest)
+		return
+	}
+
+	var matched []Record
+	for _, rec := range records {
+		if rec.Webdate.Before(sdt) || rec.Webdate.After(edt) || !matchWords(rec, req.Wd) ||
+			!matchCategory(rec, req.Categories()) {
+			continue
+		}
+		matched = append(matched, rec)
+	}
+	sort.SliceStable(matched, func(i, j int) bool { return matched[i].Webdate.After(matched[j].Webdate) })
+
+	type record struct {
+		Title   string `json:"title"`
+		Content string `json:"content"`
+		Webdate string `json:"webdate"`
+		Linkurl string `json:"linkurl"`
+	}
+	page := []record{}
+	for i := req.Pn; i < len(matched) && i < req.Pn+req.Rn; i++ {
+		rec := matched[i]
+		page = append(page, record{
+			Title:   rec.Title,
+			Content: rec.Content,
+			Webdate: rec.Webdate.Format(timeLayout),
+			Linkurl: rec.Linkurl,
+		})
+	}
+
+	var resp struct {
+		Result struct {
+			Totalcount int      `json:"totalcount"`
+			Records    []record `json:"records"`
+		} `json:"result"`
+	}
+	resp.Result.Totalcount = len(matched)
+	resp.Result.Records = page
+	w.Header().Set("Content-Type", "application/json;charset=utf-8")
+	json.NewEncoder(w).Encode(resp)
+}
+
+// matchWords wd 为空格分隔的检索词，为空时全部命中
+// serveDetail 返回 Linkurl 与请求路径相同的公告详情页
+func (s *SzggzyServer) serveDetail(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	records, delay := append([]Record(nil), s.records...), s.detailDelay
+	s.mu.Unlock()
+	time.Sleep(delay)
+	for _, rec := range records {
+		if rec.Linkurl == r.URL.Path && rec.Body != "" {
+			w.Header().Set("Content-Type", "text/html;charset=utf-8")
+			fmt.Fprintf(w, `<html><body><div class="ewb-article">%s</div></body></html>`, html.EscapeString(rec.Body))
+			return
+		}
+	}
+	http.NotFound(w, r)
+}
+
+func matchWords(rec Record, wd string) bool {
+	words := strings.Fields(wd)
+	if len(words) == 0 {
+		return true
+	}
+	for _, word := range words {
+		if strings.Contains(rec.Title, word) || strings.Contains(rec.Content, word) {
+			return true
+		}
+	}
+	return false
+}
+
+// matchCategory 分类编号以任一给定编号开头即命中，未给定时全部命中
+func matchCategory(rec Record, categories []string) bool {
+	if len(categories) == 0 {
+		return true
+	}
+	for _, c := range categories {
+		if strings.HasPrefix(rec.Category, c) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,376 @@
+package crawler
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+	"github.com/ieasydevops/demo-scrapy/internal/database"
+	"github.com/ieasydevops/demo-scrapy/internal/extract"
+	"github.com/ieasydevops/demo-scrapy/internal/fetch"
+	"github.com/ieasydevops/demo-scrapy/internal/models"
+	"github.com/ieasydevops/demo-scrapy/internal/webhook"
+	"golang.org/x/net/html/charset"
+)
+
+// DetailOptions 详情页采集选项
+type DetailOptions struct {
+	DownloadAttachments bool
+	AttachmentDir       string
+	MaxAttachmentSize   int64
+	// Client 详情页和附件请求使用的客户端，为空时使用网页数据源的客户端
+	Client *fetch.Client
+	// Backfill 历史回填的公告，详情页的变化不写入 webhook 事件
+	Backfill bool
+}
+
+// attachmentExts 识别为附件的链接扩展名
+var attachmentExts = map[string]bool{
+	".pdf": true, ".doc": true, ".docx": true, ".xls": true, ".xlsx": true,
+	".zip": true, ".rar": true, ".7z": true, ".wps": true,
+}
+
+// bodySelectors 未配置 detail_selector 时依次尝试的正文区域，取第一个有文本的
+var bodySelectors = []string{
+	".ewb-article-info", ".ewb-article", ".article-content", ".article",
+	"#content", ".content", "article", "main", "body",
+}
+
+// FetchDetails 逐条抓取公告详情页，保存正文和附件信息。
+// 网页的 source_params 可通过 detail_selector 指定正文区域
+func FetchDetails(ctx context.Context, announcements []models.Announcement, page models.WebPage, opts DetailOptions) {
+	selector := page.SourceParams["detail_selector"]
+	var hooks *webhook.Set
+	if !opts.Backfill {
+		var err error
+		if hooks, err = webhook.Load(); err != nil {
+			log.Printf("读取 webhook 失败，详情页的变化不推送: %v", err)
+		}
+	}
+	events := 0
+	defer func() {
+		if events > 0 {
+			webhook.Wake()
+		}
+	}()
+	if opts.Client == nil {
+		source := page.Source
+		if source == "" {
+			source = DefaultSource
+		}
+		opts.Client = fetch.For(source)
+	}
+
+	for _, ann := range announcements {
+		if ctx.Err() != nil {
+			return
+		}
+
+		body, attachments, err := FetchDetail(ctx, opts.Client, ann.URL, selector)
+		if err != nil {
+			log.Printf("抓取公告详情失败: %s: %v", ann.URL, err)
+			continue
+		}
+
+		if opts.DownloadAttachments {
+			for j := range attachments {
+				if err := StoreAttachment(ctx, &attachments[j], opts); err != nil {
+					attachments[j].Error = err.Error()
+					log.Printf("下载附件失败: %s: %v", attachments[j].URL, err)
+				}
+			}
+		}
+
+		ann.Body = body
+		extract.Apply(&ann, page.Source)
+		n, err := SaveDetail(ann, attachments, hooks)
+		if err != nil {
+			log.Printf("保存公告详情失败: %s: %v", ann.URL, err)
+		}
+		events += n
+	}
+}
+
+// FetchDetail 抓取详情页，返回正文纯文本和附件链接
+func FetchDetail(ctx context.Context, client *fetch.Client, pageURL, selector string) (string, []models.Attachment, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", pageURL, nil)
+	if err != nil {
+		return "", nil, fmt.Errorf("创建请求失败: %v", err)
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return "", nil, fmt.Errorf("请求失败: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return "", nil, fmt.Errorf("HTTP状态码错误: %d", resp.StatusCode)
+	}
+
+	reader, err := charset.NewReader(resp.Body, resp.Header.Get("Content-Type"))
+	if err != nil {
+		return "", nil, fmt.Errorf("识别页面编码失败: %v", err)
+	}
+
+	doc, err := goquery.NewDocumentFromReader(reader)
+	if err != nil {
+		return "", nil, fmt.Errorf("解析HTML失败: %v", err)
+	}
+
+	base, _ := url.Parse(pageURL)
+	body, attachments := parseDetail(doc, base, selector)
+	return body, attachments, nil
+}
+
+func parseDetail(doc *goquery.Document, base *url.URL, selector string) (string, []models.Attachment) {
+	doc.Find("script, style, noscript").Remove()
+
+	var region *goquery.Selection
+	if selector != "" {
+		region = doc.Find(selector).First()
+	}
+	if region == nil || region.Length() == 0 {
+		region = nil
+		for _, sel := range bodySelectors {
+			candidate := doc.Find(sel).First()
+			if strings.TrimSpace(candidate.Text()) != "" {
+				region = candidate
+				break
+			}
+		}
+	}
+	if region == nil {
+		return "", nil
+	}
+
+	body := blockText(region)
+
+	seen := map[string]bool{}
+	var attachments []models.Attachment
+	doc.Find("a[href]").Each(func(_ int, a *goquery.Selection) {
+		href, _ := a.Attr("href")
+		link := resolveURL(base, href)
+		ext := attachmentExt(link)
+		if ext == "" || seen[link] {
+			return
+		}
+		seen[link] = true
+
+		name := collapseSpace(a.Text())
+		if name == "" {
+			name, _ = a.Attr("title")
+		}
+		if name == "" {
+			name = path.Base(link)
+		}
+		attachments = append(attachments, models.Attachment{Name: name, URL: link, Ext: ext})
+	})
+
+	return body, attachments
+}
+
+// blockText 提取纯文本正文，块级元素之间保留换行
+func blockText(sel *goquery.Selection) string {
+	sel.Find("br").ReplaceWithHtml("\n")
+	sel.Find("p, div, tr, li, h1, h2, h3, h4, h5, h6").Each(func(_ int, s *goquery.Selection) {
+		s.AppendHtml("\n")
+	})
+
+	var lines []string
+	for _, line := range strings.Split(sel.Text(), "\n") {
+		if line = collapseSpace(line); line != "" {
+			lines = append(lines, line)
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+func attachmentExt(link string) string {
+	u, err := url.Parse(link)
+	if err != nil {
+		return ""
+	}
+	ext := strings.ToLower(path.Ext(u.Path))
+	if attachmentExts[ext] {
+		return ext
+	}
+	return ""
+}
+
+// StoreAttachment 下载附件到按 SHA-256 寻址的本地目录，超过大小限制时放弃
+func StoreAttachment(ctx context.Context, att *models.Attachment, opts DetailOptions) error {
+	req, err := http.NewRequestWithContext(ctx, "GET", att.URL, nil)
+	if err != nil {
+		return fmt.Errorf("创建请求失败: %v", err)
+	}
+
+	client := opts.Client
+	if client == nil {
+		client = fetch.Default()
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("请求失败: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("HTTP状态码错误: %d", resp.StatusCode)
+	}
+	if opts.MaxAttachmentSize > 0 && resp.ContentLength > opts.MaxAttachmentSize {
+		return fmt.Errorf("附件大小 %d 超过限制 %d", resp.ContentLength, opts.MaxAttachmentSize)
+	}
+
+	if err := os.MkdirAll(opts.AttachmentDir, 0755); err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(opts.AttachmentDir, "download-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	defer tmp.Close()
+
+	hash := sha256.New()
+	var src io.Reader = resp.Body
+	if opts.MaxAttachmentSize > 0 {
+		src = io.LimitReader(resp.Body, opts.MaxAttachmentSize+1)
+	}
+	size, err := io.Copy(io.MultiWriter(tmp, hash), src)
+	if err != nil {
+		return fmt.Errorf("下载失败: %v", err)
+	}
+	if opts.MaxAttachmentSize > 0 && size > opts.MaxAttachmentSize {
+		return fmt.Errorf("附件大小超过限制 %d", opts.MaxAttachmentSize)
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+
+	sum := hex.EncodeToString(hash.Sum(nil))
+	dir := filepath.Join(opts.AttachmentDir, sum[:2])
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+	dest := filepath.Join(dir, sum+att.Ext)
+	if _, err := os.Stat(dest); os.IsNotExist(err) {
+		if err := os.Rename(tmp.Name(), dest); err != nil {
+			return err
+		}
+	}
+
+	att.Size = size
+	att.SHA256 = sum
+	att.LocalPath = dest
+	att.Downloaded = true
+	return nil
+}
+
+// SaveDetail 保存公告正文、从正文抽取的字段和附件信息。重新采集时附件没有下载（未开启下载或下载失败）
+// 则保留此前下载的文件、大小和 SHA-256。类型或抽取的字段有变化时在同一事务中写入 hooks 的事件
+// （hooks 为 nil 时不写入），返回写入的条数
+func SaveDetail(ann models.Announcement, attachments []models.Attachment, hooks *webhook.Set) (int, error) {
+	announcementID := ann.ID
+	tx, err := database.DB.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	var prev models.Announcement
+	if err := tx.QueryRow(`SELECT COALESCE(type, ''), COALESCE(publisher, ''), COALESCE(project_number, ''),
+			COALESCE(purchaser, ''), COALESCE(agency, ''), COALESCE(budget_amount, 0), COALESCE(bid_deadline, ''),
+			COALESCE(opening_time, ''), COALESCE(contact, '')
+		FROM announcements WHERE id = ?`, announcementID).Scan(
+		&prev.Type, &prev.Publisher, &prev.ProjectNumber, &prev.Purchaser, &prev.Agency, &prev.BudgetAmount,
+		&prev.BidDeadline, &prev.OpeningTime, &prev.Contact); err != nil {
+		return 0, err
+	}
+
+	if _, err := tx.Exec(`UPDATE announcements SET body = ?, detail_fetched_at = CURRENT_TIMESTAMP,
+			publisher = ?, type = ?, project_number = ?, purchaser = ?, agency = ?, budget_amount = ?,
+			bid_deadline = ?, opening_time = ?, contact = ?
+		WHERE id = ?`,
+		ann.Body, ann.Publisher, ann.Type, ann.ProjectNumber, ann.Purchaser, ann.Agency, database.NullFloat(ann.BudgetAmount),
+		ann.BidDeadline, ann.OpeningTime, ann.Contact, announcementID); err != nil {
+		return 0, err
+	}
+
+	for _, att := range attachments {
+		_, err := tx.Exec(`INSERT INTO attachments (announcement_id, name, url, ext, size, sha256, local_path, error)
+			VALUES (?, ?, ?, ?, ?, ?, ?, ?)
+			ON CONFLICT(announcement_id, url) DO UPDATE SET
+				name = excluded.name,
+				size = COALESCE(NULLIF(excluded.size, 0), attachments.size),
+				sha256 = COALESCE(NULLIF(excluded.sha256, ''), attachments.sha256),
+				local_path = COALESCE(NULLIF(excluded.local_path, ''), attachments.local_path),
+				error = CASE WHEN excluded.local_path = '' AND attachments.local_path != '' THEN attachments.error
+					ELSE excluded.error END`,
+			announcementID, att.Name, att.URL, att.Ext, att.Size, att.SHA256, att.LocalPath, att.Error)
+		if err != nil {
+			return 0, fmt.Errorf("保存附件失败: %v, URL: %s", err, att.URL)
+		}
+	}
+
+	events := 0
+	if hooks != nil {
+		if events, err = hooks.EnqueueUpdate(tx, ann, prev.Type, fieldsChanged(prev, ann)); err != nil {
+			return 0, err
+		}
+	}
+	return events, tx.Commit()
+}
+
+// fieldsChanged 判断抽取的字段是否有变化
+func fieldsChanged(a, b models.Announcement) bool {
+	return a.Publisher != b.Publisher || a.ProjectNumber != b.ProjectNumber || a.Purchaser != b.Purchaser ||
+		a.Agency != b.Agency || a.BudgetAmount != b.BudgetAmount || a.BidDeadline != b.BidDeadline ||
+		a.OpeningTime != b.OpeningTime || a.Contact != b.Contact
+}
+
+// GetAttachments 读取公告的附件列表
+func GetAttachments(announcementID int) ([]models.Attachment, error) {
+	rows, err := database.DB.Query(`
+		SELECT id, announcement_id, name, url, ext, size, sha256, local_path, error
+		FROM attachments WHERE announcement_id = ? ORDER BY id
+	`, announcementID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var attachments []models.Attachment
+	for rows.Next() {
+		var att models.Attachment
+		if err := rows.Scan(&att.ID, &att.AnnouncementID, &att.Name, &att.URL, &att.Ext,
+			&att.Size, &att.SHA256, &att.LocalPath, &att.Error); err != nil {
+			return nil, err
+		}
+		att.Downloaded = att.LocalPath != ""
+		attachments = append(attachments, att)
+	}
+	return attachments, rows.Err()
+}
+
+// GetAttachment 按 ID 读取附件
+func GetAttachment(id int) (models.Attachment, error) {
+	var att models.Attachment
+	err := database.DB.QueryRow(`
+		SELECT id, announcement_id, name, url, ext, size, sha256, local_path, error
+		FROM attachments WHERE id = ?
+	`, id).Scan(&att.ID, &att.AnnouncementID, &att.Name, &att.URL, &att.Ext,
+		&att.Size, &att.SHA256, &att.LocalPath, &att.Error)
+	att.Downloaded = att.LocalPath != ""
+	return att, err
+}
@@ -0,0 +1,39 @@
+package crawler
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+)
+
+// driftSampleSize 结构异常时保留的原始响应长度（字节）
+const driftSampleSize = 8 << 10
+
+// DriftError 上游响应与预期的结构不符，例如字段缺失、计数前后矛盾或返回了错误页面，
+// 通常意味着门户改版或接口变更。Sample 为原始响应的开头部分，用于排查
+type DriftError struct {
+	Reason string
+	Sample string
+}
+
+func (e *DriftError) Error() string {
+	return "响应结构异常: " + e.Reason
+}
+
+func newDriftError(body []byte, format string, args ...interface{}) *DriftError {
+	if len(body) > driftSampleSize {
+		body = body[:driftSampleSize]
+	}
+	return &DriftError{
+		Reason: fmt.Sprintf(format, args...),
+		Sample: strings.ToValidUTF8(string(body), ""),
+	}
+}
+
+// looksLikeHTML 判断期望 JSON 的响应是否为 HTML 页面（如登录页、错误页或防火墙拦截页）
+func looksLikeHTML(contentType string, body []byte) bool {
+	if strings.Contains(strings.ToLower(contentType), "html") {
+		return true
+	}
+	return bytes.HasPrefix(bytes.TrimSpace(body), []byte("<"))
+}
@@ -0,0 +1,294 @@
+package crawler
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/PuerkitoBio/goquery"
+	"github.com/andybalholm/cascadia"
+	"github.com/ieasydevops/demo-scrapy/internal/fetch"
+	"github.com/ieasydevops/demo-scrapy/internal/models"
+	"golang.org/x/net/html/charset"
+)
+
+func init() {
+	RegisterSource("html", newHTMLSource)
+}
+
+// HTMLSelectors 列表页解析规则
+type HTMLSelectors struct {
+	Item    string
+	Title   string
+	Link    string
+	Date    string
+	Next    string
+	Content string
+}
+
+// htmlSource 按 CSS 选择器解析公告列表页的通用适配器
+//
+// 支持的参数:
+//   - url: 列表页地址，默认使用网页记录的 url
+//   - item_selector: 列表项选择器（必填）
+//   - title_selector: 标题选择器，相对列表项，默认与 link_selector 相同
+//   - link_selector: 链接选择器，相对列表项，默认 a
+//   - date_selector: 发布日期选择器，相对列表项
+//   - next_selector: 下一页链接选择器，相对整个页面
+//   - content_selector: 摘要选择器，相对列表项
+//   - max_pages: 最多翻页数，默认 10
+type htmlSource struct {
+	client    *fetch.Client
+	listURL   string
+	selectors HTMLSelectors
+	maxPages  int
+
+	// pageURLs 记录本次采集各页的地址，第 N 页的地址由第 N-1 页的下一页链接得到
+	pageURLs []string
+}
+
+func newHTMLSource(params map[string]string) (Source, error) {
+	s := &htmlSource{
+		client:   fetch.For("html"),
+		listURL:  params["url"],
+		maxPages: 10,
+		selectors: HTMLSelectors{
+			Item:    params["item_selector"],
+			Title:   params["title_selector"],
+			Link:    params["link_selector"],
+			Date:    params["date_selector"],
+			Next:    params["next_selector"],
+			Content: params["content_selector"],
+		},
+	}
+
+	if s.listURL == "" {
+		return nil, fmt.Errorf("html 数据源缺少 url 参数")
+	}
+	if _, err := url.Parse(s.listURL); err != nil {
+		return nil, fmt.Errorf("列表页地址无效: %v", err)
+	}
+	if s.selectors.Item == "" {
+		return nil, fmt.Errorf("html 数据源缺少 item_selector 参数")
+	}
+	if s.selectors.Link == "" {
+		s.selectors.Link = "a"
+	}
+	if s.selectors.Title == "" {
+		s.selectors.Title = s.selectors.Link
+	}
+	if v := params["max_pages"]; v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n <= 0 {
+			return nil, fmt.Errorf("max_pages 参数无效: %s", v)
+		}
+		s.maxPages = n
+	}
+
+	for name, sel := range map[string]string{
+		"item_selector":    s.selectors.Item,
+		"title_selector":   s.selectors.Title,
+		"link_selector":    s.selectors.Link,
+		"date_selector":    s.selectors.Date,
+		"next_selector":    s.selectors.Next,
+		"content_selector": s.selectors.Content,
+	} {
+		if sel == "" {
+			continue
+		}
+		if _, err := cascadia.ParseGroup(sel); err != nil {
+			return nil, fmt.Errorf("%s 无效: %v", name, err)
+		}
+	}
+
+	s.pageURLs = []string{s.listURL}
+	return s, nil
+}
+
+func (s *htmlSource) Name() string {
+	return "html"
+}
+
+// FetchPage 抓取第 params.Page 页列表。列表页没有服务端时间过滤，
+// 发布日期早于 StartTime 的条目被丢弃，整页都早于窗口时停止翻页。
+func (s *htmlSource) FetchPage(ctx context.Context, params SearchParams) (*SearchPage, error) {
+	if params.Page >= len(s.pageURLs) || params.Page >= s.maxPages {
+		return &SearchPage{}, nil
+	}
+
+	pageURL := s.pageURLs[params.Page]
+	rows, nextURL, err := s.fetchList(ctx, pageURL)
+	if err != nil {
+		return nil, err
+	}
+
+	page := &SearchPage{}
+	older := 0
+	for _, ann := range rows {
+		if t, err := time.ParseInLocation("2006-01-02", ann.PublishDate, time.Local); err == nil {
+			if !params.StartTime.IsZero() && t.Before(truncateDay(params.StartTime)) {
+				older++
+				continue
+			}
+			if !params.EndTime.IsZero() && t.After(params.EndTime) {
+				continue
+			}
+		}
+		page.Announcements = append(page.Announcements, ann)
+	}
+	page.Total = len(page.Announcements)
+
+	// 整页都早于时间窗口时不再翻页，列表页通常按发布时间倒序排列
+	allOlder := len(rows) > 0 && older == len(rows)
+	if nextURL != "" && !allOlder && params.Page+1 < s.maxPages && !s.seen(nextURL) {
+		s.pageURLs = append(s.pageURLs, nextURL)
+		page.HasMore = true
+	}
+
+	return page, nil
+}
+
+func (s *htmlSource) seen(pageURL string) bool {
+	for _, u := range s.pageURLs {
+		if u == pageURL {
+			return true
+		}
+	}
+	return false
+}
+
+// fetchList 抓取并解析一页列表，返回解析出的条目和下一页地址
+func (s *htmlSource) fetchList(ctx context.Context, pageURL string) ([]models.Announcement, string, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", pageURL, nil)
+	if err != nil {
+		return nil, "", fmt.Errorf("创建请求失败: %v", err)
+	}
+	req.Header.Set("Accept", "text/html,application/xhtml+xml,application/xml;q=0.9,*/*;q=0.8")
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return nil, "", fmt.Errorf("请求失败: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, "", fmt.Errorf("HTTP状态码错误: %d", resp.StatusCode)
+	}
+
+	reader, err := charset.NewReader(resp.Body, resp.Header.Get("Content-Type"))
+	if err != nil {
+		return nil, "", fmt.Errorf("识别页面编码失败: %v", err)
+	}
+	// 保留转码后的页面，解析不出条目时作为样本记录
+	raw, err := io.ReadAll(reader)
+	if err != nil {
+		return nil, "", fmt.Errorf("读取页面失败: %v", err)
+	}
+
+	doc, err := goquery.NewDocumentFromReader(bytes.NewReader(raw))
+	if err != nil {
+		return nil, "", fmt.Errorf("解析HTML失败: %v", err)
+	}
+
+	base, _ := url.Parse(pageURL)
+	rows, nextURL := s.parseList(doc, base)
+	// 列表页总有条目，一条都解析不出时多半是页面改版或返回了状态码为 200 的错误页
+	if len(rows) == 0 {
+		return nil, "", newDriftError(raw, "列表页没有匹配 %s 且带链接和标题的条目", s.selectors.Item)
+	}
+	return rows, nextURL, nil
+}
+
+func (s *htmlSource) parseList(doc *goquery.Document, base *url.URL) ([]models.Announcement, string) {
+	var rows []models.Announcement
+	doc.Find(s.selectors.Item).Each(func(_ int, item *goquery.Selection) {
+		link := item.Find(s.selectors.Link).First()
+		href, ok := link.Attr("href")
+		if !ok || strings.TrimSpace(href) == "" {
+			return
+		}
+
+		titleSel := item.Find(s.selectors.Title).First()
+		title, ok := titleSel.Attr("title")
+		if !ok || strings.TrimSpace(title) == "" {
+			title = titleSel.Text()
+		}
+		title = collapseSpace(title)
+		if title == "" {
+			return
+		}
+
+		ann := models.Announcement{
+			Title: title,
+			URL:   resolveURL(base, href),
+		}
+		if s.selectors.Date != "" {
+			ann.PublishDate = extractDate(item.Find(s.selectors.Date).First().Text())
+		}
+		if s.selectors.Content != "" {
+			ann.Content = collapseSpace(item.Find(s.selectors.Content).First().Text())
+		}
+		rows = append(rows, ann)
+	})
+
+	var nextURL string
+	if s.selectors.Next != "" {
+		if href, ok := doc.Find(s.selectors.Next).First().Attr("href"); ok {
+			href = strings.TrimSpace(href)
+			if href != "" && !strings.HasPrefix(strings.ToLower(href), "javascript:") && href != "#" {
+				nextURL = resolveURL(base, href)
+			}
+		}
+	}
+
+	return rows, nextURL
+}
+
+// TestHTMLSelectors 抓取列表页第一页并按给定参数解析，用于在管理界面调试选择器
+func TestHTMLSelectors(ctx context.Context, params map[string]string) ([]models.Announcement, string, error) {
+	src, err := newHTMLSource(params)
+	if err != nil {
+		return nil, "", err
+	}
+	s := src.(*htmlSource)
+	return s.fetchList(ctx, s.listURL)
+}
+
+var datePattern = regexp.MustCompile(`(\d{4})\s*[-/.年]\s*(\d{1,2})\s*[-/.月]\s*(\d{1,2})`)
+
+// extractDate 从文本中提取日期并格式化为 2006-01-02，无法识别时返回原文
+func extractDate(text string) string {
+	text = collapseSpace(text)
+	m := datePattern.FindStringSubmatch(text)
+	if m == nil {
+		return text
+	}
+	y, _ := strconv.Atoi(m[1])
+	mo, _ := strconv.Atoi(m[2])
+	d, _ := strconv.Atoi(m[3])
+	return fmt.Sprintf("%04d-%02d-%02d", y, mo, d)
+}
+
+func resolveURL(base *url.URL, href string) string {
+	ref, err := url.Parse(strings.TrimSpace(href))
+	if err != nil || base == nil {
+		return href
+	}
+	return base.ResolveReference(ref).String()
+}
+
+func collapseSpace(s string) string {
+	return strings.Join(strings.Fields(s), " ")
+}
+
+func truncateDay(t time.Time) time.Time {
+	y, m, d := t.Date()
+	return time.Date(y, m, d, 0, 0, 0, 0, t.Location())
+}
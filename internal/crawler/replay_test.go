@@ -0,0 +1,107 @@
+package crawler_test
+
+import (
+	"context"
+	"flag"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ieasydevops/demo-scrapy/internal/crawler"
+	"github.com/ieasydevops/demo-scrapy/internal/crawler/crawlertest"
+	"github.com/ieasydevops/demo-scrapy/internal/fetch"
+)
+
+// go test ./internal/crawler -run Replay -record 访问真实门户，把真实响应录制到 testdata/fixtures
+var record = flag.Bool("record", false, "访问真实门户并录制真实响应")
+
+// 回放的目录：testdata/fixtures 为 -record 录制的真实响应；testdata/synthetic 为按门户响应格式
+// 构造的合成响应，不是真实录制，没有真实录制时使用
+const (
+	recordedDir  = "testdata/fixtures"
+	syntheticDir = "testdata/synthetic"
+)
+
+// 回放文件中的请求包含时间窗口，使用固定的时区和日期保证请求不随运行环境变化
+var (
+	cst       = time.FixedZone("CST", 8*3600)
+	replayDay = time.Date(2025, 6, 2, 0, 0, 0, 0, cst)
+)
+
+// useFixtures 配置数据源的录制或回放，返回是否回放合成响应（只有合成响应的条数是确定的）
+func useFixtures(t *testing.T, source string) bool {
+	opts := fetch.FixtureOptions{Mode: fetch.FixtureReplay, Dir: recordedDir}
+	synthetic := false
+	if *record {
+		opts.Mode = fetch.FixtureRecord
+	} else if _, err := os.Stat(filepath.Join(recordedDir, source)); os.IsNotExist(err) {
+		opts.Dir, synthetic = syntheticDir, true
+	}
+	crawlertest.ConfigureFetch(t, opts, source)
+	return synthetic
+}
+
+func TestSzggzyReplay(t *testing.T) {
+	synthetic := useFixtures(t, "szggzy")
+	src, err := crawler.NewSource("szggzy", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	anns, stats, err := crawler.Crawl(context.Background(), src, []string{"生态环境局"}, replayDay, replayDay.Add(24*time.Hour))
+	if err != nil {
+		t.Fatalf("Crawl: %v", err)
+	}
+	if len(anns) == 0 {
+		t.Fatal("没有采集到公告")
+	}
+	if synthetic && (stats.Pages != 2 || len(anns) != 53) {
+		t.Errorf("%d 页 %d 条，期望合成响应中的 2 页 53 条", stats.Pages, len(anns))
+	}
+	for _, ann := range anns {
+		if strings.Contains(ann.Title, "<") || strings.Contains(ann.Content, "<") {
+			t.Errorf("未清除高亮标签: %q", ann.Title)
+		}
+		if !strings.HasPrefix(ann.URL, "http://zfcg.szggzy.com:8081/") {
+			t.Errorf("链接未补全门户地址: %s", ann.URL)
+		}
+		if ann.PublishDate != "2025-06-02" {
+			t.Errorf("%s 发布日期 %s 不在窗口内", ann.Title, ann.PublishDate)
+		}
+	}
+}
+
+func TestHTMLSourceReplay(t *testing.T) {
+	synthetic := useFixtures(t, "html")
+	src, err := crawler.NewSource("html", map[string]string{
+		"url":           "http://meeb.sz.gov.cn/xxgk/qt/tzgg/",
+		"item_selector": "ul.news_list li",
+		"date_selector": "span",
+		"next_selector": "a.next",
+		"max_pages":     "2",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	anns, stats, err := crawler.Crawl(context.Background(), src, []string{"监测"}, replayDay, replayDay.Add(24*time.Hour))
+	if err != nil {
+		t.Fatalf("Crawl: %v", err)
+	}
+	if len(anns) == 0 {
+		t.Fatal("没有采集到公告")
+	}
+	if synthetic && (stats.Pages != 2 || len(anns) != 3) {
+		t.Errorf("%d 页 %d 条，期望合成响应中的 2 页 3 条", stats.Pages, len(anns))
+	}
+	for _, ann := range anns {
+		if !strings.HasPrefix(ann.URL, "http://meeb.sz.gov.cn/") {
+			t.Errorf("相对链接未按列表页地址解析: %s", ann.URL)
+		}
+		if !strings.Contains(ann.Title, "监测") {
+			t.Errorf("未按关键词过滤: %s", ann.Title)
+		}
+	}
+}
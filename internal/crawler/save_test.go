@@ -0,0 +1,98 @@
+package crawler_test
+
+import (
+	"testing"
+
+	"github.com/ieasydevops/demo-scrapy/internal/crawler"
+	"github.com/ieasydevops/demo-scrapy/internal/crawler/crawlertest"
+	"github.com/ieasydevops/demo-scrapy/internal/database"
+	"github.com/ieasydevops/demo-scrapy/internal/models"
+)
+
+func TestSaveAnnouncementsSkipsExisting(t *testing.T) {
+	crawlertest.OpenDB(t)
+	pageID := crawlertest.InsertWebPage(t, "深圳政府采购网", "http://zfcg.szggzy.com:8081", "szggzy", "{}")
+
+	first := []models.Announcement{
+		{Title: "深圳市生态环境局监测服务采购公告", URL: "http://example.com/a.html", PublishDate: "2025-06-02"},
+		{Title: "深圳市生态环境局设备维护项目结果公告", URL: "http://example.com/b.html", PublishDate: "2025-06-02"},
+	}
+	saved, skipped, err := crawler.SaveAnnouncements(first, pageID, 0, false)
+	if err != nil {
+		t.Fatalf("SaveAnnouncements: %v", err)
+	}
+	if len(saved) != 2 || skipped != 0 {
+		t.Fatalf("新增 %d 条、跳过 %d 条，期望新增 2 条", len(saved), skipped)
+	}
+	for _, ann := range saved {
+		if ann.ID == 0 || ann.WebPageID != pageID {
+			t.Errorf("保存结果缺少 ID 或网页: %+v", ann)
+		}
+	}
+	if saved[0].Type != "tender" || saved[1].Type != "award" {
+		t.Errorf("公告类型 %q %q，期望 tender award", saved[0].Type, saved[1].Type)
+	}
+
+	again := append(first, models.Announcement{Title: "深圳市生态环境局更正公告", URL: "http://example.com/c.html", PublishDate: "2025-06-03"})
+	saved, skipped, err = crawler.SaveAnnouncements(again, pageID, 0, false)
+	if err != nil {
+		t.Fatalf("SaveAnnouncements: %v", err)
+	}
+	if len(saved) != 1 || skipped != 2 || saved[0].URL != "http://example.com/c.html" {
+		t.Errorf("新增 %d 条、跳过 %d 条，期望只新增 c.html", len(saved), skipped)
+	}
+
+	var count int
+	if err := database.DB.QueryRow("SELECT COUNT(*) FROM announcements WHERE web_page_id = ?", pageID).Scan(&count); err != nil {
+		t.Fatal(err)
+	}
+	if count != 3 {
+		t.Errorf("数据库中 %d 条公告，期望 3 条", count)
+	}
+}
+
+func TestSaveDetailKeepsDownloadedAttachment(t *testing.T) {
+	crawlertest.OpenDB(t)
+	pageID := crawlertest.InsertWebPage(t, "深圳政府采购网", "http://zfcg.szggzy.com:8081", "szggzy", "{}")
+	saved, _, err := crawler.SaveAnnouncements([]models.Announcement{
+		{Title: "深圳市生态环境局监测服务采购公告", URL: "http://example.com/a.html", PublishDate: "2025-06-02"},
+	}, pageID, 0, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ann := saved[0]
+	ann.Body = "采购文件见附件"
+
+	downloaded := models.Attachment{Name: "采购文件.pdf", URL: "http://example.com/a.pdf", Ext: "pdf",
+		Size: 1024, SHA256: "abc123", LocalPath: "attachments/abc123.pdf"}
+	if _, err := crawler.SaveDetail(ann, []models.Attachment{downloaded}, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	// 重新采集时下载失败：保留此前下载的文件信息，更新名称
+	if _, err := crawler.SaveDetail(ann, []models.Attachment{
+		{Name: "采购文件（更新）.pdf", URL: "http://example.com/a.pdf", Ext: "pdf", Error: "下载超时"},
+	}, nil); err != nil {
+		t.Fatal(err)
+	}
+	list, err := crawler.GetAttachments(ann.ID)
+	if err != nil || len(list) != 1 {
+		t.Fatalf("附件 %+v（%v），期望 1 个", list, err)
+	}
+	got := list[0]
+	if got.Name != "采购文件（更新）.pdf" || got.Size != 1024 || got.SHA256 != "abc123" ||
+		got.LocalPath != "attachments/abc123.pdf" || got.Error != "" {
+		t.Errorf("重新采集后的附件 %+v，期望保留下载的文件", got)
+	}
+
+	// 重新下载成功时使用新的文件
+	if _, err := crawler.SaveDetail(ann, []models.Attachment{
+		{Name: "采购文件.pdf", URL: "http://example.com/a.pdf", Ext: "pdf", Size: 2048, SHA256: "def456", LocalPath: "attachments/def456.pdf"},
+	}, nil); err != nil {
+		t.Fatal(err)
+	}
+	list, _ = crawler.GetAttachments(ann.ID)
+	if got := list[0]; got.Size != 2048 || got.SHA256 != "def456" || got.LocalPath != "attachments/def456.pdf" {
+		t.Errorf("重新下载后的附件 %+v", got)
+	}
+}
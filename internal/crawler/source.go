@@ -0,0 +1,195 @@
+package crawler
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/ieasydevops/demo-scrapy/internal/keyword"
+	"github.com/ieasydevops/demo-scrapy/internal/models"
+)
+
+// DefaultSource 未指定适配器的网页使用的数据源
+const DefaultSource = "szggzy"
+
+// SearchParams 单页检索参数
+type SearchParams struct {
+	// Keywords 由关键词表达式推导的上游检索词，比表达式宽松，结果仍会在本地按表达式过滤；
+	// 为空时应返回时间窗口内的全部公告
+	Keywords  []string
+	StartTime time.Time
+	EndTime   time.Time
+	Page      int
+	PageSize  int
+}
+
+// SearchPage 单页检索结果，公告已归一化为 models.Announcement。
+// HasMore 为 false 时 Crawl 停止翻页
+type SearchPage struct {
+	Announcements []models.Announcement
+	Total         int
+	HasMore       bool
+}
+
+// Source 公告数据源适配器，每个采购门户实现一个
+type Source interface {
+	Name() string
+	FetchPage(ctx context.Context, params SearchParams) (*SearchPage, error)
+}
+
+// ParamsReporter 可选接口，返回数据源实际生效的参数（含默认值），用于在接口中展示
+type ParamsReporter interface {
+	EffectiveParams() map[string]string
+}
+
+// SourceFactory 根据网页配置的参数创建数据源
+type SourceFactory func(params map[string]string) (Source, error)
+
+var (
+	sourcesMu sync.RWMutex
+	sources   = map[string]SourceFactory{}
+)
+
+// RegisterSource 注册数据源适配器，通常在适配器文件的 init 中调用
+func RegisterSource(name string, factory SourceFactory) {
+	sourcesMu.Lock()
+	defer sourcesMu.Unlock()
+
+	if _, exists := sources[name]; exists {
+		panic(fmt.Sprintf("数据源重复注册: %s", name))
+	}
+	sources[name] = factory
+}
+
+// NewSource 按名称创建数据源，名称为空时使用 DefaultSource
+func NewSource(name string, params map[string]string) (Source, error) {
+	if name == "" {
+		name = DefaultSource
+	}
+
+	sourcesMu.RLock()
+	factory, ok := sources[name]
+	sourcesMu.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("未知的数据源: %s", name)
+	}
+
+	return factory(params)
+}
+
+// SourceForWebPage 根据网页记录中的适配器名称和参数创建数据源
+// 参数中未设置 url 时使用网页记录的 url
+func SourceForWebPage(page models.WebPage) (Source, error) {
+	params := make(map[string]string, len(page.SourceParams)+1)
+	for k, v := range page.SourceParams {
+		params[k] = v
+	}
+	if params["url"] == "" {
+		params["url"] = page.URL
+	}
+	return NewSource(page.Source, params)
+}
+
+// WithSourceParams 返回参数合并了 overrides 的网页副本，overrides 中的同名参数优先。
+// 监控配置用它在同一网页上检索不同的频道或分类
+func WithSourceParams(page models.WebPage, overrides map[string]string) models.WebPage {
+	if len(overrides) == 0 {
+		return page
+	}
+	params := make(map[string]string, len(page.SourceParams)+len(overrides))
+	for k, v := range page.SourceParams {
+		params[k] = v
+	}
+	for k, v := range overrides {
+		params[k] = v
+	}
+	page.SourceParams = params
+	return page
+}
+
+// SourceNames 返回已注册的数据源名称
+func SourceNames() []string {
+	sourcesMu.RLock()
+	defer sourcesMu.RUnlock()
+
+	names := make([]string, 0, len(sources))
+	for name := range sources {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// CrawlStats 一次采集的翻页和过滤计数，失败时为失败前已完成的部分
+type CrawlStats struct {
+	Pages   int
+	Fetched int
+	Matched int
+}
+
+// Crawl 分页拉取数据源在时间窗口内的结果，并按关键词表达式过滤。
+// 某一页失败时返回错误以及此前各页已匹配的公告，调用方可以先保存这部分结果。
+// 请求间隔由 fetch 客户端按主机限速控制
+func Crawl(ctx context.Context, src Source, keywords []string, startTime, endTime time.Time) ([]models.Announcement, CrawlStats, error) {
+	var stats CrawlStats
+	exprs, err := keyword.ParseAll(keywords)
+	if err != nil {
+		return nil, stats, err
+	}
+	upstream := keyword.UpstreamTerms(exprs)
+
+	var allAnnouncements []models.Announcement
+	pageSize := 50
+
+	for pageNum := 0; ; pageNum++ {
+		page, err := src.FetchPage(ctx, SearchParams{
+			Keywords:  upstream,
+			StartTime: startTime,
+			EndTime:   endTime,
+			Page:      pageNum,
+			PageSize:  pageSize,
+		})
+		if err != nil {
+			return allAnnouncements, stats, fmt.Errorf("%s 第 %d 页采集失败: %w", src.Name(), pageNum+1, err)
+		}
+		stats.Pages++
+		stats.Fetched += len(page.Announcements)
+
+		for _, ann := range page.Announcements {
+			if matchKeywords(ann, exprs) {
+				allAnnouncements = append(allAnnouncements, ann)
+				stats.Matched++
+				log.Printf("采集公告: %s", ann.Title)
+			}
+		}
+
+		if !page.HasMore {
+			break
+		}
+		if err := ctx.Err(); err != nil {
+			return allAnnouncements, stats, err
+		}
+	}
+
+	return allAnnouncements, stats, nil
+}
+
+// matchKeywords 没有关键词时保留全部公告
+func matchKeywords(ann models.Announcement, exprs []keyword.Expr) bool {
+	if len(exprs) == 0 {
+		return true
+	}
+	return keyword.MatchAny(exprs, AnnouncementDocument(ann))
+}
+
+// AnnouncementDocument 返回用于关键词匹配的公告文本，正文和摘要都计入 content
+func AnnouncementDocument(ann models.Announcement) keyword.Document {
+	content := ann.Content
+	if ann.Body != "" {
+		content += "\n" + ann.Body
+	}
+	return keyword.Document{Title: ann.Title, Content: content}
+}
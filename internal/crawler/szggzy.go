@@ -0,0 +1,331 @@
+package crawler
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/ieasydevops/demo-scrapy/internal/fetch"
+	"github.com/ieasydevops/demo-scrapy/internal/models"
+)
+
+func init() {
+	RegisterSource("szggzy", newSzggzySource)
+}
+
+const (
+	szggzyDefaultBaseURL = "http://zfcg.szggzy.com:8081"
+	szggzyDefaultAPIPath = "/inteligentsearch/rest/esinteligentsearch/getFullTextDataNew"
+)
+
+// szggzyNumber 频道和分类编号由数字组成
+var szggzyNumber = regexp.MustCompile(`^[0-9]+$`)
+
+type APISearchRequest struct {
+	Pn           int    `json:"pn"`
+	Rn           int    `json:"rn"`
+	Sdt          string `json:"sdt"`
+	Edt          string `json:"edt"`
+	Wd           string `json:"wd"`
+	Fields       string `json:"fields"`
+	Cnum         string `json:"cnum"`
+	Sort         string `json:"sort"`
+	Ssort        string `json:"ssort"`
+	Cl           int    `json:"cl"`
+	Highlights   string `json:"highlights"`
+	NoParticiple string `json:"noParticiple"`
+	// Condition 附加过滤条件，按分类检索时使用
+	Condition []APISearchCondition `json:"condition,omitempty"`
+}
+
+// APISearchCondition 检索接口的字段过滤条件，LikeType 为 2 时按前缀匹配
+type APISearchCondition struct {
+	FieldName string   `json:"fieldName"`
+	EqualList []string `json:"equalList"`
+	IsLike    bool     `json:"isLike"`
+	LikeType  int      `json:"likeType"`
+}
+
+type APISearchResponse struct {
+	Result struct {
+		Totalcount int `json:"totalcount"`
+		Records    []struct {
+			Title   string `json:"title"`
+			Content string `json:"content"`
+			Webdate string `json:"webdate"`
+			Linkurl string `json:"linkurl"`
+		} `json:"records"`
+	} `json:"result"`
+}
+
+// szggzySource 深圳政府采购网全文检索接口（getFullTextDataNew）
+//
+// 支持的参数:
+//   - base_url: 门户地址，默认 http://zfcg.szggzy.com:8081
+//   - api_path: 检索接口路径，默认 /inteligentsearch/rest/esinteligentsearch/getFullTextDataNew
+//   - cnum: 检索频道编号，默认 002
+//   - categories: 分类编号，多个用逗号分隔，按前缀匹配（如 002001 包含其下的子分类），默认不过滤
+//   - fields: 检索字段，分号分隔，默认 title;content
+//   - highlights: 高亮字段，分号分隔，默认 title;content
+//   - sort: 排序，JSON 对象，值 0 为降序、1 为升序，默认 {"webdate":"0"}
+//   - ssort: 二级排序字段，默认 title
+//   - cl: 摘要截取长度，默认 500
+//   - participle: 是否对检索词分词，true/false，默认 true
+type szggzySource struct {
+	client  *fetch.Client
+	baseURL string
+	apiPath string
+
+	cnum       string
+	categories []string
+	fields     string
+	highlights string
+	sort       string
+	ssort      string
+	cl         int
+	participle bool
+}
+
+func newSzggzySource(params map[string]string) (Source, error) {
+	s := &szggzySource{
+		client:     fetch.For("szggzy"),
+		baseURL:    szggzyDefaultBaseURL,
+		apiPath:    szggzyDefaultAPIPath,
+		cnum:       "002",
+		fields:     "title;content",
+		highlights: "title;content",
+		sort:       `{"webdate":"0"}`,
+		ssort:      "title",
+		cl:         500,
+		participle: true,
+	}
+
+	if v := params["base_url"]; v != "" {
+		u, err := url.Parse(v)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return nil, fmt.Errorf("base_url 无效: %s", v)
+		}
+		s.baseURL = strings.TrimRight(v, "/")
+	}
+	if v := params["api_path"]; v != "" {
+		if !strings.HasPrefix(v, "/") {
+			return nil, fmt.Errorf("api_path 应以 / 开头: %s", v)
+		}
+		s.apiPath = v
+	}
+	if v := params["cnum"]; v != "" {
+		if !szggzyNumber.MatchString(v) {
+			return nil, fmt.Errorf("cnum 应为数字编号: %s", v)
+		}
+		s.cnum = v
+	}
+	for _, v := range strings.Split(params["categories"], ",") {
+		if v = strings.TrimSpace(v); v == "" {
+			continue
+		}
+		if !szggzyNumber.MatchString(v) {
+			return nil, fmt.Errorf("categories 中的分类编号应为数字: %s", v)
+		}
+		s.categories = append(s.categories, v)
+	}
+	if v := params["fields"]; v != "" {
+		s.fields = v
+	}
+	if v := params["highlights"]; v != "" {
+		s.highlights = v
+	}
+	if v := params["sort"]; v != "" {
+		var order map[string]string
+		if err := json.Unmarshal([]byte(v), &order); err != nil || len(order) == 0 {
+			return nil, fmt.Errorf(`sort 应为 JSON 对象，如 {"webdate":"0"}: %s`, v)
+		}
+		s.sort = v
+	}
+	if v := params["ssort"]; v != "" {
+		s.ssort = v
+	}
+	if v := params["cl"]; v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n <= 0 {
+			return nil, fmt.Errorf("cl 应为正整数: %s", v)
+		}
+		s.cl = n
+	}
+	if v := params["participle"]; v != "" {
+		b, err := strconv.ParseBool(v)
+		if err != nil {
+			return nil, fmt.Errorf("participle 应为 true 或 false: %s", v)
+		}
+		s.participle = b
+	}
+	return s, nil
+}
+
+// EffectiveParams 返回包含默认值在内的实际检索参数
+func (s *szggzySource) EffectiveParams() map[string]string {
+	return map[string]string{
+		"base_url":   s.baseURL,
+		"api_path":   s.apiPath,
+		"cnum":       s.cnum,
+		"categories": strings.Join(s.categories, ","),
+		"fields":     s.fields,
+		"highlights": s.highlights,
+		"sort":       s.sort,
+		"ssort":      s.ssort,
+		"cl":         strconv.Itoa(s.cl),
+		"participle": strconv.FormatBool(s.participle),
+	}
+}
+
+func (s *szggzySource) Name() string {
+	return "szggzy"
+}
+
+func (s *szggzySource) FetchPage(ctx context.Context, params SearchParams) (*SearchPage, error) {
+	// 没有检索词（如只有排除条件的表达式）时 wd 为空，拉取时间窗口内的全部公告，由 Crawl 在本地过滤
+	keywordStr := strings.Join(params.Keywords, " ")
+
+	searchReq := APISearchRequest{
+		Pn:           params.Page * params.PageSize,
+		Rn:           params.PageSize,
+		Sdt:          params.StartTime.Format("2006-01-02 15:04:05"),
+		Edt:          params.EndTime.Format("2006-01-02 15:04:05"),
+		Wd:           keywordStr,
+		Fields:       s.fields,
+		Cnum:         s.cnum,
+		Sort:         s.sort,
+		Ssort:        s.ssort,
+		Cl:           s.cl,
+		Highlights:   s.highlights,
+		NoParticiple: "1",
+	}
+	if s.participle {
+		searchReq.NoParticiple = "0"
+	}
+	if len(s.categories) > 0 {
+		searchReq.Condition = []APISearchCondition{{
+			FieldName: "categorynum",
+			EqualList: s.categories,
+			IsLike:    true,
+			LikeType:  2,
+		}}
+	}
+
+	apiResponse, err := s.sendAPISearchRequest(ctx, searchReq)
+	if err != nil {
+		return nil, fmt.Errorf("API请求失败: %w", err)
+	}
+
+	page := &SearchPage{Total: apiResponse.Result.Totalcount}
+	for _, record := range apiResponse.Result.Records {
+		page.Announcements = append(page.Announcements, models.Announcement{
+			Title:       cleanHTMLTags(record.Title),
+			URL:         s.buildFullURL(record.Linkurl),
+			PublishDate: formatDateString(record.Webdate),
+			Content:     cleanHTMLTags(record.Content),
+		})
+	}
+	page.HasMore = len(apiResponse.Result.Records) > 0 && searchReq.Pn+len(apiResponse.Result.Records) < page.Total
+
+	return page, nil
+}
+
+func (s *szggzySource) sendAPISearchRequest(ctx context.Context, reqData APISearchRequest) (*APISearchResponse, error) {
+	apiURL := s.baseURL + s.apiPath
+
+	jsonData, err := json.Marshal(reqData)
+	if err != nil {
+		return nil, fmt.Errorf("JSON编码失败: %v", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", apiURL, strings.NewReader(string(jsonData)))
+	if err != nil {
+		return nil, fmt.Errorf("创建请求失败: %v", err)
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept", "application/json, text/plain, */*")
+	req.Header.Set("Origin", s.baseURL)
+	req.Header.Set("Referer", s.baseURL+"/")
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("请求失败: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("HTTP状态码错误: %d", resp.StatusCode)
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("读取响应失败: %v", err)
+	}
+
+	if looksLikeHTML(resp.Header.Get("Content-Type"), body) {
+		return nil, newDriftError(body, "接口返回了 HTML 页面而不是 JSON")
+	}
+	var apiResp APISearchResponse
+	if err := json.Unmarshal(body, &apiResp); err != nil {
+		return nil, newDriftError(body, "JSON解析失败: %v", err)
+	}
+	if err := checkSzggzyResponse(body, reqData.Pn, len(apiResp.Result.Records), apiResp.Result.Totalcount); err != nil {
+		return nil, err
+	}
+
+	return &apiResp, nil
+}
+
+// szggzyRecordFields 每条记录解析时用到的字段
+var szggzyRecordFields = []string{"title", "linkurl", "webdate"}
+
+// checkSzggzyResponse 检查响应包含解析所需的字段且计数一致。
+// 字段改名或层级变化时 json.Unmarshal 不报错而是得到零值，看起来像是没有结果
+func checkSzggzyResponse(body []byte, offset, records, total int) error {
+	var raw struct {
+		Result *struct {
+			Totalcount json.RawMessage              `json:"totalcount"`
+			Records    []map[string]json.RawMessage `json:"records"`
+		} `json:"result"`
+	}
+	if err := json.Unmarshal(body, &raw); err != nil {
+		return newDriftError(body, "JSON解析失败: %v", err)
+	}
+	if raw.Result == nil {
+		return newDriftError(body, "缺少 result 字段")
+	}
+	if raw.Result.Totalcount == nil {
+		return newDriftError(body, "缺少 result.totalcount 字段")
+	}
+	for i, rec := range raw.Result.Records {
+		for _, field := range szggzyRecordFields {
+			if _, ok := rec[field]; !ok {
+				return newDriftError(body, "第 %d 条记录缺少 %s 字段", offset+i+1, field)
+			}
+		}
+	}
+	if records == 0 && total > offset {
+		return newDriftError(body, "totalcount 为 %d，但从第 %d 条起没有返回 records", total, offset+1)
+	}
+	if records > 0 && total == 0 {
+		return newDriftError(body, "返回了 %d 条 records，但 totalcount 为 0", records)
+	}
+	return nil
+}
+
+func (s *szggzySource) buildFullURL(href string) string {
+	if strings.HasPrefix(href, "http://") || strings.HasPrefix(href, "https://") {
+		return href
+	}
+	if strings.HasPrefix(href, "/") {
+		return s.baseURL + href
+	}
+	return s.baseURL + "/gsgg/" + href
+}
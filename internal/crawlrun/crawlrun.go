@@ -0,0 +1,214 @@
+package crawlrun
+
+import (
+	"database/sql"
+	"strings"
+	"time"
+
+	"github.com/ieasydevops/demo-scrapy/internal/database"
+	"github.com/ieasydevops/demo-scrapy/internal/models"
+)
+
+// 采集的触发方式
+const (
+	TriggerSchedule = "schedule"
+	TriggerStartup  = "startup"
+	TriggerManual   = "manual"
+	TriggerBackfill = "backfill"
+)
+
+// 采集状态
+const (
+	StatusRunning = "running"
+	StatusSuccess = "success"
+	StatusFailed  = "failed"
+)
+
+// Stats 一次采集的计数
+type Stats struct {
+	PagesFetched   int
+	RecordsFetched int
+	Matched        int
+	Inserted       int
+	Skipped        int
+}
+
+// Start 记录一次开始执行的采集，返回记录 ID
+func Start(run models.CrawlRun) (int, error) {
+	result, err := database.DB.Exec(`
+		INSERT INTO crawl_runs (monitor_config_id, web_page_id, trigger, source, keywords, window_start, window_end, status, backfill_job_id)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`, database.NullInt(run.MonitorConfigID), database.NullInt(run.WebPageID), run.Trigger, run.Source, strings.Join(run.Keywords, ","),
+		run.WindowStart, run.WindowEnd, StatusRunning, database.NullInt(run.BackfillJobID))
+	if err != nil {
+		return 0, err
+	}
+	id, err := result.LastInsertId()
+	return int(id), err
+}
+
+// Finish 记录采集结束，runErr 不为空时状态为 failed
+func Finish(id int, started time.Time, stats Stats, runErr error) error {
+	status, message := StatusSuccess, ""
+	if runErr != nil {
+		status, message = StatusFailed, runErr.Error()
+	}
+	_, err := database.DB.Exec(`
+		UPDATE crawl_runs SET status = ?, finished_at = CURRENT_TIMESTAMP, duration_ms = ?,
+			pages_fetched = ?, records_fetched = ?, matched = ?, inserted = ?, skipped = ?, error = ?
+		WHERE id = ?
+	`, status, time.Since(started).Milliseconds(), stats.PagesFetched, stats.RecordsFetched,
+		stats.Matched, stats.Inserted, stats.Skipped, message, id)
+	return err
+}
+
+// AbandonRunning 将上次进程退出时仍在执行的采集标记为失败，返回处理的条数
+func AbandonRunning() (int, error) {
+	result, err := database.DB.Exec(`
+		UPDATE crawl_runs SET status = ?, finished_at = CURRENT_TIMESTAMP, error = '进程退出时采集尚未完成'
+		WHERE status = ?
+	`, StatusFailed, StatusRunning)
+	if err != nil {
+		return 0, err
+	}
+	n, err := result.RowsAffected()
+	return int(n), err
+}
+
+// Filter 采集记录查询条件，零值表示不限制
+type Filter struct {
+	MonitorConfigID int
+	Status          string
+	Trigger         string
+	BackfillJobID   int
+}
+
+const runColumns = `
+	r.id, r.monitor_config_id, r.web_page_id, wp.name, r.trigger, r.source, r.keywords,
+	r.window_start, r.window_end, r.status, r.started_at, r.finished_at, r.duration_ms,
+	r.pages_fetched, r.records_fetched, r.matched, r.inserted, r.skipped, r.error, r.backfill_job_id`
+
+// List 分页查询采集记录，按开始时间倒序
+func List(filter Filter, page, pageSize int) ([]models.CrawlRun, int, error) {
+	where := " WHERE 1=1"
+	var args []interface{}
+	if filter.MonitorConfigID > 0 {
+		where += " AND r.monitor_config_id = ?"
+		args = append(args, filter.MonitorConfigID)
+	}
+	if filter.Status != "" {
+		where += " AND r.status = ?"
+		args = append(args, filter.Status)
+	}
+	if filter.Trigger != "" {
+		where += " AND r.trigger = ?"
+		args = append(args, filter.Trigger)
+	}
+	if filter.BackfillJobID > 0 {
+		where += " AND r.backfill_job_id = ?"
+		args = append(args, filter.BackfillJobID)
+	}
+
+	var total int
+	if err := database.DB.QueryRow("SELECT COUNT(*) FROM crawl_runs r"+where, args...).Scan(&total); err != nil {
+		return nil, 0, err
+	}
+
+	rows, err := database.DB.Query("SELECT "+runColumns+`
+		FROM crawl_runs r
+		LEFT JOIN web_pages wp ON r.web_page_id = wp.id`+where+`
+		ORDER BY r.started_at DESC, r.id DESC
+		LIMIT ? OFFSET ?`, append(args, pageSize, (page-1)*pageSize)...)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	var runs []models.CrawlRun
+	for rows.Next() {
+		run, err := scanRun(rows)
+		if err != nil {
+			return nil, 0, err
+		}
+		runs = append(runs, run)
+	}
+	return runs, total, rows.Err()
+}
+
+// Get 按 ID 读取采集记录
+func Get(id int) (models.CrawlRun, error) {
+	row := database.DB.QueryRow("SELECT "+runColumns+`
+		FROM crawl_runs r
+		LEFT JOIN web_pages wp ON r.web_page_id = wp.id
+		WHERE r.id = ?`, id)
+	return scanRun(row)
+}
+
+func scanRun(row interface{ Scan(...interface{}) error }) (models.CrawlRun, error) {
+	var run models.CrawlRun
+	var configID, webPageID, backfillJobID sql.NullInt64
+	var webPageName, windowStart, windowEnd, finishedAt sql.NullString
+	var keywords string
+	if err := row.Scan(&run.ID, &configID, &webPageID, &webPageName, &run.Trigger, &run.Source, &keywords,
+		&windowStart, &windowEnd, &run.Status, &run.StartedAt, &finishedAt, &run.DurationMS,
+		&run.PagesFetched, &run.RecordsFetched, &run.Matched, &run.Inserted, &run.Skipped, &run.Error, &backfillJobID); err != nil {
+		return run, err
+	}
+	run.MonitorConfigID = int(configID.Int64)
+	run.WebPageID = int(webPageID.Int64)
+	run.BackfillJobID = int(backfillJobID.Int64)
+	run.WebPageName = webPageName.String
+	run.WindowStart = windowStart.String
+	run.WindowEnd = windowEnd.String
+	run.FinishedAt = finishedAt.String
+	if keywords != "" {
+		run.Keywords = strings.Split(keywords, ",")
+	}
+	return run, nil
+}
+
+// EmptyStreak 判断监控配置最近 n 次已结束的非回填采集是否都成功但没有拉取到任何记录，
+// 且该配置更早的采集拉取到过记录（回填的日窗口可能本就没有公告，不计入）。
+// 同一网页上的各监控配置关键词不同，各自按自己的采集记录判断；monitorConfigID 为 0 时判断直接按网页执行的采集
+func EmptyStreak(monitorConfigID, webPageID, n int) (bool, error) {
+	target := "monitor_config_id = ?"
+	args := []interface{}{monitorConfigID}
+	if monitorConfigID == 0 {
+		target = "monitor_config_id IS NULL AND web_page_id = ?"
+		args = []interface{}{webPageID}
+	}
+
+	rows, err := database.DB.Query(`
+		SELECT status, records_fetched FROM crawl_runs
+		WHERE `+target+` AND trigger != ? AND status != ?
+		ORDER BY id DESC LIMIT ?
+	`, append(args, TriggerBackfill, StatusRunning, n)...)
+	if err != nil {
+		return false, err
+	}
+	count := 0
+	for rows.Next() {
+		var status string
+		var fetched int
+		if err := rows.Scan(&status, &fetched); err != nil {
+			rows.Close()
+			return false, err
+		}
+		if status != StatusSuccess || fetched > 0 {
+			rows.Close()
+			return false, nil
+		}
+		count++
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil || count < n {
+		return false, err
+	}
+
+	var earlier int
+	err = database.DB.QueryRow(`
+		SELECT COUNT(*) FROM crawl_runs
+		WHERE `+target+` AND status = ? AND records_fetched > 0
+	`, append(args, StatusSuccess)...).Scan(&earlier)
+	return earlier > 0, err
+}
@@ -0,0 +1,313 @@
+package database
+
+import (
+	"crypto/sha256"
+	"database/sql"
+	"embed"
+	"encoding/hex"
+	"fmt"
+	"io/fs"
+	"log"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+//go:embed migrations/*.sql
+var migrationFiles embed.FS
+
+// Migration 一个版本的结构变更，文件名为 <版本>_<名称>.up.sql 和 <版本>_<名称>.down.sql
+type Migration struct {
+	Version  int
+	Name     string
+	Up       string
+	Down     string
+	Checksum string
+}
+
+// MigrationState 迁移在当前数据库中的状态
+type MigrationState struct {
+	Version   int
+	Name      string
+	Applied   bool
+	AppliedAt string
+	// Modified 已执行的迁移文件内容与记录的校验和不一致
+	Modified bool
+}
+
+// Migrations 读取内嵌的迁移文件，按版本升序返回
+func Migrations() ([]Migration, error) {
+	entries, err := fs.ReadDir(migrationFiles, "migrations")
+	if err != nil {
+		return nil, err
+	}
+
+	byVersion := map[int]*Migration{}
+	for _, entry := range entries {
+		name := entry.Name()
+		var direction string
+		switch {
+		case strings.HasSuffix(name, ".up.sql"):
+			direction = "up"
+		case strings.HasSuffix(name, ".down.sql"):
+			direction = "down"
+		default:
+			return nil, fmt.Errorf("无法识别的迁移文件: %s", name)
+		}
+
+		base := strings.TrimSuffix(name, "."+direction+".sql")
+		prefix, title, ok := strings.Cut(base, "_")
+		if !ok {
+			return nil, fmt.Errorf("迁移文件名应为 <版本>_<名称>: %s", name)
+		}
+		version, err := strconv.Atoi(prefix)
+		if err != nil || version <= 0 {
+			return nil, fmt.Errorf("迁移文件版本号无效: %s", name)
+		}
+
+		data, err := migrationFiles.ReadFile("migrations/" + name)
+		if err != nil {
+			return nil, err
+		}
+
+		m := byVersion[version]
+		if m == nil {
+			m = &Migration{Version: version, Name: title}
+			byVersion[version] = m
+		} else if m.Name != title {
+			return nil, fmt.Errorf("迁移版本 %d 重复: %s 和 %s", version, m.Name, title)
+		}
+		if direction == "up" {
+			m.Up = string(data)
+			sum := sha256.Sum256(data)
+			m.Checksum = hex.EncodeToString(sum[:])
+		} else {
+			m.Down = string(data)
+		}
+	}
+
+	migrations := make([]Migration, 0, len(byVersion))
+	for _, m := range byVersion {
+		if m.Up == "" {
+			return nil, fmt.Errorf("迁移 %d_%s 缺少 up 文件", m.Version, m.Name)
+		}
+		migrations = append(migrations, *m)
+	}
+	sort.Slice(migrations, func(i, j int) bool { return migrations[i].Version < migrations[j].Version })
+	return migrations, nil
+}
+
+type appliedMigration struct {
+	checksum  string
+	appliedAt string
+}
+
+func ensureMigrationTable() error {
+	_, err := DB.Exec(`CREATE TABLE IF NOT EXISTS schema_migrations (
+		version INTEGER PRIMARY KEY,
+		name TEXT NOT NULL,
+		checksum TEXT NOT NULL,
+		applied_at DATETIME DEFAULT CURRENT_TIMESTAMP
+	)`)
+	return err
+}
+
+func appliedMigrations() (map[int]appliedMigration, error) {
+	rows, err := DB.Query("SELECT version, checksum, applied_at FROM schema_migrations")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	applied := map[int]appliedMigration{}
+	for rows.Next() {
+		var version int
+		var a appliedMigration
+		if err := rows.Scan(&version, &a.checksum, &a.appliedAt); err != nil {
+			return nil, err
+		}
+		applied[version] = a
+	}
+	return applied, rows.Err()
+}
+
+// MigrationStatus 返回每个迁移的执行状态
+func MigrationStatus() ([]MigrationState, error) {
+	if err := ensureMigrationTable(); err != nil {
+		return nil, err
+	}
+	migrations, err := Migrations()
+	if err != nil {
+		return nil, err
+	}
+	applied, err := appliedMigrations()
+	if err != nil {
+		return nil, err
+	}
+
+	states := make([]MigrationState, 0, len(migrations))
+	for _, m := range migrations {
+		state := MigrationState{Version: m.Version, Name: m.Name}
+		if a, ok := applied[m.Version]; ok {
+			state.Applied = true
+			state.AppliedAt = a.appliedAt
+			state.Modified = a.checksum != m.Checksum
+		}
+		states = append(states, state)
+	}
+	return states, nil
+}
+
+// verifyApplied 确认已执行的迁移都存在于程序中且内容未被修改
+func verifyApplied(migrations []Migration, applied map[int]appliedMigration) error {
+	known := map[int]Migration{}
+	for _, m := range migrations {
+		known[m.Version] = m
+	}
+	for version, a := range applied {
+		m, ok := known[version]
+		if !ok {
+			return fmt.Errorf("数据库已执行迁移 %d，但当前程序中不存在，请使用更新版本的程序", version)
+		}
+		if a.checksum != m.Checksum {
+			return fmt.Errorf("迁移 %d_%s 在执行后被修改（校验和不一致），请新增迁移而不是修改已发布的迁移", m.Version, m.Name)
+		}
+	}
+	return nil
+}
+
+// MigrateUp 按版本顺序执行所有未执行的迁移，每个迁移在独立事务中执行，返回执行的数量
+func MigrateUp() (int, error) {
+	if err := ensureMigrationTable(); err != nil {
+		return 0, err
+	}
+	migrations, err := Migrations()
+	if err != nil {
+		return 0, err
+	}
+	applied, err := appliedMigrations()
+	if err != nil {
+		return 0, err
+	}
+	if err := verifyApplied(migrations, applied); err != nil {
+		return 0, err
+	}
+
+	if len(applied) == 0 {
+		if err := adoptLegacySchema(); err != nil {
+			return 0, err
+		}
+	}
+
+	count := 0
+	for _, m := range migrations {
+		if _, ok := applied[m.Version]; ok {
+			continue
+		}
+		if err := runMigration(m.Up, func(tx *sql.Tx) error {
+			_, err := tx.Exec("INSERT INTO schema_migrations (version, name, checksum) VALUES (?, ?, ?)",
+				m.Version, m.Name, m.Checksum)
+			return err
+		}); err != nil {
+			return count, fmt.Errorf("执行迁移 %d_%s 失败: %v", m.Version, m.Name, err)
+		}
+		log.Printf("已执行数据库迁移 %d_%s", m.Version, m.Name)
+		count++
+	}
+	return count, nil
+}
+
+// MigrateDownTo 按版本倒序回滚高于 version 的迁移，version 为 0 时回滚全部，返回回滚的数量
+func MigrateDownTo(version int) (int, error) {
+	if err := ensureMigrationTable(); err != nil {
+		return 0, err
+	}
+	migrations, err := Migrations()
+	if err != nil {
+		return 0, err
+	}
+	applied, err := appliedMigrations()
+	if err != nil {
+		return 0, err
+	}
+	if err := verifyApplied(migrations, applied); err != nil {
+		return 0, err
+	}
+
+	count := 0
+	for i := len(migrations) - 1; i >= 0; i-- {
+		m := migrations[i]
+		if m.Version <= version {
+			break
+		}
+		if _, ok := applied[m.Version]; !ok {
+			continue
+		}
+		if m.Down == "" {
+			return count, fmt.Errorf("迁移 %d_%s 没有 down 文件，无法回滚", m.Version, m.Name)
+		}
+		if err := runMigration(m.Down, func(tx *sql.Tx) error {
+			_, err := tx.Exec("DELETE FROM schema_migrations WHERE version = ?", m.Version)
+			return err
+		}); err != nil {
+			return count, fmt.Errorf("回滚迁移 %d_%s 失败: %v", m.Version, m.Name, err)
+		}
+		log.Printf("已回滚数据库迁移 %d_%s", m.Version, m.Name)
+		count++
+	}
+	return count, nil
+}
+
+func runMigration(script string, record func(tx *sql.Tx) error) error {
+	tx, err := DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(script); err != nil {
+		return err
+	}
+	if err := record(tx); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// legacyColumns 迁移框架之前由程序自动添加的列，旧数据库在执行基线迁移前补齐
+var legacyColumns = []struct{ table, column, definition string }{
+	{"web_pages", "source", "TEXT NOT NULL DEFAULT 'szggzy'"},
+	{"web_pages", "source_params", "TEXT NOT NULL DEFAULT '{}'"},
+	{"subscribe_config", "keywords", "TEXT NOT NULL DEFAULT ''"},
+	{"subscribe_config", "web_page_ids", "TEXT NOT NULL DEFAULT ''"},
+	{"subscribe_config", "types", "TEXT NOT NULL DEFAULT ''"},
+	{"announcements", "web_page_id", "INTEGER"},
+	{"announcements", "publisher", "TEXT"},
+	{"announcements", "body", "TEXT"},
+	{"announcements", "detail_fetched_at", "DATETIME"},
+	{"announcements", "project_number", "TEXT"},
+	{"announcements", "purchaser", "TEXT"},
+	{"announcements", "agency", "TEXT"},
+	{"announcements", "budget_amount", "REAL"},
+	{"announcements", "bid_deadline", "TEXT"},
+	{"announcements", "opening_time", "TEXT"},
+	{"announcements", "contact", "TEXT"},
+	{"announcements", "type", "TEXT NOT NULL DEFAULT ''"},
+}
+
+// adoptLegacySchema 为没有迁移记录的旧数据库补齐列，使基线迁移可以在其上执行
+func adoptLegacySchema() error {
+	for _, col := range legacyColumns {
+		var tables int
+		if err := DB.QueryRow("SELECT COUNT(*) FROM sqlite_master WHERE type = 'table' AND name = ?", col.table).Scan(&tables); err != nil {
+			return err
+		}
+		if tables == 0 {
+			continue
+		}
+		if err := addColumnIfMissing(col.table, col.column, col.definition); err != nil {
+			return err
+		}
+	}
+	return nil
+}
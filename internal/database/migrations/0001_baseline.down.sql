@@ -0,0 +1,8 @@
+DROP TABLE IF EXISTS deliveries;
+DROP TABLE IF EXISTS attachments;
+DROP TABLE IF EXISTS announcements;
+DROP TABLE IF EXISTS subscribe_config;
+DROP TABLE IF EXISTS monitor_config;
+DROP TABLE IF EXISTS push_config;
+DROP TABLE IF EXISTS keywords;
+DROP TABLE IF EXISTS web_pages;
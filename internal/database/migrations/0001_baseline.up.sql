@@ -0,0 +1,94 @@
+-- 基线结构。已有数据库在执行前会由 adoptLegacySchema 补齐旧版缺少的列
+CREATE TABLE IF NOT EXISTS web_pages (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	url TEXT NOT NULL,
+	name TEXT NOT NULL,
+	source TEXT NOT NULL DEFAULT 'szggzy',
+	source_params TEXT NOT NULL DEFAULT '{}'
+);
+
+CREATE TABLE IF NOT EXISTS keywords (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	keyword TEXT NOT NULL UNIQUE
+);
+
+CREATE TABLE IF NOT EXISTS push_config (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	email TEXT NOT NULL,
+	push_time TEXT NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS monitor_config (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	web_page_id INTEGER NOT NULL,
+	crawl_time TEXT NOT NULL,
+	crawl_freq TEXT NOT NULL,
+	keywords TEXT NOT NULL,
+	created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+	updated_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+	FOREIGN KEY (web_page_id) REFERENCES web_pages(id)
+);
+
+CREATE TABLE IF NOT EXISTS subscribe_config (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	email TEXT NOT NULL UNIQUE,
+	push_time TEXT NOT NULL,
+	keywords TEXT NOT NULL DEFAULT '',
+	web_page_ids TEXT NOT NULL DEFAULT '',
+	types TEXT NOT NULL DEFAULT '',
+	created_at DATETIME DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE IF NOT EXISTS announcements (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	title TEXT NOT NULL,
+	url TEXT NOT NULL UNIQUE,
+	publish_date TEXT NOT NULL,
+	content TEXT,
+	created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+	web_page_id INTEGER,
+	publisher TEXT,
+	body TEXT,
+	detail_fetched_at DATETIME,
+	project_number TEXT,
+	purchaser TEXT,
+	agency TEXT,
+	budget_amount REAL,
+	bid_deadline TEXT,
+	opening_time TEXT,
+	contact TEXT,
+	type TEXT NOT NULL DEFAULT '',
+	FOREIGN KEY (web_page_id) REFERENCES web_pages(id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_announcements_budget ON announcements (budget_amount);
+CREATE INDEX IF NOT EXISTS idx_announcements_deadline ON announcements (bid_deadline);
+CREATE INDEX IF NOT EXISTS idx_announcements_type ON announcements (type);
+
+CREATE TABLE IF NOT EXISTS attachments (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	announcement_id INTEGER NOT NULL,
+	name TEXT NOT NULL,
+	url TEXT NOT NULL,
+	ext TEXT NOT NULL,
+	size INTEGER NOT NULL DEFAULT 0,
+	sha256 TEXT NOT NULL DEFAULT '',
+	local_path TEXT NOT NULL DEFAULT '',
+	error TEXT NOT NULL DEFAULT '',
+	created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+	UNIQUE (announcement_id, url),
+	FOREIGN KEY (announcement_id) REFERENCES announcements(id)
+);
+
+CREATE TABLE IF NOT EXISTS deliveries (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	announcement_id INTEGER NOT NULL,
+	subscriber_id INTEGER,
+	recipient TEXT NOT NULL,
+	channel TEXT NOT NULL,
+	sent_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+	UNIQUE (announcement_id, recipient, channel),
+	FOREIGN KEY (announcement_id) REFERENCES announcements(id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_deliveries_subscriber ON deliveries (subscriber_id, sent_at);
@@ -0,0 +1,2 @@
+-- 数据迁移不可逆，迁移后的邮箱保留在 subscribe_config 中
+SELECT 1;
@@ -0,0 +1,5 @@
+-- 将旧版 push_config 中的邮箱迁移为订阅配置
+INSERT OR IGNORE INTO subscribe_config (email, push_time)
+	SELECT email, push_time FROM push_config WHERE email != '';
+
+DELETE FROM push_config;
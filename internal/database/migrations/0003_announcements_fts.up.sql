@@ -0,0 +1,24 @@
+-- 公告全文索引，trigram 分词适用于中文，由触发器与 announcements 保持同步
+CREATE VIRTUAL TABLE IF NOT EXISTS announcements_fts USING fts5(
+	title, content, body,
+	content = 'announcements',
+	content_rowid = 'id',
+	tokenize = 'trigram'
+);
+
+CREATE TRIGGER IF NOT EXISTS announcements_fts_insert AFTER INSERT ON announcements BEGIN
+	INSERT INTO announcements_fts (rowid, title, content, body) VALUES (new.id, new.title, new.content, new.body);
+END;
+
+CREATE TRIGGER IF NOT EXISTS announcements_fts_delete AFTER DELETE ON announcements BEGIN
+	INSERT INTO announcements_fts (announcements_fts, rowid, title, content, body)
+	VALUES ('delete', old.id, old.title, old.content, old.body);
+END;
+
+CREATE TRIGGER IF NOT EXISTS announcements_fts_update AFTER UPDATE OF title, content, body ON announcements BEGIN
+	INSERT INTO announcements_fts (announcements_fts, rowid, title, content, body)
+	VALUES ('delete', old.id, old.title, old.content, old.body);
+	INSERT INTO announcements_fts (rowid, title, content, body) VALUES (new.id, new.title, new.content, new.body);
+END;
+
+INSERT INTO announcements_fts (announcements_fts) VALUES ('rebuild');
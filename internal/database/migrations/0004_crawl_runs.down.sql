@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS idx_announcements_crawl_run;
+ALTER TABLE announcements DROP COLUMN crawl_run_id;
+DROP TABLE IF EXISTS crawl_runs;
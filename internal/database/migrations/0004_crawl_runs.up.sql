@@ -0,0 +1,27 @@
+-- 每次采集的执行记录，announcements.crawl_run_id 指向新增该公告的那次采集
+CREATE TABLE IF NOT EXISTS crawl_runs (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	monitor_config_id INTEGER,
+	web_page_id INTEGER,
+	trigger TEXT NOT NULL,
+	source TEXT NOT NULL DEFAULT '',
+	keywords TEXT NOT NULL DEFAULT '',
+	window_start DATETIME,
+	window_end DATETIME,
+	status TEXT NOT NULL DEFAULT 'running',
+	started_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+	finished_at DATETIME,
+	duration_ms INTEGER NOT NULL DEFAULT 0,
+	pages_fetched INTEGER NOT NULL DEFAULT 0,
+	records_fetched INTEGER NOT NULL DEFAULT 0,
+	matched INTEGER NOT NULL DEFAULT 0,
+	inserted INTEGER NOT NULL DEFAULT 0,
+	skipped INTEGER NOT NULL DEFAULT 0,
+	error TEXT NOT NULL DEFAULT ''
+);
+
+CREATE INDEX IF NOT EXISTS idx_crawl_runs_config ON crawl_runs (monitor_config_id, started_at);
+CREATE INDEX IF NOT EXISTS idx_crawl_runs_started ON crawl_runs (started_at);
+
+ALTER TABLE announcements ADD COLUMN crawl_run_id INTEGER;
+CREATE INDEX IF NOT EXISTS idx_announcements_crawl_run ON announcements (crawl_run_id);
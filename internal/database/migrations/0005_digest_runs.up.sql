@@ -0,0 +1,16 @@
+-- 每次摘要推送的执行记录
+CREATE TABLE IF NOT EXISTS digest_runs (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	subscriber_id INTEGER NOT NULL,
+	recipient TEXT NOT NULL DEFAULT '',
+	trigger TEXT NOT NULL,
+	status TEXT NOT NULL DEFAULT 'running',
+	started_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+	finished_at DATETIME,
+	duration_ms INTEGER NOT NULL DEFAULT 0,
+	pending INTEGER NOT NULL DEFAULT 0,
+	sent INTEGER NOT NULL DEFAULT 0,
+	error TEXT NOT NULL DEFAULT ''
+);
+
+CREATE INDEX IF NOT EXISTS idx_digest_runs_subscriber ON digest_runs (subscriber_id, started_at);
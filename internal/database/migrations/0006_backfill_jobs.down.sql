@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS idx_crawl_runs_backfill;
+ALTER TABLE crawl_runs DROP COLUMN backfill_job_id;
+DROP TABLE IF EXISTS backfill_jobs;
@@ -0,0 +1,23 @@
+-- 历史回填任务，按天推进 cursor 记录进度，重启后从 cursor 继续
+CREATE TABLE IF NOT EXISTS backfill_jobs (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	monitor_config_id INTEGER,
+	web_page_id INTEGER NOT NULL,
+	keywords TEXT NOT NULL DEFAULT '',
+	start_date TEXT NOT NULL,
+	end_date TEXT NOT NULL,
+	cursor TEXT NOT NULL,
+	status TEXT NOT NULL DEFAULT 'running',
+	days_total INTEGER NOT NULL DEFAULT 0,
+	days_done INTEGER NOT NULL DEFAULT 0,
+	inserted INTEGER NOT NULL DEFAULT 0,
+	skipped INTEGER NOT NULL DEFAULT 0,
+	error TEXT NOT NULL DEFAULT '',
+	created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+	updated_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+	finished_at DATETIME
+);
+
+-- 回填产生的每个日窗口记为一次 trigger = 'backfill' 的采集
+ALTER TABLE crawl_runs ADD COLUMN backfill_job_id INTEGER;
+CREATE INDEX IF NOT EXISTS idx_crawl_runs_backfill ON crawl_runs (backfill_job_id);
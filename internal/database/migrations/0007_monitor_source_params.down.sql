@@ -0,0 +1 @@
+ALTER TABLE monitor_config DROP COLUMN source_params;
@@ -0,0 +1,2 @@
+-- 监控配置的数据源参数，采集时覆盖网页记录中的同名参数
+ALTER TABLE monitor_config ADD COLUMN source_params TEXT NOT NULL DEFAULT '{}';
@@ -0,0 +1,12 @@
+-- 每个网页数据源的健康状态，响应结构异常或连续多次没有结果时为 degraded，
+-- sample 保存触发异常的原始响应
+CREATE TABLE IF NOT EXISTS source_health (
+	web_page_id INTEGER PRIMARY KEY,
+	source TEXT NOT NULL DEFAULT '',
+	status TEXT NOT NULL DEFAULT 'ok',
+	reason TEXT NOT NULL DEFAULT '',
+	sample TEXT NOT NULL DEFAULT '',
+	crawl_run_id INTEGER,
+	degraded_at DATETIME,
+	checked_at DATETIME DEFAULT CURRENT_TIMESTAMP
+);
@@ -0,0 +1,3 @@
+ALTER TABLE digest_runs DROP COLUMN channel_results;
+ALTER TABLE subscribe_config DROP COLUMN channels;
+DROP TABLE IF EXISTS notify_channels;
@@ -0,0 +1,15 @@
+-- 推送渠道：钉钉、企业微信、飞书机器人和通用 webhook，邮件渠道固定名为 email，不在此表中
+CREATE TABLE IF NOT EXISTS notify_channels (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	name TEXT NOT NULL UNIQUE,
+	type TEXT NOT NULL,
+	url TEXT NOT NULL,
+	secret TEXT NOT NULL DEFAULT '',
+	created_at DATETIME DEFAULT CURRENT_TIMESTAMP
+);
+
+-- 订阅使用的渠道名称，逗号分隔
+ALTER TABLE subscribe_config ADD COLUMN channels TEXT NOT NULL DEFAULT 'email';
+
+-- 每个渠道的推送结果（JSON 数组）
+ALTER TABLE digest_runs ADD COLUMN channel_results TEXT NOT NULL DEFAULT '[]';
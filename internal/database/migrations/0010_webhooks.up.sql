@@ -0,0 +1,33 @@
+-- 出站 webhook，events、types 逗号分隔，keywords 为关键词表达式，逗号分隔
+CREATE TABLE IF NOT EXISTS webhooks (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	name TEXT NOT NULL,
+	url TEXT NOT NULL,
+	secret TEXT NOT NULL,
+	events TEXT NOT NULL,
+	types TEXT NOT NULL DEFAULT '',
+	keywords TEXT NOT NULL DEFAULT '',
+	enabled INTEGER NOT NULL DEFAULT 1,
+	created_at DATETIME DEFAULT CURRENT_TIMESTAMP
+);
+
+-- webhook 投递记录，同时作为待投递队列：与公告在同一事务中写入，投递失败时按退避时间重试
+CREATE TABLE IF NOT EXISTS webhook_deliveries (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	webhook_id INTEGER NOT NULL,
+	event TEXT NOT NULL,
+	payload TEXT NOT NULL,
+	status TEXT NOT NULL DEFAULT 'pending',
+	attempts INTEGER NOT NULL DEFAULT 0,
+	next_attempt_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+	response_status INTEGER,
+	response_body TEXT NOT NULL DEFAULT '',
+	error TEXT NOT NULL DEFAULT '',
+	replay_of INTEGER,
+	created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+	delivered_at DATETIME,
+	FOREIGN KEY (webhook_id) REFERENCES webhooks(id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_webhook_deliveries_due ON webhook_deliveries (status, next_attempt_at);
+CREATE INDEX IF NOT EXISTS idx_webhook_deliveries_webhook ON webhook_deliveries (webhook_id, created_at);
@@ -0,0 +1,2 @@
+ALTER TABLE subscribe_config DROP COLUMN group_by;
+DROP TABLE IF EXISTS email_templates;
@@ -0,0 +1,11 @@
+-- 数据库中保存的邮件模板，为空的部分使用模板目录或内置模板
+CREATE TABLE IF NOT EXISTS email_templates (
+	name TEXT PRIMARY KEY,
+	subject TEXT NOT NULL DEFAULT '',
+	text TEXT NOT NULL DEFAULT '',
+	html TEXT NOT NULL DEFAULT '',
+	updated_at DATETIME DEFAULT CURRENT_TIMESTAMP
+);
+
+-- 邮件摘要的分组方式：source、keyword、type，为空时不分组
+ALTER TABLE subscribe_config ADD COLUMN group_by TEXT NOT NULL DEFAULT '';
@@ -0,0 +1,4 @@
+DROP TABLE IF EXISTS email_flagged_recipients;
+DROP INDEX IF EXISTS idx_email_outbox_recipient;
+DROP INDEX IF EXISTS idx_email_outbox_due;
+DROP TABLE IF EXISTS email_outbox;
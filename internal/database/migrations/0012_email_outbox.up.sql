@@ -0,0 +1,28 @@
+-- 邮件发件箱：摘要和告警先写入此表，每个收件人一条，由后台发送，失败时按退避时间重试
+CREATE TABLE IF NOT EXISTS email_outbox (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	recipient TEXT NOT NULL,
+	kind TEXT NOT NULL,
+	subject TEXT NOT NULL,
+	text TEXT NOT NULL,
+	html TEXT NOT NULL DEFAULT '',
+	status TEXT NOT NULL DEFAULT 'pending',
+	attempts INTEGER NOT NULL DEFAULT 0,
+	next_attempt_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+	smtp_code INTEGER,
+	error TEXT NOT NULL DEFAULT '',
+	created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+	sent_at DATETIME
+);
+
+CREATE INDEX IF NOT EXISTS idx_email_outbox_due ON email_outbox (status, next_attempt_at);
+CREATE INDEX IF NOT EXISTS idx_email_outbox_recipient ON email_outbox (recipient, created_at);
+
+-- 服务器永久拒收（RCPT TO 返回 5xx）的收件人，发往这些地址的邮件不再发送，重新发送时解除
+CREATE TABLE IF NOT EXISTS email_flagged_recipients (
+	recipient TEXT PRIMARY KEY COLLATE NOCASE,
+	smtp_code INTEGER NOT NULL,
+	reason TEXT NOT NULL,
+	outbox_id INTEGER,
+	created_at DATETIME DEFAULT CURRENT_TIMESTAMP
+);
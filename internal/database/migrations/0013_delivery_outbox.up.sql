@@ -0,0 +1,2 @@
+-- 邮件渠道的推送记录关联发件箱中的邮件，邮件最终发送失败时这些公告重新进入待推送
+ALTER TABLE deliveries ADD COLUMN outbox_id INTEGER;
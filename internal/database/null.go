@@ -0,0 +1,17 @@
+package database
+
+// NullInt 0 写入为 NULL，用于可选的外键 ID、状态码等
+func NullInt(n int) interface{} {
+	if n == 0 {
+		return nil
+	}
+	return n
+}
+
+// NullFloat 0 写入为 NULL，用于未抽取到的金额等，避免 0 参与范围筛选和排序
+func NullFloat(v float64) interface{} {
+	if v == 0 {
+		return nil
+	}
+	return v
+}
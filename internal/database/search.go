@@ -0,0 +1,11 @@
+package database
+
+// RebuildSearchIndex 按 announcements 表重建全文索引并合并索引段，
+// 用于索引与数据不一致（如直接修改数据库文件）后的修复
+func RebuildSearchIndex() error {
+	if _, err := DB.Exec("INSERT INTO announcements_fts (announcements_fts) VALUES ('rebuild')"); err != nil {
+		return err
+	}
+	_, err := DB.Exec("INSERT INTO announcements_fts (announcements_fts) VALUES ('optimize')")
+	return err
+}
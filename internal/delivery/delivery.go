@@ -0,0 +1,182 @@
+package delivery
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+
+	"github.com/ieasydevops/demo-scrapy/internal/database"
+	"github.com/ieasydevops/demo-scrapy/internal/email"
+	"github.com/ieasydevops/demo-scrapy/internal/keyword"
+	"github.com/ieasydevops/demo-scrapy/internal/models"
+)
+
+// Filter 订阅的过滤条件，未设置的条件不做限制
+type Filter struct {
+	WebPageIDs []int
+	Types      []string
+	// Keywords 匹配任一表达式即可
+	Keywords []keyword.Expr
+}
+
+// where 转换为 SQL 条件，以 AND 开头
+func (f Filter) where() (string, []interface{}) {
+	var b strings.Builder
+	var args []interface{}
+	if len(f.WebPageIDs) > 0 {
+		b.WriteString(" AND a.web_page_id IN (?" + strings.Repeat(", ?", len(f.WebPageIDs)-1) + ")")
+		for _, id := range f.WebPageIDs {
+			args = append(args, id)
+		}
+	}
+	if len(f.Types) > 0 {
+		b.WriteString(" AND a.type IN (?" + strings.Repeat(", ?", len(f.Types)-1) + ")")
+		for _, t := range f.Types {
+			args = append(args, t)
+		}
+	}
+	if len(f.Keywords) > 0 {
+		conds := make([]string, len(f.Keywords))
+		for i, x := range f.Keywords {
+			var condArgs []interface{}
+			conds[i], condArgs = keyword.SQLCondition(x, keywordColumns)
+			args = append(args, condArgs...)
+		}
+		b.WriteString(" AND (" + strings.Join(conds, " OR ") + ")")
+	}
+	return b.String(), args
+}
+
+// keywordColumns 关键词字段对应的公告列，与采集时匹配的标题、摘要和正文一致
+func keywordColumns(field string) []string {
+	switch field {
+	case keyword.FieldTitle:
+		return []string{"a.title"}
+	case keyword.FieldContent:
+		return []string{"a.content", "a.body"}
+	}
+	return []string{"a.title", "a.content", "a.body"}
+}
+
+// Pending 返回尚未通过 channel 推送给 recipient 且符合 filter 的公告，包括推送邮件在发件箱中最终发送失败的公告。
+// 为避免新订阅者收到全部历史公告，只包含订阅创建前一天之后入库的公告，且不包含历史回填入库的公告。
+// 不符合订阅条件的公告不返回，也不写推送记录，订阅条件修改后符合新条件的公告仍会推送
+func Pending(subscriberID int, recipient, channel string, filter Filter) ([]models.Announcement, error) {
+	filterSQL, filterArgs := filter.where()
+	args := append([]interface{}{recipient, channel, email.StatusFailed, subscriberID}, filterArgs...)
+	rows, err := database.DB.Query(`
+		SELECT a.id, a.title, a.url, a.publish_date, a.content, a.created_at,
+		       a.web_page_id, wp.name as web_page_name, a.type,
+		       a.body, a.purchaser, a.budget_amount, a.bid_deadline
+		FROM announcements a
+		LEFT JOIN web_pages wp ON a.web_page_id = wp.id
+		LEFT JOIN deliveries d ON d.announcement_id = a.id AND d.recipient = ? AND d.channel = ?
+		LEFT JOIN email_outbox o ON o.id = d.outbox_id
+		LEFT JOIN crawl_runs cr ON cr.id = a.crawl_run_id
+		WHERE (d.id IS NULL OR o.status = ?)
+		  AND cr.backfill_job_id IS NULL
+		  AND a.created_at >= (SELECT datetime(created_at, '-1 day') FROM subscribe_config WHERE id = ?)`+filterSQL+`
+		ORDER BY a.created_at DESC
+	`, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var announcements []models.Announcement
+	for rows.Next() {
+		var ann models.Announcement
+		var content, webPageName, body, purchaser, bidDeadline sql.NullString
+		var webPageID sql.NullInt64
+		var budget sql.NullFloat64
+		if err := rows.Scan(&ann.ID, &ann.Title, &ann.URL, &ann.PublishDate, &content,
+			&ann.CreatedAt, &webPageID, &webPageName, &ann.Type,
+			&body, &purchaser, &budget, &bidDeadline); err != nil {
+			return nil, err
+		}
+		ann.Content = content.String
+		ann.Body = body.String
+		ann.Purchaser = purchaser.String
+		ann.BudgetAmount = budget.Float64
+		ann.BidDeadline = bidDeadline.String
+		ann.WebPageID = int(webPageID.Int64)
+		ann.WebPageName = webPageName.String
+		announcements = append(announcements, ann)
+	}
+
+	return announcements, rows.Err()
+}
+
+// Record 记录公告已通过 channel 推送给 recipient。outboxID 为写入发件箱的邮件 ID（其他渠道为 0），
+// 邮件最终发送失败时 Pending 重新返回这些公告；重新推送时更新原记录
+func Record(subscriberID int, recipient, channel string, announcements []models.Announcement, outboxID int) error {
+	tx, err := database.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	stmt, err := tx.Prepare(`INSERT INTO deliveries (announcement_id, subscriber_id, recipient, channel, outbox_id)
+		VALUES (?, ?, ?, ?, ?)
+		ON CONFLICT (announcement_id, recipient, channel) DO UPDATE SET
+			subscriber_id = excluded.subscriber_id, outbox_id = excluded.outbox_id, sent_at = CURRENT_TIMESTAMP`)
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+
+	for _, ann := range announcements {
+		if _, err := stmt.Exec(ann.ID, subscriberID, recipient, channel, database.NullInt(outboxID)); err != nil {
+			return fmt.Errorf("记录推送失败: %v, 公告ID: %d", err, ann.ID)
+		}
+	}
+
+	return tx.Commit()
+}
+
+// History 分页查询订阅者的推送记录，按推送时间倒序，channel 不为空时只返回该渠道的记录
+func History(subscriberID int, channel string, page, pageSize int) ([]models.Delivery, int, error) {
+	where, args := "WHERE d.subscriber_id = ?", []interface{}{subscriberID}
+	if channel != "" {
+		where += " AND d.channel = ?"
+		args = append(args, channel)
+	}
+
+	var total int
+	if err := database.DB.QueryRow("SELECT COUNT(*) FROM deliveries d "+where, args...).Scan(&total); err != nil {
+		return nil, 0, err
+	}
+
+	rows, err := database.DB.Query(`
+		SELECT d.id, d.announcement_id, d.subscriber_id, d.recipient, d.channel, d.sent_at,
+		       a.title, a.url, d.outbox_id, o.status
+		FROM deliveries d
+		LEFT JOIN announcements a ON d.announcement_id = a.id
+		LEFT JOIN email_outbox o ON o.id = d.outbox_id
+		`+where+`
+		ORDER BY d.sent_at DESC, d.id DESC
+		LIMIT ? OFFSET ?
+	`, append(args, pageSize, (page-1)*pageSize)...)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	var deliveries []models.Delivery
+	for rows.Next() {
+		var d models.Delivery
+		var title, url, outboxStatus sql.NullString
+		var outboxID sql.NullInt64
+		if err := rows.Scan(&d.ID, &d.AnnouncementID, &d.SubscriberID, &d.Recipient, &d.Channel, &d.SentAt,
+			&title, &url, &outboxID, &outboxStatus); err != nil {
+			return nil, 0, err
+		}
+		d.Title = title.String
+		d.URL = url.String
+		d.OutboxID = int(outboxID.Int64)
+		d.OutboxStatus = outboxStatus.String
+		deliveries = append(deliveries, d)
+	}
+
+	return deliveries, total, rows.Err()
+}
@@ -0,0 +1,126 @@
+package digestrun
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/ieasydevops/demo-scrapy/internal/database"
+	"github.com/ieasydevops/demo-scrapy/internal/models"
+)
+
+// 推送的触发方式
+const (
+	TriggerSchedule = "schedule"
+	TriggerManual   = "manual"
+)
+
+// 推送状态
+const (
+	StatusRunning = "running"
+	StatusSuccess = "success"
+	StatusFailed  = "failed"
+)
+
+// Start 记录一次开始执行的摘要推送，返回记录 ID
+func Start(subscriberID int, recipient, trigger string) (int, error) {
+	result, err := database.DB.Exec(`
+		INSERT INTO digest_runs (subscriber_id, recipient, trigger, status) VALUES (?, ?, ?, ?)
+	`, subscriberID, recipient, trigger, StatusRunning)
+	if err != nil {
+		return 0, err
+	}
+	id, err := result.LastInsertId()
+	return int(id), err
+}
+
+// Finish 记录推送结束，pending 为各渠道过滤前的待推送条数之和，sent 为各渠道实际发送的条数之和
+func Finish(id int, started time.Time, pending, sent int, channels []models.ChannelResult, runErr error) error {
+	status, message := StatusSuccess, ""
+	if runErr != nil {
+		status, message = StatusFailed, runErr.Error()
+	}
+	if channels == nil {
+		channels = []models.ChannelResult{}
+	}
+	results, err := json.Marshal(channels)
+	if err != nil {
+		return err
+	}
+	_, err = database.DB.Exec(`
+		UPDATE digest_runs SET status = ?, finished_at = CURRENT_TIMESTAMP, duration_ms = ?,
+			pending = ?, sent = ?, error = ?, channel_results = ?
+		WHERE id = ?
+	`, status, time.Since(started).Milliseconds(), pending, sent, message, string(results), id)
+	return err
+}
+
+// AbandonRunning 将上次进程退出时仍在执行的推送标记为失败，返回处理的条数
+func AbandonRunning() (int, error) {
+	result, err := database.DB.Exec(`
+		UPDATE digest_runs SET status = ?, finished_at = CURRENT_TIMESTAMP, error = '进程退出时推送尚未完成'
+		WHERE status = ?
+	`, StatusFailed, StatusRunning)
+	if err != nil {
+		return 0, err
+	}
+	n, err := result.RowsAffected()
+	return int(n), err
+}
+
+const runColumns = `id, subscriber_id, recipient, trigger, status, started_at, finished_at,
+	duration_ms, pending, sent, error, channel_results`
+
+// List 分页查询推送记录，subscriberID 为 0 时不限制，按开始时间倒序
+func List(subscriberID, page, pageSize int) ([]models.DigestRun, int, error) {
+	where := " WHERE 1=1"
+	var args []interface{}
+	if subscriberID > 0 {
+		where += " AND subscriber_id = ?"
+		args = append(args, subscriberID)
+	}
+
+	var total int
+	if err := database.DB.QueryRow("SELECT COUNT(*) FROM digest_runs"+where, args...).Scan(&total); err != nil {
+		return nil, 0, err
+	}
+
+	rows, err := database.DB.Query("SELECT "+runColumns+" FROM digest_runs"+where+`
+		ORDER BY started_at DESC, id DESC
+		LIMIT ? OFFSET ?`, append(args, pageSize, (page-1)*pageSize)...)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	var runs []models.DigestRun
+	for rows.Next() {
+		run, err := scanRun(rows)
+		if err != nil {
+			return nil, 0, err
+		}
+		runs = append(runs, run)
+	}
+	return runs, total, rows.Err()
+}
+
+// Get 按 ID 读取推送记录
+func Get(id int) (models.DigestRun, error) {
+	return scanRun(database.DB.QueryRow("SELECT "+runColumns+" FROM digest_runs WHERE id = ?", id))
+}
+
+func scanRun(row interface{ Scan(...interface{}) error }) (models.DigestRun, error) {
+	var run models.DigestRun
+	var finishedAt sql.NullString
+	var channels string
+	if err := row.Scan(&run.ID, &run.SubscriberID, &run.Recipient, &run.Trigger, &run.Status, &run.StartedAt,
+		&finishedAt, &run.DurationMS, &run.Pending, &run.Sent, &run.Error, &channels); err != nil {
+		return run, err
+	}
+	run.FinishedAt = finishedAt.String
+	if err := json.Unmarshal([]byte(channels), &run.Channels); err != nil {
+		return run, fmt.Errorf("解析推送记录 %d 的渠道结果失败: %v", run.ID, err)
+	}
+	return run, nil
+}
@@ -0,0 +1,185 @@
+package email
+
+import (
+	"fmt"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/ieasydevops/demo-scrapy/internal/extract"
+	"github.com/ieasydevops/demo-scrapy/internal/keyword"
+	"github.com/ieasydevops/demo-scrapy/internal/models"
+)
+
+// 摘要的分组方式
+const (
+	GroupNone    = ""
+	GroupSource  = "source"
+	GroupKeyword = "keyword"
+	GroupType    = "type"
+)
+
+// ValidGroupBy 判断是否为已知的分组方式
+func ValidGroupBy(s string) bool {
+	switch s {
+	case GroupNone, GroupSource, GroupKeyword, GroupType:
+		return true
+	}
+	return false
+}
+
+// snippetLength 摘要中每条公告正文摘录的最大字符数
+const snippetLength = 120
+
+// DigestRequest 生成一封摘要所需的订阅信息和公告
+type DigestRequest struct {
+	To            string
+	Announcements []models.Announcement
+	// Keywords 订阅的关键词表达式，用于按关键词分组和标注匹配的关键词
+	Keywords []string
+	GroupBy  string
+}
+
+// Digest 摘要模板的数据
+type Digest struct {
+	To          string
+	Count       int
+	GroupBy     string
+	Groups      []Group
+	GeneratedAt string
+}
+
+// Group 摘要中的一组公告，不分组时只有一组且 Name 为空
+type Group struct {
+	Name  string
+	Items []Item
+}
+
+// Item 摘要中的一条公告，字段均为纯文本，由模板负责转义
+type Item struct {
+	Title       string
+	URL         string
+	PublishDate string
+	Source      string
+	Type        string
+	TypeLabel   string
+	Snippet     string
+	Purchaser   string
+	Budget      string
+	BidDeadline string
+	// Keywords 公告匹配的订阅关键词
+	Keywords []string
+}
+
+// NewDigest 按订阅的分组方式整理公告
+func NewDigest(req DigestRequest) Digest {
+	d := Digest{
+		To:          req.To,
+		Count:       len(req.Announcements),
+		GroupBy:     req.GroupBy,
+		GeneratedAt: time.Now().Format("2006-01-02 15:04"),
+	}
+	exprs, err := keyword.ParseAll(req.Keywords)
+	if err != nil {
+		exprs = nil
+	}
+
+	index := map[string]int{}
+	add := func(name string, item Item) {
+		i, ok := index[name]
+		if !ok {
+			i = len(d.Groups)
+			index[name] = i
+			d.Groups = append(d.Groups, Group{Name: name})
+		}
+		d.Groups[i].Items = append(d.Groups[i].Items, item)
+	}
+	for _, ann := range req.Announcements {
+		item := newItem(ann)
+		doc := keyword.Document{Title: ann.Title, Content: ann.Content + "\n" + ann.Body}
+		for i, x := range exprs {
+			if x.Match(doc) {
+				item.Keywords = append(item.Keywords, req.Keywords[i])
+			}
+		}
+
+		switch req.GroupBy {
+		case GroupSource:
+			add(orDefault(item.Source, "其他来源"), item)
+		case GroupKeyword:
+			if len(item.Keywords) > 0 {
+				add(item.Keywords[0], item)
+			} else {
+				add("其他", item)
+			}
+		case GroupType:
+			add(orDefault(item.TypeLabel, "未分类"), item)
+		default:
+			add("", item)
+		}
+	}
+
+	if req.GroupBy == GroupType {
+		// 按类型的匹配优先级排列分组
+		groups := make([]Group, 0, len(d.Groups))
+		for _, info := range append(extract.Types(), extract.TypeInfo{Label: "未分类"}) {
+			for _, g := range d.Groups {
+				if g.Name == info.Label {
+					groups = append(groups, g)
+				}
+			}
+		}
+		d.Groups = groups
+	}
+	return d
+}
+
+func newItem(ann models.Announcement) Item {
+	item := Item{
+		Title:       ann.Title,
+		URL:         ann.URL,
+		PublishDate: ann.PublishDate,
+		Source:      ann.WebPageName,
+		Type:        ann.Type,
+		Purchaser:   ann.Purchaser,
+		BidDeadline: ann.BidDeadline,
+		Snippet:     snippet(ann),
+	}
+	for _, info := range extract.Types() {
+		if info.Type == ann.Type {
+			item.TypeLabel = info.Label
+		}
+	}
+	if ann.BudgetAmount > 0 {
+		item.Budget = formatBudget(ann.BudgetAmount)
+	}
+	return item
+}
+
+// snippet 取正文（没有时取列表页摘要）的开头，合并空白
+func snippet(ann models.Announcement) string {
+	text := ann.Body
+	if text == "" {
+		text = ann.Content
+	}
+	text = strings.Join(strings.Fields(text), " ")
+	if utf8.RuneCountInString(text) <= snippetLength {
+		return text
+	}
+	return string([]rune(text)[:snippetLength]) + "…"
+}
+
+// formatBudget 一万元以上以万元为单位
+func formatBudget(amount float64) string {
+	if amount >= 10000 {
+		return strings.TrimSuffix(strings.TrimRight(fmt.Sprintf("%.2f", amount/10000), "0"), ".") + " 万元"
+	}
+	return strings.TrimSuffix(strings.TrimRight(fmt.Sprintf("%.2f", amount), "0"), ".") + " 元"
+}
+
+func orDefault(s, def string) string {
+	if s == "" {
+		return def
+	}
+	return s
+}
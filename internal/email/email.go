@@ -1,47 +1,40 @@
+// Package email 通过 SMTP 发送摘要和告警邮件，摘要正文由可自定义的模板渲染。
+// 邮件先写入发件箱（email_outbox 表），由 Run 在后台发送和重试。
 package email
 
 import (
 	"fmt"
-	"os"
-	"strings"
+	"time"
 
 	"gopkg.in/gomail.v2"
-	"github.com/ieasydevops/demo-scrapy/internal/models"
 )
 
-func SendEmail(to string, announcements []models.Announcement) error {
-	if len(announcements) == 0 {
-		return nil
-	}
+// sendMail 发送一封邮件。html 不为空时纯文本和 HTML 正文组成 multipart/alternative，邮件客户端优先显示 HTML
+func sendMail(to, subject, text, html string) error {
+	return send([]string{to}, subject, func(m *gomail.Message) {
+		m.SetBody("text/plain", text)
+		if html != "" {
+			m.AddAlternative("text/html", html)
+		}
+	})
+}
 
-	var content strings.Builder
-	content.WriteString("<h2>今日新增公告</h2>")
-	content.WriteString("<ul>")
-	for _, ann := range announcements {
-		content.WriteString(fmt.Sprintf("<li><a href='%s'>%s</a> - %s</li>", ann.URL, ann.Title, ann.PublishDate))
-	}
-	content.WriteString("</ul>")
+// Test 建立新连接发送一封测试邮件，返回 SMTP 会话记录。失败时返回 *Error，其中包含出错前的会话记录
+func Test(to string) ([]string, error) {
+	c := current()
 
-	m := gomail.NewMessage()
-	
-	smtpUser := os.Getenv("SMTP_USER")
-	smtpPass := os.Getenv("SMTP_PASS")
-	smtpHost := os.Getenv("SMTP_HOST")
-	smtpPort := 587
-	
-	if smtpHost == "" {
-		smtpHost = "smtp.qq.com"
+	s, err := dial(c)
+	if err != nil {
+		return nil, err
 	}
-	if smtpUser == "" {
-		smtpUser = "403608355@qq.com"
+	m := newMessage(c, []string{to}, "政府采购网公告监控测试邮件")
+	m.SetBody("text/plain", fmt.Sprintf("SMTP 配置正确（%s:%d，%s），发送时间 %s。",
+		c.Host, c.Port, c.Security, time.Now().Format("2006-01-02 15:04:05")))
+	if err := s.send(c.From, []string{to}, m); err != nil {
+		s.close()
+		return nil, err
 	}
-	
-	m.SetHeader("From", smtpUser)
-	m.SetHeader("To", to)
-	m.SetHeader("Subject", fmt.Sprintf("政府采购网公告通知 - %d条新公告", len(announcements)))
-	m.SetBody("text/html", content.String())
-
-	d := gomail.NewDialer(smtpHost, smtpPort, smtpUser, smtpPass)
-
-	return d.DialAndSend(m)
+	s.log("> QUIT")
+	s.quit()
+	return s.transcript, nil
 }
@@ -0,0 +1,249 @@
+package email
+
+import (
+	"database/sql"
+	"errors"
+	"strings"
+
+	"github.com/ieasydevops/demo-scrapy/internal/database"
+	"github.com/ieasydevops/demo-scrapy/internal/models"
+)
+
+// 邮件类型
+const (
+	KindDigest = "digest"
+	KindAlert  = "alert"
+)
+
+// 发件箱中邮件的状态
+const (
+	StatusPending = "pending"
+	StatusSent    = "sent"
+	StatusFailed  = "failed"
+)
+
+// ErrNotFailed 只能重新发送失败的邮件
+var ErrNotFailed = errors.New("只能重新发送失败的邮件")
+
+// QueueDigest 把渲染好的摘要邮件写入发件箱，由后台发送，返回发件箱中的邮件 ID
+func QueueDigest(to string, r Rendered) (int, error) {
+	ids, err := queue(KindDigest, []string{to}, r.Subject, r.Text, r.HTML)
+	if err != nil {
+		return 0, err
+	}
+	return ids[0], nil
+}
+
+// QueueAlert 把纯文本告警写入发件箱，每个收件人一封
+func QueueAlert(to []string, subject, body string) error {
+	_, err := queue(KindAlert, to, subject, body, "")
+	return err
+}
+
+func queue(kind string, to []string, subject, text, html string) ([]int, error) {
+	if len(to) == 0 {
+		return nil, nil
+	}
+	tx, err := database.DB.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+	ids := make([]int, 0, len(to))
+	for _, addr := range to {
+		result, err := tx.Exec(`
+			INSERT INTO email_outbox (recipient, kind, subject, text, html) VALUES (?, ?, ?, ?, ?)
+		`, addr, kind, subject, text, html)
+		if err != nil {
+			return nil, err
+		}
+		id, err := result.LastInsertId()
+		if err != nil {
+			return nil, err
+		}
+		ids = append(ids, int(id))
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	Wake()
+	return ids, nil
+}
+
+// OutboxFilter 发件箱的查询条件，零值表示不过滤
+type OutboxFilter struct {
+	Status    string
+	Recipient string
+	Kind      string
+}
+
+const outboxColumns = `
+	id, recipient, kind, subject, status, attempts, next_attempt_at, smtp_code, error, created_at, sent_at`
+
+// Outbox 分页查询发件箱，按创建时间倒序，不包含正文
+func Outbox(filter OutboxFilter, page, pageSize int) ([]models.EmailMessage, int, error) {
+	var where []string
+	var args []interface{}
+	if filter.Status != "" {
+		where = append(where, "status = ?")
+		args = append(args, filter.Status)
+	}
+	if filter.Recipient != "" {
+		where = append(where, "recipient = ? COLLATE NOCASE")
+		args = append(args, filter.Recipient)
+	}
+	if filter.Kind != "" {
+		where = append(where, "kind = ?")
+		args = append(args, filter.Kind)
+	}
+	whereSQL := ""
+	if len(where) > 0 {
+		whereSQL = "WHERE " + strings.Join(where, " AND ")
+	}
+
+	var total int
+	if err := database.DB.QueryRow("SELECT COUNT(*) FROM email_outbox "+whereSQL, args...).Scan(&total); err != nil {
+		return nil, 0, err
+	}
+
+	rows, err := database.DB.Query("SELECT "+outboxColumns+" FROM email_outbox "+whereSQL+`
+		ORDER BY id DESC
+		LIMIT ? OFFSET ?`, append(args, pageSize, (page-1)*pageSize)...)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	var list []models.EmailMessage
+	for rows.Next() {
+		var m models.EmailMessage
+		if err := scanMessage(rows, &m); err != nil {
+			return nil, 0, err
+		}
+		list = append(list, m)
+	}
+	return list, total, rows.Err()
+}
+
+// GetMessage 按 ID 读取发件箱中的邮件，包含正文
+func GetMessage(id int) (models.EmailMessage, error) {
+	var m models.EmailMessage
+	row := database.DB.QueryRow("SELECT "+outboxColumns+", text, html FROM email_outbox WHERE id = ?", id)
+	err := scanMessage(row, &m, &m.Text, &m.HTML)
+	return m, err
+}
+
+// Resend 把失败的邮件重新放入发件箱，发送次数清零，并解除收件人的拒收标记
+func Resend(id int) error {
+	tx, err := database.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var status, recipient string
+	if err := tx.QueryRow("SELECT status, recipient FROM email_outbox WHERE id = ?", id).Scan(&status, &recipient); err != nil {
+		return err
+	}
+	if status != StatusFailed {
+		return ErrNotFailed
+	}
+	if _, err := tx.Exec("DELETE FROM email_flagged_recipients WHERE recipient = ?", recipient); err != nil {
+		return err
+	}
+	if _, err := tx.Exec(requeueSQL+" WHERE id = ?", StatusPending, id); err != nil {
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+	Wake()
+	return nil
+}
+
+// ResendFailed 重新发送所有失败的邮件（recipient 不为空时只处理该收件人），返回重新发送的封数
+func ResendFailed(recipient string) (int, error) {
+	tx, err := database.DB.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	where := " WHERE status = ?"
+	args := []interface{}{StatusFailed}
+	if recipient != "" {
+		where += " AND recipient = ? COLLATE NOCASE"
+		args = append(args, recipient)
+	}
+	if _, err := tx.Exec("DELETE FROM email_flagged_recipients WHERE recipient IN (SELECT recipient FROM email_outbox"+where+")", args...); err != nil {
+		return 0, err
+	}
+	result, err := tx.Exec(requeueSQL+where, append([]interface{}{StatusPending}, args...)...)
+	if err != nil {
+		return 0, err
+	}
+	n, err := result.RowsAffected()
+	if err != nil {
+		return 0, err
+	}
+	if err := tx.Commit(); err != nil {
+		return 0, err
+	}
+	Wake()
+	return int(n), nil
+}
+
+const requeueSQL = `UPDATE email_outbox SET status = ?, attempts = 0, next_attempt_at = CURRENT_TIMESTAMP, smtp_code = NULL, error = ''`
+
+// FlaggedRecipients 返回被永久拒收的收件人
+func FlaggedRecipients() ([]models.FlaggedRecipient, error) {
+	rows, err := database.DB.Query(`
+		SELECT recipient, smtp_code, reason, outbox_id, created_at
+		FROM email_flagged_recipients ORDER BY created_at DESC`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var list []models.FlaggedRecipient
+	for rows.Next() {
+		var f models.FlaggedRecipient
+		var outboxID sql.NullInt64
+		if err := rows.Scan(&f.Recipient, &f.SMTPCode, &f.Reason, &outboxID, &f.CreatedAt); err != nil {
+			return nil, err
+		}
+		f.OutboxID = int(outboxID.Int64)
+		list = append(list, f)
+	}
+	return list, rows.Err()
+}
+
+// Unflag 解除收件人的拒收标记，之后写入发件箱的邮件正常发送
+func Unflag(recipient string) error {
+	result, err := database.DB.Exec("DELETE FROM email_flagged_recipients WHERE recipient = ?", recipient)
+	if err != nil {
+		return err
+	}
+	if n, err := result.RowsAffected(); err != nil {
+		return err
+	} else if n == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+func scanMessage(row interface{ Scan(...interface{}) error }, m *models.EmailMessage, extra ...interface{}) error {
+	var nextAttemptAt, sentAt sql.NullString
+	var code sql.NullInt64
+	dest := append([]interface{}{&m.ID, &m.Recipient, &m.Kind, &m.Subject, &m.Status, &m.Attempts, &nextAttemptAt,
+		&code, &m.Error, &m.CreatedAt, &sentAt}, extra...)
+	if err := row.Scan(dest...); err != nil {
+		return err
+	}
+	if m.Status == StatusPending {
+		m.NextAttemptAt = nextAttemptAt.String
+	}
+	m.SMTPCode = int(code.Int64)
+	m.SentAt = sentAt.String
+	return nil
+}
@@ -0,0 +1,137 @@
+package email
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"github.com/ieasydevops/demo-scrapy/internal/database"
+)
+
+func openDB(t *testing.T) {
+	t.Helper()
+	if err := database.InitDB(filepath.Join(t.TempDir(), "test.db")); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { database.Close() })
+}
+
+// sendDue 发送到期的邮件，busy@ 的重试时间提前到现在
+func sendDue(t *testing.T) {
+	t.Helper()
+	if _, err := database.DB.Exec("UPDATE email_outbox SET next_attempt_at = CURRENT_TIMESTAMP"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := SendDue(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func statuses(t *testing.T) map[string]string {
+	t.Helper()
+	list, _, err := Outbox(OutboxFilter{}, 1, 100)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := map[string]string{}
+	for _, m := range list {
+		got[m.Recipient] = m.Status
+	}
+	return got
+}
+
+func TestOutboxRetriesAndFlagsRecipient(t *testing.T) {
+	openDB(t)
+	srv := newSMTPServer(t)
+	srv.configure(t)
+	c := current()
+	c.MaxAttempts = 2
+	if err := Configure(c); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := QueueDigest("ok@example.com", Rendered{Subject: "摘要", Text: "纯文本", HTML: "<p>HTML</p>"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := QueueAlert([]string{"busy@example.com", "bad@example.com"}, "告警", "正文"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := SendDue(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	// 4xx 等待重试，5xx 立即失败并标记收件人
+	want := map[string]string{"ok@example.com": StatusSent, "busy@example.com": StatusPending, "bad@example.com": StatusFailed}
+	if got := statuses(t); !equal(got, want) {
+		t.Fatalf("状态 = %v", got)
+	}
+	list, _, err := Outbox(OutboxFilter{Recipient: "BUSY@example.com"}, 1, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m := list[0]; m.Attempts != 1 || m.SMTPCode != 451 || m.NextAttemptAt == "" {
+		t.Errorf("重试中的邮件 = %+v", m)
+	}
+	flagged, err := FlaggedRecipients()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(flagged) != 1 || flagged[0].Recipient != "bad@example.com" || flagged[0].SMTPCode != 550 {
+		t.Fatalf("拒收的收件人 = %+v", flagged)
+	}
+
+	// 发往被标记收件人的邮件不再发送；busy@ 达到最大次数后失败
+	if err := QueueAlert([]string{"Bad@example.com"}, "告警", "正文"); err != nil {
+		t.Fatal(err)
+	}
+	sendDue(t)
+	failed, total, err := Outbox(OutboxFilter{Status: StatusFailed}, 1, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if total != 3 {
+		t.Fatalf("失败的邮件 = %+v", failed)
+	}
+	for _, m := range failed {
+		if m.Recipient == "Bad@example.com" && m.Attempts != 0 {
+			t.Errorf("被标记收件人的邮件不应发送: %+v", m)
+		}
+		if m.Recipient == "busy@example.com" && m.Attempts != 2 {
+			t.Errorf("busy@ 发送次数 = %d", m.Attempts)
+		}
+	}
+
+	// 重新发送清零发送次数并解除标记
+	if err := Resend(failed[0].ID); err != nil {
+		t.Fatal(err)
+	}
+	if err := Resend(failed[0].ID); err != ErrNotFailed {
+		t.Errorf("重复重新发送 err = %v", err)
+	}
+	n, err := ResendFailed("")
+	if err != nil || n != 2 {
+		t.Fatalf("ResendFailed = %d, %v", n, err)
+	}
+	if flagged, _ := FlaggedRecipients(); len(flagged) != 0 {
+		t.Errorf("重新发送后仍有拒收标记: %+v", flagged)
+	}
+	m, err := GetMessage(failed[0].ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m.Status != StatusPending || m.Attempts != 0 || m.Text != "正文" {
+		t.Errorf("重新发送的邮件 = %+v", m)
+	}
+}
+
+func equal(a, b map[string]string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for k, v := range a {
+		if b[k] != v {
+			return false
+		}
+	}
+	return true
+}
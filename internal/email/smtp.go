@@ -0,0 +1,406 @@
+package email
+
+import (
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net"
+	"net/smtp"
+	"net/textproto"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"gopkg.in/gomail.v2"
+)
+
+// 连接加密方式
+const (
+	SecurityStartTLS = "starttls" // 明文连接后升级为 TLS，服务器不支持 STARTTLS 时拒绝发送
+	SecurityTLS      = "tls"      // 隐式 TLS，通常为 465 端口
+	SecurityNone     = "none"     // 不加密，只用于内网中继
+)
+
+// Config SMTP 服务器和发件人设置
+type Config struct {
+	Host     string
+	Port     int // 为 0 时按加密方式取 587、465 或 25
+	Username string
+	Password string
+	Security string
+	From     string // 发件地址，为空时使用 Username
+	FromName string // 发件人显示名称
+	ReplyTo  string
+	Timeout  time.Duration // 连接和单封邮件的超时
+	// IdleTimeout 发送后保持连接的时间，期间发送的邮件复用同一连接
+	IdleTimeout time.Duration
+
+	MaxAttempts int           // 发件箱中每封邮件的最大发送次数，用尽后标记为 failed
+	RetryDelay  time.Duration // 第一次重试前的等待，之后每次翻倍
+	MaxDelay    time.Duration // 重试等待的上限
+}
+
+var (
+	poolMu sync.Mutex
+	cfg    = Config{
+		Security: SecurityStartTLS, Timeout: 30 * time.Second, IdleTimeout: 30 * time.Second,
+		MaxAttempts: 6, RetryDelay: time.Minute, MaxDelay: time.Hour,
+	}
+	// idle 上次发送后保持的连接
+	idle      *session
+	idleTimer *time.Timer
+)
+
+// Configure 设置 SMTP 服务器，关闭已建立的连接
+func Configure(c Config) error {
+	if c.Security == "" {
+		c.Security = SecurityStartTLS
+	}
+	if c.Port == 0 {
+		c.Port = defaultPort(c.Security)
+	}
+	if c.Port < 0 || c.Port > 65535 {
+		return fmt.Errorf("SMTP 端口无效: %d", c.Port)
+	}
+	if defaultPort(c.Security) == 0 {
+		return fmt.Errorf("未知的 SMTP 加密方式 %q，可选 starttls、tls、none", c.Security)
+	}
+	if c.From == "" {
+		c.From = c.Username
+	}
+	if c.Timeout <= 0 {
+		c.Timeout = 30 * time.Second
+	}
+	if c.IdleTimeout <= 0 {
+		c.IdleTimeout = 30 * time.Second
+	}
+	if c.MaxAttempts <= 0 {
+		c.MaxAttempts = 6
+	}
+	if c.RetryDelay <= 0 {
+		c.RetryDelay = time.Minute
+	}
+	if c.MaxDelay <= 0 {
+		c.MaxDelay = time.Hour
+	}
+
+	poolMu.Lock()
+	defer poolMu.Unlock()
+	cfg = c
+	closeIdle()
+	return nil
+}
+
+// current 返回当前配置
+func current() Config {
+	poolMu.Lock()
+	defer poolMu.Unlock()
+	return cfg
+}
+
+func defaultPort(security string) int {
+	switch security {
+	case SecurityStartTLS:
+		return 587
+	case SecurityTLS:
+		return 465
+	case SecurityNone:
+		return 25
+	}
+	return 0
+}
+
+// newMessage 创建设置了发件人和回复地址的邮件
+func newMessage(c Config, to []string, subject string) *gomail.Message {
+	m := gomail.NewMessage()
+	m.SetAddressHeader("From", c.From, c.FromName)
+	if c.ReplyTo != "" {
+		m.SetHeader("Reply-To", c.ReplyTo)
+	}
+	m.SetHeader("To", to...)
+	m.SetHeader("Subject", subject)
+	return m
+}
+
+// send 通过保持的连接发送邮件，没有连接或连接已断开时重新建立，发送后保持 IdleTimeout。
+// setBody 设置邮件正文
+func send(to []string, subject string, setBody func(*gomail.Message)) error {
+	poolMu.Lock()
+	defer poolMu.Unlock()
+
+	c := cfg
+	m := newMessage(c, to, subject)
+	setBody(m)
+
+	if idle != nil {
+		if idleTimer != nil {
+			idleTimer.Stop()
+		}
+		// 复用前用 RSET 确认连接仍然可用，服务器可能已因空闲断开
+		if err := idle.reset(); err != nil {
+			idle.close()
+			idle = nil
+		} else {
+			idle.transcript = []string{"* 复用连接"}
+		}
+	}
+	if idle == nil {
+		s, err := dial(c)
+		if err != nil {
+			return err
+		}
+		idle = s
+	}
+
+	err := idle.send(c.From, to, m)
+	var smtpErr *Error
+	if err != nil && !(errors.As(err, &smtpErr) && smtpErr.Code != 0) {
+		// 连接错误，丢弃连接；服务器拒绝收件人等应答错误后连接仍可继续使用
+		idle.close()
+		idle = nil
+		return err
+	}
+	s := idle
+	idleTimer = time.AfterFunc(c.IdleTimeout, func() {
+		poolMu.Lock()
+		defer poolMu.Unlock()
+		if idle == s {
+			closeIdle()
+		}
+	})
+	return err
+}
+
+// closeIdle 关闭保持的连接，调用方持有 poolMu
+func closeIdle() {
+	if idleTimer != nil {
+		idleTimer.Stop()
+		idleTimer = nil
+	}
+	if idle != nil {
+		idle.quit()
+		idle = nil
+	}
+}
+
+// Close 关闭保持的 SMTP 连接，服务退出时调用
+func Close() {
+	poolMu.Lock()
+	defer poolMu.Unlock()
+	closeIdle()
+}
+
+// Error SMTP 会话中的错误。Code 为服务器的应答码，连接、TLS 等错误为 0；
+// Transcript 为出错前发送的命令和服务器的错误应答，不含密码和邮件内容
+type Error struct {
+	Command    string
+	Code       int
+	Message    string
+	Transcript []string
+}
+
+func (e *Error) Error() string {
+	if e.Code != 0 {
+		return fmt.Sprintf("SMTP %s 失败: %d %s", e.Command, e.Code, e.Message)
+	}
+	return fmt.Sprintf("SMTP %s 失败: %s", e.Command, e.Message)
+}
+
+// Temporary 判断是否可以稍后重试：4xx 应答和连接错误可以重试，5xx 为永久错误
+func (e *Error) Temporary() bool {
+	return e.Code < 500
+}
+
+// session 一个 SMTP 连接，记录会话过程用于排查
+type session struct {
+	conn       net.Conn
+	client     *smtp.Client
+	timeout    time.Duration
+	transcript []string
+}
+
+// dial 连接并登录 SMTP 服务器
+func dial(c Config) (*session, error) {
+	s := &session{timeout: c.Timeout}
+	if c.Host == "" {
+		return nil, s.fail("CONNECT", errors.New("未配置 SMTP 服务器（email.smtp_host 或 SMTP_HOST）"))
+	}
+	if c.From == "" {
+		return nil, s.fail("CONNECT", errors.New("未配置发件地址（email.from 或 email.smtp_user）"))
+	}
+
+	addr := net.JoinHostPort(c.Host, strconv.Itoa(c.Port))
+	s.log("* 连接 %s（%s）", addr, c.Security)
+	conn, err := net.DialTimeout("tcp", addr, c.Timeout)
+	if err != nil {
+		return nil, s.fail("CONNECT", err)
+	}
+	tlsConfig := &tls.Config{ServerName: c.Host}
+	if c.Security == SecurityTLS {
+		conn = tls.Client(conn, tlsConfig)
+	}
+	s.conn = conn
+	s.deadline()
+
+	s.client, err = smtp.NewClient(conn, c.Host)
+	if err != nil {
+		conn.Close()
+		return nil, s.fail("CONNECT", err)
+	}
+	s.log("> EHLO")
+	if err := s.client.Hello(localName()); err != nil {
+		s.close()
+		return nil, s.fail("EHLO", err)
+	}
+
+	if c.Security == SecurityStartTLS {
+		if ok, _ := s.client.Extension("STARTTLS"); !ok {
+			s.close()
+			return nil, s.fail("STARTTLS", errors.New("服务器不支持 STARTTLS，可设置 email.security 为 tls 或 none"))
+		}
+		s.log("> STARTTLS")
+		if err := s.client.StartTLS(tlsConfig); err != nil {
+			s.close()
+			return nil, s.fail("STARTTLS", err)
+		}
+	}
+
+	if c.Username != "" {
+		_, mechanisms := s.client.Extension("AUTH")
+		auth := &auth{username: c.Username, password: c.Password}
+		if !strings.Contains(" "+strings.ToUpper(mechanisms)+" ", " PLAIN ") &&
+			strings.Contains(" "+strings.ToUpper(mechanisms)+" ", " LOGIN ") {
+			auth.login = true
+		}
+		s.log("> AUTH %s %s", auth.mechanism(), c.Username)
+		if err := s.client.Auth(auth); err != nil {
+			s.close()
+			return nil, s.fail("AUTH", err)
+		}
+	}
+	return s, nil
+}
+
+// send 发送一封邮件
+func (s *session) send(from string, to []string, m *gomail.Message) error {
+	s.deadline()
+	s.log("> MAIL FROM:<%s>", from)
+	if err := s.client.Mail(from); err != nil {
+		return s.fail("MAIL FROM", err)
+	}
+	for _, addr := range to {
+		s.log("> RCPT TO:<%s>", addr)
+		if err := s.client.Rcpt(addr); err != nil {
+			s.client.Reset()
+			return s.fail("RCPT TO", err)
+		}
+	}
+	s.log("> DATA")
+	w, err := s.client.Data()
+	if err != nil {
+		return s.fail("DATA", err)
+	}
+	if _, err := m.WriteTo(w); err != nil {
+		w.Close()
+		return s.fail("DATA", err)
+	}
+	if err := w.Close(); err != nil {
+		return s.fail("DATA", err)
+	}
+	s.log("< 250 已接收")
+	return nil
+}
+
+func (s *session) reset() error {
+	s.deadline()
+	return s.client.Reset()
+}
+
+// quit 正常结束会话
+func (s *session) quit() {
+	s.deadline()
+	if err := s.client.Quit(); err != nil {
+		s.close()
+	}
+}
+
+func (s *session) close() {
+	if s.client != nil {
+		s.client.Close()
+	} else if s.conn != nil {
+		s.conn.Close()
+	}
+}
+
+func (s *session) deadline() {
+	if s.conn != nil {
+		s.conn.SetDeadline(time.Now().Add(s.timeout))
+	}
+}
+
+func (s *session) log(format string, args ...interface{}) {
+	s.transcript = append(s.transcript, fmt.Sprintf(format, args...))
+}
+
+// fail 记录服务器的错误应答并返回 *Error
+func (s *session) fail(command string, err error) *Error {
+	e := &Error{Command: command, Message: err.Error()}
+	var tpErr *textproto.Error
+	if errors.As(err, &tpErr) {
+		e.Code = tpErr.Code
+		e.Message = tpErr.Msg
+		s.log("< %d %s", tpErr.Code, tpErr.Msg)
+	} else {
+		s.log("! %v", err)
+	}
+	e.Transcript = append([]string(nil), s.transcript...)
+	return e
+}
+
+// localName EHLO 使用的本机名称
+func localName() string {
+	if name, err := os.Hostname(); err == nil && name != "" {
+		return name
+	}
+	return "localhost"
+}
+
+// auth PLAIN 或 LOGIN 认证。不加密（security 为 none）时 net/smtp 的 PlainAuth 会拒绝发送密码，
+// 这里按配置的选择执行
+type auth struct {
+	username string
+	password string
+	login    bool
+}
+
+func (a *auth) mechanism() string {
+	if a.login {
+		return "LOGIN"
+	}
+	return "PLAIN"
+}
+
+func (a *auth) Start(*smtp.ServerInfo) (string, []byte, error) {
+	if a.login {
+		return "LOGIN", nil, nil
+	}
+	return "PLAIN", []byte("\x00" + a.username + "\x00" + a.password), nil
+}
+
+func (a *auth) Next(fromServer []byte, more bool) ([]byte, error) {
+	if !more {
+		return nil, nil
+	}
+	if !a.login {
+		return nil, errors.New("服务器要求额外的认证信息")
+	}
+	switch strings.ToLower(strings.TrimSpace(string(fromServer))) {
+	case "username:":
+		return []byte(a.username), nil
+	case "password:":
+		return []byte(a.password), nil
+	}
+	return nil, fmt.Errorf("未知的 LOGIN 认证提示: %s", fromServer)
+}
@@ -0,0 +1,169 @@
+package email
+
+import (
+	"bufio"
+	"errors"
+	"net"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// smtpServer 最小的 SMTP 服务器，永久拒绝 bad@ 开头的收件人、暂时拒绝 busy@ 开头的收件人，
+// 记录连接数和收到的邮件
+type smtpServer struct {
+	net.Listener
+	mu       sync.Mutex
+	conns    int
+	messages []string
+}
+
+func newSMTPServer(t *testing.T) *smtpServer {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := &smtpServer{Listener: ln}
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			s.mu.Lock()
+			s.conns++
+			s.mu.Unlock()
+			go s.serve(conn)
+		}
+	}()
+	t.Cleanup(func() { ln.Close() })
+	return s
+}
+
+func (s *smtpServer) serve(conn net.Conn) {
+	defer conn.Close()
+	r := bufio.NewReader(conn)
+	reply := func(lines ...string) {
+		conn.Write([]byte(strings.Join(lines, "\r\n") + "\r\n"))
+	}
+	reply("220 fake ESMTP")
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			return
+		}
+		cmd := strings.ToUpper(strings.TrimSpace(line))
+		switch {
+		case strings.HasPrefix(cmd, "EHLO"):
+			reply("250-fake", "250 AUTH PLAIN LOGIN")
+		case strings.HasPrefix(cmd, "AUTH PLAIN"):
+			reply("235 ok")
+		case strings.HasPrefix(cmd, "RCPT TO:<BAD@"):
+			reply("550 5.1.1 mailbox unavailable")
+		case strings.HasPrefix(cmd, "RCPT TO:<BUSY@"):
+			reply("451 4.7.1 try again later")
+		case strings.HasPrefix(cmd, "DATA"):
+			reply("354 go ahead")
+			var data strings.Builder
+			for {
+				l, err := r.ReadString('\n')
+				if err != nil {
+					return
+				}
+				if l == ".\r\n" {
+					break
+				}
+				data.WriteString(l)
+			}
+			s.mu.Lock()
+			s.messages = append(s.messages, data.String())
+			s.mu.Unlock()
+			reply("250 queued")
+		case strings.HasPrefix(cmd, "QUIT"):
+			reply("221 bye")
+			return
+		default:
+			reply("250 ok")
+		}
+	}
+}
+
+func (s *smtpServer) configure(t *testing.T) {
+	port := s.Addr().(*net.TCPAddr).Port
+	err := Configure(Config{
+		Host: "127.0.0.1", Port: port, Security: SecurityNone,
+		Username: "monitor@example.com", Password: "secret",
+		FromName: "采购公告监控", ReplyTo: "reply@example.com",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(Close)
+}
+
+func TestSendReusesConnection(t *testing.T) {
+	srv := newSMTPServer(t)
+	srv.configure(t)
+
+	if err := sendMail("a@example.com", "摘要", "纯文本", "<p>HTML</p>"); err != nil {
+		t.Fatal(err)
+	}
+	if err := sendMail("b@example.com", "摘要", "纯文本", "<p>HTML</p>"); err != nil {
+		t.Fatal(err)
+	}
+	if err := sendMail("ops@example.com", "告警", "正文", ""); err != nil {
+		t.Fatal(err)
+	}
+
+	srv.mu.Lock()
+	defer srv.mu.Unlock()
+	if srv.conns != 1 {
+		t.Errorf("连接数 = %d，应复用同一连接", srv.conns)
+	}
+	if len(srv.messages) != 3 {
+		t.Fatalf("收到 %d 封邮件", len(srv.messages))
+	}
+	msg := srv.messages[0]
+	for _, want := range []string{"Reply-To: reply@example.com", "<monitor@example.com>", "=?UTF-8?", "multipart/alternative", "text/html"} {
+		if !strings.Contains(msg, want) {
+			t.Errorf("邮件缺少 %q:\n%s", want, msg)
+		}
+	}
+}
+
+func TestRejectedRecipient(t *testing.T) {
+	srv := newSMTPServer(t)
+	srv.configure(t)
+
+	err := sendMail("bad@example.com", "告警", "正文", "")
+	var smtpErr *Error
+	if !errors.As(err, &smtpErr) {
+		t.Fatalf("err = %v，应为 *Error", err)
+	}
+	if smtpErr.Code != 550 || smtpErr.Command != "RCPT TO" || smtpErr.Temporary() {
+		t.Errorf("err = %+v", smtpErr)
+	}
+	if got := strings.Join(smtpErr.Transcript, "\n"); !strings.Contains(got, "> RCPT TO:<bad@example.com>\n< 550") ||
+		strings.Contains(got, "secret") {
+		t.Errorf("会话记录:\n%s", got)
+	}
+
+	// 收件人被拒绝后连接仍可继续使用
+	if err := sendMail("ops@example.com", "告警", "正文", ""); err != nil {
+		t.Fatal(err)
+	}
+	srv.mu.Lock()
+	conns := srv.conns
+	srv.mu.Unlock()
+	if conns != 1 {
+		t.Errorf("连接数 = %d", conns)
+	}
+
+	transcript, err := Test("ops@example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(transcript[0], "* 连接 127.0.0.1:") || transcript[len(transcript)-1] != "> QUIT" {
+		t.Errorf("会话记录: %v", transcript)
+	}
+}
@@ -0,0 +1,213 @@
+package email
+
+import (
+	"bytes"
+	"database/sql"
+	"embed"
+	"fmt"
+	htmltemplate "html/template"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	texttemplate "text/template"
+
+	"github.com/ieasydevops/demo-scrapy/internal/database"
+	"github.com/ieasydevops/demo-scrapy/internal/models"
+)
+
+//go:embed templates/*.tmpl
+var builtinFS embed.FS
+
+// 模板文件名，模板目录和内置模板使用相同的文件名
+const (
+	subjectFile = "digest.subject.tmpl"
+	textFile    = "digest.txt.tmpl"
+	htmlFile    = "digest.html.tmpl"
+)
+
+// Template 摘要邮件的主题、纯文本正文和 HTML 正文模板，分别以 text/template、text/template 和 html/template 解析，
+// 数据为 Digest。为空的部分使用下一级来源：数据库 > 模板目录 > 内置模板
+type Template struct {
+	Subject string `json:"subject"`
+	Text    string `json:"text"`
+	HTML    string `json:"html"`
+}
+
+// Rendered 渲染后的摘要邮件
+type Rendered struct {
+	Subject string `json:"subject"`
+	Text    string `json:"text"`
+	HTML    string `json:"html"`
+}
+
+var (
+	defaultMu sync.RWMutex
+	// defaultTemplate 内置模板，LoadTemplateDir 后被目录中的文件覆盖
+	defaultTemplate = mustBuiltin()
+)
+
+var funcs = map[string]interface{}{
+	"join": strings.Join,
+}
+
+func mustBuiltin() Template {
+	read := func(name string) string {
+		data, err := builtinFS.ReadFile("templates/" + name)
+		if err != nil {
+			panic(err)
+		}
+		return string(data)
+	}
+	return Template{Subject: read(subjectFile), Text: read(textFile), HTML: read(htmlFile)}
+}
+
+// LoadTemplateDir 读取模板目录中的 digest.subject.tmpl、digest.txt.tmpl 和 digest.html.tmpl，
+// 存在的文件覆盖内置模板，dir 为空时只使用内置模板
+func LoadTemplateDir(dir string) error {
+	t := mustBuiltin()
+	if dir != "" {
+		for name, part := range map[string]*string{subjectFile: &t.Subject, textFile: &t.Text, htmlFile: &t.HTML} {
+			data, err := os.ReadFile(filepath.Join(dir, name))
+			if os.IsNotExist(err) {
+				continue
+			}
+			if err != nil {
+				return err
+			}
+			*part = string(data)
+		}
+	}
+	if err := t.Validate(); err != nil {
+		return fmt.Errorf("模板目录 %s: %v", dir, err)
+	}
+
+	defaultMu.Lock()
+	defer defaultMu.Unlock()
+	defaultTemplate = t
+	return nil
+}
+
+// DefaultTemplate 返回模板目录和内置模板合并后的模板，不包含数据库中保存的模板
+func DefaultTemplate() Template {
+	defaultMu.RLock()
+	defer defaultMu.RUnlock()
+	return defaultTemplate
+}
+
+// Merge 用 t 中不为空的部分覆盖 base
+func (t Template) Merge(base Template) Template {
+	if t.Subject != "" {
+		base.Subject = t.Subject
+	}
+	if t.Text != "" {
+		base.Text = t.Text
+	}
+	if t.HTML != "" {
+		base.HTML = t.HTML
+	}
+	return base
+}
+
+// Validate 解析模板并以示例数据试渲染，检查语法错误和引用了不存在的字段
+func (t Template) Validate() error {
+	_, err := t.Merge(DefaultTemplate()).Render(sampleDigest())
+	return err
+}
+
+// Render 渲染摘要，主题中的换行替换为空格
+func (t Template) Render(d Digest) (Rendered, error) {
+	var r Rendered
+	var err error
+	if r.Subject, err = renderText("subject", t.Subject, d); err != nil {
+		return r, err
+	}
+	r.Subject = strings.Join(strings.Fields(r.Subject), " ")
+	if r.Text, err = renderText("text", t.Text, d); err != nil {
+		return r, err
+	}
+
+	html, err := htmltemplate.New("html").Funcs(funcs).Parse(t.HTML)
+	if err != nil {
+		return r, fmt.Errorf("HTML 模板: %v", err)
+	}
+	var buf bytes.Buffer
+	if err := html.Execute(&buf, d); err != nil {
+		return r, fmt.Errorf("HTML 模板: %v", err)
+	}
+	r.HTML = buf.String()
+	return r, nil
+}
+
+func renderText(name, text string, d Digest) (string, error) {
+	label := map[string]string{"subject": "主题模板", "text": "纯文本模板"}[name]
+	tmpl, err := texttemplate.New(name).Funcs(funcs).Parse(text)
+	if err != nil {
+		return "", fmt.Errorf("%s: %v", label, err)
+	}
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, d); err != nil {
+		return "", fmt.Errorf("%s: %v", label, err)
+	}
+	return buf.String(), nil
+}
+
+// sampleDigest 校验模板时使用的示例数据，标题包含需要转义的字符
+func sampleDigest() Digest {
+	return NewDigest(DigestRequest{
+		To:       "user@example.com",
+		Keywords: []string{"监测"},
+		GroupBy:  GroupType,
+		Announcements: []models.Announcement{{
+			Title: `深圳市生态环境局<监测>服务"采购"公告`, URL: "http://example.com/1.html?a=1&b=2", PublishDate: "2025-06-01",
+			WebPageName: "深圳政府采购网", Type: "tender", Content: "项目概况", Purchaser: "深圳市生态环境局", BudgetAmount: 1200000,
+			BidDeadline: "2025-06-20 09:30",
+		}},
+	})
+}
+
+// StoredTemplate 读取数据库中保存的摘要模板，未保存时返回空模板
+func StoredTemplate() (Template, string, error) {
+	var t Template
+	var updatedAt string
+	err := database.DB.QueryRow("SELECT subject, text, html, updated_at FROM email_templates WHERE name = 'digest'").
+		Scan(&t.Subject, &t.Text, &t.HTML, &updatedAt)
+	if err == sql.ErrNoRows {
+		return Template{}, "", nil
+	}
+	return t, updatedAt, err
+}
+
+// SaveTemplate 保存摘要模板，为空的部分使用模板目录或内置模板
+func SaveTemplate(t Template) error {
+	_, err := database.DB.Exec(`
+		INSERT INTO email_templates (name, subject, text, html, updated_at) VALUES ('digest', ?, ?, ?, CURRENT_TIMESTAMP)
+		ON CONFLICT (name) DO UPDATE SET subject = excluded.subject, text = excluded.text, html = excluded.html,
+			updated_at = CURRENT_TIMESTAMP
+	`, t.Subject, t.Text, t.HTML)
+	return err
+}
+
+// DeleteTemplate 删除数据库中保存的摘要模板，恢复使用模板目录或内置模板
+func DeleteTemplate() error {
+	_, err := database.DB.Exec("DELETE FROM email_templates WHERE name = 'digest'")
+	return err
+}
+
+// CurrentTemplate 返回发送摘要时使用的模板
+func CurrentTemplate() (Template, error) {
+	stored, _, err := StoredTemplate()
+	if err != nil {
+		return Template{}, err
+	}
+	return stored.Merge(DefaultTemplate()), nil
+}
+
+// RenderDigest 按当前模板渲染摘要邮件
+func RenderDigest(req DigestRequest) (Rendered, error) {
+	t, err := CurrentTemplate()
+	if err != nil {
+		return Rendered{}, fmt.Errorf("读取邮件模板失败: %v", err)
+	}
+	return t.Render(NewDigest(req))
+}
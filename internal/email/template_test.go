@@ -0,0 +1,94 @@
+package email
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/ieasydevops/demo-scrapy/internal/extract"
+	"github.com/ieasydevops/demo-scrapy/internal/models"
+)
+
+func TestDigestHTMLEscapesTitlesAndURLs(t *testing.T) {
+	d := NewDigest(DigestRequest{
+		To: "a@example.com",
+		Announcements: []models.Announcement{{
+			Title:       `<script>alert("x")</script> 监测服务 & "维护"`,
+			URL:         `javascript:alert(1)`,
+			PublishDate: "2025-06-01",
+		}, {
+			Title: "正常公告",
+			URL:   `http://example.com/a.html?x=1&y="2"`,
+		}},
+	})
+	r, err := DefaultTemplate().Render(d)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if strings.Contains(r.HTML, "<script>") {
+		t.Errorf("HTML 正文未转义标题:\n%s", r.HTML)
+	}
+	if !strings.Contains(r.HTML, "&lt;script&gt;") || !strings.Contains(r.HTML, "&amp;") {
+		t.Errorf("HTML 正文缺少转义后的标题:\n%s", r.HTML)
+	}
+	if strings.Contains(r.HTML, `href="javascript:`) {
+		t.Errorf("HTML 正文保留了 javascript 链接:\n%s", r.HTML)
+	}
+	if !strings.Contains(r.HTML, `href="http://example.com/a.html?x=1&amp;y=%222%22"`) {
+		t.Errorf("链接属性未正确转义:\n%s", r.HTML)
+	}
+	// 纯文本正文保留原文
+	if !strings.Contains(r.Text, `<script>alert("x")</script>`) {
+		t.Errorf("纯文本正文不应转义:\n%s", r.Text)
+	}
+	if r.Subject != "政府采购网公告通知 - 2条新公告" {
+		t.Errorf("主题 = %q", r.Subject)
+	}
+}
+
+func TestDigestGroups(t *testing.T) {
+	anns := []models.Announcement{
+		{Title: "监测服务中标结果公告", Type: extract.TypeAward, WebPageName: "深圳"},
+		{Title: "设备采购招标公告", Type: extract.TypeTender, WebPageName: "广东"},
+		{Title: "监测服务招标公告", Type: extract.TypeTender},
+	}
+
+	d := NewDigest(DigestRequest{Announcements: anns, Keywords: []string{"监测", "设备"}, GroupBy: GroupType})
+	if got := groupNames(d); got != "中标/成交结果 1|招标公告 2" {
+		t.Errorf("按类型分组 = %s", got)
+	}
+	if kws := d.Groups[1].Items[1].Keywords; len(kws) != 1 || kws[0] != "监测" {
+		t.Errorf("匹配的关键词 = %v", kws)
+	}
+
+	d = NewDigest(DigestRequest{Announcements: anns, Keywords: []string{"设备"}, GroupBy: GroupKeyword})
+	if got := groupNames(d); got != "其他 2|设备 1" {
+		t.Errorf("按关键词分组 = %s", got)
+	}
+
+	d = NewDigest(DigestRequest{Announcements: anns, GroupBy: GroupSource})
+	if got := groupNames(d); got != "深圳 1|广东 1|其他来源 1" {
+		t.Errorf("按来源分组 = %s", got)
+	}
+}
+
+func TestTemplateValidate(t *testing.T) {
+	if err := (Template{HTML: "{{range .Groups}}"}).Merge(DefaultTemplate()).Validate(); err == nil {
+		t.Error("未闭合的模板应校验失败")
+	}
+	if err := (Template{Text: "{{.Missing}}"}).Merge(DefaultTemplate()).Validate(); err == nil {
+		t.Error("引用不存在字段的模板应校验失败")
+	}
+	if err := (Template{Subject: "{{.Count}} 条"}).Merge(DefaultTemplate()).Validate(); err != nil {
+		t.Error(err)
+	}
+}
+
+func groupNames(d Digest) string {
+	var names []string
+	for _, g := range d.Groups {
+		names = append(names, fmt.Sprintf("%s %d", g.Name, len(g.Items)))
+	}
+	return strings.Join(names, "|")
+}
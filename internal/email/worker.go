@@ -0,0 +1,130 @@
+package email
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"log"
+
+	"github.com/ieasydevops/demo-scrapy/internal/database"
+	"github.com/ieasydevops/demo-scrapy/internal/outbox"
+)
+
+var poller = outbox.New("处理邮件发件箱失败", SendDue)
+
+// Wake 通知 Run 立即发送新写入的邮件
+func Wake() { poller.Wake() }
+
+// Run 在后台发送发件箱中到期的邮件，直到 ctx 取消。上次退出时未发送的邮件在启动后继续发送
+func Run(ctx context.Context) { poller.Run(ctx) }
+
+// queued 发件箱中一封到期的邮件
+type queued struct {
+	id        int
+	recipient string
+	subject   string
+	text      string
+	html      string
+	attempts  int
+	// flagged 收件人被永久拒收的原因
+	flagged sql.NullString
+}
+
+// SendDue 发送一批到期的邮件，返回处理的封数。邮箱地址不区分大小写，发往被标记收件人任一写法的邮件都不发送
+func SendDue(ctx context.Context) (int, error) {
+	rows, err := database.DB.Query(`
+		SELECT o.id, o.recipient, o.subject, o.text, o.html, o.attempts, f.reason
+		FROM email_outbox o
+		LEFT JOIN email_flagged_recipients f ON f.recipient = o.recipient COLLATE NOCASE
+		WHERE o.status = ? AND o.next_attempt_at <= CURRENT_TIMESTAMP
+		ORDER BY o.next_attempt_at, o.id
+		LIMIT ?
+	`, StatusPending, outbox.BatchSize)
+	if err != nil {
+		return 0, err
+	}
+	var batch []queued
+	for rows.Next() {
+		var q queued
+		if err := rows.Scan(&q.id, &q.recipient, &q.subject, &q.text, &q.html, &q.attempts, &q.flagged); err != nil {
+			rows.Close()
+			return 0, err
+		}
+		batch = append(batch, q)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, err
+	}
+
+	c := current()
+	for i, q := range batch {
+		if ctx.Err() != nil {
+			return i, nil
+		}
+		if err := deliver(q, c); err != nil {
+			return i, err
+		}
+	}
+	return len(batch), nil
+}
+
+// deliver 发送一次并记录结果。服务器对收件人或邮件内容返回 5xx 时不再重试，
+// 拒收收件人时标记该收件人；4xx、连接错误以及登录等与具体邮件无关的错误按退避时间重试，
+// MaxAttempts 次后标记为 failed
+func deliver(q queued, c Config) error {
+	if q.flagged.Valid {
+		_, err := database.DB.Exec(`UPDATE email_outbox SET status = ?, error = ? WHERE id = ?`,
+			StatusFailed, "收件人已被标记为拒收，未发送: "+q.flagged.String, q.id)
+		return err
+	}
+
+	sendErr := sendMail(q.recipient, q.subject, q.text, q.html)
+	attempts := q.attempts + 1
+	if sendErr == nil {
+		_, err := database.DB.Exec(`
+			UPDATE email_outbox SET status = ?, attempts = ?, smtp_code = NULL, error = '', sent_at = CURRENT_TIMESTAMP
+			WHERE id = ?
+		`, StatusSent, attempts, q.id)
+		return err
+	}
+
+	var smtpErr *Error
+	code := 0
+	permanent := false
+	if errors.As(sendErr, &smtpErr) {
+		code = smtpErr.Code
+		permanent = !smtpErr.Temporary() && (smtpErr.Command == "RCPT TO" || smtpErr.Command == "DATA")
+	}
+	if permanent || attempts >= c.MaxAttempts {
+		log.Printf("发往 %s 的邮件 %d 发送失败 %d 次，不再重试: %v", q.recipient, q.id, attempts, sendErr)
+		if _, err := database.DB.Exec(`
+			UPDATE email_outbox SET status = ?, attempts = ?, smtp_code = ?, error = ? WHERE id = ?
+		`, StatusFailed, attempts, database.NullInt(code), sendErr.Error(), q.id); err != nil {
+			return err
+		}
+		if permanent && smtpErr.Command == "RCPT TO" {
+			return flag(q, smtpErr)
+		}
+		return nil
+	}
+
+	delay := outbox.Backoff(c.RetryDelay, c.MaxDelay, attempts)
+	log.Printf("发往 %s 的邮件 %d 第 %d 次发送失败，%s 后重试: %v", q.recipient, q.id, attempts, delay, sendErr)
+	_, err := database.DB.Exec(`
+		UPDATE email_outbox SET attempts = ?, smtp_code = ?, error = ?, next_attempt_at = datetime('now', ?)
+		WHERE id = ?
+	`, attempts, database.NullInt(code), sendErr.Error(), outbox.After(delay), q.id)
+	return err
+}
+
+// flag 标记被永久拒收的收件人，之后发往该地址的邮件不再发送
+func flag(q queued, smtpErr *Error) error {
+	log.Printf("收件人 %s 被服务器拒收，已标记: %d %s", q.recipient, smtpErr.Code, smtpErr.Message)
+	_, err := database.DB.Exec(`
+		INSERT INTO email_flagged_recipients (recipient, smtp_code, reason, outbox_id) VALUES (?, ?, ?, ?)
+		ON CONFLICT (recipient) DO UPDATE SET
+			smtp_code = excluded.smtp_code, reason = excluded.reason, outbox_id = excluded.outbox_id, created_at = CURRENT_TIMESTAMP
+	`, q.recipient, smtpErr.Code, smtpErr.Message, q.id)
+	return err
+}
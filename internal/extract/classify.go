@@ -0,0 +1,146 @@
+package extract
+
+import (
+	"fmt"
+	"regexp"
+)
+
+// 公告类型
+const (
+	TypeTender       = "tender"
+	TypeAward        = "award"
+	TypeCorrection   = "correction"
+	TypeCancellation = "cancellation"
+	TypeIntention    = "intention"
+	TypeContract     = "contract"
+	TypeOther        = "other"
+)
+
+// TypeInfo 公告类型及其中文名称
+type TypeInfo struct {
+	Type  string `json:"type"`
+	Label string `json:"label"`
+}
+
+// typeOrder 按匹配优先级排列，如 "招标公告更正" 应归为更正而不是招标
+var typeOrder = []TypeInfo{
+	{TypeCancellation, "废标/终止公告"},
+	{TypeCorrection, "更正公告"},
+	{TypeAward, "中标/成交结果"},
+	{TypeContract, "合同公示"},
+	{TypeIntention, "采购意向"},
+	{TypeTender, "招标公告"},
+	{TypeOther, "其他"},
+}
+
+// typeRule 一个类型的标题规则和正文规则
+type typeRule struct {
+	title []*regexp.Regexp
+	body  []*regexp.Regexp
+}
+
+var defaultTypeRules = map[string]typeRule{
+	TypeCancellation: {
+		title: []*regexp.Regexp{regexp.MustCompile(`废标|终止|流标|采购失败|取消公告|撤销`)},
+		body:  []*regexp.Regexp{regexp.MustCompile(`(?:废标|终止|流标)(?:公告|原因)`)},
+	},
+	TypeCorrection: {
+		title: []*regexp.Regexp{regexp.MustCompile(`更正|变更|澄清|补遗|延期|修改公告|补充公告`)},
+		body:  []*regexp.Regexp{regexp.MustCompile(`更正(?:事项|内容|信息)`)},
+	},
+	TypeAward: {
+		title: []*regexp.Regexp{regexp.MustCompile(`中标|成交|结果公[告示]|候选人|评审结果|入围结果`)},
+		body:  []*regexp.Regexp{regexp.MustCompile(`(?:中标|成交)(?:供应商|人|金额)`)},
+	},
+	TypeContract: {
+		title: []*regexp.Regexp{regexp.MustCompile(`合同公[告示]|合同备案|初始合同`)},
+		body:  []*regexp.Regexp{regexp.MustCompile(`合同编号`)},
+	},
+	TypeIntention: {
+		title: []*regexp.Regexp{regexp.MustCompile(`采购意向|意向公开`)},
+		body:  []*regexp.Regexp{regexp.MustCompile(`预计采购时间`)},
+	},
+	TypeTender: {
+		title: []*regexp.Regexp{regexp.MustCompile(`招标公告|采购公告|磋商公告|谈判公告|询价公告|单一来源|比选公告|资格预审|邀请`)},
+		body:  []*regexp.Regexp{regexp.MustCompile(`投标截止时间|提交投标文件截止时间|响应文件提交截止时间|获取招标文件`)},
+	},
+}
+
+var customTypeRules = map[string]typeRule{}
+
+// Types 返回全部公告类型，按匹配优先级排列
+func Types() []TypeInfo {
+	return append([]TypeInfo(nil), typeOrder...)
+}
+
+// ValidType 判断是否为已知的公告类型
+func ValidType(t string) bool {
+	for _, info := range typeOrder {
+		if info.Type == t {
+			return true
+		}
+	}
+	return false
+}
+
+// Classify 根据标题和正文判断公告类型。先按优先级匹配所有标题规则，
+// 标题无法判断时再匹配正文规则，配置的规则优先于默认规则
+func Classify(title, body string) string {
+	mu.RLock()
+	defer mu.RUnlock()
+
+	match := func(text string, rules func(typeRule) []*regexp.Regexp) string {
+		if text == "" {
+			return ""
+		}
+		for _, info := range typeOrder {
+			for _, re := range rules(customTypeRules[info.Type]) {
+				if re.MatchString(text) {
+					return info.Type
+				}
+			}
+			for _, re := range rules(defaultTypeRules[info.Type]) {
+				if re.MatchString(text) {
+					return info.Type
+				}
+			}
+		}
+		return ""
+	}
+
+	if t := match(title, func(r typeRule) []*regexp.Regexp { return r.title }); t != "" {
+		return t
+	}
+	if t := match(body, func(r typeRule) []*regexp.Regexp { return r.body }); t != "" {
+		return t
+	}
+	return TypeOther
+}
+
+// LoadTypeRules 编译并注册某个公告类型的附加标题和正文规则
+func LoadTypeRules(typ string, title, body []string) error {
+	if !ValidType(typ) {
+		return fmt.Errorf("未知的公告类型: %s", typ)
+	}
+
+	var rule typeRule
+	for _, pattern := range title {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return fmt.Errorf("公告类型 %s 的标题规则无效: %v", typ, err)
+		}
+		rule.title = append(rule.title, re)
+	}
+	for _, pattern := range body {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return fmt.Errorf("公告类型 %s 的正文规则无效: %v", typ, err)
+		}
+		rule.body = append(rule.body, re)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	customTypeRules[typ] = rule
+	return nil
+}
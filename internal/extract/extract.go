@@ -0,0 +1,212 @@
+package extract
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/ieasydevops/demo-scrapy/internal/models"
+)
+
+// 可抽取的字段名
+const (
+	FieldProjectNumber = "project_number"
+	FieldPurchaser     = "purchaser"
+	FieldAgency        = "agency"
+	FieldBudget        = "budget_amount"
+	FieldBidDeadline   = "bid_deadline"
+	FieldOpeningTime   = "opening_time"
+	FieldContact       = "contact"
+)
+
+// RuleSet 字段名到正则列表的映射，每个正则的第一个分组为字段值，按顺序匹配第一个命中的
+type RuleSet map[string][]*regexp.Regexp
+
+// sep 匹配标签后的冒号和空白
+const sep = `\s*[:：]?\s*`
+
+// labelTail 匹配标签后的括号说明和"信息 名称"，如 "采购人（甲方）"、"采购代理机构 名称"
+const labelTail = `(?:[（(][^）)\n]{0,20}[）)])?(?:信息)?\s*(?:名称)?`
+
+// labelBoundary 摘要中字段常常首尾相连，在这些标签前断行以确定字段值的结尾
+var labelBoundary = regexp.MustCompile(`项目名称|项目编号|采购单位|采购人|采购代理机构|代理机构|预算金额|采购预算|采购品目|采购需求|` +
+	`联系人|联系电话|联系方式|地址|预计采购时间|备注|供应商|投标截止时间|开标时间`)
+
+var defaultRules = RuleSet{
+	FieldProjectNumber: {
+		regexp.MustCompile(`(?:项目编号|招标编号|采购编号)[^:：\n]{0,40}[:：]\s*([A-Za-z0-9][A-Za-z0-9\-_\[\]【】]*)`),
+	},
+	FieldPurchaser: {
+		regexp.MustCompile(`采购人` + labelTail + sep + `([^\s,，;；。:：]+)`),
+		regexp.MustCompile(`采购单位` + labelTail + sep + `([^\s,，;；。:：]+)`),
+	},
+	FieldAgency: {
+		regexp.MustCompile(`(?:采购代理机构|代理机构|集中采购机构)` + labelTail + sep + `([^\s,，;；。:：]+)`),
+	},
+	FieldBudget: {
+		regexp.MustCompile(`(?:预算金额|采购预算|项目预算|最高限价)[^0-9]{0,20}?([0-9][0-9,，.]*\s*(?:亿元|万元|元)?)`),
+	},
+	FieldBidDeadline: {
+		regexp.MustCompile(`(?:投标截止时间|提交投标文件截止时间|响应文件提交截止时间|递交截止时间)[^0-9]{0,20}?(\d{4}\s*[-/.年]\s*\d{1,2}\s*[-/.月]\s*\d{1,2}\s*日?(?:\s*\d{1,2}\s*[:：时点]\s*\d{0,2}\s*分?)?)`),
+	},
+	FieldOpeningTime: {
+		regexp.MustCompile(`(?:开标时间|开启时间)[^0-9]{0,20}?(\d{4}\s*[-/.年]\s*\d{1,2}\s*[-/.月]\s*\d{1,2}\s*日?(?:\s*\d{1,2}\s*[:：时点]\s*\d{0,2}\s*分?)?)`),
+	},
+	FieldContact: {
+		regexp.MustCompile(`(?:项目联系人|联系人)` + sep + `([^\s,，;；。:：]+)`),
+	},
+}
+
+var phoneRule = regexp.MustCompile(`(?:联系电话|联系方式|电话)` + sep + `([0-9\-－—()（）\s]{7,20}[0-9])`)
+
+var (
+	mu       sync.RWMutex
+	ruleSets = map[string]RuleSet{}
+)
+
+// Register 为数据源注册额外的抽取规则，优先于默认规则匹配
+func Register(source string, rules RuleSet) {
+	mu.Lock()
+	defer mu.Unlock()
+	ruleSets[source] = rules
+}
+
+// Apply 从公告标题和正文（没有正文时使用摘要）中抽取结构化字段并判断公告类型，
+// 结果写入 ann，未匹配的字段保持原值
+func Apply(ann *models.Announcement, source string) {
+	text := ann.Body
+	if text == "" {
+		text = ann.Content
+	}
+	ann.Type = Classify(ann.Title, text)
+	text = labelBoundary.ReplaceAllString(ann.Title+"\n"+text, "\n$0")
+
+	mu.RLock()
+	custom := ruleSets[source]
+	mu.RUnlock()
+
+	find := func(field string) string {
+		for _, re := range custom[field] {
+			if m := re.FindStringSubmatch(text); m != nil {
+				return strings.TrimSpace(m[1])
+			}
+		}
+		for _, re := range defaultRules[field] {
+			if m := re.FindStringSubmatch(text); m != nil {
+				return strings.TrimSpace(m[1])
+			}
+		}
+		return ""
+	}
+
+	if v := find(FieldProjectNumber); v != "" {
+		ann.ProjectNumber = v
+	}
+	if v := find(FieldPurchaser); v != "" {
+		ann.Purchaser = v
+	}
+	if v := find(FieldAgency); v != "" {
+		ann.Agency = v
+	}
+	if v := find(FieldBudget); v != "" {
+		if amount, err := ParseAmount(v); err == nil {
+			ann.BudgetAmount = amount
+		}
+	}
+	if v := find(FieldBidDeadline); v != "" {
+		ann.BidDeadline = ParseDateTime(v)
+	}
+	if v := find(FieldOpeningTime); v != "" {
+		ann.OpeningTime = ParseDateTime(v)
+	}
+
+	contact := find(FieldContact)
+	if m := phoneRule.FindStringSubmatch(text); m != nil {
+		phone := strings.Join(strings.Fields(m[1]), "")
+		if contact != "" {
+			contact += " " + phone
+		} else {
+			contact = phone
+		}
+	}
+	if contact != "" {
+		ann.Contact = contact
+	}
+
+	if ann.Publisher == "" {
+		if ann.Purchaser != "" {
+			ann.Publisher = ann.Purchaser
+		} else {
+			ann.Publisher = ann.Agency
+		}
+	}
+}
+
+// ParseAmount 将 "100万元"、"1,234.50 元"、"1.2亿元" 等金额转换为以元为单位的数值
+func ParseAmount(s string) (float64, error) {
+	s = strings.TrimSpace(s)
+	unit := 1.0
+	switch {
+	case strings.HasSuffix(s, "亿元"):
+		unit = 1e8
+		s = strings.TrimSuffix(s, "亿元")
+	case strings.HasSuffix(s, "万元"):
+		unit = 1e4
+		s = strings.TrimSuffix(s, "万元")
+	default:
+		s = strings.TrimSuffix(s, "元")
+	}
+	s = strings.NewReplacer(",", "", "，", "", " ", "").Replace(s)
+	s = strings.TrimRight(s, ".")
+
+	v, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return 0, fmt.Errorf("无法解析金额: %q", s)
+	}
+	return v * unit, nil
+}
+
+var dateTimePattern = regexp.MustCompile(`(\d{4})\s*[-/.年]\s*(\d{1,2})\s*[-/.月]\s*(\d{1,2})\s*日?(?:\s*(\d{1,2})\s*[:：时点]\s*(\d{0,2}))?`)
+
+// ParseDateTime 将中文或数字日期时间统一为 "2006-01-02 15:04"，没有时间时为 "2006-01-02"
+func ParseDateTime(s string) string {
+	m := dateTimePattern.FindStringSubmatch(s)
+	if m == nil {
+		return strings.TrimSpace(s)
+	}
+	y, _ := strconv.Atoi(m[1])
+	mo, _ := strconv.Atoi(m[2])
+	d, _ := strconv.Atoi(m[3])
+	if m[4] == "" {
+		return fmt.Sprintf("%04d-%02d-%02d", y, mo, d)
+	}
+	h, _ := strconv.Atoi(m[4])
+	mi, _ := strconv.Atoi(m[5])
+	return fmt.Sprintf("%04d-%02d-%02d %02d:%02d", y, mo, d, h, mi)
+}
+
+// LoadRules 编译配置文件中的抽取规则并按数据源注册，格式为 数据源 -> 字段 -> 正则列表
+func LoadRules(rules map[string]map[string][]string) error {
+	for source, fields := range rules {
+		rs := RuleSet{}
+		for field, patterns := range fields {
+			if _, ok := defaultRules[field]; !ok {
+				return fmt.Errorf("数据源 %s 的抽取规则字段未知: %s", source, field)
+			}
+			for _, pattern := range patterns {
+				re, err := regexp.Compile(pattern)
+				if err != nil {
+					return fmt.Errorf("数据源 %s 字段 %s 的正则无效: %v", source, field, err)
+				}
+				if re.NumSubexp() < 1 {
+					return fmt.Errorf("数据源 %s 字段 %s 的正则缺少捕获分组: %s", source, field, pattern)
+				}
+				rs[field] = append(rs[field], re)
+			}
+		}
+		Register(source, rs)
+	}
+	return nil
+}
@@ -0,0 +1,132 @@
+package fetch
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"net/http/cookiejar"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// savedCookie 持久化的 Cookie，Expires 为零表示会话 Cookie
+type savedCookie struct {
+	URL      string    `json:"url"`
+	Name     string    `json:"name"`
+	Value    string    `json:"value"`
+	Path     string    `json:"path,omitempty"`
+	Domain   string    `json:"domain,omitempty"`
+	Expires  time.Time `json:"expires,omitempty"`
+	Secure   bool      `json:"secure,omitempty"`
+	HttpOnly bool      `json:"http_only,omitempty"`
+}
+
+// cookieJar 在标准 cookiejar 之外记录收到的 Cookie，每次变化后写入文件
+type cookieJar struct {
+	*cookiejar.Jar
+	file string
+
+	mu    sync.Mutex
+	saved map[string]savedCookie
+}
+
+// newCookieJar 创建 Cookie 容器，file 不为空时从文件恢复并持久化
+func newCookieJar(file string) (http.CookieJar, error) {
+	jar, err := cookiejar.New(nil)
+	if err != nil {
+		return nil, err
+	}
+	if file == "" {
+		return jar, nil
+	}
+
+	j := &cookieJar{Jar: jar, file: file, saved: map[string]savedCookie{}}
+	if err := j.load(); err != nil {
+		return nil, fmt.Errorf("读取 Cookie 文件 %s 失败: %v", file, err)
+	}
+	return j, nil
+}
+
+func (j *cookieJar) SetCookies(u *url.URL, cookies []*http.Cookie) {
+	j.Jar.SetCookies(u, cookies)
+
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	now := time.Now()
+	origin := u.Scheme + "://" + u.Host
+	for _, c := range cookies {
+		key := origin + " " + c.Domain + " " + c.Path + " " + c.Name
+		expires := c.Expires
+		if c.MaxAge > 0 {
+			expires = now.Add(time.Duration(c.MaxAge) * time.Second)
+		}
+		if c.MaxAge < 0 || (!expires.IsZero() && expires.Before(now)) {
+			delete(j.saved, key)
+			continue
+		}
+		j.saved[key] = savedCookie{
+			URL: origin, Name: c.Name, Value: c.Value, Path: c.Path, Domain: c.Domain,
+			Expires: expires, Secure: c.Secure, HttpOnly: c.HttpOnly,
+		}
+	}
+	if err := j.save(); err != nil {
+		log.Printf("保存 Cookie 文件 %s 失败: %v", j.file, err)
+	}
+}
+
+func (j *cookieJar) load() error {
+	data, err := os.ReadFile(j.file)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	var cookies []savedCookie
+	if err := json.Unmarshal(data, &cookies); err != nil {
+		return err
+	}
+	now := time.Now()
+	for _, c := range cookies {
+		if !c.Expires.IsZero() && c.Expires.Before(now) {
+			continue
+		}
+		u, err := url.Parse(c.URL)
+		if err != nil {
+			continue
+		}
+		j.Jar.SetCookies(u, []*http.Cookie{{
+			Name: c.Name, Value: c.Value, Path: c.Path, Domain: c.Domain,
+			Expires: c.Expires, Secure: c.Secure, HttpOnly: c.HttpOnly,
+		}})
+		j.saved[c.URL+" "+c.Domain+" "+c.Path+" "+c.Name] = c
+	}
+	return nil
+}
+
+// save 先写临时文件再改名，避免写入中断时损坏原文件
+func (j *cookieJar) save() error {
+	cookies := make([]savedCookie, 0, len(j.saved))
+	for _, c := range j.saved {
+		cookies = append(cookies, c)
+	}
+	data, err := json.MarshalIndent(cookies, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(j.file), 0o755); err != nil {
+		return err
+	}
+	tmp := j.file + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, j.file)
+}
@@ -0,0 +1,307 @@
+// Package fetch 提供采集共用的 HTTP 客户端：超时、连接错误、429 和 5xx 时按指数退避重试，
+// 并按主机限速，避免对采购门户造成压力。每个数据源可以单独配置代理池、请求头、TLS 和 Cookie，
+// 也可以把真实响应录制到文件，供测试离线回放。
+package fetch
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"math/rand"
+	"net"
+	"net/http"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// Options 客户端参数
+type Options struct {
+	// Timeout 单次请求的超时时间
+	Timeout time.Duration
+	// MaxRetries 失败后的最大重试次数
+	MaxRetries int
+	// BaseDelay 第一次重试前的等待时间，之后每次翻倍
+	BaseDelay time.Duration
+	// MaxDelay 单次等待时间的上限，也用于限制 Retry-After
+	MaxDelay time.Duration
+	// Rate 每个主机每秒允许的请求数，0 表示不限速
+	Rate float64
+	// Burst 每个主机允许的突发请求数
+	Burst int
+	// Transport 代理、请求头、TLS 和 Cookie 设置
+	Transport TransportOptions
+	// Fixtures 录制真实响应或在测试中回放
+	Fixtures FixtureOptions
+}
+
+// DefaultOptions 未配置时使用的参数
+func DefaultOptions() Options {
+	return Options{
+		Timeout:    30 * time.Second,
+		MaxRetries: 3,
+		BaseDelay:  time.Second,
+		MaxDelay:   30 * time.Second,
+		Rate:       2,
+		Burst:      2,
+	}
+}
+
+// Client 带重试和按主机限速的 HTTP 客户端，可并发使用
+type Client struct {
+	http      *http.Client
+	opts      Options
+	limiter   *hostLimiter
+	transport TransportOptions
+	proxies   *proxyPool
+}
+
+// New 按参数创建客户端
+func New(opts Options) (*Client, error) {
+	return newClient("", opts, newHostLimiter(opts.Rate, opts.Burst))
+}
+
+func newClient(source string, opts Options, limiter *hostLimiter) (*Client, error) {
+	t := opts.Transport
+	proxies, err := newProxyPool(source, t.Proxies, t.ProxyMaxFailures, t.ProxyCooldown)
+	if err != nil {
+		return nil, err
+	}
+	fixtures := opts.Fixtures
+	if fixtures.Mode != "" {
+		fixtures.Dir = filepath.Join(fixtures.Dir, source)
+	}
+	httpClient, err := newHTTPClient(opts.Timeout, t, proxies, fixtures)
+	if err != nil {
+		return nil, err
+	}
+	return &Client{http: httpClient, opts: opts, limiter: limiter, transport: t, proxies: proxies}, nil
+}
+
+var (
+	defaultMu     sync.RWMutex
+	defaultClient = mustNew(DefaultOptions())
+	sourceClients = map[string]*Client{}
+)
+
+func mustNew(opts Options) *Client {
+	c, err := New(opts)
+	if err != nil {
+		panic(err)
+	}
+	return c
+}
+
+// Configure 创建共用客户端和各数据源的客户端，所有客户端共享按主机的限速。
+// sources 中的传输设置用于对应数据源的列表页、详情页和附件请求，未配置的数据源使用 opts.Transport
+func Configure(opts Options, sources map[string]TransportOptions) error {
+	limiter := newHostLimiter(opts.Rate, opts.Burst)
+	def, err := newClient("default", opts, limiter)
+	if err != nil {
+		return err
+	}
+	clients := make(map[string]*Client, len(sources))
+	for name, t := range sources {
+		sourceOpts := opts
+		sourceOpts.Transport = t
+		c, err := newClient(name, sourceOpts, limiter)
+		if err != nil {
+			return fmt.Errorf("数据源 %s: %v", name, err)
+		}
+		clients[name] = c
+	}
+
+	defaultMu.Lock()
+	defer defaultMu.Unlock()
+	defaultClient = def
+	sourceClients = clients
+	return nil
+}
+
+// Default 返回未单独配置传输设置时使用的客户端
+func Default() *Client {
+	defaultMu.RLock()
+	defer defaultMu.RUnlock()
+	return defaultClient
+}
+
+// For 返回数据源使用的客户端，未单独配置时返回 Default
+func For(source string) *Client {
+	defaultMu.RLock()
+	defer defaultMu.RUnlock()
+	if c, ok := sourceClients[source]; ok {
+		return c
+	}
+	return defaultClient
+}
+
+// ProxyStates 返回所有客户端代理池的健康状态
+func ProxyStates() []ProxyState {
+	defaultMu.RLock()
+	clients := []*Client{defaultClient}
+	names := make([]string, 0, len(sourceClients))
+	for name := range sourceClients {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		clients = append(clients, sourceClients[name])
+	}
+	defaultMu.RUnlock()
+
+	states := []ProxyState{}
+	for _, c := range clients {
+		if c.proxies != nil {
+			states = append(states, c.proxies.states()...)
+		}
+	}
+	return states
+}
+
+// Do 发送请求。超时、连接错误、429 和 5xx 时按指数退避重试，429/503 的 Retry-After 优先；
+// 配置了代理池时每次尝试轮换代理。重试用尽后返回最后一次的响应或错误。
+// 带请求体的请求需可重放（由 http.NewRequest 创建时自动满足）
+func (c *Client) Do(req *http.Request) (*http.Response, error) {
+	ctx := req.Context()
+	req = req.Clone(ctx)
+	c.transport.applyHeaders(req)
+
+	for attempt := 0; ; attempt++ {
+		if err := c.limiter.wait(ctx, req.URL.Host); err != nil {
+			return nil, err
+		}
+
+		attemptReq, err := rewind(req, attempt)
+		if err != nil {
+			return nil, err
+		}
+		var px *proxy
+		if c.proxies != nil {
+			px = c.proxies.pick()
+		}
+		resp, err := c.http.Do(withProxy(attemptReq, px))
+
+		var reason string
+		var retryAfter time.Duration
+		switch {
+		case err != nil:
+			if ctx.Err() != nil {
+				return nil, ctx.Err()
+			}
+			c.reportProxy(px, err.Error())
+			if !retryable(err) && px == nil {
+				return nil, err
+			}
+			reason = err.Error()
+		case resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500:
+			reason = fmt.Sprintf("HTTP状态码 %d", resp.StatusCode)
+			retryAfter = parseRetryAfter(resp.Header.Get("Retry-After"))
+			if resp.StatusCode == http.StatusTooManyRequests {
+				c.reportProxy(px, reason)
+			} else {
+				c.reportProxy(px, "")
+			}
+		case px != nil && (resp.StatusCode == http.StatusForbidden || resp.StatusCode == http.StatusProxyAuthRequired):
+			// 被门户封禁或代理认证失败，换一个代理重试
+			reason = fmt.Sprintf("HTTP状态码 %d", resp.StatusCode)
+			c.reportProxy(px, reason)
+		default:
+			c.reportProxy(px, "")
+			return resp, nil
+		}
+
+		if attempt >= c.opts.MaxRetries {
+			return resp, err
+		}
+		if resp != nil {
+			io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+			resp.Body.Close()
+		}
+		if px != nil {
+			reason += "，代理 " + px.url.Redacted()
+		}
+
+		delay := c.backoff(attempt, retryAfter)
+		log.Printf("请求 %s 失败（第 %d 次）: %s，%v 后重试", req.URL.Redacted(), attempt+1, reason, delay.Round(time.Millisecond))
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(delay):
+		}
+	}
+}
+
+// reportProxy 记录代理本次请求的结果，reason 为空表示成功
+func (c *Client) reportProxy(px *proxy, reason string) {
+	if px == nil {
+		return
+	}
+	if reason == "" {
+		c.proxies.success(px)
+	} else {
+		c.proxies.failure(px, reason)
+	}
+}
+
+// backoff 第 attempt 次失败后的等待时间：BaseDelay*2^attempt 的一半加随机抖动，不超过 MaxDelay
+func (c *Client) backoff(attempt int, retryAfter time.Duration) time.Duration {
+	if retryAfter > 0 {
+		return min(retryAfter, c.opts.MaxDelay)
+	}
+	d := c.opts.BaseDelay << attempt
+	if d <= 0 || d > c.opts.MaxDelay {
+		d = c.opts.MaxDelay
+	}
+	half := d / 2
+	return half + time.Duration(rand.Int63n(int64(half)+1))
+}
+
+// rewind 为每次尝试复制请求，避免上一次尝试写入的请求头（如 Cookie）累积；第二次及以后重新生成请求体
+func rewind(req *http.Request, attempt int) (*http.Request, error) {
+	clone := req.Clone(req.Context())
+	if attempt == 0 || req.Body == nil || req.Body == http.NoBody {
+		return clone, nil
+	}
+	if req.GetBody == nil {
+		return nil, errors.New("请求体不可重放，无法重试")
+	}
+	body, err := req.GetBody()
+	if err != nil {
+		return nil, err
+	}
+	clone.Body = body
+	return clone, nil
+}
+
+// retryable 判断请求错误是否可能是暂时的
+func retryable(err error) bool {
+	var netErr net.Error
+	if errors.As(err, &netErr) && netErr.Timeout() {
+		return true
+	}
+	var dnsErr *net.DNSError
+	if errors.As(err, &dnsErr) {
+		return dnsErr.IsTemporary || dnsErr.IsTimeout
+	}
+	return errors.Is(err, io.ErrUnexpectedEOF) || errors.Is(err, io.EOF) ||
+		errors.Is(err, syscall.ECONNRESET) || errors.Is(err, syscall.ECONNREFUSED) ||
+		errors.Is(err, syscall.ECONNABORTED) || errors.Is(err, syscall.EPIPE)
+}
+
+// parseRetryAfter 解析 Retry-After，支持秒数和 HTTP 日期
+func parseRetryAfter(v string) time.Duration {
+	if v == "" {
+		return 0
+	}
+	if secs, err := strconv.Atoi(v); err == nil && secs > 0 {
+		return time.Duration(secs) * time.Second
+	}
+	if t, err := http.ParseTime(v); err == nil {
+		return time.Until(t)
+	}
+	return 0
+}
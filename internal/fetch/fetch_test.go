@@ -0,0 +1,170 @@
+package fetch
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func testOptions() Options {
+	return Options{Timeout: 5 * time.Second, MaxRetries: 2, BaseDelay: time.Millisecond, MaxDelay: 5 * time.Millisecond}
+}
+
+func get(t *testing.T, c *Client, url string) (*http.Response, error) {
+	t.Helper()
+	req, err := http.NewRequest("GET", url, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return c.Do(req)
+}
+
+func TestDoRetriesThenGivesUp(t *testing.T) {
+	var calls int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch atomic.AddInt32(&calls, 1) {
+		case 1:
+			w.WriteHeader(http.StatusServiceUnavailable)
+		case 2:
+			w.Header().Set("Retry-After", "0")
+			w.WriteHeader(http.StatusTooManyRequests)
+		default:
+			io.WriteString(w, "ok")
+		}
+	}))
+	defer srv.Close()
+
+	c, err := New(testOptions())
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp, err := get(t, c, srv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if string(body) != "ok" || calls != 3 {
+		t.Fatalf("响应 %q、请求 %d 次，期望重试两次后成功", body, calls)
+	}
+
+	// 重试用尽后返回最后一次的响应
+	var failures int32
+	down := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&failures, 1)
+		w.WriteHeader(http.StatusBadGateway)
+	}))
+	defer down.Close()
+	resp, err = get(t, c, down.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusBadGateway || failures != 3 {
+		t.Fatalf("状态码 %d、请求 %d 次，期望 MaxRetries+1 次后返回 502", resp.StatusCode, failures)
+	}
+}
+
+func TestDoSendsConfiguredHeaders(t *testing.T) {
+	var ua, lang string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ua, lang = r.UserAgent(), r.Header.Get("Accept-Language")
+	}))
+	defer srv.Close()
+
+	opts := testOptions()
+	opts.Transport = TransportOptions{UserAgent: "demo-bot/1.0", Headers: map[string]string{"Accept-Language": "zh-CN"}}
+	c, err := New(opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp, err := get(t, c, srv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if ua != "demo-bot/1.0" || lang != "zh-CN" {
+		t.Errorf("User-Agent %q、Accept-Language %q", ua, lang)
+	}
+}
+
+func TestProxyPoolEvictsFailingProxy(t *testing.T) {
+	pool, err := newProxyPool("test", []string{"http://10.0.0.1:3128", "socks5://10.0.0.2:1080"}, 2, time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	bad := pool.pick()
+	good := pool.pick()
+	if bad == good {
+		t.Fatal("没有轮换代理")
+	}
+
+	pool.failure(bad, "connection refused")
+	if pool.pick() != bad {
+		t.Fatal("连续失败未达上限时不应暂停")
+	}
+	pool.failure(bad, "connection refused")
+	for i := 0; i < 3; i++ {
+		if pool.pick() != good {
+			t.Fatal("已暂停的代理仍被使用")
+		}
+	}
+
+	states := pool.states()
+	if states[0].Available || states[0].Evictions != 1 || states[0].EvictedUntil == nil || !states[1].Available {
+		t.Errorf("代理状态不符: %+v", states)
+	}
+
+	if _, err := newProxyPool("test", []string{"ftp://10.0.0.1"}, 1, time.Minute); err == nil {
+		t.Error("应拒绝不支持的代理协议")
+	}
+}
+
+func TestFixtureRecordReplay(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		w.Header().Set("Content-Type", "text/plain")
+		w.Header().Set("Set-Cookie", "sid=secret")
+		io.WriteString(w, "echo:"+string(body))
+	}))
+	dir := t.TempDir()
+
+	post := func(c *Client, body string) (string, error) {
+		req, _ := http.NewRequest("POST", srv.URL+"/search", strings.NewReader(body))
+		resp, err := c.Do(req)
+		if err != nil {
+			return "", err
+		}
+		defer resp.Body.Close()
+		data, _ := io.ReadAll(resp.Body)
+		return resp.Header.Get("Content-Type") + " " + string(data), nil
+	}
+
+	opts := testOptions()
+	opts.Fixtures = FixtureOptions{Mode: FixtureRecord, Dir: dir}
+	recorder, err := New(opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := post(recorder, "a"); err != nil {
+		t.Fatal(err)
+	}
+	srv.Close()
+
+	opts.Fixtures.Mode = FixtureReplay
+	replayer, err := New(opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := post(replayer, "a")
+	if err != nil || got != "text/plain echo:a" {
+		t.Fatalf("回放得到 %q, %v", got, err)
+	}
+	if _, err := post(replayer, "b"); err == nil || !strings.Contains(err.Error(), "录制文件") {
+		t.Fatalf("请求体不同时应找不到录制文件，得到 %v", err)
+	}
+}
@@ -0,0 +1,183 @@
+package fetch
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"unicode/utf8"
+)
+
+// 录制模式
+const (
+	// FixtureRecord 正常发出请求，并把请求和响应写入录制文件
+	FixtureRecord = "record"
+	// FixtureReplay 不访问网络，按请求查找录制文件返回响应，找不到时返回错误
+	FixtureReplay = "replay"
+)
+
+// FixtureOptions 录制和回放设置，Mode 为空时不启用
+type FixtureOptions struct {
+	Mode string
+	// Dir 录制文件目录，Configure 为每个数据源使用其下的同名子目录（共用客户端为 default）
+	Dir string
+}
+
+// Fixture 一次请求和响应的录制内容
+type Fixture struct {
+	Request  FixtureRequest  `json:"request"`
+	Response FixtureResponse `json:"response"`
+}
+
+type FixtureRequest struct {
+	Method string `json:"method"`
+	URL    string `json:"url"`
+	Body   string `json:"body,omitempty"`
+}
+
+type FixtureResponse struct {
+	Status int         `json:"status"`
+	Header http.Header `json:"header,omitempty"`
+	// Body 为文本时原样保存，否则 BodyBase64 为 true 且 Body 为 base64 编码
+	Body       string `json:"body"`
+	BodyBase64 bool   `json:"body_base64,omitempty"`
+}
+
+// fixtureTransport 按模式录制或回放请求
+type fixtureTransport struct {
+	mode string
+	dir  string
+	next http.RoundTripper
+}
+
+// NewFixtureTransport 创建录制或回放的 RoundTripper，next 用于录制模式下实际发出请求
+func NewFixtureTransport(opts FixtureOptions, next http.RoundTripper) (http.RoundTripper, error) {
+	switch opts.Mode {
+	case FixtureRecord, FixtureReplay:
+	default:
+		return nil, fmt.Errorf("未知的录制模式 %q，可选 record、replay", opts.Mode)
+	}
+	if opts.Dir == "" {
+		return nil, errors.New("未设置录制文件目录")
+	}
+	return &fixtureTransport{mode: opts.Mode, dir: opts.Dir, next: next}, nil
+}
+
+func (t *fixtureTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	var body []byte
+	if req.Body != nil && req.Body != http.NoBody {
+		var err error
+		body, err = io.ReadAll(req.Body)
+		req.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+		req.Body = io.NopCloser(bytes.NewReader(body))
+	}
+	path := filepath.Join(t.dir, FixtureName(req.Method, req.URL.String(), body))
+
+	if t.mode == FixtureReplay {
+		return t.replay(req, path)
+	}
+	return t.record(req, path, body)
+}
+
+func (t *fixtureTransport) replay(req *http.Request, path string) (*http.Response, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("没有 %s %s 的录制文件 %s，请使用 record 模式重新录制", req.Method, req.URL.Redacted(), path)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var f Fixture
+	if err := json.Unmarshal(data, &f); err != nil {
+		return nil, fmt.Errorf("解析录制文件 %s 失败: %v", path, err)
+	}
+	body := []byte(f.Response.Body)
+	if f.Response.BodyBase64 {
+		if body, err = base64.StdEncoding.DecodeString(f.Response.Body); err != nil {
+			return nil, fmt.Errorf("解析录制文件 %s 失败: %v", path, err)
+		}
+	}
+	header := f.Response.Header
+	if header == nil {
+		header = http.Header{}
+	}
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", f.Response.Status, http.StatusText(f.Response.Status)),
+		StatusCode:    f.Response.Status,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        header,
+		Body:          io.NopCloser(bytes.NewReader(body)),
+		ContentLength: int64(len(body)),
+		Request:       req,
+	}, nil
+}
+
+func (t *fixtureTransport) record(req *http.Request, path string, reqBody []byte) (*http.Response, error) {
+	resp, err := t.next.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+	body, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	resp.Body = io.NopCloser(bytes.NewReader(body))
+
+	header := http.Header{}
+	for _, k := range fixtureHeaders {
+		if v := resp.Header.Values(k); len(v) > 0 {
+			header[k] = v
+		}
+	}
+	f := Fixture{
+		Request:  FixtureRequest{Method: req.Method, URL: req.URL.String(), Body: string(reqBody)},
+		Response: FixtureResponse{Status: resp.StatusCode, Header: header, Body: string(body)},
+	}
+	if !utf8.Valid(body) {
+		f.Response.Body = base64.StdEncoding.EncodeToString(body)
+		f.Response.BodyBase64 = true
+	}
+	if err := writeFixture(path, f); err != nil {
+		return nil, fmt.Errorf("写入录制文件 %s 失败: %v", path, err)
+	}
+	return resp, nil
+}
+
+// fixtureHeaders 录制时保留的响应头，其余（如 Set-Cookie、Date）与解析无关或含会话信息
+var fixtureHeaders = []string{"Content-Type", "Location", "Retry-After"}
+
+func writeFixture(path string, f Fixture) error {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(f); err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	return os.WriteFile(path, buf.Bytes(), 0o644)
+}
+
+// FixtureName 请求对应的录制文件名，由方法、URL 和请求体的哈希组成
+func FixtureName(method, url string, body []byte) string {
+	h := sha256.New()
+	h.Write([]byte(method + " " + url + "\n"))
+	h.Write(body)
+	return hex.EncodeToString(h.Sum(nil))[:16] + ".json"
+}
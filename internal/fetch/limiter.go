@@ -0,0 +1,66 @@
+package fetch
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// hostLimiter 按主机的令牌桶限速器
+type hostLimiter struct {
+	rate    float64
+	burst   float64
+	mu      sync.Mutex
+	buckets map[string]*bucket
+}
+
+type bucket struct {
+	tokens float64
+	last   time.Time
+}
+
+func newHostLimiter(rate float64, burst int) *hostLimiter {
+	if burst < 1 {
+		burst = 1
+	}
+	return &hostLimiter{rate: rate, burst: float64(burst), buckets: map[string]*bucket{}}
+}
+
+// wait 等待 host 的令牌桶中有可用令牌，ctx 结束时返回其错误
+func (l *hostLimiter) wait(ctx context.Context, host string) error {
+	if l.rate <= 0 {
+		return nil
+	}
+	for {
+		delay := l.reserve(host)
+		if delay == 0 {
+			return nil
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(delay):
+		}
+	}
+}
+
+// reserve 有可用令牌时取走一个并返回 0，否则返回预计等待时间
+func (l *hostLimiter) reserve(host string) time.Duration {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := time.Now()
+	b, ok := l.buckets[host]
+	if !ok {
+		b = &bucket{tokens: l.burst, last: now}
+		l.buckets[host] = b
+	}
+	b.tokens = min(l.burst, b.tokens+now.Sub(b.last).Seconds()*l.rate)
+	b.last = now
+
+	if b.tokens >= 1 {
+		b.tokens--
+		return 0
+	}
+	return time.Duration((1 - b.tokens) / l.rate * float64(time.Second))
+}
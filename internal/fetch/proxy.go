@@ -0,0 +1,145 @@
+package fetch
+
+import (
+	"fmt"
+	"log"
+	"net/url"
+	"sync"
+	"time"
+)
+
+// ProxyState 代理的健康状态
+type ProxyState struct {
+	Source    string `json:"source"`
+	Proxy     string `json:"proxy"`
+	Available bool   `json:"available"`
+	// ConsecutiveFailures 连续失败次数，成功一次后清零
+	ConsecutiveFailures int        `json:"consecutive_failures"`
+	Successes           int        `json:"successes"`
+	Failures            int        `json:"failures"`
+	Evictions           int        `json:"evictions"`
+	EvictedUntil        *time.Time `json:"evicted_until,omitempty"`
+	LastUsedAt          *time.Time `json:"last_used_at,omitempty"`
+	LastError           string     `json:"last_error,omitempty"`
+}
+
+type proxy struct {
+	url          *url.URL
+	consecutive  int
+	successes    int
+	failures     int
+	evictions    int
+	evictedUntil time.Time
+	lastUsed     time.Time
+	lastError    string
+}
+
+// proxyPool 轮换使用的代理，连续失败达到上限的代理暂停使用一段时间
+type proxyPool struct {
+	source      string
+	maxFailures int
+	cooldown    time.Duration
+
+	mu      sync.Mutex
+	proxies []*proxy
+	next    int
+}
+
+func newProxyPool(source string, addrs []string, maxFailures int, cooldown time.Duration) (*proxyPool, error) {
+	if len(addrs) == 0 {
+		return nil, nil
+	}
+	if maxFailures < 1 {
+		maxFailures = 1
+	}
+
+	pool := &proxyPool{source: source, maxFailures: maxFailures, cooldown: cooldown}
+	for _, addr := range addrs {
+		u, err := url.Parse(addr)
+		if err != nil || u.Host == "" {
+			return nil, fmt.Errorf("代理地址无效: %s", addr)
+		}
+		switch u.Scheme {
+		case "http", "https", "socks5", "socks5h":
+		default:
+			return nil, fmt.Errorf("不支持的代理协议 %q，可选 http、https、socks5", u.Scheme)
+		}
+		pool.proxies = append(pool.proxies, &proxy{url: u})
+	}
+	return pool, nil
+}
+
+// pick 按轮换顺序返回下一个可用的代理；全部暂停时返回最早恢复的一个
+func (p *proxyPool) pick() *proxy {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	now := time.Now()
+	var earliest *proxy
+	for i := 0; i < len(p.proxies); i++ {
+		px := p.proxies[(p.next+i)%len(p.proxies)]
+		if !now.Before(px.evictedUntil) {
+			p.next = (p.next + i + 1) % len(p.proxies)
+			px.lastUsed = now
+			return px
+		}
+		if earliest == nil || px.evictedUntil.Before(earliest.evictedUntil) {
+			earliest = px
+		}
+	}
+	earliest.lastUsed = now
+	return earliest
+}
+
+func (p *proxyPool) success(px *proxy) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	px.successes++
+	px.consecutive = 0
+}
+
+// failure 记录一次失败，连续失败达到上限时暂停使用该代理
+func (p *proxyPool) failure(px *proxy, reason string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	px.failures++
+	px.consecutive++
+	px.lastError = reason
+	if px.consecutive >= p.maxFailures {
+		px.evictions++
+		px.evictedUntil = time.Now().Add(p.cooldown)
+		log.Printf("数据源 %s 的代理 %s 连续失败 %d 次，暂停使用 %v: %s",
+			p.source, px.url.Redacted(), px.consecutive, p.cooldown, reason)
+	}
+}
+
+func (p *proxyPool) states() []ProxyState {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	now := time.Now()
+	states := make([]ProxyState, 0, len(p.proxies))
+	for _, px := range p.proxies {
+		s := ProxyState{
+			Source:              p.source,
+			Proxy:               px.url.Redacted(),
+			Available:           !now.Before(px.evictedUntil),
+			ConsecutiveFailures: px.consecutive,
+			Successes:           px.successes,
+			Failures:            px.failures,
+			Evictions:           px.evictions,
+			LastError:           px.lastError,
+		}
+		if !s.Available {
+			t := px.evictedUntil
+			s.EvictedUntil = &t
+		}
+		if !px.lastUsed.IsZero() {
+			t := px.lastUsed
+			s.LastUsedAt = &t
+		}
+		states = append(states, s)
+	}
+	return states
+}
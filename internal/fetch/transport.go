@@ -0,0 +1,129 @@
+package fetch
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"time"
+)
+
+// DefaultUserAgent 未配置 user_agent 时使用的浏览器标识
+const DefaultUserAgent = "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/120.0.0.0 Safari/537.36"
+
+// TransportOptions 请求的代理、请求头、TLS 和 Cookie 设置
+type TransportOptions struct {
+	// Proxies 代理地址，支持 http://、https://、socks5://，多个时轮换使用；为空时使用 HTTP_PROXY 等环境变量
+	Proxies []string
+	// ProxyMaxFailures 代理连续失败多少次后暂停使用
+	ProxyMaxFailures int
+	// ProxyCooldown 代理暂停使用的时间，到期后重新参与轮换
+	ProxyCooldown time.Duration
+	// UserAgent 为空时使用 DefaultUserAgent
+	UserAgent string
+	// Headers 附加到每个请求的请求头，覆盖同名请求头
+	Headers map[string]string
+	// InsecureSkipVerify 不校验服务器证书
+	InsecureSkipVerify bool
+	// CAFile 额外信任的 CA 证书（PEM）
+	CAFile string
+	// MinTLSVersion 最低 TLS 版本，如 "1.2"
+	MinTLSVersion string
+	// Cookies 在请求之间保存 Cookie
+	Cookies bool
+	// CookieFile 不为空时 Cookie 持久化到该文件，重启后继续使用
+	CookieFile string
+}
+
+var tlsVersions = map[string]uint16{
+	"1.0": tls.VersionTLS10,
+	"1.1": tls.VersionTLS11,
+	"1.2": tls.VersionTLS12,
+	"1.3": tls.VersionTLS13,
+}
+
+type proxyKey struct{}
+
+// newHTTPClient 按传输设置创建底层客户端，proxies 为 nil 时使用环境变量中的代理
+func newHTTPClient(timeout time.Duration, t TransportOptions, proxies *proxyPool, fixtures FixtureOptions) (*http.Client, error) {
+	tlsConfig, err := t.tlsConfig()
+	if err != nil {
+		return nil, err
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.TLSClientConfig = tlsConfig
+	transport.Proxy = http.ProxyFromEnvironment
+	if proxies != nil {
+		transport.Proxy = func(req *http.Request) (*url.URL, error) {
+			if p, ok := req.Context().Value(proxyKey{}).(*proxy); ok {
+				return p.url, nil
+			}
+			return nil, nil
+		}
+	}
+
+	client := &http.Client{Timeout: timeout, Transport: transport}
+	if fixtures.Mode != "" {
+		if client.Transport, err = NewFixtureTransport(fixtures, transport); err != nil {
+			return nil, err
+		}
+	}
+	if t.Cookies || t.CookieFile != "" {
+		jar, err := newCookieJar(t.CookieFile)
+		if err != nil {
+			return nil, err
+		}
+		client.Jar = jar
+	}
+	return client, nil
+}
+
+func (t TransportOptions) tlsConfig() (*tls.Config, error) {
+	cfg := &tls.Config{InsecureSkipVerify: t.InsecureSkipVerify}
+	if t.MinTLSVersion != "" {
+		v, ok := tlsVersions[t.MinTLSVersion]
+		if !ok {
+			return nil, fmt.Errorf("不支持的 TLS 版本: %s，可选 1.0、1.1、1.2、1.3", t.MinTLSVersion)
+		}
+		cfg.MinVersion = v
+	}
+	if t.CAFile != "" {
+		pem, err := os.ReadFile(t.CAFile)
+		if err != nil {
+			return nil, fmt.Errorf("读取 CA 证书失败: %v", err)
+		}
+		pool, err := x509.SystemCertPool()
+		if err != nil {
+			pool = x509.NewCertPool()
+		}
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("CA 证书 %s 中没有有效的 PEM 证书", t.CAFile)
+		}
+		cfg.RootCAs = pool
+	}
+	return cfg, nil
+}
+
+// applyHeaders 设置 User-Agent 和附加请求头
+func (t TransportOptions) applyHeaders(req *http.Request) {
+	ua := t.UserAgent
+	if ua == "" {
+		ua = DefaultUserAgent
+	}
+	req.Header.Set("User-Agent", ua)
+	for k, v := range t.Headers {
+		req.Header.Set(k, v)
+	}
+}
+
+// withProxy 让本次请求经由 p 发出
+func withProxy(req *http.Request, p *proxy) *http.Request {
+	if p == nil {
+		return req
+	}
+	return req.WithContext(context.WithValue(req.Context(), proxyKey{}, p))
+}
@@ -0,0 +1,119 @@
+package health
+
+import (
+	"database/sql"
+
+	"github.com/ieasydevops/demo-scrapy/internal/database"
+	"github.com/ieasydevops/demo-scrapy/internal/models"
+)
+
+// 数据源健康状态
+const (
+	StatusOK       = "ok"
+	StatusDegraded = "degraded"
+)
+
+// Degrade 将网页数据源标记为异常并记录原因和原始响应样本，
+// 返回此前是否正常（用于只在状态变化时告警）。同一网页的多个监控配置同时采集时只有一个返回 true
+func Degrade(webPageID int, source, reason, sample string, runID int) (bool, error) {
+	result, err := database.DB.Exec(`
+		INSERT INTO source_health (web_page_id, source, status, reason, sample, crawl_run_id, degraded_at, checked_at)
+		VALUES (?, ?, ?, ?, ?, ?, CURRENT_TIMESTAMP, CURRENT_TIMESTAMP)
+		ON CONFLICT (web_page_id) DO NOTHING
+	`, webPageID, source, StatusDegraded, reason, sample, database.NullInt(runID))
+	if changed, err := affected(result, err); err != nil || changed {
+		return changed, err
+	}
+
+	result, err = database.DB.Exec(`
+		UPDATE source_health SET source = ?, status = ?, reason = ?, sample = ?, crawl_run_id = ?,
+			degraded_at = CURRENT_TIMESTAMP, checked_at = CURRENT_TIMESTAMP
+		WHERE web_page_id = ? AND status != ?
+	`, source, StatusDegraded, reason, sample, database.NullInt(runID), webPageID, StatusDegraded)
+	if changed, err := affected(result, err); err != nil || changed {
+		return changed, err
+	}
+
+	// 已经是异常状态，只更新最近一次的原因和样本
+	_, err = database.DB.Exec(`
+		UPDATE source_health SET source = ?, reason = ?, sample = ?, crawl_run_id = ?, checked_at = CURRENT_TIMESTAMP
+		WHERE web_page_id = ?
+	`, source, reason, sample, database.NullInt(runID), webPageID)
+	return false, err
+}
+
+// Recover 将网页数据源标记为正常，保留上次异常的原因和样本，返回此前是否为异常
+func Recover(webPageID int, source string, runID int) (bool, error) {
+	result, err := database.DB.Exec(`
+		UPDATE source_health SET source = ?, status = ?, crawl_run_id = ?, checked_at = CURRENT_TIMESTAMP
+		WHERE web_page_id = ? AND status = ?
+	`, source, StatusOK, database.NullInt(runID), webPageID, StatusDegraded)
+	if changed, err := affected(result, err); err != nil || changed {
+		return changed, err
+	}
+
+	_, err = database.DB.Exec(`
+		INSERT INTO source_health (web_page_id, source, status, crawl_run_id, checked_at)
+		VALUES (?, ?, ?, ?, CURRENT_TIMESTAMP)
+		ON CONFLICT (web_page_id) DO UPDATE SET
+			source = excluded.source, crawl_run_id = excluded.crawl_run_id, checked_at = CURRENT_TIMESTAMP
+	`, webPageID, source, StatusOK, database.NullInt(runID))
+	return false, err
+}
+
+func affected(result sql.Result, err error) (bool, error) {
+	if err != nil {
+		return false, err
+	}
+	n, err := result.RowsAffected()
+	return n > 0, err
+}
+
+const healthColumns = `
+	h.web_page_id, wp.name, h.source, h.status, h.reason, h.crawl_run_id, h.degraded_at, h.checked_at`
+
+// List 返回所有已检查过的数据源，异常的排在前面，不包含响应样本
+func List() ([]models.SourceHealth, error) {
+	rows, err := database.DB.Query("SELECT " + healthColumns + `
+		FROM source_health h
+		LEFT JOIN web_pages wp ON h.web_page_id = wp.id
+		ORDER BY h.status = 'ok', h.web_page_id`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var list []models.SourceHealth
+	for rows.Next() {
+		var h models.SourceHealth
+		if err := scan(rows, &h); err != nil {
+			return nil, err
+		}
+		list = append(list, h)
+	}
+	return list, rows.Err()
+}
+
+// Get 按网页读取健康状态，包含响应样本
+func Get(webPageID int) (models.SourceHealth, error) {
+	var h models.SourceHealth
+	row := database.DB.QueryRow("SELECT "+healthColumns+`, h.sample
+		FROM source_health h
+		LEFT JOIN web_pages wp ON h.web_page_id = wp.id
+		WHERE h.web_page_id = ?`, webPageID)
+	err := scan(row, &h, &h.Sample)
+	return h, err
+}
+
+func scan(row interface{ Scan(...interface{}) error }, h *models.SourceHealth, extra ...interface{}) error {
+	var name, degradedAt sql.NullString
+	var runID sql.NullInt64
+	dest := append([]interface{}{&h.WebPageID, &name, &h.Source, &h.Status, &h.Reason, &runID, &degradedAt, &h.CheckedAt}, extra...)
+	if err := row.Scan(dest...); err != nil {
+		return err
+	}
+	h.WebPageName = name.String
+	h.CrawlRunID = int(runID.Int64)
+	h.DegradedAt = degradedAt.String
+	return nil
+}
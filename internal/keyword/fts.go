@@ -0,0 +1,226 @@
+package keyword
+
+import (
+	"html"
+	"strings"
+	"unicode/utf8"
+)
+
+// minTrigramLen trigram 分词只能检索不少于 3 个字符的词
+const minTrigramLen = 3
+
+// FTSQuery 将表达式转换为 FTS5 trigram 查询，columns 返回字段对应的列过滤器（如 "{title body}"）。
+// 表达式含少于 3 个字符的词，或排除条件没有可依附的正向条件时无法转换，返回 false
+func FTSQuery(x Expr, columns func(field string) string) (string, bool) {
+	switch x := x.(type) {
+	case *termExpr:
+		if utf8.RuneCountInString(x.text) < minTrigramLen {
+			return "", false
+		}
+		return columns(x.field) + ` : "` + strings.ReplaceAll(x.text, `"`, `""`) + `"`, true
+	case *andExpr:
+		var positive, negative []string
+		for _, child := range x.xs {
+			if n, ok := child.(*notExpr); ok {
+				q, ok := FTSQuery(n.x, columns)
+				if !ok {
+					return "", false
+				}
+				negative = append(negative, q)
+				continue
+			}
+			q, ok := FTSQuery(child, columns)
+			if !ok {
+				return "", false
+			}
+			positive = append(positive, q)
+		}
+		if len(positive) == 0 {
+			return "", false
+		}
+		q := "(" + strings.Join(positive, " AND ") + ")"
+		for _, n := range negative {
+			q = "(" + q + " NOT " + n + ")"
+		}
+		return q, true
+	case *orExpr:
+		parts := make([]string, 0, len(x.xs))
+		for _, child := range x.xs {
+			q, ok := FTSQuery(child, columns)
+			if !ok {
+				return "", false
+			}
+			parts = append(parts, q)
+		}
+		return "(" + strings.Join(parts, " OR ") + ")", true
+	}
+	return "", false
+}
+
+// SQLCondition 将表达式转换为等价的 LIKE 条件，columns 返回字段对应的列名
+func SQLCondition(x Expr, columns func(field string) []string) (string, []interface{}) {
+	switch x := x.(type) {
+	case *termExpr:
+		pattern := ContainsPattern(x.text)
+		cols := columns(x.field)
+		conds := make([]string, len(cols))
+		args := make([]interface{}, len(cols))
+		for i, col := range cols {
+			conds[i] = "COALESCE(" + col + ", '') LIKE ? ESCAPE '\\'"
+			args[i] = pattern
+		}
+		return "(" + strings.Join(conds, " OR ") + ")", args
+	case *notExpr:
+		cond, args := SQLCondition(x.x, columns)
+		return "NOT " + cond, args
+	case *andExpr:
+		return joinConditions(x.xs, " AND ", columns)
+	case *orExpr:
+		return joinConditions(x.xs, " OR ", columns)
+	}
+	return "1=1", nil
+}
+
+var likeEscaper = strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`)
+
+// ContainsPattern 返回匹配包含 s 的 LIKE 模式，s 中的 %、_ 按原样匹配，需配合 ESCAPE '\' 使用
+func ContainsPattern(s string) string {
+	return "%" + likeEscaper.Replace(s) + "%"
+}
+
+func joinConditions(xs []Expr, sep string, columns func(field string) []string) (string, []interface{}) {
+	conds := make([]string, len(xs))
+	var args []interface{}
+	for i, child := range xs {
+		var childArgs []interface{}
+		conds[i], childArgs = SQLCondition(child, columns)
+		args = append(args, childArgs...)
+	}
+	return "(" + strings.Join(conds, sep) + ")", args
+}
+
+// Terms 返回表达式中的正向检索词（不含排除条件中的词），用于高亮
+func Terms(x Expr) []string {
+	switch x := x.(type) {
+	case *termExpr:
+		return []string{x.text}
+	case *andExpr:
+		return collectTerms(x.xs)
+	case *orExpr:
+		return collectTerms(x.xs)
+	}
+	return nil
+}
+
+func collectTerms(xs []Expr) []string {
+	var terms []string
+	for _, child := range xs {
+		terms = append(terms, Terms(child)...)
+	}
+	return terms
+}
+
+// Highlight 用 open/close 标记 text 中出现的检索词，英文不区分大小写。
+// 结果用于 HTML：text 按 HTML 转义，open/close 原样插入
+func Highlight(text string, terms []string, open, close string) string {
+	lower := asciiLower(text)
+	marked := make([]bool, len(text))
+	for _, term := range terms {
+		needle := asciiLower(term)
+		if needle == "" {
+			continue
+		}
+		for from := 0; ; {
+			i := strings.Index(lower[from:], needle)
+			if i < 0 {
+				break
+			}
+			for j := from + i; j < from+i+len(needle); j++ {
+				marked[j] = true
+			}
+			from += i + len(needle)
+		}
+	}
+
+	// 按标记的边界分段转义，标记位置不受转义后长度变化的影响
+	var b strings.Builder
+	inMark := false
+	segment := 0
+	for i := 0; i <= len(text); i++ {
+		if i < len(text) && marked[i] == inMark {
+			continue
+		}
+		b.WriteString(html.EscapeString(text[segment:i]))
+		segment = i
+		if inMark {
+			b.WriteString(close)
+		} else if i < len(text) {
+			b.WriteString(open)
+		}
+		inMark = !inMark
+	}
+	return b.String()
+}
+
+// Snippet 截取 text 中第一个检索词附近约 radius 个字符并高亮，没有命中时返回开头部分。
+// 与 Highlight 相同，截取的文本按 HTML 转义
+func Snippet(text string, terms []string, radius int, open, close, ellipsis string) string {
+	runes := []rune(text)
+	lower := []rune(asciiLower(text))
+	hit := -1
+	for _, term := range terms {
+		needle := []rune(asciiLower(term))
+		if i := runeIndex(lower, needle); i >= 0 && (hit < 0 || i < hit) {
+			hit = i
+		}
+	}
+	if hit < 0 {
+		hit = 0
+	}
+
+	start, end := hit-radius, hit+radius
+	if start < 0 {
+		start = 0
+	}
+	if end > len(runes) {
+		end = len(runes)
+	}
+	snippet := Highlight(string(runes[start:end]), terms, open, close)
+	if start > 0 {
+		snippet = ellipsis + snippet
+	}
+	if end < len(runes) {
+		snippet += ellipsis
+	}
+	return snippet
+}
+
+// asciiLower 只转换 ASCII 字母，保证转换前后字节位置一致
+func asciiLower(s string) string {
+	b := []byte(s)
+	for i, c := range b {
+		if 'A' <= c && c <= 'Z' {
+			b[i] = c + 'a' - 'A'
+		}
+	}
+	return string(b)
+}
+
+func runeIndex(haystack, needle []rune) int {
+	if len(needle) == 0 {
+		return -1
+	}
+	for i := 0; i+len(needle) <= len(haystack); i++ {
+		match := true
+		for j := range needle {
+			if haystack[i+j] != needle[j] {
+				match = false
+				break
+			}
+		}
+		if match {
+			return i
+		}
+	}
+	return -1
+}
@@ -0,0 +1,427 @@
+// Package keyword 解析和执行关键词表达式。
+//
+// 语法：
+//
+//	生态环境局 监测          同时包含（空格或 AND）
+//	生态环境局 OR 环保局      任一包含（OR 或 |）
+//	-更正 / NOT 更正         不包含
+//	"环境 监测"              短语，按原样匹配
+//	(A OR B) C              括号分组
+//	title:生态环境局          只匹配标题，content: 只匹配摘要和正文，可作用于括号分组
+package keyword
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+// 可限定的字段
+const (
+	FieldAny     = ""
+	FieldTitle   = "title"
+	FieldContent = "content"
+)
+
+// Document 被匹配的公告文本
+type Document struct {
+	Title   string
+	Content string
+}
+
+// Expr 解析后的关键词表达式
+type Expr interface {
+	Match(doc Document) bool
+	String() string
+	// recall 返回任何匹配文档都必然包含其一的检索词，ok 为 false 表示无法用正向检索词约束
+	recall() (terms []string, ok bool)
+}
+
+// SyntaxError 表达式语法错误，Pos 为出错位置（从 1 开始的字符序号）
+type SyntaxError struct {
+	Expr string
+	Pos  int
+	Msg  string
+}
+
+func (e *SyntaxError) Error() string {
+	return fmt.Sprintf("关键词表达式 %q 第 %d 个字符: %s", e.Expr, e.Pos, e.Msg)
+}
+
+type termExpr struct {
+	field  string
+	text   string
+	phrase bool
+}
+
+func (t *termExpr) Match(doc Document) bool {
+	needle := strings.ToLower(t.text)
+	switch t.field {
+	case FieldTitle:
+		return strings.Contains(strings.ToLower(doc.Title), needle)
+	case FieldContent:
+		return strings.Contains(strings.ToLower(doc.Content), needle)
+	default:
+		return strings.Contains(strings.ToLower(doc.Title), needle) ||
+			strings.Contains(strings.ToLower(doc.Content), needle)
+	}
+}
+
+func (t *termExpr) String() string {
+	s := t.text
+	if t.phrase {
+		s = `"` + s + `"`
+	}
+	if t.field != FieldAny {
+		s = t.field + ":" + s
+	}
+	return s
+}
+
+func (t *termExpr) recall() ([]string, bool) {
+	return []string{t.text}, true
+}
+
+type notExpr struct{ x Expr }
+
+func (n *notExpr) Match(doc Document) bool { return !n.x.Match(doc) }
+func (n *notExpr) String() string          { return "-" + n.x.String() }
+func (n *notExpr) recall() ([]string, bool) {
+	return nil, false
+}
+
+type andExpr struct{ xs []Expr }
+
+func (a *andExpr) Match(doc Document) bool {
+	for _, x := range a.xs {
+		if !x.Match(doc) {
+			return false
+		}
+	}
+	return true
+}
+
+func (a *andExpr) String() string { return "(" + joinExprs(a.xs, " ") + ")" }
+
+// recall 匹配需满足所有子表达式，取检索词最少的一个即可
+func (a *andExpr) recall() ([]string, bool) {
+	var best []string
+	found := false
+	for _, x := range a.xs {
+		if terms, ok := x.recall(); ok && (!found || len(terms) < len(best)) {
+			best, found = terms, true
+		}
+	}
+	return best, found
+}
+
+type orExpr struct{ xs []Expr }
+
+func (o *orExpr) Match(doc Document) bool {
+	for _, x := range o.xs {
+		if x.Match(doc) {
+			return true
+		}
+	}
+	return false
+}
+
+func (o *orExpr) String() string { return "(" + joinExprs(o.xs, " OR ") + ")" }
+
+func (o *orExpr) recall() ([]string, bool) {
+	var all []string
+	for _, x := range o.xs {
+		terms, ok := x.recall()
+		if !ok {
+			return nil, false
+		}
+		all = append(all, terms...)
+	}
+	return all, true
+}
+
+func joinExprs(xs []Expr, sep string) string {
+	parts := make([]string, len(xs))
+	for i, x := range xs {
+		parts[i] = x.String()
+	}
+	return strings.Join(parts, sep)
+}
+
+// Parse 解析单个关键词表达式
+func Parse(s string) (Expr, error) {
+	p := &parser{src: s, input: []rune(s)}
+	p.skipSpace()
+	if p.eof() {
+		return nil, p.errorf(p.pos, "表达式为空")
+	}
+	x, err := p.parseOr(FieldAny)
+	if err != nil {
+		return nil, err
+	}
+	p.skipSpace()
+	if !p.eof() {
+		if p.peek() == ')' {
+			return nil, p.errorf(p.pos, "多余的右括号")
+		}
+		return nil, p.errorf(p.pos, "无法解析")
+	}
+	return x, nil
+}
+
+// ParseAll 解析关键词列表，返回第一个错误
+func ParseAll(keywords []string) ([]Expr, error) {
+	exprs := make([]Expr, 0, len(keywords))
+	for _, kw := range keywords {
+		x, err := Parse(kw)
+		if err != nil {
+			return nil, err
+		}
+		exprs = append(exprs, x)
+	}
+	return exprs, nil
+}
+
+// MatchAny 判断文档是否匹配任一表达式
+func MatchAny(exprs []Expr, doc Document) bool {
+	for _, x := range exprs {
+		if x.Match(doc) {
+			return true
+		}
+	}
+	return false
+}
+
+// UpstreamTerms 推导可交给上游全文检索的检索词：任何匹配 exprs 的公告都至少包含其中一个。
+// 无法推导时（如表达式只有排除条件）返回 nil，此时应不带检索词拉取并完全在本地过滤
+func UpstreamTerms(exprs []Expr) []string {
+	seen := map[string]bool{}
+	var terms []string
+	for _, x := range exprs {
+		ts, ok := x.recall()
+		if !ok {
+			return nil
+		}
+		for _, t := range ts {
+			if !seen[t] {
+				seen[t] = true
+				terms = append(terms, t)
+			}
+		}
+	}
+	return terms
+}
+
+type parser struct {
+	src   string
+	input []rune
+	pos   int
+}
+
+func (p *parser) eof() bool  { return p.pos >= len(p.input) }
+func (p *parser) peek() rune { return p.input[p.pos] }
+
+func (p *parser) errorf(pos int, format string, args ...interface{}) error {
+	return &SyntaxError{Expr: p.src, Pos: pos + 1, Msg: fmt.Sprintf(format, args...)}
+}
+
+func (p *parser) skipSpace() {
+	for !p.eof() && unicode.IsSpace(p.peek()) {
+		p.pos++
+	}
+}
+
+// keywordAt 判断当前位置是否为独立的运算符单词，如 OR、AND、NOT
+func (p *parser) keywordAt(word string) bool {
+	end := p.pos + len(word)
+	if end > len(p.input) || string(p.input[p.pos:end]) != word {
+		return false
+	}
+	return end == len(p.input) || unicode.IsSpace(p.input[end]) || p.input[end] == '(' || p.input[end] == '"'
+}
+
+func (p *parser) parseOr(field string) (Expr, error) {
+	first, err := p.parseAnd(field)
+	if err != nil {
+		return nil, err
+	}
+	xs := []Expr{first}
+	for {
+		p.skipSpace()
+		switch {
+		case !p.eof() && p.peek() == '|':
+			p.pos++
+		case p.keywordAt("OR"):
+			p.pos += 2
+		default:
+			if len(xs) == 1 {
+				return first, nil
+			}
+			return &orExpr{xs}, nil
+		}
+		p.skipSpace()
+		if p.eof() || p.peek() == ')' {
+			return nil, p.errorf(p.pos, "OR 后缺少条件")
+		}
+		x, err := p.parseAnd(field)
+		if err != nil {
+			return nil, err
+		}
+		xs = append(xs, x)
+	}
+}
+
+func (p *parser) parseAnd(field string) (Expr, error) {
+	first, err := p.parseUnary(field)
+	if err != nil {
+		return nil, err
+	}
+	xs := []Expr{first}
+	for {
+		p.skipSpace()
+		if p.eof() || p.peek() == ')' || p.peek() == '|' || p.keywordAt("OR") {
+			break
+		}
+		if !p.eof() && p.peek() == '&' {
+			p.pos++
+		} else if p.keywordAt("AND") {
+			p.pos += 3
+		}
+		p.skipSpace()
+		if p.eof() || p.peek() == ')' {
+			return nil, p.errorf(p.pos, "AND 后缺少条件")
+		}
+		x, err := p.parseUnary(field)
+		if err != nil {
+			return nil, err
+		}
+		xs = append(xs, x)
+	}
+	if len(xs) == 1 {
+		return first, nil
+	}
+	return &andExpr{xs}, nil
+}
+
+func (p *parser) parseUnary(field string) (Expr, error) {
+	p.skipSpace()
+	start := p.pos
+	switch {
+	case !p.eof() && p.peek() == '-':
+		p.pos++
+	case p.keywordAt("NOT"):
+		p.pos += 3
+	default:
+		return p.parsePrimary(field)
+	}
+	p.skipSpace()
+	if p.eof() || p.peek() == ')' {
+		return nil, p.errorf(start, "排除条件后缺少关键词")
+	}
+	x, err := p.parseUnary(field)
+	if err != nil {
+		return nil, err
+	}
+	return &notExpr{x}, nil
+}
+
+func (p *parser) parsePrimary(field string) (Expr, error) {
+	if p.eof() {
+		return nil, p.errorf(p.pos, "缺少关键词")
+	}
+
+	switch r := p.peek(); {
+	case r == '(':
+		open := p.pos
+		p.pos++
+		p.skipSpace()
+		if !p.eof() && p.peek() == ')' {
+			return nil, p.errorf(open, "括号内为空")
+		}
+		x, err := p.parseOr(field)
+		if err != nil {
+			return nil, err
+		}
+		p.skipSpace()
+		if p.eof() || p.peek() != ')' {
+			return nil, p.errorf(open, "缺少对应的右括号")
+		}
+		p.pos++
+		return x, nil
+	case r == ')':
+		return nil, p.errorf(p.pos, "多余的右括号")
+	case r == '"':
+		return p.parsePhrase(field)
+	}
+
+	start := p.pos
+	for !p.eof() {
+		r := p.peek()
+		if unicode.IsSpace(r) || r == '(' || r == ')' || r == '"' || r == '|' || r == '&' {
+			break
+		}
+		if r == ',' {
+			return nil, p.errorf(p.pos, "不能包含英文逗号，多个关键词请分别添加")
+		}
+		if r == ':' && field == FieldAny {
+			name := strings.ToLower(string(p.input[start:p.pos]))
+			switch name {
+			case FieldTitle, FieldContent:
+				p.pos++
+				if p.eof() || unicode.IsSpace(p.peek()) {
+					return nil, p.errorf(p.pos, "字段 %s: 后缺少关键词", name)
+				}
+				return p.parseScoped(name)
+			}
+			if isASCIIWord(name) {
+				return nil, p.errorf(start, "未知的字段 %s，可用字段为 title、content", name)
+			}
+		}
+		p.pos++
+	}
+	if p.pos == start {
+		return nil, p.errorf(p.pos, "缺少关键词")
+	}
+	return &termExpr{field: field, text: string(p.input[start:p.pos])}, nil
+}
+
+// parseScoped 解析字段限定后的关键词、短语或括号分组
+func (p *parser) parseScoped(field string) (Expr, error) {
+	if p.peek() == '-' {
+		return nil, p.errorf(p.pos, "排除条件应写在字段前，如 -%s:关键词", field)
+	}
+	return p.parsePrimary(field)
+}
+
+func (p *parser) parsePhrase(field string) (Expr, error) {
+	open := p.pos
+	p.pos++
+	start := p.pos
+	for !p.eof() && p.peek() != '"' {
+		if p.peek() == ',' {
+			return nil, p.errorf(p.pos, "不能包含英文逗号，多个关键词请分别添加")
+		}
+		p.pos++
+	}
+	if p.eof() {
+		return nil, p.errorf(open, "引号未闭合")
+	}
+	text := string(p.input[start:p.pos])
+	p.pos++
+	if strings.TrimSpace(text) == "" {
+		return nil, p.errorf(open, "短语为空")
+	}
+	return &termExpr{field: field, text: text, phrase: true}, nil
+}
+
+func isASCIIWord(s string) bool {
+	if s == "" {
+		return false
+	}
+	for _, r := range s {
+		if r > unicode.MaxASCII || !(unicode.IsLetter(r) || unicode.IsDigit(r) || r == '_') {
+			return false
+		}
+	}
+	return true
+}
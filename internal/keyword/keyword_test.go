@@ -0,0 +1,168 @@
+package keyword
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	for _, tc := range []struct{ in, want string }{
+		{"生态环境局", "生态环境局"},
+		{"生态环境局 监测", "(生态环境局 监测)"},
+		{"生态环境局 AND 监测", "(生态环境局 监测)"},
+		{"生态环境局 OR 环保局", "(生态环境局 OR 环保局)"},
+		{"生态环境局|环保局", "(生态环境局 OR 环保局)"},
+		{"-更正", "-更正"},
+		{"NOT 更正", "-更正"},
+		{"监测 OR -更正", "(监测 OR -更正)"},
+		{`"环境 监测"`, `"环境 监测"`},
+		{"(监测 OR 检测) 生态环境局", "((监测 OR 检测) 生态环境局)"},
+		{"title:生态环境局 -content:更正", "(title:生态环境局 -content:更正)"},
+		{`title:("环境 监测" OR 检测)`, `(title:"环境 监测" OR title:检测)`},
+		// 小写的 or、not 是普通关键词
+		{"监测 or 检测", "(监测 or 检测)"},
+	} {
+		x, err := Parse(tc.in)
+		if err != nil {
+			t.Errorf("Parse(%q): %v", tc.in, err)
+			continue
+		}
+		if got := x.String(); got != tc.want {
+			t.Errorf("Parse(%q) = %s，期望 %s", tc.in, got, tc.want)
+		}
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	for _, in := range []string{
+		"",
+		"   ",
+		"(监测",
+		"监测)",
+		"()",
+		`"监测`,
+		`""`,
+		"监测 OR",
+		"| 监测",
+		"监测 AND",
+		"-",
+		"NOT",
+		"监测,检测",
+		"foo:监测",
+		"title:",
+		"title:-更正",
+	} {
+		_, err := Parse(in)
+		var syntaxErr *SyntaxError
+		if !errors.As(err, &syntaxErr) {
+			t.Errorf("Parse(%q) 错误 %v，期望 *SyntaxError", in, err)
+		}
+	}
+}
+
+func TestMatchAny(t *testing.T) {
+	exprs, err := ParseAll([]string{"title:生态环境局 -更正", `"环境 监测" OR 检测`})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, tc := range []struct {
+		doc  Document
+		want bool
+	}{
+		{Document{Title: "深圳市生态环境局采购公告"}, true},
+		{Document{Title: "深圳市生态环境局更正公告"}, false},
+		{Document{Title: "采购公告", Content: "生态环境局"}, false},
+		{Document{Title: "环境 监测服务"}, true},
+		{Document{Title: "环境监测服务"}, false},
+		{Document{Title: "采购公告", Content: "水质检测"}, true},
+	} {
+		if got := MatchAny(exprs, tc.doc); got != tc.want {
+			t.Errorf("MatchAny(%+v) = %v，期望 %v", tc.doc, got, tc.want)
+		}
+	}
+
+	// 英文不区分大小写
+	exprs, _ = ParseAll([]string{"LED"})
+	if !MatchAny(exprs, Document{Title: "led 显示屏"}) {
+		t.Error("英文关键词应不区分大小写")
+	}
+}
+
+func TestUpstreamTerms(t *testing.T) {
+	for _, tc := range []struct {
+		keywords []string
+		want     string
+	}{
+		{[]string{"生态环境局 -更正"}, "[生态环境局]"},
+		{[]string{"监测 OR 检测", "监测"}, "[监测 检测]"},
+		{[]string{"(监测 OR 检测) 生态环境局"}, "[生态环境局]"},
+		{[]string{"title:生态环境局"}, "[生态环境局]"},
+		// 无法用正向检索词约束时返回 nil，由调用方拉取全部后在本地过滤
+		{[]string{"-更正"}, "[]"},
+		{[]string{"NOT 更正"}, "[]"},
+		{[]string{"监测 OR -更正"}, "[]"},
+		{[]string{"生态环境局", "-更正"}, "[]"},
+	} {
+		exprs, err := ParseAll(tc.keywords)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := fmt.Sprint(UpstreamTerms(exprs)); got != tc.want {
+			t.Errorf("UpstreamTerms(%q) = %s，期望 %s", tc.keywords, got, tc.want)
+		}
+	}
+}
+
+func TestFTSQuery(t *testing.T) {
+	columns := func(field string) string {
+		if field == FieldAny {
+			return "{title body}"
+		}
+		return "{" + field + "}"
+	}
+	for _, tc := range []struct {
+		in   string
+		want string
+		ok   bool
+	}{
+		{"生态环境局", `{title body} : "生态环境局"`, true},
+		{"生态环境局 -更正公告", `(({title body} : "生态环境局") NOT {title body} : "更正公告")`, true},
+		{"生态环境局 OR title:环保局", `({title body} : "生态环境局" OR {title} : "环保局")`, true},
+		{`"环境 监测"`, `{title body} : "环境 监测"`, true},
+		// 少于 3 个字符的词和只有排除条件的表达式无法使用 trigram 索引
+		{"监测", "", false},
+		{"生态环境局 OR 监测", "", false},
+		{"-更正公告", "", false},
+	} {
+		x, err := Parse(tc.in)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got, ok := FTSQuery(x, columns)
+		if got != tc.want || ok != tc.ok {
+			t.Errorf("FTSQuery(%q) = %q, %v，期望 %q, %v", tc.in, got, ok, tc.want, tc.ok)
+		}
+	}
+}
+
+func TestHighlightEscapesHTML(t *testing.T) {
+	terms := []string{"监测", "led"}
+	title := `<script>alert("监测")</script> LED & 监测`
+	want := `&lt;script&gt;alert(&#34;<mark>监测</mark>&#34;)&lt;/script&gt; <mark>LED</mark> &amp; <mark>监测</mark>`
+	if got := Highlight(title, terms, "<mark>", "</mark>"); got != want {
+		t.Errorf("Highlight = %s，期望 %s", got, want)
+	}
+
+	content := `前言<img src=x onerror=alert(1)>环境监测服务`
+	want = `前言&lt;img src=x onerror=alert(1)&gt;环境<mark>监测</mark>服务`
+	if got := Snippet(content, terms, 40, "<mark>", "</mark>", "…"); got != want {
+		t.Errorf("Snippet = %s，期望 %s", got, want)
+	}
+}
+
+func TestContainsPatternEscapesWildcards(t *testing.T) {
+	if got, want := ContainsPattern(`100%_采购\中心`), `%100\%\_采购\\中心%`; got != want {
+		t.Errorf("ContainsPattern = %s，期望 %s", got, want)
+	}
+}
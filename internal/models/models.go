@@ -1,9 +1,13 @@
 package models
 
+import "encoding/json"
+
 type WebPage struct {
-	ID   int    `json:"id" db:"id"`
-	URL  string `json:"url" db:"url"`
-	Name string `json:"name" db:"name"`
+	ID           int               `json:"id" db:"id"`
+	URL          string            `json:"url" db:"url"`
+	Name         string            `json:"name" db:"name"`
+	Source       string            `json:"source" db:"source"`
+	SourceParams map[string]string `json:"source_params" db:"source_params"`
 }
 
 type Keyword struct {
@@ -17,17 +21,106 @@ type MonitorConfig struct {
 	CrawlTime string `json:"crawl_time" db:"crawl_time"`
 	CrawlFreq string `json:"crawl_freq" db:"crawl_freq"`
 	Keywords  string `json:"keywords" db:"keywords"`
-	CreatedAt string `json:"created_at" db:"created_at"`
-	UpdatedAt string `json:"updated_at" db:"updated_at"`
+	// SourceParams 覆盖网页记录中的同名数据源参数
+	SourceParams map[string]string `json:"source_params" db:"source_params"`
+	CreatedAt    string            `json:"created_at" db:"created_at"`
+	UpdatedAt    string            `json:"updated_at" db:"updated_at"`
 }
 
 type SubscribeConfig struct {
-	ID        int    `json:"id" db:"id"`
-	Email     string `json:"email" db:"email"`
-	PushTime  string `json:"push_time" db:"push_time"`
+	ID         int      `json:"id" db:"id"`
+	Email      string   `json:"email" db:"email"`
+	PushTime   string   `json:"push_time" db:"push_time"`
+	Keywords   []string `json:"keywords" db:"keywords"`
+	WebPageIDs []int    `json:"web_page_ids" db:"web_page_ids"`
+	Types      []string `json:"types" db:"types"`
+	// Channels 推送渠道名称，email 为订阅邮箱，其余为推送渠道表中的名称；为空时只发邮件
+	Channels []string `json:"channels" db:"channels"`
+	// GroupBy 邮件摘要的分组方式：source 按来源网页，keyword 按匹配的关键词，type 按公告类型，为空时不分组
+	GroupBy   string `json:"group_by" db:"group_by"`
+	CreatedAt string `json:"created_at" db:"created_at"`
+}
+
+// NotifyChannel 推送渠道：钉钉、企业微信、飞书机器人或通用 webhook
+type NotifyChannel struct {
+	ID   int    `json:"id" db:"id"`
+	Name string `json:"name" db:"name"`
+	Type string `json:"type" db:"type"`
+	URL  string `json:"url" db:"url"`
+	// Secret 签名密钥，接口返回时不包含，HasSecret 表示是否已设置
+	Secret    string `json:"secret,omitempty" db:"secret"`
+	HasSecret bool   `json:"has_secret" db:"-"`
+	CreatedAt string `json:"created_at" db:"created_at"`
+}
+
+// Webhook 出站 webhook：公告入库、分类、匹配关键词或采集失败时推送到外部系统
+type Webhook struct {
+	ID   int    `json:"id" db:"id"`
+	Name string `json:"name" db:"name"`
+	URL  string `json:"url" db:"url"`
+	// Secret 签名密钥，只在创建时返回，HasSecret 表示是否已设置
+	Secret    string `json:"secret,omitempty" db:"secret"`
+	HasSecret bool   `json:"has_secret" db:"-"`
+	// Events 订阅的事件：announcement.created、announcement.classified、announcement.updated、keyword.matched、crawl.failed
+	Events []string `json:"events" db:"events"`
+	// Types announcement.classified 事件只推送这些类型的公告，为空时推送所有已分类的公告
+	Types []string `json:"types" db:"types"`
+	// Keywords keyword.matched 事件的关键词表达式
+	Keywords  []string `json:"keywords" db:"keywords"`
+	Enabled   bool     `json:"enabled" db:"enabled"`
+	CreatedAt string   `json:"created_at" db:"created_at"`
+}
+
+// WebhookDelivery webhook 的一次投递，待投递时为 pending，重试用尽后为 failed
+type WebhookDelivery struct {
+	ID             int    `json:"id" db:"id"`
+	WebhookID      int    `json:"webhook_id" db:"webhook_id"`
+	WebhookName    string `json:"webhook_name" db:"webhook_name"`
+	Event          string `json:"event" db:"event"`
+	Status         string `json:"status" db:"status"`
+	Attempts       int    `json:"attempts" db:"attempts"`
+	NextAttemptAt  string `json:"next_attempt_at,omitempty" db:"next_attempt_at"`
+	ResponseStatus int    `json:"response_status,omitempty" db:"response_status"`
+	ResponseBody   string `json:"response_body,omitempty" db:"response_body"`
+	Error          string `json:"error,omitempty" db:"error"`
+	// ReplayOf 重放时为原投递记录的 ID
+	ReplayOf    int    `json:"replay_of,omitempty" db:"replay_of"`
+	CreatedAt   string `json:"created_at" db:"created_at"`
+	DeliveredAt string `json:"delivered_at,omitempty" db:"delivered_at"`
+	// Payload 请求体，仅详情接口返回
+	Payload json.RawMessage `json:"payload,omitempty" db:"payload" swaggertype:"object"`
+}
+
+// EmailMessage 邮件发件箱中的一封邮件，待发送或等待重试时为 pending，已发送为 sent，重试用尽或被永久拒收时为 failed
+type EmailMessage struct {
+	ID            int    `json:"id" db:"id"`
+	Recipient     string `json:"recipient" db:"recipient"`
+	Kind          string `json:"kind" db:"kind"`
+	Subject       string `json:"subject" db:"subject"`
+	Status        string `json:"status" db:"status"`
+	Attempts      int    `json:"attempts" db:"attempts"`
+	NextAttemptAt string `json:"next_attempt_at,omitempty" db:"next_attempt_at"`
+	// SMTPCode 最近一次失败时服务器的应答码，连接错误等为 0
+	SMTPCode  int    `json:"smtp_code,omitempty" db:"smtp_code"`
+	Error     string `json:"error,omitempty" db:"error"`
 	CreatedAt string `json:"created_at" db:"created_at"`
+	SentAt    string `json:"sent_at,omitempty" db:"sent_at"`
+	// Text、HTML 邮件正文，仅详情接口返回
+	Text string `json:"text,omitempty" db:"text"`
+	HTML string `json:"html,omitempty" db:"html"`
 }
 
+// FlaggedRecipient 被服务器永久拒收的收件人
+type FlaggedRecipient struct {
+	Recipient string `json:"recipient" db:"recipient"`
+	SMTPCode  int    `json:"smtp_code" db:"smtp_code"`
+	Reason    string `json:"reason" db:"reason"`
+	// OutboxID 被拒收的邮件
+	OutboxID  int    `json:"outbox_id,omitempty" db:"outbox_id"`
+	CreatedAt string `json:"created_at" db:"created_at"`
+}
+
+// PushConfig 旧版单邮箱推送配置，启动时迁移到 subscribe_config
 type PushConfig struct {
 	ID       int    `json:"id" db:"id"`
 	Email    string `json:"email" db:"email"`
@@ -44,4 +137,134 @@ type Announcement struct {
 	WebPageID   int    `json:"web_page_id" db:"web_page_id"`
 	WebPageName string `json:"web_page_name" db:"web_page_name"`
 	Publisher   string `json:"publisher" db:"publisher"`
+	Body        string `json:"body,omitempty" db:"body"`
+	Type        string `json:"type" db:"type"`
+
+	ProjectNumber string  `json:"project_number" db:"project_number"`
+	Purchaser     string  `json:"purchaser" db:"purchaser"`
+	Agency        string  `json:"agency" db:"agency"`
+	BudgetAmount  float64 `json:"budget_amount" db:"budget_amount"`
+	BidDeadline   string  `json:"bid_deadline" db:"bid_deadline"`
+	OpeningTime   string  `json:"opening_time" db:"opening_time"`
+	Contact       string  `json:"contact" db:"contact"`
+
+	Attachments []Attachment `json:"attachments,omitempty"`
+
+	// 全文检索时返回，检索词以 <mark> 标记，其余文本按 HTML 转义
+	TitleHighlight string `json:"title_highlight,omitempty"`
+	Snippet        string `json:"snippet,omitempty"`
+}
+
+type Attachment struct {
+	ID             int    `json:"id" db:"id"`
+	AnnouncementID int    `json:"announcement_id" db:"announcement_id"`
+	Name           string `json:"name" db:"name"`
+	URL            string `json:"url" db:"url"`
+	Ext            string `json:"ext" db:"ext"`
+	Size           int64  `json:"size" db:"size"`
+	SHA256         string `json:"sha256" db:"sha256"`
+	LocalPath      string `json:"-" db:"local_path"`
+	Downloaded     bool   `json:"downloaded"`
+	Error          string `json:"error,omitempty" db:"error"`
+}
+
+type Delivery struct {
+	ID             int    `json:"id" db:"id"`
+	AnnouncementID int    `json:"announcement_id" db:"announcement_id"`
+	SubscriberID   int    `json:"subscriber_id" db:"subscriber_id"`
+	Recipient      string `json:"recipient" db:"recipient"`
+	Channel        string `json:"channel" db:"channel"`
+	SentAt         string `json:"sent_at" db:"sent_at"`
+	Title          string `json:"title" db:"title"`
+	URL            string `json:"url" db:"url"`
+	// 邮件渠道的推送邮件在发件箱中的 ID 和发送状态（pending、sent、failed），failed 的公告在下次推送时重发
+	OutboxID     int    `json:"outbox_id,omitempty" db:"outbox_id"`
+	OutboxStatus string `json:"outbox_status,omitempty" db:"outbox_status"`
+}
+
+// CrawlRun 一次采集的执行记录
+type CrawlRun struct {
+	ID              int      `json:"id" db:"id"`
+	MonitorConfigID int      `json:"monitor_config_id" db:"monitor_config_id"`
+	WebPageID       int      `json:"web_page_id" db:"web_page_id"`
+	WebPageName     string   `json:"web_page_name" db:"web_page_name"`
+	Trigger         string   `json:"trigger" db:"trigger"`
+	Source          string   `json:"source" db:"source"`
+	Keywords        []string `json:"keywords" db:"keywords"`
+	WindowStart     string   `json:"window_start" db:"window_start"`
+	WindowEnd       string   `json:"window_end" db:"window_end"`
+	Status          string   `json:"status" db:"status"`
+	StartedAt       string   `json:"started_at" db:"started_at"`
+	FinishedAt      string   `json:"finished_at,omitempty" db:"finished_at"`
+	DurationMS      int64    `json:"duration_ms" db:"duration_ms"`
+	PagesFetched    int      `json:"pages_fetched" db:"pages_fetched"`
+	RecordsFetched  int      `json:"records_fetched" db:"records_fetched"`
+	Matched         int      `json:"matched" db:"matched"`
+	Inserted        int      `json:"inserted" db:"inserted"`
+	Skipped         int      `json:"skipped" db:"skipped"`
+	Error           string   `json:"error,omitempty" db:"error"`
+	BackfillJobID   int      `json:"backfill_job_id,omitempty" db:"backfill_job_id"`
+	// Announcements 本次采集新增的公告，仅详情接口返回
+	Announcements []Announcement `json:"announcements,omitempty" db:"-"`
+}
+
+// DigestRun 一次摘要推送的执行记录
+type DigestRun struct {
+	ID           int    `json:"id" db:"id"`
+	SubscriberID int    `json:"subscriber_id" db:"subscriber_id"`
+	Recipient    string `json:"recipient" db:"recipient"`
+	Trigger      string `json:"trigger" db:"trigger"`
+	Status       string `json:"status" db:"status"`
+	StartedAt    string `json:"started_at" db:"started_at"`
+	FinishedAt   string `json:"finished_at,omitempty" db:"finished_at"`
+	DurationMS   int64  `json:"duration_ms" db:"duration_ms"`
+	Pending      int    `json:"pending" db:"pending"`
+	Sent         int    `json:"sent" db:"sent"`
+	Error        string `json:"error,omitempty" db:"error"`
+	// Channels 每个推送渠道的结果
+	Channels []ChannelResult `json:"channels" db:"channel_results"`
+}
+
+// ChannelResult 一次摘要推送在单个渠道上的结果
+type ChannelResult struct {
+	Channel string `json:"channel"`
+	Pending int    `json:"pending"`
+	Sent    int    `json:"sent"`
+	Error   string `json:"error,omitempty"`
+}
+
+// BackfillJob 历史回填任务，StartDate 到 EndDate（含）按天采集，Cursor 为下一个待采集的日期
+type BackfillJob struct {
+	ID              int      `json:"id" db:"id"`
+	MonitorConfigID int      `json:"monitor_config_id" db:"monitor_config_id"`
+	WebPageID       int      `json:"web_page_id" db:"web_page_id"`
+	WebPageName     string   `json:"web_page_name" db:"web_page_name"`
+	Keywords        []string `json:"keywords" db:"keywords"`
+	StartDate       string   `json:"start_date" db:"start_date"`
+	EndDate         string   `json:"end_date" db:"end_date"`
+	Cursor          string   `json:"cursor" db:"cursor"`
+	Status          string   `json:"status" db:"status"`
+	DaysTotal       int      `json:"days_total" db:"days_total"`
+	DaysDone        int      `json:"days_done" db:"days_done"`
+	Inserted        int      `json:"inserted" db:"inserted"`
+	Skipped         int      `json:"skipped" db:"skipped"`
+	Error           string   `json:"error,omitempty" db:"error"`
+	CreatedAt       string   `json:"created_at" db:"created_at"`
+	UpdatedAt       string   `json:"updated_at" db:"updated_at"`
+	FinishedAt      string   `json:"finished_at,omitempty" db:"finished_at"`
+}
+
+// SourceHealth 网页数据源的健康状态。响应结构异常或连续多次没有结果时 Status 为 degraded，
+// 恢复正常后保留最近一次异常的原因和样本
+type SourceHealth struct {
+	WebPageID   int    `json:"web_page_id" db:"web_page_id"`
+	WebPageName string `json:"web_page_name" db:"web_page_name"`
+	Source      string `json:"source" db:"source"`
+	Status      string `json:"status" db:"status"`
+	Reason      string `json:"reason,omitempty" db:"reason"`
+	// Sample 触发异常的原始响应开头部分，仅详情接口返回
+	Sample     string `json:"sample,omitempty" db:"sample"`
+	CrawlRunID int    `json:"crawl_run_id,omitempty" db:"crawl_run_id"`
+	DegradedAt string `json:"degraded_at,omitempty" db:"degraded_at"`
+	CheckedAt  string `json:"checked_at" db:"checked_at"`
 }
@@ -0,0 +1,59 @@
+package notify
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+// dingTalkLimit 钉钉 markdown 消息的长度上限（字节）
+const dingTalkLimit = 18000
+
+// dingTalk 钉钉自定义机器人，设置了加签密钥时在地址上附加 timestamp 和 sign
+type dingTalk struct {
+	url    string
+	secret string
+}
+
+func (d *dingTalk) Send(ctx context.Context, msg Message) error {
+	target := d.url
+	if d.secret != "" {
+		u, err := url.Parse(d.url)
+		if err != nil {
+			return err
+		}
+		ts := time.Now().UnixMilli()
+		q := u.Query()
+		q.Set("timestamp", strconv.FormatInt(ts, 10))
+		q.Set("sign", dingTalkSign(d.secret, ts))
+		u.RawQuery = q.Encode()
+		target = u.String()
+	}
+
+	payload := map[string]interface{}{
+		"msgtype": "markdown",
+		"markdown": map[string]string{
+			"title": msg.Subject,
+			"text":  markdown(msg, dingTalkLimit),
+		},
+	}
+	data, err := postJSON(ctx, target, payload)
+	if err != nil {
+		return err
+	}
+	return robotResult{}.err(data)
+}
+
+// Split 按每条最多 maxChatItems 条公告和 markdown 长度上限拆分摘要
+func (d *dingTalk) Split(msg Message) []Message { return split(msg, markdownFits(dingTalkLimit)) }
+
+// dingTalkSign 钉钉加签：以密钥对 "timestamp\nsecret" 做 HMAC-SHA256 后 base64 编码
+func dingTalkSign(secret string, ts int64) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(strconv.FormatInt(ts, 10) + "\n" + secret))
+	return base64.StdEncoding.EncodeToString(mac.Sum(nil))
+}
@@ -0,0 +1,47 @@
+package notify
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/ieasydevops/demo-scrapy/internal/email"
+)
+
+// Email 邮件渠道。摘要按邮件模板渲染后为每个收件人写入一封，告警以纯文本写入；
+// 写入发件箱即返回，由后台发送和重试
+type Email struct {
+	To []string
+}
+
+func (e *Email) Send(ctx context.Context, msg Message) error {
+	if len(e.To) == 0 {
+		return fmt.Errorf("没有收件人")
+	}
+	if len(msg.Announcements) == 0 {
+		return email.QueueAlert(e.To, msg.Subject, msg.Text)
+	}
+	_, err := e.Queue(ctx, msg)
+	return err
+}
+
+// Queue 为每个收件人渲染并写入一封摘要，返回发件箱中的邮件 ID
+func (e *Email) Queue(ctx context.Context, msg Message) ([]int, error) {
+	if len(e.To) == 0 {
+		return nil, fmt.Errorf("没有收件人")
+	}
+	ids := make([]int, 0, len(e.To))
+	for _, to := range e.To {
+		r, err := email.RenderDigest(email.DigestRequest{
+			To: to, Announcements: msg.Announcements, Keywords: msg.Keywords, GroupBy: msg.GroupBy,
+		})
+		if err != nil {
+			return nil, err
+		}
+		id, err := email.QueueDigest(to, r)
+		if err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	return ids, nil
+}
@@ -0,0 +1,83 @@
+package notify
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"time"
+)
+
+// feishu 飞书自定义机器人，发送富文本消息，设置了签名校验密钥时在请求体中附加 timestamp 和 sign
+type feishu struct {
+	url    string
+	secret string
+}
+
+type feishuElement struct {
+	Tag  string `json:"tag"`
+	Text string `json:"text"`
+	Href string `json:"href,omitempty"`
+}
+
+func (f *feishu) Send(ctx context.Context, msg Message) error {
+	var content [][]feishuElement
+	if msg.Text != "" {
+		content = append(content, []feishuElement{{Tag: "text", Text: truncate(msg.Text, maxChatText)}})
+	}
+	for i, ann := range msg.Announcements {
+		if i == maxChatItems {
+			content = append(content, []feishuElement{{Tag: "text", Text: fmt.Sprintf("另有 %d 条公告未列出", len(msg.Announcements)-i)}})
+			break
+		}
+		line := []feishuElement{{Tag: "a", Text: ann.Title, Href: ann.URL}, {Tag: "text", Text: " " + ann.PublishDate}}
+		if ann.WebPageName != "" {
+			line[1].Text += " " + ann.WebPageName
+		}
+		content = append(content, line)
+	}
+
+	payload := map[string]interface{}{
+		"msg_type": "post",
+		"content": map[string]interface{}{
+			"post": map[string]interface{}{
+				"zh_cn": map[string]interface{}{"title": msg.Subject, "content": content},
+			},
+		},
+	}
+	if f.secret != "" {
+		ts := time.Now().Unix()
+		payload["timestamp"] = strconv.FormatInt(ts, 10)
+		payload["sign"] = feishuSign(f.secret, ts)
+	}
+
+	data, err := postJSON(ctx, f.url, payload)
+	if err != nil {
+		return err
+	}
+	var result struct {
+		Code int    `json:"code"`
+		Msg  string `json:"msg"`
+	}
+	if err := json.Unmarshal(data, &result); err != nil {
+		return fmt.Errorf("解析响应失败: %v", err)
+	}
+	if result.Code != 0 {
+		return fmt.Errorf("机器人返回错误 %d: %s", result.Code, result.Msg)
+	}
+	return nil
+}
+
+// Split 按每条最多 maxChatItems 条公告拆分摘要
+func (f *feishu) Split(msg Message) []Message {
+	return split(msg, func(Message) bool { return true })
+}
+
+// feishuSign 飞书签名：以 "timestamp\nsecret" 为密钥对空内容做 HMAC-SHA256 后 base64 编码
+func feishuSign(secret string, ts int64) string {
+	mac := hmac.New(sha256.New, []byte(strconv.FormatInt(ts, 10)+"\n"+secret))
+	return base64.StdEncoding.EncodeToString(mac.Sum(nil))
+}
@@ -0,0 +1,102 @@
+package notify
+
+import (
+	"fmt"
+	"strings"
+	"unicode/utf8"
+)
+
+// 聊天机器人消息最多列出的公告条数，其余只给出数量
+const maxChatItems = 20
+
+// maxChatText 告警正文在聊天消息中保留的最大字符数，原始响应样本等长内容会被截断
+const maxChatText = 1500
+
+// markdown 渲染钉钉和企业微信机器人使用的 markdown 消息，limit 为字节数上限（0 表示不限制）
+func markdown(msg Message, limit int) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "### %s\n", escapeMarkdown(msg.Subject))
+	if msg.Text != "" {
+		fmt.Fprintf(&b, "\n%s\n", truncate(msg.Text, maxChatText))
+	}
+
+	var lines []string
+	for i, ann := range msg.Announcements {
+		if i == maxChatItems {
+			break
+		}
+		line := fmt.Sprintf("- [%s](%s) %s", escapeMarkdown(ann.Title), ann.URL, ann.PublishDate)
+		if ann.WebPageName != "" {
+			line += " " + escapeMarkdown(ann.WebPageName)
+		}
+		lines = append(lines, line)
+	}
+	if len(lines) > 0 {
+		b.WriteString("\n")
+	}
+
+	// 超出长度上限时减少列出的条数
+	for n := len(lines); n >= 0; n-- {
+		var list strings.Builder
+		for _, line := range lines[:n] {
+			list.WriteString(line + "\n")
+		}
+		if rest := len(msg.Announcements) - n; rest > 0 {
+			fmt.Fprintf(&list, "\n另有 %d 条公告未列出\n", rest)
+		}
+		if limit == 0 || b.Len()+list.Len() <= limit || n == 0 {
+			b.WriteString(list.String())
+			break
+		}
+	}
+	return b.String()
+}
+
+// partSuffix 拆分后附加在标题后的序号，估算长度时按最长的序号预留
+const partSuffix = "（%d/%d）"
+
+// split 将摘要拆成每条最多列出 maxChatItems 条公告的消息，fits 判断消息是否在渠道的长度上限之内，
+// 超出时减少该条消息列出的条数（至少一条）。拆成多条时在标题后附加序号
+func split(msg Message, fits func(Message) bool) []Message {
+	var parts []Message
+	rest := msg.Announcements
+	for len(rest) > 0 {
+		part := msg
+		part.Subject = msg.Subject + fmt.Sprintf(partSuffix, 999, 999)
+		n := min(len(rest), maxChatItems)
+		for ; n > 1; n-- {
+			part.Announcements = rest[:n]
+			if fits(part) {
+				break
+			}
+		}
+		part.Announcements = rest[:n]
+		parts = append(parts, part)
+		rest = rest[n:]
+	}
+	if len(parts) <= 1 {
+		return []Message{msg}
+	}
+	for i := range parts {
+		parts[i].Subject = msg.Subject + fmt.Sprintf(partSuffix, i+1, len(parts))
+	}
+	return parts
+}
+
+// markdownFits 返回判断 markdown 消息是否不超过 limit 字节的函数
+func markdownFits(limit int) func(Message) bool {
+	return func(msg Message) bool { return len(markdown(msg, 0)) <= limit }
+}
+
+// escapeMarkdown 替换标题中会破坏链接语法的方括号
+func escapeMarkdown(s string) string {
+	return strings.NewReplacer("[", "［", "]", "］", "\n", " ").Replace(s)
+}
+
+// truncate 按字符截断，超出时以省略号结尾
+func truncate(s string, n int) string {
+	if utf8.RuneCountInString(s) <= n {
+		return s
+	}
+	return string([]rune(s)[:n]) + "…"
+}
@@ -0,0 +1,170 @@
+// Package notify 通过邮件、钉钉、企业微信、飞书机器人和通用 webhook 发送摘要和告警。
+package notify
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/ieasydevops/demo-scrapy/internal/models"
+)
+
+// 渠道类型
+const (
+	TypeEmail    = "email"
+	TypeDingTalk = "dingtalk"
+	TypeWeCom    = "wecom"
+	TypeFeishu   = "feishu"
+	TypeWebhook  = "webhook"
+)
+
+// ChannelEmail 邮件渠道的名称，发送到订阅邮箱或告警邮箱，不需要在推送渠道表中配置
+const ChannelEmail = "email"
+
+// Types 返回可以在推送渠道表中配置的渠道类型
+func Types() []string {
+	return []string{TypeDingTalk, TypeWeCom, TypeFeishu, TypeWebhook}
+}
+
+// Message 一条推送消息：摘要包含公告列表，告警只有 Text
+type Message struct {
+	Subject       string
+	Text          string
+	Announcements []models.Announcement
+	// Keywords、GroupBy 为订阅的关键词和分组方式，邮件摘要按其分组并标注匹配的关键词
+	Keywords []string
+	GroupBy  string
+}
+
+// Notifier 推送渠道，每种渠道按自己的格式渲染消息并签名
+type Notifier interface {
+	Send(ctx context.Context, msg Message) error
+}
+
+// Queuer 先写入发件箱、由后台发送的渠道。Queue 写入摘要并返回每个收件人的发件箱记录 ID，
+// 推送记录据此判断是否最终送达
+type Queuer interface {
+	Queue(ctx context.Context, msg Message) ([]int, error)
+}
+
+// Splitter 单条消息能列出的公告有限的渠道（聊天机器人）。Split 将摘要拆成若干条消息，
+// 每条都完整列出其中的公告，调用方逐条发送并分别记录推送结果，不会有公告只计为已推送而未列出
+type Splitter interface {
+	Split(msg Message) []Message
+}
+
+// New 按推送渠道配置创建 Notifier
+func New(ch models.NotifyChannel) (Notifier, error) {
+	if err := Validate(ch); err != nil {
+		return nil, err
+	}
+	switch ch.Type {
+	case TypeDingTalk:
+		return &dingTalk{url: ch.URL, secret: ch.Secret}, nil
+	case TypeWeCom:
+		return &weCom{url: ch.URL}, nil
+	case TypeFeishu:
+		return &feishu{url: ch.URL, secret: ch.Secret}, nil
+	default:
+		return &webhook{url: ch.URL, secret: ch.Secret}, nil
+	}
+}
+
+// Validate 校验渠道名称、类型和地址
+func Validate(ch models.NotifyChannel) error {
+	if ch.Name == "" {
+		return fmt.Errorf("渠道名称不能为空")
+	}
+	if ch.Name == ChannelEmail {
+		return fmt.Errorf("渠道名称 %s 为邮件渠道保留", ChannelEmail)
+	}
+	switch ch.Type {
+	case TypeDingTalk, TypeFeishu, TypeWebhook:
+	case TypeWeCom:
+		if ch.Secret != "" {
+			return fmt.Errorf("企业微信机器人不支持签名，密钥已包含在 webhook 地址的 key 参数中")
+		}
+	default:
+		return fmt.Errorf("未知的渠道类型 %q，可选 dingtalk、wecom、feishu、webhook", ch.Type)
+	}
+	u, err := url.Parse(ch.URL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("渠道地址无效: %s", ch.URL)
+	}
+	return nil
+}
+
+// Resolve 按名称返回渠道：email 发送到 emails，其余从推送渠道表读取
+func Resolve(name string, emails []string) (Notifier, error) {
+	if name == ChannelEmail {
+		return &Email{To: emails}, nil
+	}
+	ch, err := GetByName(name)
+	if err != nil {
+		return nil, fmt.Errorf("读取推送渠道 %s 失败: %v", name, err)
+	}
+	return New(ch)
+}
+
+var httpClient = &http.Client{Timeout: 15 * time.Second}
+
+// postJSON 以 JSON 发送 payload，返回 2xx 响应的内容
+func postJSON(ctx context.Context, target string, payload interface{}) ([]byte, error) {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return nil, err
+	}
+	return post(ctx, target, body, nil)
+}
+
+func post(ctx context.Context, target string, body []byte, header http.Header) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, "POST", target, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	for k, v := range header {
+		req.Header[k] = v
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		// 去掉错误中的地址，机器人的 access_token 等凭证不应出现在日志和推送记录中
+		var urlErr *url.Error
+		if errors.As(err, &urlErr) {
+			return nil, urlErr.Err
+		}
+		return nil, err
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(io.LimitReader(resp.Body, 64<<10))
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return nil, fmt.Errorf("HTTP状态码错误: %d: %s", resp.StatusCode, truncate(string(data), 200))
+	}
+	return data, nil
+}
+
+// robotResult 钉钉和企业微信机器人的响应，errcode 不为 0 表示失败
+type robotResult struct {
+	ErrCode int    `json:"errcode"`
+	ErrMsg  string `json:"errmsg"`
+}
+
+func (r robotResult) err(data []byte) error {
+	if err := json.Unmarshal(data, &r); err != nil {
+		return fmt.Errorf("解析响应失败: %v", err)
+	}
+	if r.ErrCode != 0 {
+		return fmt.Errorf("机器人返回错误 %d: %s", r.ErrCode, r.ErrMsg)
+	}
+	return nil
+}
@@ -0,0 +1,142 @@
+package notify
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/ieasydevops/demo-scrapy/internal/models"
+)
+
+// robot 记录收到的请求并返回固定响应
+type robot struct {
+	*httptest.Server
+	reqs   []*http.Request
+	bodies [][]byte
+}
+
+func newRobot(t *testing.T, response string) *robot {
+	r := &robot{}
+	r.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		body, _ := io.ReadAll(req.Body)
+		r.reqs = append(r.reqs, req)
+		r.bodies = append(r.bodies, body)
+		io.WriteString(w, response)
+	}))
+	t.Cleanup(r.Close)
+	return r
+}
+
+var digest = Message{
+	Subject: "政府采购网公告通知 - 1条新公告",
+	Announcements: []models.Announcement{
+		{Title: "[重新招标]监测服务采购公告", URL: "http://example.com/1.html", PublishDate: "2025-06-01"},
+	},
+}
+
+func TestDingTalkSignsAndRendersMarkdown(t *testing.T) {
+	r := newRobot(t, `{"errcode":0,"errmsg":"ok"}`)
+	n, err := New(models.NotifyChannel{Name: "ops", Type: TypeDingTalk, URL: r.URL + "/robot/send?access_token=x", Secret: "SEC1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := n.Send(context.Background(), digest); err != nil {
+		t.Fatal(err)
+	}
+
+	q := r.reqs[0].URL.Query()
+	ts, _ := strconv.ParseInt(q.Get("timestamp"), 10, 64)
+	if q.Get("access_token") != "x" || q.Get("sign") != dingTalkSign("SEC1", ts) {
+		t.Errorf("请求地址 %s 缺少 access_token 或签名不符", r.reqs[0].URL)
+	}
+	var payload struct {
+		Markdown struct{ Text string } `json:"markdown"`
+	}
+	json.Unmarshal(r.bodies[0], &payload)
+	if !strings.Contains(payload.Markdown.Text, "- [［重新招标］监测服务采购公告](http://example.com/1.html)") {
+		t.Errorf("markdown 内容不符:\n%s", payload.Markdown.Text)
+	}
+}
+
+func TestRobotErrorCode(t *testing.T) {
+	r := newRobot(t, `{"errcode":93000,"errmsg":"invalid webhook url"}`)
+	n, _ := New(models.NotifyChannel{Name: "ops", Type: TypeWeCom, URL: r.URL})
+	if err := n.Send(context.Background(), digest); err == nil || !strings.Contains(err.Error(), "93000") {
+		t.Errorf("Send = %v，期望返回机器人的错误码", err)
+	}
+}
+
+func TestWebhookSignature(t *testing.T) {
+	r := newRobot(t, "")
+	n, _ := New(models.NotifyChannel{Name: "hook", Type: TypeWebhook, URL: r.URL, Secret: "s3cret"})
+	if err := n.Send(context.Background(), digest); err != nil {
+		t.Fatal(err)
+	}
+
+	req, body := r.reqs[0], r.bodies[0]
+	ts, _ := strconv.ParseInt(req.Header.Get(HeaderTimestamp), 10, 64)
+	if got := req.Header.Get(HeaderSignature); got == "" || got != Sign("s3cret", ts, body) {
+		t.Errorf("签名 %q 与请求体不符", got)
+	}
+	var payload WebhookPayload
+	if err := json.Unmarshal(body, &payload); err != nil || payload.Event != "digest" || len(payload.Announcements) != 1 {
+		t.Errorf("请求体 %s 不符", body)
+	}
+}
+
+func TestMarkdownLimit(t *testing.T) {
+	msg := Message{Subject: "摘要"}
+	for i := 0; i < 50; i++ {
+		msg.Announcements = append(msg.Announcements, models.Announcement{
+			Title: strings.Repeat("公告", 40), URL: "http://example.com/" + strconv.Itoa(i), PublishDate: "2025-06-01",
+		})
+	}
+	text := markdown(msg, weComLimit)
+	if len(text) > weComLimit || !strings.Contains(text, "条公告未列出") {
+		t.Errorf("markdown 长度 %d，期望不超过 %d 并提示未列出的条数", len(text), weComLimit)
+	}
+}
+
+func TestSplitListsEveryAnnouncement(t *testing.T) {
+	msg := Message{Subject: "摘要"}
+	for i := 0; i < 50; i++ {
+		msg.Announcements = append(msg.Announcements, models.Announcement{
+			Title: strings.Repeat("公告", 40), URL: "http://example.com/" + strconv.Itoa(i), PublishDate: "2025-06-01",
+		})
+	}
+
+	// 企业微信按长度上限拆分，每条都不截断
+	parts := (&weCom{}).Split(msg)
+	next := 0
+	for i, part := range parts {
+		text := markdown(part, weComLimit)
+		if len(text) > weComLimit || strings.Contains(text, "条公告未列出") {
+			t.Errorf("第 %d 条消息长度 %d，期望不超过 %d 并列出全部公告", i+1, len(text), weComLimit)
+		}
+		if want := "摘要（" + strconv.Itoa(i+1) + "/" + strconv.Itoa(len(parts)) + "）"; part.Subject != want {
+			t.Errorf("第 %d 条消息标题 %s，期望 %s", i+1, part.Subject, want)
+		}
+		for _, ann := range part.Announcements {
+			if ann.URL != msg.Announcements[next].URL {
+				t.Fatalf("第 %d 条消息的公告 %s，期望 %s", i+1, ann.URL, msg.Announcements[next].URL)
+			}
+			next++
+		}
+	}
+	if next != len(msg.Announcements) {
+		t.Errorf("拆分后共列出 %d 条公告，期望 %d 条", next, len(msg.Announcements))
+	}
+
+	// 飞书每条最多列出 maxChatItems 条，不超过时不拆分
+	if parts := (&feishu{}).Split(msg); len(parts) != 3 || len(parts[0].Announcements) != maxChatItems || len(parts[2].Announcements) != 10 {
+		t.Errorf("飞书拆成 %d 条消息，期望按 %d 条一组拆成 3 条", len(parts), maxChatItems)
+	}
+	if parts := (&feishu{}).Split(digest); len(parts) != 1 || parts[0].Subject != digest.Subject {
+		t.Errorf("不超过 %d 条的摘要应原样发送", maxChatItems)
+	}
+}
@@ -0,0 +1,109 @@
+package notify
+
+import (
+	"database/sql"
+	"strings"
+
+	"github.com/ieasydevops/demo-scrapy/internal/database"
+	"github.com/ieasydevops/demo-scrapy/internal/models"
+)
+
+const channelColumns = "id, name, type, url, secret, created_at"
+
+// List 返回所有推送渠道，不包含密钥
+func List() ([]models.NotifyChannel, error) {
+	rows, err := database.DB.Query("SELECT " + channelColumns + " FROM notify_channels ORDER BY id")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var list []models.NotifyChannel
+	for rows.Next() {
+		var ch models.NotifyChannel
+		if err := scan(rows, &ch); err != nil {
+			return nil, err
+		}
+		list = append(list, Masked(ch))
+	}
+	return list, rows.Err()
+}
+
+// Get 按 ID 读取推送渠道，包含密钥
+func Get(id int) (models.NotifyChannel, error) {
+	var ch models.NotifyChannel
+	err := scan(database.DB.QueryRow("SELECT "+channelColumns+" FROM notify_channels WHERE id = ?", id), &ch)
+	return ch, err
+}
+
+// GetByName 按名称读取推送渠道，包含密钥
+func GetByName(name string) (models.NotifyChannel, error) {
+	var ch models.NotifyChannel
+	err := scan(database.DB.QueryRow("SELECT "+channelColumns+" FROM notify_channels WHERE name = ?", name), &ch)
+	return ch, err
+}
+
+// Create 创建推送渠道，返回新 ID
+func Create(ch models.NotifyChannel) (int, error) {
+	result, err := database.DB.Exec("INSERT INTO notify_channels (name, type, url, secret) VALUES (?, ?, ?, ?)",
+		ch.Name, ch.Type, ch.URL, ch.Secret)
+	if err != nil {
+		return 0, err
+	}
+	id, err := result.LastInsertId()
+	return int(id), err
+}
+
+// Update 更新推送渠道
+func Update(ch models.NotifyChannel) error {
+	result, err := database.DB.Exec("UPDATE notify_channels SET name = ?, type = ?, url = ?, secret = ? WHERE id = ?",
+		ch.Name, ch.Type, ch.URL, ch.Secret, ch.ID)
+	if err != nil {
+		return err
+	}
+	if n, err := result.RowsAffected(); err == nil && n == 0 {
+		return sql.ErrNoRows
+	}
+	return err
+}
+
+// Delete 删除推送渠道
+func Delete(id int) error {
+	_, err := database.DB.Exec("DELETE FROM notify_channels WHERE id = ?", id)
+	return err
+}
+
+// Subscribers 返回使用了指定渠道的订阅邮箱
+func Subscribers(name string) ([]string, error) {
+	rows, err := database.DB.Query("SELECT email, channels FROM subscribe_config ORDER BY id")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var emails []string
+	for rows.Next() {
+		var email, channels string
+		if err := rows.Scan(&email, &channels); err != nil {
+			return nil, err
+		}
+		for _, c := range strings.Split(channels, ",") {
+			if strings.TrimSpace(c) == name {
+				emails = append(emails, email)
+				break
+			}
+		}
+	}
+	return emails, rows.Err()
+}
+
+// Masked 去掉密钥，只保留是否已设置
+func Masked(ch models.NotifyChannel) models.NotifyChannel {
+	ch.HasSecret = ch.Secret != ""
+	ch.Secret = ""
+	return ch
+}
+
+func scan(row interface{ Scan(...interface{}) error }, ch *models.NotifyChannel) error {
+	return row.Scan(&ch.ID, &ch.Name, &ch.Type, &ch.URL, &ch.Secret, &ch.CreatedAt)
+}
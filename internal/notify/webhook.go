@@ -0,0 +1,70 @@
+package notify
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/ieasydevops/demo-scrapy/internal/models"
+)
+
+// 通用 webhook 的签名请求头
+const (
+	HeaderTimestamp = "X-Timestamp"
+	HeaderSignature = "X-Signature"
+)
+
+// webhook 通用 JSON webhook，设置了密钥时附加 HMAC-SHA256 签名，见 Sign
+type webhook struct {
+	url    string
+	secret string
+}
+
+// WebhookPayload 通用 webhook 的请求体，event 为 digest（摘要）或 alert（告警）
+type WebhookPayload struct {
+	Event         string                `json:"event"`
+	Subject       string                `json:"subject"`
+	Text          string                `json:"text,omitempty"`
+	Announcements []models.Announcement `json:"announcements,omitempty"`
+	SentAt        string                `json:"sent_at"`
+}
+
+func (w *webhook) Send(ctx context.Context, msg Message) error {
+	payload := WebhookPayload{
+		Event:         "alert",
+		Subject:       msg.Subject,
+		Text:          msg.Text,
+		Announcements: msg.Announcements,
+		SentAt:        time.Now().Format(time.RFC3339),
+	}
+	if len(msg.Announcements) > 0 {
+		payload.Event = "digest"
+	}
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+
+	header := http.Header{}
+	if w.secret != "" {
+		ts := time.Now().Unix()
+		header.Set(HeaderTimestamp, strconv.FormatInt(ts, 10))
+		header.Set(HeaderSignature, Sign(w.secret, ts, body))
+	}
+	_, err = post(ctx, w.url, body, header)
+	return err
+}
+
+// Sign 返回 webhook 签名 "sha256=<hex>"，内容为 "timestamp.body" 的 HMAC-SHA256。
+// 接收方用同一密钥计算并比较，同时检查 X-Timestamp 与当前时间的差距以防重放
+func Sign(secret string, ts int64, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(strconv.FormatInt(ts, 10) + "."))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
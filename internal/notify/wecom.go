@@ -0,0 +1,28 @@
+package notify
+
+import "context"
+
+// weComLimit 企业微信 markdown 消息的长度上限（字节）
+const weComLimit = 4096
+
+// weCom 企业微信群机器人，地址中的 key 即为凭证，不支持签名
+type weCom struct {
+	url string
+}
+
+func (w *weCom) Send(ctx context.Context, msg Message) error {
+	payload := map[string]interface{}{
+		"msgtype": "markdown",
+		"markdown": map[string]string{
+			"content": markdown(msg, weComLimit),
+		},
+	}
+	data, err := postJSON(ctx, w.url, payload)
+	if err != nil {
+		return err
+	}
+	return robotResult{}.err(data)
+}
+
+// Split 按每条最多 maxChatItems 条公告和 markdown 长度上限拆分摘要
+func (w *weCom) Split(msg Message) []Message { return split(msg, markdownFits(weComLimit)) }
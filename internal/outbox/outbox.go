@@ -0,0 +1,79 @@
+// Package outbox 后台处理数据库中到期记录的轮询器，webhook 投递记录和邮件发件箱共用。
+// 记录先在数据库中写入，由 Poller 逐批处理，失败时按退避时间重试，服务重启不会丢失
+package outbox
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+)
+
+// PollInterval 没有唤醒时检查到期重试的间隔
+const PollInterval = 10 * time.Second
+
+// BatchSize 每次读取的到期记录条数
+const BatchSize = 50
+
+// Poller 在后台反复调用 process 处理到期的记录
+type Poller struct {
+	name string
+	// process 处理一批到期记录，返回处理的条数，不超过 BatchSize
+	process func(ctx context.Context) (int, error)
+	// wake 有新记录写入时唤醒 Run
+	wake chan struct{}
+}
+
+// New 创建轮询器，name 为 process 返回错误时的日志前缀
+func New(name string, process func(ctx context.Context) (int, error)) *Poller {
+	return &Poller{name: name, process: process, wake: make(chan struct{}, 1)}
+}
+
+// Wake 通知 Run 立即处理新写入的记录
+func (p *Poller) Wake() {
+	select {
+	case p.wake <- struct{}{}:
+	default:
+	}
+}
+
+// Run 处理到期的记录直到 ctx 取消。一批处理满 BatchSize 条时继续下一批，
+// 否则等待唤醒或 PollInterval；上次退出时未完成的记录在启动后继续处理
+func (p *Poller) Run(ctx context.Context) {
+	ticker := time.NewTicker(PollInterval)
+	defer ticker.Stop()
+	for {
+		for ctx.Err() == nil {
+			n, err := p.process(ctx)
+			if err != nil {
+				log.Printf("%s: %v", p.name, err)
+			}
+			if err != nil || n < BatchSize {
+				break
+			}
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		case <-p.wake:
+		}
+	}
+}
+
+// Backoff 第 attempts 次失败后的等待时间：base 每次翻倍，不超过 max
+func Backoff(base, max time.Duration, attempts int) time.Duration {
+	delay := base
+	for i := 1; i < attempts && delay < max; i++ {
+		delay *= 2
+	}
+	if delay > max {
+		delay = max
+	}
+	return delay
+}
+
+// After 转换为 SQLite datetime('now', ?) 的时间修饰符，用于计算下一次处理的时间
+func After(d time.Duration) string {
+	return fmt.Sprintf("+%d seconds", int(d.Seconds()))
+}
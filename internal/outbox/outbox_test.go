@@ -0,0 +1,63 @@
+package outbox
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestBackoff(t *testing.T) {
+	for attempts, want := range map[int]time.Duration{
+		1: time.Minute, 2: 2 * time.Minute, 3: 4 * time.Minute, 6: 32 * time.Minute, 7: time.Hour, 20: time.Hour,
+	} {
+		if got := Backoff(time.Minute, time.Hour, attempts); got != want {
+			t.Errorf("Backoff(%d) = %s，期望 %s", attempts, got, want)
+		}
+	}
+}
+
+func TestPollerDrainsFullBatchesAndWakes(t *testing.T) {
+	calls := make(chan int, 10)
+	remaining := 2*BatchSize + 1
+	p := New("测试", func(ctx context.Context) (int, error) {
+		n := remaining
+		if n > BatchSize {
+			n = BatchSize
+		}
+		remaining -= n
+		calls <- n
+		return n, nil
+	})
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		p.Run(ctx)
+		close(done)
+	}()
+
+	// 启动后连续处理满批，直到不足一批
+	for _, want := range []int{BatchSize, BatchSize, 1} {
+		if got := <-calls; got != want {
+			t.Fatalf("处理 %d 条，期望 %d 条", got, want)
+		}
+	}
+	select {
+	case n := <-calls:
+		t.Fatalf("不足一批后应等待，又处理了 %d 条", n)
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	remaining = 3
+	p.Wake()
+	select {
+	case n := <-calls:
+		if n != 3 {
+			t.Fatalf("唤醒后处理 %d 条，期望 3 条", n)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Wake 没有唤醒 Run")
+	}
+
+	cancel()
+	<-done
+}
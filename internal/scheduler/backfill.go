@@ -0,0 +1,292 @@
+package scheduler
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/ieasydevops/demo-scrapy/internal/backfill"
+	"github.com/ieasydevops/demo-scrapy/internal/config"
+	"github.com/ieasydevops/demo-scrapy/internal/crawler"
+	"github.com/ieasydevops/demo-scrapy/internal/crawlrun"
+	"github.com/ieasydevops/demo-scrapy/internal/models"
+	"github.com/robfig/cron/v3"
+)
+
+// BackfillRequest 历史回填的目标、关键词和日期范围，MonitorConfigID 和 WebPageID 二选一。
+// Keywords 为空时使用监控配置的关键词（按网页回填时使用关键词表），EndDate 为零值时回填到 LastBackfillDay
+type BackfillRequest struct {
+	MonitorConfigID int
+	WebPageID       int
+	Keywords        []string
+	StartDate       time.Time
+	EndDate         time.Time
+}
+
+// ErrBackfillDone 回填任务已完成，无法继续
+var ErrBackfillDone = errors.New("回填任务已完成")
+
+// ErrBackfillRange 回填日期范围无效或与定时采集的时间窗口重叠
+var ErrBackfillRange = errors.New("回填日期范围无效")
+
+// CreateBackfill 创建回填任务，不启动执行，返回任务 ID
+func CreateBackfill(req BackfillRequest) (int, error) {
+	job := models.BackfillJob{
+		MonitorConfigID: req.MonitorConfigID,
+		WebPageID:       req.WebPageID,
+		Keywords:        req.Keywords,
+		StartDate:       req.StartDate.Format(backfill.DateLayout),
+		EndDate:         req.EndDate.Format(backfill.DateLayout),
+	}
+	if req.MonitorConfigID > 0 {
+		mc, err := loadMonitorConfig(req.MonitorConfigID)
+		if err != nil {
+			return 0, err
+		}
+		job.WebPageID = mc.WebPageID
+	} else if _, err := crawler.GetWebPage(req.WebPageID); err != nil {
+		return 0, err
+	}
+
+	last, err := LastBackfillDay(job.WebPageID, time.Now())
+	if err != nil {
+		return 0, err
+	}
+	if req.EndDate.IsZero() {
+		req.EndDate = last
+		job.EndDate = last.Format(backfill.DateLayout)
+	}
+	if req.EndDate.After(last) {
+		return 0, fmt.Errorf("%w: 结束日期 %s 晚于 %s，之后的公告由定时采集入库并推送，回填入库的公告不会推送",
+			ErrBackfillRange, job.EndDate, last.Format(backfill.DateLayout))
+	}
+	if req.StartDate.After(req.EndDate) {
+		return 0, fmt.Errorf("%w: 开始日期 %s 晚于结束日期 %s（可以回填的最后一天为 %s）",
+			ErrBackfillRange, job.StartDate, job.EndDate, last.Format(backfill.DateLayout))
+	}
+
+	job.DaysTotal = int(req.EndDate.Sub(req.StartDate).Hours()/24+0.5) + 1
+	return backfill.Create(job)
+}
+
+// LastBackfillDay 返回网页可以回填的最后一天：网页上各监控配置下一次定时采集和此时重启服务的启动采集
+// 中最早的时间窗口开始的前一天。回填入库的公告不进入订阅摘要，与定时采集的窗口重叠时，
+// 先被回填入库的公告在定时采集时作为重复跳过，不会推送给订阅者
+func LastBackfillDay(webPageID int, now time.Time) (time.Time, error) {
+	configs, err := loadMonitorConfigs()
+	if err != nil {
+		return time.Time{}, err
+	}
+	windowStart := now.Add(-minCrawlWindow)
+	for _, mc := range configs {
+		if mc.WebPageID != webPageID {
+			continue
+		}
+		spec, err := CrawlSpec(mc.CrawlFreq, mc.CrawlTime)
+		if err != nil {
+			continue
+		}
+		schedule, err := cron.ParseStandard(spec)
+		if err != nil {
+			continue
+		}
+		if start := now.Add(-crawlWindow(mc, now)); start.Before(windowStart) {
+			windowStart = start
+		}
+		if next := schedule.Next(now); !next.IsZero() {
+			if start := next.Add(-crawlWindow(mc, next)); start.Before(windowStart) {
+				windowStart = start
+			}
+		}
+	}
+	y, m, d := windowStart.In(time.Local).Date()
+	return time.Date(y, m, d-1, 0, 0, 0, 0, time.Local), nil
+}
+
+// StartBackfill 在后台执行回填任务，失败或取消的任务从进度处继续。任务已在执行时返回 false
+func StartBackfill(id int) (bool, error) {
+	if !beginTask() {
+		return false, ErrNotRunning
+	}
+	key, claimed, err := claimBackfill(id)
+	if err != nil || !claimed {
+		tasks.Done()
+		return false, err
+	}
+	go func() {
+		defer tasks.Done()
+		defer releaseJob(key)
+		err := runBackfill(id, nil)
+		switch {
+		case errors.Is(err, ErrNotRunning):
+			log.Printf("回填任务 %d 已暂停，下次启动时继续", id)
+		case err != nil:
+			log.Printf("回填任务 %d 未完成: %v", id, err)
+		}
+	}()
+	return true, nil
+}
+
+// RunBackfill 同步执行回填任务直到完成、失败、被取消或调度器停止，progress 在每完成一天后调用，day 为完成的日期
+func RunBackfill(id int, progress func(job models.BackfillJob, day string)) error {
+	if !beginTask() {
+		return ErrNotRunning
+	}
+	defer tasks.Done()
+
+	key, claimed, err := claimBackfill(id)
+	if err != nil {
+		return err
+	}
+	if !claimed {
+		return fmt.Errorf("回填任务 %d 正在执行", id)
+	}
+	defer releaseJob(key)
+	return runBackfill(id, progress)
+}
+
+// CancelBackfill 取消回填任务，执行中的任务在当前一天采集完成后停止
+func CancelBackfill(id int) error {
+	job, err := backfill.Get(id)
+	if err != nil {
+		return err
+	}
+	if job.Status == backfill.StatusCompleted {
+		return ErrBackfillDone
+	}
+	return backfill.SetStatus(id, backfill.StatusCancelled, "")
+}
+
+// ResumeBackfills 继续上次进程退出时未完成的回填任务
+func ResumeBackfills() {
+	ids, err := backfill.Unfinished()
+	if err != nil {
+		log.Printf("读取未完成的回填任务失败: %v", err)
+		return
+	}
+	for _, id := range ids {
+		if _, err := StartBackfill(id); err != nil {
+			log.Printf("继续回填任务 %d 失败: %v", id, err)
+			continue
+		}
+		log.Printf("继续回填任务 %d", id)
+	}
+}
+
+// claimBackfill 登记执行中的回填任务，并将失败或取消的任务重新置为执行中
+func claimBackfill(id int) (string, bool, error) {
+	key := fmt.Sprintf("backfill:%d", id)
+	_, claimed, err := claimJob(key, func() (int, error) {
+		job, err := backfill.Get(id)
+		if err != nil {
+			return 0, err
+		}
+		if job.Status == backfill.StatusCompleted {
+			return 0, ErrBackfillDone
+		}
+		if job.Status != backfill.StatusRunning {
+			if err := backfill.SetStatus(id, backfill.StatusRunning, ""); err != nil {
+				return 0, err
+			}
+		}
+		return id, nil
+	})
+	return key, claimed, err
+}
+
+func runBackfill(id int, progress func(job models.BackfillJob, day string)) error {
+	job, err := backfill.Get(id)
+	if err != nil {
+		return err
+	}
+	fail := func(err error) error {
+		if setErr := backfill.SetStatus(id, backfill.StatusFailed, err.Error()); setErr != nil {
+			log.Printf("回填任务 %d 更新状态失败: %v", id, setErr)
+		}
+		return err
+	}
+
+	mc := models.MonitorConfig{WebPageID: job.WebPageID, CrawlFreq: FreqDaily}
+	if job.MonitorConfigID > 0 {
+		if mc, err = loadMonitorConfig(job.MonitorConfigID); err != nil {
+			return fail(fmt.Errorf("读取监控配置 %d 失败: %v", job.MonitorConfigID, err))
+		}
+	}
+	if len(job.Keywords) > 0 {
+		mc.Keywords = strings.Join(job.Keywords, ",")
+	}
+
+	end, err := time.ParseInLocation(backfill.DateLayout, job.EndDate, time.Local)
+	if err != nil {
+		return fail(fmt.Errorf("结束日期无效: %v", err))
+	}
+	interval := backfillInterval()
+	stop := stopSignal()
+
+	for {
+		day, err := time.ParseInLocation(backfill.DateLayout, job.Cursor, time.Local)
+		if err != nil {
+			return fail(fmt.Errorf("进度日期无效: %v", err))
+		}
+		if day.After(end) {
+			log.Printf("回填任务 %d 完成: 新增 %d 条, 跳过 %d 条", id, job.Inserted, job.Skipped)
+			return backfill.SetStatus(id, backfill.StatusCompleted, "")
+		}
+		// 调度器停止时保持 running 状态，下次启动从进度处继续
+		select {
+		case <-stop:
+			return ErrNotRunning
+		default:
+		}
+		if current, err := backfill.Get(id); err != nil {
+			return err
+		} else if current.Status != backfill.StatusRunning {
+			log.Printf("回填任务 %d 已%s，停止执行", id, current.Status)
+			return nil
+		}
+
+		next := day.AddDate(0, 0, 1)
+		cj := newCrawlJob("", mc, crawlrun.TriggerBackfill, day, next)
+		cj.backfillJobID = id
+		runID, err := cj.start()
+		if err != nil {
+			return fail(fmt.Errorf("记录采集开始失败: %v", err))
+		}
+		stats, err := cj.run(runID)
+		if err != nil {
+			if taskCtx.Err() != nil {
+				return taskCtx.Err()
+			}
+			return fail(fmt.Errorf("%s 采集失败: %v", job.Cursor, err))
+		}
+		if err := backfill.Advance(id, next.Format(backfill.DateLayout), stats.Inserted, stats.Skipped); err != nil {
+			return fail(fmt.Errorf("记录进度失败: %v", err))
+		}
+
+		if job, err = backfill.Get(id); err != nil {
+			return err
+		}
+		if progress != nil {
+			progress(job, day.Format(backfill.DateLayout))
+		}
+		if next.After(end) {
+			continue
+		}
+
+		select {
+		case <-stop:
+			return ErrNotRunning
+		case <-time.After(interval):
+		}
+	}
+}
+
+// backfillInterval 回填每采集完一天后的等待时间
+func backfillInterval() time.Duration {
+	if cfg := config.GlobalConfig; cfg != nil && cfg.Crawler.BackfillInterval > 0 {
+		return time.Duration(cfg.Crawler.BackfillInterval) * time.Second
+	}
+	return 5 * time.Second
+}
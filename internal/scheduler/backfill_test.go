@@ -0,0 +1,74 @@
+package scheduler
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/ieasydevops/demo-scrapy/internal/backfill"
+	"github.com/ieasydevops/demo-scrapy/internal/crawler/crawlertest"
+)
+
+func TestLastBackfillDayBeforeLiveWindow(t *testing.T) {
+	crawlertest.OpenDB(t)
+	pageID := crawlertest.InsertWebPage(t, "深圳政府采购网", "http://127.0.0.1", "szggzy", "")
+	otherID := crawlertest.InsertWebPage(t, "其他网页", "http://127.0.0.1", "szggzy", "")
+	exec(t, "INSERT INTO monitor_config (web_page_id, crawl_time, crawl_freq, keywords) VALUES (?, '9', 'daily', '生态环境局')", pageID)
+
+	at := func(day, hour, minute int) time.Time { return time.Date(2025, 6, day, hour, minute, 0, 0, time.Local) }
+	check := func(webPageID int, now time.Time, want string) {
+		t.Helper()
+		last, err := LastBackfillDay(webPageID, now)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := last.Format(backfill.DateLayout); got != want {
+			t.Errorf("LastBackfillDay(%d, %s) = %s，期望 %s", webPageID, now.Format(time.DateTime), got, want)
+		}
+	}
+	// 下一次定时采集回溯到今天 8 点，此时重启服务的启动采集回溯到昨天 8 点
+	check(pageID, at(4, 10, 0), "2025-06-02")
+	// 今天 9 点的采集还未执行，它会回溯到昨天 8 点；启动采集回溯到前天 8 点
+	check(pageID, at(4, 8, 30), "2025-06-01")
+	// 没有监控配置的网页按启动时的采集回溯一天
+	check(otherID, at(4, 10, 0), "2025-06-02")
+
+	// 同一网页上每周三执行的配置：今天 9 点的采集回溯到上周三，启动采集回溯到上上周三
+	exec(t, "INSERT INTO monitor_config (web_page_id, crawl_time, crawl_freq, keywords) VALUES (?, '3 9:00', 'weekly', '监测')", pageID)
+	check(pageID, at(4, 8, 30), "2025-05-20")
+	check(pageID, at(4, 10, 0), "2025-05-27")
+}
+
+func TestCreateBackfillRejectsLiveWindow(t *testing.T) {
+	crawlertest.OpenDB(t)
+	pageID := crawlertest.InsertWebPage(t, "深圳政府采购网", "http://127.0.0.1", "szggzy", "")
+	// 每年执行一次的配置，下一次采集回溯到上一次执行的时间，昨天在它的时间窗口内
+	configID := exec(t, "INSERT INTO monitor_config (web_page_id, crawl_time, crawl_freq, keywords) VALUES (?, '0 9 1 1 *', 'custom', '生态环境局')", pageID)
+
+	today := time.Now()
+	y, m, d := today.Date()
+	yesterday := time.Date(y, m, d-1, 0, 0, 0, 0, time.Local)
+	start := time.Date(y-2, m, d, 0, 0, 0, 0, time.Local)
+
+	_, err := CreateBackfill(BackfillRequest{MonitorConfigID: configID, StartDate: start, EndDate: yesterday})
+	if !errors.Is(err, ErrBackfillRange) {
+		t.Fatalf("结束日期在定时采集的时间窗口内，err = %v，期望 ErrBackfillRange", err)
+	}
+
+	id, err := CreateBackfill(BackfillRequest{MonitorConfigID: configID, StartDate: start})
+	if err != nil {
+		t.Fatal(err)
+	}
+	job, err := backfill.Get(id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	last, _ := LastBackfillDay(pageID, time.Now())
+	if want := last.Format(backfill.DateLayout); job.EndDate != want || !last.Before(yesterday) {
+		t.Errorf("未指定结束日期时回填到 %s，期望 %s", job.EndDate, want)
+	}
+
+	if _, _, err := backfill.ParseRange(yesterday.Format(backfill.DateLayout), today.Format(backfill.DateLayout)); err == nil {
+		t.Error("回填范围包含今天时应返回错误")
+	}
+}
@@ -0,0 +1,281 @@
+package scheduler
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ieasydevops/demo-scrapy/internal/database"
+	"github.com/ieasydevops/demo-scrapy/internal/delivery"
+	"github.com/ieasydevops/demo-scrapy/internal/digestrun"
+	"github.com/ieasydevops/demo-scrapy/internal/keyword"
+	"github.com/ieasydevops/demo-scrapy/internal/models"
+	"github.com/ieasydevops/demo-scrapy/internal/notify"
+	"github.com/robfig/cron/v3"
+)
+
+// PushSpec 将订阅的推送时间（"H" 或 "H:MM"）转换为每日执行的 cron 表达式
+func PushSpec(pushTime string) (string, error) {
+	hour, minute, err := parseClock(strings.TrimSpace(pushTime))
+	if err != nil {
+		return "", err
+	}
+	spec := fmt.Sprintf("%d %d * * *", minute, hour)
+	if _, err := cron.ParseStandard(spec); err != nil {
+		return "", err
+	}
+	return spec, nil
+}
+
+// JoinWebPageIDs 将网页ID列表序列化为逗号分隔的字符串
+func JoinWebPageIDs(ids []int) string {
+	parts := make([]string, 0, len(ids))
+	for _, id := range ids {
+		parts = append(parts, strconv.Itoa(id))
+	}
+	return strings.Join(parts, ",")
+}
+
+// SplitWebPageIDs 解析逗号分隔的网页ID列表，忽略无效项
+func SplitWebPageIDs(s string) []int {
+	var ids []int
+	for _, part := range SplitKeywords(s) {
+		if id, err := strconv.Atoi(part); err == nil {
+			ids = append(ids, id)
+		}
+	}
+	return ids
+}
+
+// LoadSubscriber 按 ID 读取订阅配置
+func LoadSubscriber(id int) (models.SubscribeConfig, error) {
+	row := database.DB.QueryRow(`
+		SELECT id, email, push_time, keywords, web_page_ids, types, channels, group_by, created_at
+		FROM subscribe_config WHERE id = ?
+	`, id)
+	return scanSubscriber(row)
+}
+
+func loadSubscribers() ([]models.SubscribeConfig, error) {
+	rows, err := database.DB.Query(`
+		SELECT id, email, push_time, keywords, web_page_ids, types, channels, group_by, created_at
+		FROM subscribe_config ORDER BY id
+	`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var subs []models.SubscribeConfig
+	for rows.Next() {
+		sub, err := scanSubscriber(rows)
+		if err != nil {
+			return nil, err
+		}
+		subs = append(subs, sub)
+	}
+	return subs, rows.Err()
+}
+
+func scanSubscriber(row interface{ Scan(...interface{}) error }) (models.SubscribeConfig, error) {
+	var sub models.SubscribeConfig
+	var keywords, webPageIDs, types, channels string
+	if err := row.Scan(&sub.ID, &sub.Email, &sub.PushTime, &keywords, &webPageIDs, &types, &channels, &sub.GroupBy, &sub.CreatedAt); err != nil {
+		return sub, err
+	}
+	sub.Keywords = SplitKeywords(keywords)
+	sub.WebPageIDs = SplitWebPageIDs(webPageIDs)
+	sub.Types = SplitKeywords(types)
+	sub.Channels = SplitKeywords(channels)
+	return sub, nil
+}
+
+// PendingForSubscriber 返回尚未通过 channel 推送给订阅者、且符合订阅的网页、公告类型和关键词的公告，
+// 未设置的条件不做限制
+func PendingForSubscriber(sub models.SubscribeConfig, channel string) ([]models.Announcement, error) {
+	exprs, err := keyword.ParseAll(sub.Keywords)
+	if err != nil {
+		return nil, fmt.Errorf("订阅的关键词无效: %v", err)
+	}
+	return delivery.Pending(sub.ID, sub.Email, channel, delivery.Filter{
+		WebPageIDs: sub.WebPageIDs, Types: sub.Types, Keywords: exprs,
+	})
+}
+
+// TriggerDigest 在后台立即为订阅者生成并发送一次摘要，返回推送记录 ID。
+// 该订阅者已有推送在执行时不再重复执行，返回执行中的记录 ID，coalesced 为 true
+func TriggerDigest(subscriberID int) (runID int, coalesced bool, err error) {
+	sub, err := LoadSubscriber(subscriberID)
+	if err != nil {
+		return 0, false, err
+	}
+
+	if !beginTask() {
+		return 0, false, ErrNotRunning
+	}
+	started := time.Now()
+	key := digestJobKey(sub.ID)
+	runID, claimed, err := claimJob(key, func() (int, error) {
+		return digestrun.Start(sub.ID, sub.Email, digestrun.TriggerManual)
+	})
+	if err != nil || !claimed {
+		tasks.Done()
+		return runID, err == nil, err
+	}
+	go func() {
+		defer tasks.Done()
+		defer releaseJob(key)
+		runDigest(sub, runID, started)
+	}()
+	return runID, false, nil
+}
+
+// ExecuteDigestTask 为单个订阅者生成并发送摘要，该订阅者已有推送在执行时跳过
+func ExecuteDigestTask(subscriberID int, trigger string) {
+	if !beginTask() {
+		return
+	}
+	defer tasks.Done()
+
+	sub, err := LoadSubscriber(subscriberID)
+	if err != nil {
+		log.Printf("读取订阅配置 %d 失败: %v", subscriberID, err)
+		return
+	}
+
+	started := time.Now()
+	key := digestJobKey(sub.ID)
+	runID, claimed, err := claimJob(key, func() (int, error) {
+		return digestrun.Start(sub.ID, sub.Email, trigger)
+	})
+	if err != nil {
+		log.Printf("订阅 %s 记录推送开始失败: %v", sub.Email, err)
+		return
+	}
+	if !claimed {
+		log.Printf("订阅 %s 已有推送在执行（推送记录 %d），跳过本次触发", sub.Email, runID)
+		return
+	}
+	defer releaseJob(key)
+	runDigest(sub, runID, started)
+}
+
+func digestJobKey(subscriberID int) string { return fmt.Sprintf("digest:%d", subscriberID) }
+
+// notifierFor 按名称创建推送渠道，测试中替换为记录调用的假实现
+var notifierFor = notify.Resolve
+
+// runDigest 依次通过订阅的每个渠道发送摘要并更新推送记录，各渠道独立记录已推送的公告，
+// 一个渠道失败不影响其他渠道，下次推送时只重发失败渠道未送达的公告
+func runDigest(sub models.SubscribeConfig, runID int, started time.Time) {
+	var results []models.ChannelResult
+	var errs []error
+	var pending, sent int
+	for _, channel := range subscriberChannels(sub) {
+		result, err := digestChannel(sub, channel)
+		if err != nil {
+			log.Printf("订阅 %s 通过渠道 %s 推送失败: %v", sub.Email, channel, err)
+			errs = append(errs, fmt.Errorf("%s: %v", channel, err))
+		}
+		results = append(results, result)
+		pending += result.Pending
+		sent += result.Sent
+	}
+
+	if err := digestrun.Finish(runID, started, pending, sent, results, errors.Join(errs...)); err != nil {
+		log.Printf("订阅 %s 记录推送结果失败: %v", sub.Email, err)
+	}
+}
+
+// digestChannel 通过单个渠道发送摘要
+func digestChannel(sub models.SubscribeConfig, channel string) (models.ChannelResult, error) {
+	result := models.ChannelResult{Channel: channel}
+	fail := func(err error) (models.ChannelResult, error) {
+		result.Error = err.Error()
+		return result, err
+	}
+
+	announcements, err := PendingForSubscriber(sub, channel)
+	if err != nil {
+		return fail(fmt.Errorf("获取待推送公告失败: %v", err))
+	}
+	result.Pending = len(announcements)
+	if len(announcements) == 0 {
+		log.Printf("订阅 %s 在渠道 %s 没有需要推送的公告", sub.Email, channel)
+		return result, nil
+	}
+
+	n, err := notifierFor(channel, []string{sub.Email})
+	if err != nil {
+		return fail(err)
+	}
+	msg := notify.Message{
+		Subject:       fmt.Sprintf("政府采购网公告通知 - %d条新公告", len(announcements)),
+		Announcements: announcements,
+		Keywords:      sub.Keywords,
+		GroupBy:       sub.GroupBy,
+	}
+	// 聊天机器人一条消息列出的公告有限，拆成多条逐条发送，每条成功后只记录其中的公告，
+	// 中途失败时之后的公告留待下次推送
+	parts := []notify.Message{msg}
+	if s, ok := n.(notify.Splitter); ok {
+		parts = s.Split(msg)
+	}
+	for _, part := range parts {
+		// 写入发件箱的渠道关联发件箱记录，邮件最终发送失败时这些公告在下次推送时重发
+		var outboxID int
+		if q, ok := n.(notify.Queuer); ok {
+			ids, err := q.Queue(taskCtx, part)
+			if err != nil {
+				return fail(fmt.Errorf("写入发件箱失败: %v", err))
+			}
+			outboxID = ids[0]
+		} else if err := n.Send(taskCtx, part); err != nil {
+			return fail(fmt.Errorf("发送失败: %v", err))
+		}
+		result.Sent += len(part.Announcements)
+		if err := delivery.Record(sub.ID, sub.Email, channel, part.Announcements, outboxID); err != nil {
+			return fail(fmt.Errorf("已发送，记录推送结果失败: %v", err))
+		}
+	}
+	log.Printf("成功通过渠道 %s 发送 %d 条公告到订阅 %s", channel, result.Sent, sub.Email)
+	return result, nil
+}
+
+// subscriberChannels 返回订阅的推送渠道，未设置时只发邮件
+func subscriberChannels(sub models.SubscribeConfig) []string {
+	if len(sub.Channels) == 0 {
+		return []string{notify.ChannelEmail}
+	}
+	return sub.Channels
+}
+
+// addDigestJobs 为每个订阅者注册独立的定时推送任务
+func addDigestJobs() {
+	subs, err := loadSubscribers()
+	if err != nil {
+		log.Printf("读取订阅配置失败: %v", err)
+		return
+	}
+
+	for _, sub := range subs {
+		spec, err := PushSpec(sub.PushTime)
+		if err != nil {
+			log.Printf("订阅 %s 推送时间无效，已跳过: %v", sub.Email, err)
+			continue
+		}
+
+		id := sub.ID
+		if _, err := c.AddFunc(spec, func() {
+			log.Printf("执行订阅 %d 的定时邮件推送任务...", id)
+			ExecuteDigestTask(id, digestrun.TriggerSchedule)
+		}); err != nil {
+			log.Printf("添加订阅 %s 的定时推送任务失败: %v", sub.Email, err)
+			continue
+		}
+		log.Printf("已添加订阅 %s 的定时推送任务: %s", sub.Email, spec)
+	}
+}
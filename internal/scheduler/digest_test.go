@@ -0,0 +1,203 @@
+package scheduler
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/ieasydevops/demo-scrapy/internal/crawler/crawlertest"
+	"github.com/ieasydevops/demo-scrapy/internal/delivery"
+	"github.com/ieasydevops/demo-scrapy/internal/digestrun"
+	"github.com/ieasydevops/demo-scrapy/internal/email"
+	"github.com/ieasydevops/demo-scrapy/internal/notify"
+)
+
+func TestDigestChannelsAreIndependent(t *testing.T) {
+	crawlertest.OpenDB(t)
+	sent := map[string]int{}
+	down := true
+	useNotifier(t, func(channel string, to []string, msg notify.Message) error {
+		if channel == "ops" && down {
+			return errors.New("机器人不可用")
+		}
+		sent[channel] += len(msg.Announcements)
+		return nil
+	})
+
+	exec(t, "INSERT INTO announcements (title, url, publish_date) VALUES ('监测服务采购公告', 'http://example.com/1', '2025-06-01')")
+	exec(t, "INSERT INTO announcements (title, url, publish_date) VALUES ('车辆租赁招标公告', 'http://example.com/2', '2025-06-01')")
+	subID := exec(t, "INSERT INTO subscribe_config (email, push_time, channels) VALUES ('ops@example.com', '8', 'email,ops')")
+	startScheduler(t)
+
+	// ops 渠道失败不影响邮件渠道，推送记录为失败并分别记录各渠道结果
+	ExecuteDigestTask(subID, digestrun.TriggerManual)
+	runs, _, err := digestrun.List(subID, 1, 10)
+	if err != nil || len(runs) != 1 {
+		t.Fatalf("推送记录 %d 条（%v），期望 1 条", len(runs), err)
+	}
+	run := runs[0]
+	if run.Status != digestrun.StatusFailed || run.Sent != 2 || len(run.Channels) != 2 {
+		t.Fatalf("推送记录 %+v，期望失败、发送 2 条并包含 2 个渠道的结果", run)
+	}
+	if run.Channels[0].Sent != 2 || run.Channels[0].Error != "" || run.Channels[1].Sent != 0 || run.Channels[1].Error == "" {
+		t.Errorf("渠道结果 %+v，期望邮件成功、ops 失败", run.Channels)
+	}
+
+	// 恢复后只向失败的渠道补发
+	down = false
+	ExecuteDigestTask(subID, digestrun.TriggerManual)
+	if sent[notify.ChannelEmail] != 2 || sent["ops"] != 2 {
+		t.Errorf("各渠道发送条数 %v，期望邮件和 ops 各 2 条", sent)
+	}
+	runs, _, _ = digestrun.List(subID, 1, 10)
+	if runs[0].Status != digestrun.StatusSuccess || runs[0].Sent != 2 {
+		t.Errorf("第二次推送记录 %+v，期望成功并只向 ops 发送 2 条", runs[0])
+	}
+}
+
+func TestFailedDigestEmailIsResent(t *testing.T) {
+	crawlertest.OpenDB(t)
+	// 使用真实的邮件渠道写入发件箱，不启动后台发送
+	original := notifierFor
+	notifierFor = notify.Resolve
+	t.Cleanup(func() { notifierFor = original })
+
+	exec(t, "INSERT INTO announcements (title, url, publish_date) VALUES ('监测服务采购公告', 'http://example.com/1', '2025-06-01')")
+	subID := exec(t, "INSERT INTO subscribe_config (email, push_time) VALUES ('ops@example.com', '8')")
+	startScheduler(t)
+
+	outboxStatus := func() (int, string) {
+		t.Helper()
+		history, _, err := delivery.History(subID, notify.ChannelEmail, 1, 10)
+		if err != nil || len(history) != 1 {
+			t.Fatalf("推送记录 %+v（%v），期望 1 条", history, err)
+		}
+		return history[0].OutboxID, history[0].OutboxStatus
+	}
+	digest := func(want int) {
+		t.Helper()
+		ExecuteDigestTask(subID, digestrun.TriggerManual)
+		runs, _, err := digestrun.List(subID, 1, 1)
+		if err != nil || runs[0].Sent != want {
+			t.Fatalf("推送记录 %+v（%v），期望发送 %d 条", runs, err, want)
+		}
+	}
+
+	// 写入发件箱后邮件还在等待发送，不重复推送
+	digest(1)
+	firstID, status := outboxStatus()
+	if status != email.StatusPending {
+		t.Fatalf("发件箱状态 %q", status)
+	}
+	digest(0)
+
+	// 邮件最终发送失败（如收件人被拒收）后，公告在下次推送时重新写入发件箱
+	exec(t, "UPDATE email_outbox SET status = ? WHERE id = ?", email.StatusFailed, firstID)
+	digest(1)
+	secondID, status := outboxStatus()
+	if secondID == firstID || status != email.StatusPending {
+		t.Fatalf("重新推送的邮件 %d 状态 %q，期望新的待发送邮件", secondID, status)
+	}
+
+	exec(t, "UPDATE email_outbox SET status = ? WHERE id = ?", email.StatusSent, secondID)
+	digest(0)
+}
+
+func TestChatDigestListsEveryRecordedAnnouncement(t *testing.T) {
+	crawlertest.OpenDB(t)
+	original := notifierFor
+	notifierFor = notify.Resolve
+	t.Cleanup(func() { notifierFor = original })
+
+	// 飞书机器人收到第二条消息时返回错误
+	var listed []int
+	requests := 0
+	robot := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var payload struct {
+			Content struct {
+				Post struct {
+					ZhCN struct{ Content [][]json.RawMessage } `json:"zh_cn"`
+				} `json:"post"`
+			} `json:"content"`
+		}
+		json.NewDecoder(r.Body).Decode(&payload)
+		if requests++; requests == 2 {
+			http.Error(w, "rate limited", http.StatusTooManyRequests)
+			return
+		}
+		listed = append(listed, len(payload.Content.Post.ZhCN.Content))
+		io.WriteString(w, `{"code":0}`)
+	}))
+	defer robot.Close()
+	exec(t, "INSERT INTO notify_channels (name, type, url) VALUES ('ops', 'feishu', ?)", robot.URL)
+
+	for i := 0; i < 25; i++ {
+		exec(t, "INSERT INTO announcements (title, url, publish_date) VALUES (?, ?, '2025-06-01')",
+			fmt.Sprintf("监测服务采购公告 %d", i), fmt.Sprintf("http://example.com/%d", i))
+	}
+	subID := exec(t, "INSERT INTO subscribe_config (email, push_time, channels) VALUES ('ops@example.com', '8', 'ops')")
+	startScheduler(t)
+
+	// 第一条消息列出 20 条，第二条失败，只有列出的 20 条记为已推送
+	ExecuteDigestTask(subID, digestrun.TriggerManual)
+	runs, _, err := digestrun.List(subID, 1, 1)
+	if err != nil || runs[0].Status != digestrun.StatusFailed || runs[0].Sent != 20 {
+		t.Fatalf("推送记录 %+v（%v），期望失败并发送 20 条", runs, err)
+	}
+
+	// 下次推送补发其余 5 条
+	ExecuteDigestTask(subID, digestrun.TriggerManual)
+	runs, _, _ = digestrun.List(subID, 1, 1)
+	if runs[0].Status != digestrun.StatusSuccess || runs[0].Sent != 5 {
+		t.Fatalf("第二次推送记录 %+v，期望成功并发送 5 条", runs[0])
+	}
+	if len(listed) != 2 || listed[0] != 20 || listed[1] != 5 {
+		t.Errorf("机器人消息列出的条数 %v，期望 [20 5]", listed)
+	}
+}
+
+func TestFilteredAnnouncementsAreNotPending(t *testing.T) {
+	crawlertest.OpenDB(t)
+	box := useMailbox(t)
+
+	pageID := crawlertest.InsertWebPage(t, "深圳政府采购网", "http://127.0.0.1", "szggzy", "")
+	otherID := crawlertest.InsertWebPage(t, "其他网页", "http://127.0.0.1", "szggzy", "")
+	insert := func(title, url, typ string, webPageID int) {
+		exec(t, "INSERT INTO announcements (title, url, publish_date, type, web_page_id) VALUES (?, ?, '2025-06-01', ?, ?)",
+			title, url, typ, webPageID)
+	}
+	insert("环境监测服务招标公告", "http://example.com/1", "tender", pageID)
+	insert("环境监测服务更正公告", "http://example.com/2", "tender", pageID)
+	insert("车辆租赁招标公告", "http://example.com/3", "tender", pageID)
+	insert("环境监测服务中标公告", "http://example.com/4", "award", pageID)
+	insert("环境监测服务招标公告", "http://example.com/5", "tender", otherID)
+	subID := exec(t, "INSERT INTO subscribe_config (email, push_time, keywords, web_page_ids, types) VALUES ('ops@example.com', '8', '监测 -更正', ?, 'tender')",
+		JoinWebPageIDs([]int{pageID}))
+	startScheduler(t)
+
+	// 不符合订阅条件的公告不计入待推送，也不会在之后的每次推送中反复出现
+	for i, want := range []int{1, 0} {
+		ExecuteDigestTask(subID, digestrun.TriggerManual)
+		runs, _, err := digestrun.List(subID, 1, 1)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if runs[0].Pending != want || runs[0].Sent != want {
+			t.Fatalf("第 %d 次推送待推送 %d 条、发送 %d 条，期望 %d 条", i+1, runs[0].Pending, runs[0].Sent, want)
+		}
+	}
+	if len(box.digests) != 1 || box.digests[0][0].URL != "http://example.com/1" {
+		t.Fatalf("摘要 %+v，期望只包含符合条件的公告", box.digests)
+	}
+
+	// 修改订阅条件后，符合新条件的公告仍会推送
+	exec(t, "UPDATE subscribe_config SET types = 'tender,award' WHERE id = ?", subID)
+	ExecuteDigestTask(subID, digestrun.TriggerManual)
+	if len(box.digests) != 2 || len(box.digests[1]) != 1 || box.digests[1][0].URL != "http://example.com/4" {
+		t.Fatalf("修改订阅后的摘要 %+v", box.digests)
+	}
+}
@@ -0,0 +1,144 @@
+package scheduler
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/ieasydevops/demo-scrapy/internal/config"
+	"github.com/ieasydevops/demo-scrapy/internal/crawler"
+	"github.com/ieasydevops/demo-scrapy/internal/crawlrun"
+	"github.com/ieasydevops/demo-scrapy/internal/database"
+	"github.com/ieasydevops/demo-scrapy/internal/health"
+	"github.com/ieasydevops/demo-scrapy/internal/notify"
+)
+
+// checkHealth 根据本次采集结果更新网页数据源的健康状态，状态变化时告警。
+// 响应结构异常时立即标记为异常；成功但没有拉取到任何记录时，同一监控配置连续 crawler.empty_runs 次
+// 且该配置此前有过记录才标记（回填不计入）；拉取到记录且同一网页的其他监控配置都不处于连续无结果时恢复正常。
+// 网络错误等其他失败不改变状态
+func (j *crawlJob) checkHealth(runID int, source string, stats crawlrun.Stats, crawlErr error) {
+	page := j.page
+	var drift *crawler.DriftError
+	switch {
+	case errors.As(crawlErr, &drift):
+		j.degrade(runID, source, drift.Reason, drift.Sample)
+	case crawlErr != nil:
+	case stats.RecordsFetched > 0:
+		// 其他配置仍连续没有结果时保持异常，否则状态会随各配置的采集反复切换并重复告警
+		other, err := j.emptyStreakConfig()
+		if err != nil {
+			log.Printf("%s 查询采集记录失败: %v", j.label(), err)
+			return
+		}
+		if other != 0 {
+			log.Printf("网页 %s 的监控配置 %d 仍连续没有拉取到记录，数据源保持原状态", page.Name, other)
+			return
+		}
+		recovered, err := health.Recover(page.ID, source, runID)
+		if err != nil {
+			log.Printf("网页 %s 更新数据源状态失败: %v", page.Name, err)
+			return
+		}
+		if recovered {
+			log.Printf("网页 %s 的数据源 %s 已恢复正常", page.Name, source)
+			alert(fmt.Sprintf("[数据源恢复] %s (%s)", page.Name, source),
+				fmt.Sprintf("网页 %s（ID %d）的数据源 %s 在采集记录 %d 中恢复正常，拉取到 %d 条记录。",
+					page.Name, page.ID, source, runID, stats.RecordsFetched))
+		}
+	case j.backfillJobID == 0:
+		// 本次采集尚未结束，只需此前的 n-1 次也没有结果
+		n := emptyRuns()
+		empty, err := crawlrun.EmptyStreak(j.mc.ID, page.ID, n-1)
+		if err != nil {
+			log.Printf("%s 查询采集记录失败: %v", j.label(), err)
+			return
+		}
+		if empty {
+			j.degrade(runID, source, fmt.Sprintf("%s连续 %d 次采集没有拉取到任何记录，此前的采集有记录", j.label(), n), "")
+		}
+	}
+}
+
+// emptyStreakConfig 返回同一网页上连续 crawler.empty_runs 次没有拉取到记录的其他监控配置，没有时返回 0
+func (j *crawlJob) emptyStreakConfig() (int, error) {
+	rows, err := database.DB.Query("SELECT id FROM monitor_config WHERE web_page_id = ? AND id != ? ORDER BY id", j.page.ID, j.mc.ID)
+	if err != nil {
+		return 0, err
+	}
+	var ids []int
+	for rows.Next() {
+		var id int
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			return 0, err
+		}
+		ids = append(ids, id)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, err
+	}
+
+	n := emptyRuns()
+	for _, id := range ids {
+		empty, err := crawlrun.EmptyStreak(id, j.page.ID, n)
+		if err != nil || empty {
+			return id, err
+		}
+	}
+	return 0, nil
+}
+
+func (j *crawlJob) degrade(runID int, source, reason, sample string) {
+	page := j.page
+	log.Printf("网页 %s 的数据源 %s 异常: %s", page.Name, source, reason)
+	changed, err := health.Degrade(page.ID, source, reason, sample, runID)
+	if err != nil {
+		log.Printf("网页 %s 更新数据源状态失败: %v", page.Name, err)
+		return
+	}
+	if !changed {
+		return
+	}
+
+	var body strings.Builder
+	fmt.Fprintf(&body, "网页: %s（ID %d）\n数据源: %s\n原因: %s\n采集记录: %d\n时间: %s\n",
+		page.Name, page.ID, source, reason, runID, time.Now().Format("2006-01-02 15:04:05"))
+	if sample != "" {
+		fmt.Fprintf(&body, "\n原始响应:\n%s\n", sample)
+	}
+	body.WriteString("\n恢复正常前不再重复告警，可通过 GET /api/sources/health 查看状态。\n")
+	alert(fmt.Sprintf("[数据源异常] %s (%s)", page.Name, source), body.String())
+}
+
+// alert 向 alert.emails 和 alert.channels 发送告警，未配置接收人时只记录日志
+func alert(subject, body string) {
+	cfg := config.GlobalConfig
+	if cfg == nil {
+		return
+	}
+	channels := cfg.Alert.Channels
+	if len(cfg.Alert.Emails) > 0 {
+		channels = append([]string{notify.ChannelEmail}, channels...)
+	}
+	for _, channel := range channels {
+		n, err := notifierFor(channel, cfg.Alert.Emails)
+		if err == nil {
+			err = n.Send(taskCtx, notify.Message{Subject: subject, Text: body})
+		}
+		if err != nil {
+			log.Printf("通过渠道 %s 发送告警失败: %s: %v", channel, subject, err)
+		}
+	}
+}
+
+// emptyRuns 标记数据源异常前允许的连续无结果采集次数
+func emptyRuns() int {
+	if cfg := config.GlobalConfig; cfg != nil && cfg.Crawler.EmptyRuns > 0 {
+		return cfg.Crawler.EmptyRuns
+	}
+	return 3
+}
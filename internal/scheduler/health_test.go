@@ -0,0 +1,225 @@
+package scheduler
+
+import (
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ieasydevops/demo-scrapy/internal/config"
+	"github.com/ieasydevops/demo-scrapy/internal/crawler/crawlertest"
+	"github.com/ieasydevops/demo-scrapy/internal/crawlrun"
+	"github.com/ieasydevops/demo-scrapy/internal/fetch"
+	"github.com/ieasydevops/demo-scrapy/internal/health"
+	"github.com/ieasydevops/demo-scrapy/internal/models"
+	"github.com/ieasydevops/demo-scrapy/internal/notify"
+)
+
+// useAlerts 配置告警接收人并替换 notifierFor，返回记录的邮件告警标题
+func useAlerts(t *testing.T) *[]string {
+	var subjects []string
+	originalConfig := config.GlobalConfig
+	config.GlobalConfig = &config.Config{
+		Crawler: config.CrawlerConfig{EmptyRuns: 2},
+		Alert:   config.AlertConfig{Emails: []string{"ops@example.com"}},
+	}
+	t.Cleanup(func() { config.GlobalConfig = originalConfig })
+	useNotifier(t, func(channel string, to []string, msg notify.Message) error {
+		if channel == notify.ChannelEmail {
+			subjects = append(subjects, msg.Subject)
+		}
+		return nil
+	})
+	return &subjects
+}
+
+func TestSourceHealthDegradesAndRecovers(t *testing.T) {
+	crawlertest.OpenDB(t)
+	crawlertest.ConfigureFetch(t, fetch.FixtureOptions{})
+	alerts := useAlerts(t)
+
+	now := time.Now()
+	srv := crawlertest.NewSzggzyServer(
+		crawlertest.Record{Title: "深圳市生态环境局监测服务采购公告", Linkurl: "/gsgg/1.html", Webdate: now.Add(-time.Hour)},
+	)
+	defer srv.Close()
+	pageID := crawlertest.InsertWebPage(t, "深圳政府采购网", srv.URL, "szggzy", `{"base_url":"`+srv.URL+`"}`)
+	configID := exec(t, "INSERT INTO monitor_config (web_page_id, crawl_time, crawl_freq, keywords) VALUES (?, '9', 'daily', '生态环境局')", pageID)
+	startScheduler(t)
+
+	crawl := func(req CrawlRequest) {
+		t.Helper()
+		req.MonitorConfigID = configID
+		runID, _, err := TriggerCrawl(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		waitRun(t, runID)
+	}
+	state := func() string {
+		t.Helper()
+		h, err := health.Get(pageID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return h.Status
+	}
+
+	crawl(CrawlRequest{})
+	if state() != health.StatusOK || len(*alerts) != 0 {
+		t.Fatalf("正常采集后状态 %s、告警 %v", state(), *alerts)
+	}
+
+	// 门户返回 200 的维护页面：标记异常并保存样本，持续异常时不重复告警
+	srv.Respond("text/html", "<html>系统维护中</html>")
+	crawl(CrawlRequest{})
+	crawl(CrawlRequest{})
+	h, _ := health.Get(pageID)
+	if h.Status != health.StatusDegraded || h.Sample != "<html>系统维护中</html>" || !strings.Contains(h.Reason, "HTML") {
+		t.Fatalf("数据源状态 %+v，期望 degraded 并保存样本", h)
+	}
+	if len(*alerts) != 1 || !strings.Contains((*alerts)[0], "数据源异常") {
+		t.Fatalf("告警 %v，期望只告警一次", *alerts)
+	}
+	runs, _, _ := crawlrun.List(crawlrun.Filter{MonitorConfigID: configID}, 1, 1)
+	if runs[0].Status != crawlrun.StatusFailed {
+		t.Errorf("结构异常的采集状态 %s，期望 failed", runs[0].Status)
+	}
+
+	srv.Respond("", "")
+	crawl(CrawlRequest{})
+	if state() != health.StatusOK || len(*alerts) != 2 || !strings.Contains((*alerts)[1], "恢复") {
+		t.Fatalf("恢复后状态 %s、告警 %v", state(), *alerts)
+	}
+
+	// 此前有结果的网页连续 EmptyRuns 次没有结果
+	empty := CrawlRequest{StartTime: now.Add(-72 * time.Hour), EndTime: now.Add(-48 * time.Hour)}
+	crawl(empty)
+	if state() != health.StatusOK {
+		t.Fatal("只有一次没有结果时不应标记异常")
+	}
+	crawl(empty)
+	if state() != health.StatusDegraded || len(*alerts) != 3 {
+		t.Errorf("连续两次没有结果后状态 %s、告警 %v", state(), *alerts)
+	}
+}
+
+func TestEmptyStreakIsPerMonitorConfig(t *testing.T) {
+	crawlertest.OpenDB(t)
+	crawlertest.ConfigureFetch(t, fetch.FixtureOptions{})
+	alerts := useAlerts(t)
+
+	now := time.Now()
+	srv := crawlertest.NewSzggzyServer(
+		crawlertest.Record{Title: "深圳市生态环境局监测服务采购公告", Linkurl: "/gsgg/1.html", Webdate: now.Add(-time.Hour)},
+		crawlertest.Record{Title: "深圳市水务局管网检测采购公告", Linkurl: "/gsgg/2.html", Webdate: now.Add(-time.Hour)},
+	)
+	defer srv.Close()
+	pageID := crawlertest.InsertWebPage(t, "深圳政府采购网", srv.URL, "szggzy", `{"base_url":"`+srv.URL+`"}`)
+	ecoID := exec(t, "INSERT INTO monitor_config (web_page_id, crawl_time, crawl_freq, keywords) VALUES (?, '9', 'daily', '生态环境局')", pageID)
+	waterID := exec(t, "INSERT INTO monitor_config (web_page_id, crawl_time, crawl_freq, keywords) VALUES (?, '9', 'daily', '水务局')", pageID)
+	// 关键词从未有过结果的配置
+	rareID := exec(t, "INSERT INTO monitor_config (web_page_id, crawl_time, crawl_freq, keywords) VALUES (?, '9', 'hourly', '交通运输局')", pageID)
+	startScheduler(t)
+
+	crawl := func(configID int, req CrawlRequest) {
+		t.Helper()
+		req.MonitorConfigID = configID
+		runID, _, err := TriggerCrawl(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		waitRun(t, runID)
+	}
+	state := func() models.SourceHealth {
+		t.Helper()
+		h, err := health.Get(pageID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return h
+	}
+
+	crawl(ecoID, CrawlRequest{})
+	crawl(waterID, CrawlRequest{})
+
+	// 从未有过结果的配置连续没有结果，不因同一网页上其他配置有过结果而标记异常
+	crawl(rareID, CrawlRequest{})
+	crawl(rareID, CrawlRequest{})
+	crawl(rareID, CrawlRequest{})
+	if h := state(); h.Status != health.StatusOK || len(*alerts) != 0 {
+		t.Fatalf("关键词没有结果的配置使网页状态为 %s、告警 %v", h.Status, *alerts)
+	}
+
+	// 此前有结果的配置连续没有结果，期间同一网页上其他配置的成功采集不打断它的计数
+	empty := CrawlRequest{StartTime: now.Add(-72 * time.Hour), EndTime: now.Add(-48 * time.Hour)}
+	crawl(ecoID, empty)
+	crawl(waterID, CrawlRequest{})
+	crawl(ecoID, empty)
+	h := state()
+	if h.Status != health.StatusDegraded || len(*alerts) != 1 || !strings.Contains(h.Reason, "监控配置 "+strconv.Itoa(ecoID)) {
+		t.Fatalf("数据源状态 %+v、告警 %v，期望按监控配置 %d 的连续空结果标记异常", h, *alerts, ecoID)
+	}
+}
+
+func TestHealthDoesNotFlapBetweenMonitorConfigs(t *testing.T) {
+	crawlertest.OpenDB(t)
+	crawlertest.ConfigureFetch(t, fetch.FixtureOptions{})
+	alerts := useAlerts(t)
+
+	now := time.Now()
+	srv := crawlertest.NewSzggzyServer(
+		crawlertest.Record{Title: "深圳市生态环境局监测服务采购公告", Linkurl: "/gsgg/1.html", Webdate: now.Add(-time.Hour)},
+		crawlertest.Record{Title: "深圳市水务局管网检测采购公告", Linkurl: "/gsgg/2.html", Webdate: now.Add(-time.Hour)},
+	)
+	defer srv.Close()
+	pageID := crawlertest.InsertWebPage(t, "深圳政府采购网", srv.URL, "szggzy", `{"base_url":"`+srv.URL+`"}`)
+	ecoID := exec(t, "INSERT INTO monitor_config (web_page_id, crawl_time, crawl_freq, keywords) VALUES (?, '9', 'daily', '生态环境局')", pageID)
+	waterID := exec(t, "INSERT INTO monitor_config (web_page_id, crawl_time, crawl_freq, keywords) VALUES (?, '9', 'daily', '水务局')", pageID)
+	startScheduler(t)
+
+	crawl := func(configID int, req CrawlRequest) {
+		t.Helper()
+		req.MonitorConfigID = configID
+		runID, _, err := TriggerCrawl(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		waitRun(t, runID)
+	}
+	state := func() string {
+		t.Helper()
+		h, err := health.Get(pageID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return h.Status
+	}
+
+	crawl(ecoID, CrawlRequest{})
+	crawl(waterID, CrawlRequest{})
+	empty := CrawlRequest{StartTime: now.Add(-72 * time.Hour), EndTime: now.Add(-48 * time.Hour)}
+	crawl(ecoID, empty)
+	crawl(ecoID, empty)
+	if state() != health.StatusDegraded || len(*alerts) != 1 {
+		t.Fatalf("连续两次没有结果后状态 %s、告警 %v", state(), *alerts)
+	}
+
+	// 两个配置交替采集：另一个配置有结果不使网页恢复，状态不反复切换
+	for i := 0; i < 3; i++ {
+		crawl(waterID, CrawlRequest{})
+		if state() != health.StatusDegraded {
+			t.Fatalf("第 %d 轮监控配置 %d 仍没有结果，网页状态不应恢复", i+1, ecoID)
+		}
+		crawl(ecoID, empty)
+	}
+	if len(*alerts) != 1 {
+		t.Fatalf("告警 %v，期望只告警一次", *alerts)
+	}
+
+	// 连续无结果的配置重新拉取到记录后恢复
+	crawl(ecoID, CrawlRequest{})
+	if state() != health.StatusOK || len(*alerts) != 2 || !strings.Contains((*alerts)[1], "恢复") {
+		t.Fatalf("恢复后状态 %s、告警 %v", state(), *alerts)
+	}
+}
@@ -0,0 +1,481 @@
+package scheduler
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ieasydevops/demo-scrapy/internal/config"
+	"github.com/ieasydevops/demo-scrapy/internal/crawler"
+	"github.com/ieasydevops/demo-scrapy/internal/crawlrun"
+	"github.com/ieasydevops/demo-scrapy/internal/database"
+	"github.com/ieasydevops/demo-scrapy/internal/models"
+	"github.com/ieasydevops/demo-scrapy/internal/webhook"
+	"github.com/robfig/cron/v3"
+)
+
+// 监控配置支持的采集频率
+const (
+	FreqHourly = "hourly"
+	FreqDaily  = "daily"
+	FreqWeekly = "weekly"
+	FreqCustom = "custom"
+)
+
+// CrawlSpec 将监控配置的采集频率和采集时间转换为 cron 表达式
+//
+//   - hourly: crawl_time 为分钟（0-59），也接受 "HH:MM" 并取其分钟，默认整点
+//   - daily:  crawl_time 为 "H" 或 "H:MM"
+//   - weekly: crawl_time 为 "H"、"H:MM" 或 "W H:MM"（W 为 0-6，0 表示周日），默认周一
+//   - custom: crawl_time 为标准 5 段 cron 表达式
+func CrawlSpec(freq, crawlTime string) (string, error) {
+	crawlTime = strings.TrimSpace(crawlTime)
+
+	var spec string
+	switch freq {
+	case FreqHourly:
+		minute := 0
+		if crawlTime != "" {
+			var err error
+			if strings.Contains(crawlTime, ":") {
+				_, minute, err = parseClock(crawlTime)
+			} else {
+				minute, err = parseRange(crawlTime, 0, 59, "分钟")
+			}
+			if err != nil {
+				return "", err
+			}
+		}
+		spec = fmt.Sprintf("%d * * * *", minute)
+	case FreqDaily:
+		hour, minute, err := parseClock(crawlTime)
+		if err != nil {
+			return "", err
+		}
+		spec = fmt.Sprintf("%d %d * * *", minute, hour)
+	case FreqWeekly:
+		weekday := 1
+		clock := crawlTime
+		if fields := strings.Fields(crawlTime); len(fields) == 2 {
+			var err error
+			if weekday, err = parseRange(fields[0], 0, 6, "星期"); err != nil {
+				return "", err
+			}
+			clock = fields[1]
+		}
+		hour, minute, err := parseClock(clock)
+		if err != nil {
+			return "", err
+		}
+		spec = fmt.Sprintf("%d %d * * %d", minute, hour, weekday)
+	case FreqCustom:
+		spec = crawlTime
+	default:
+		return "", fmt.Errorf("不支持的采集频率: %s（可选 hourly/daily/weekly/custom）", freq)
+	}
+
+	if _, err := cron.ParseStandard(spec); err != nil {
+		return "", fmt.Errorf("无效的 cron 表达式 %q: %v", spec, err)
+	}
+	return spec, nil
+}
+
+// 采集时间窗口的下限和在执行间隔之外多回溯的时间。上游收录公告有延迟，窗口不小于一天，
+// 重复的公告在入库时跳过
+const (
+	minCrawlWindow = 24 * time.Hour
+	crawlOverlap   = time.Hour
+)
+
+// crawlWindow 在 end 结束的采集回溯的时间范围：从监控配置上上次计划执行的时间到 end，
+// 覆盖两次执行之间的间隔（执行时间不规则时为 end 之前的实际间隔），再多回溯 crawlOverlap
+func crawlWindow(mc models.MonitorConfig, end time.Time) time.Duration {
+	spec, err := CrawlSpec(mc.CrawlFreq, mc.CrawlTime)
+	if err != nil {
+		return minCrawlWindow
+	}
+	schedule, err := cron.ParseStandard(spec)
+	if err != nil {
+		return minCrawlWindow
+	}
+	_, before, ok := lastActivations(schedule, end)
+	if !ok {
+		return minCrawlWindow
+	}
+	if window := end.Sub(before) + crawlOverlap; window > minCrawlWindow {
+		return window
+	}
+	return minCrawlWindow
+}
+
+// lastActivations 返回 schedule 在 t 及之前最近的两次执行时间，从 t 往前逐步扩大查找范围
+func lastActivations(schedule cron.Schedule, t time.Time) (last, before time.Time, ok bool) {
+	for back := time.Hour; back <= 8*366*24*time.Hour; back *= 2 {
+		count := 0
+		for a := schedule.Next(t.Add(-back)); !a.IsZero() && !a.After(t); a = schedule.Next(a) {
+			last, before = a, last
+			count++
+		}
+		if count >= 2 {
+			return last, before, true
+		}
+	}
+	return time.Time{}, time.Time{}, false
+}
+
+func parseClock(s string) (int, int, error) {
+	hourStr, minuteStr, hasMinute := strings.Cut(s, ":")
+	hour, err := parseRange(hourStr, 0, 23, "小时")
+	if err != nil {
+		return 0, 0, err
+	}
+	minute := 0
+	if hasMinute {
+		if minute, err = parseRange(minuteStr, 0, 59, "分钟"); err != nil {
+			return 0, 0, err
+		}
+	}
+	return hour, minute, nil
+}
+
+func parseRange(s string, min, max int, name string) (int, error) {
+	n, err := strconv.Atoi(strings.TrimSpace(s))
+	if err != nil || n < min || n > max {
+		return 0, fmt.Errorf("无效的%s: %q（应为 %d-%d）", name, s, min, max)
+	}
+	return n, nil
+}
+
+// SplitKeywords 解析监控配置中逗号分隔的关键词
+func SplitKeywords(s string) []string {
+	var keywords []string
+	for _, kw := range strings.Split(s, ",") {
+		if kw = strings.TrimSpace(kw); kw != "" {
+			keywords = append(keywords, kw)
+		}
+	}
+	return keywords
+}
+
+func loadMonitorConfigs() ([]models.MonitorConfig, error) {
+	rows, err := database.DB.Query(`
+		SELECT id, web_page_id, crawl_time, crawl_freq, keywords, source_params, created_at, updated_at
+		FROM monitor_config
+		ORDER BY id
+	`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var configs []models.MonitorConfig
+	for rows.Next() {
+		mc, err := scanMonitorConfig(rows)
+		if err != nil {
+			return nil, err
+		}
+		configs = append(configs, mc)
+	}
+	return configs, rows.Err()
+}
+
+func loadMonitorConfig(id int) (models.MonitorConfig, error) {
+	return scanMonitorConfig(database.DB.QueryRow(`
+		SELECT id, web_page_id, crawl_time, crawl_freq, keywords, source_params, created_at, updated_at
+		FROM monitor_config WHERE id = ?
+	`, id))
+}
+
+func scanMonitorConfig(row interface{ Scan(...interface{}) error }) (models.MonitorConfig, error) {
+	var mc models.MonitorConfig
+	var params string
+	if err := row.Scan(&mc.ID, &mc.WebPageID, &mc.CrawlTime, &mc.CrawlFreq,
+		&mc.Keywords, &params, &mc.CreatedAt, &mc.UpdatedAt); err != nil {
+		return mc, err
+	}
+	if params != "" {
+		if err := json.Unmarshal([]byte(params), &mc.SourceParams); err != nil {
+			return mc, fmt.Errorf("解析监控配置 %d 数据源参数失败: %v", mc.ID, err)
+		}
+	}
+	return mc, nil
+}
+
+// globalKeywords 读取关键词表，作为未配置关键词的监控配置的默认值
+func globalKeywords() []string {
+	var keywords []string
+	rows, err := database.DB.Query("SELECT keyword FROM keywords")
+	if err != nil {
+		return nil
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var keyword string
+		if err := rows.Scan(&keyword); err == nil {
+			keywords = append(keywords, keyword)
+		}
+	}
+	return keywords
+}
+
+// CrawlRequest 手动采集的目标和时间窗口，MonitorConfigID 和 WebPageID 二选一。
+// 按网页采集时使用关键词表；时间窗口为零值时按采集频率回溯（按网页采集时回溯 1 天）
+type CrawlRequest struct {
+	MonitorConfigID int
+	WebPageID       int
+	StartTime       time.Time
+	EndTime         time.Time
+}
+
+// TriggerCrawl 在后台立即执行一次采集，返回采集记录 ID。
+// 同一监控配置（或网页）已有采集在执行时不再重复执行，返回执行中的记录 ID，coalesced 为 true
+func TriggerCrawl(req CrawlRequest) (runID int, coalesced bool, err error) {
+	var job *crawlJob
+	if req.MonitorConfigID > 0 {
+		mc, err := loadMonitorConfig(req.MonitorConfigID)
+		if err != nil {
+			return 0, false, err
+		}
+		job = newCrawlJob(monitorJobKey(mc.ID), mc, crawlrun.TriggerManual, req.StartTime, req.EndTime)
+	} else {
+		if _, err := crawler.GetWebPage(req.WebPageID); err != nil {
+			return 0, false, err
+		}
+		mc := models.MonitorConfig{WebPageID: req.WebPageID, CrawlFreq: FreqDaily}
+		job = newCrawlJob(webPageJobKey(req.WebPageID), mc, crawlrun.TriggerManual, req.StartTime, req.EndTime)
+	}
+
+	if !beginTask() {
+		return 0, false, ErrNotRunning
+	}
+	runID, claimed, err := claimJob(job.key, job.start)
+	if err != nil || !claimed {
+		tasks.Done()
+		return runID, err == nil, err
+	}
+	go func() {
+		defer tasks.Done()
+		defer releaseJob(job.key)
+		job.run(runID)
+	}()
+	return runID, false, nil
+}
+
+// ExecuteMonitorTask 按监控配置采集其目标网页，公告记录到该配置的网页下
+func ExecuteMonitorTask(configID int, trigger string) {
+	if !beginTask() {
+		return
+	}
+	defer tasks.Done()
+
+	mc, err := loadMonitorConfig(configID)
+	if err != nil {
+		log.Printf("读取监控配置 %d 失败: %v", configID, err)
+		return
+	}
+	runMonitorConfig(mc, trigger)
+}
+
+// runMonitorConfig 按默认时间窗口同步执行一次采集，该配置已有采集在执行时跳过
+func runMonitorConfig(mc models.MonitorConfig, trigger string) {
+	job := newCrawlJob(monitorJobKey(mc.ID), mc, trigger, time.Time{}, time.Time{})
+	runID, claimed, err := claimJob(job.key, job.start)
+	if err != nil {
+		log.Printf("%s 记录采集开始失败: %v", job.label(), err)
+		return
+	}
+	if !claimed {
+		log.Printf("%s 已有采集在执行（采集记录 %d），跳过本次触发", job.label(), runID)
+		return
+	}
+	defer releaseJob(job.key)
+	job.run(runID)
+}
+
+func monitorJobKey(configID int) string  { return fmt.Sprintf("crawl:monitor:%d", configID) }
+func webPageJobKey(webPageID int) string { return fmt.Sprintf("crawl:page:%d", webPageID) }
+
+// crawlJob 一次采集的目标、关键词和时间窗口，mc.ID 为 0 表示直接按网页采集
+type crawlJob struct {
+	key       string
+	mc        models.MonitorConfig
+	trigger   string
+	keywords  []string
+	startTime time.Time
+	endTime   time.Time
+
+	// backfillJobID 不为 0 时本次采集属于该回填任务
+	backfillJobID int
+
+	started time.Time
+	page    models.WebPage
+	pageErr error
+}
+
+func newCrawlJob(key string, mc models.MonitorConfig, trigger string, startTime, endTime time.Time) *crawlJob {
+	if endTime.IsZero() {
+		endTime = time.Now()
+	}
+	if startTime.IsZero() {
+		startTime = endTime.Add(-crawlWindow(mc, endTime))
+	}
+	keywords := SplitKeywords(mc.Keywords)
+	if len(keywords) == 0 {
+		keywords = globalKeywords()
+	}
+	return &crawlJob{key: key, mc: mc, trigger: trigger, keywords: keywords, startTime: startTime, endTime: endTime}
+}
+
+func (j *crawlJob) label() string {
+	if j.backfillJobID != 0 {
+		return fmt.Sprintf("回填任务 %d", j.backfillJobID)
+	}
+	if j.mc.ID == 0 {
+		return fmt.Sprintf("网页 %d", j.mc.WebPageID)
+	}
+	return fmt.Sprintf("监控配置 %d", j.mc.ID)
+}
+
+// start 写入采集记录，返回记录 ID
+func (j *crawlJob) start() (int, error) {
+	j.started = time.Now()
+	run := models.CrawlRun{
+		MonitorConfigID: j.mc.ID,
+		WebPageID:       j.mc.WebPageID,
+		Trigger:         j.trigger,
+		Keywords:        j.keywords,
+		WindowStart:     j.startTime.Format(time.RFC3339),
+		WindowEnd:       j.endTime.Format(time.RFC3339),
+		BackfillJobID:   j.backfillJobID,
+	}
+	j.page, j.pageErr = crawler.GetWebPage(j.mc.WebPageID)
+	if j.pageErr == nil {
+		j.page = crawler.WithSourceParams(j.page, j.mc.SourceParams)
+		run.Source = j.page.Source
+	}
+	return crawlrun.Start(run)
+}
+
+// run 执行采集并更新采集记录，返回本次的计数和错误
+func (j *crawlJob) run(runID int) (stats crawlrun.Stats, err error) {
+	finish := func(runErr error) {
+		if err := crawlrun.Finish(runID, j.started, stats, runErr); err != nil {
+			log.Printf("%s 记录采集结果失败: %v", j.label(), err)
+		}
+		if runErr != nil {
+			j.notifyFailed(runID)
+		}
+	}
+
+	if j.pageErr != nil {
+		log.Printf("%s 的网页 %d 不存在: %v", j.label(), j.mc.WebPageID, j.pageErr)
+		err = fmt.Errorf("网页 %d 不存在: %v", j.mc.WebPageID, j.pageErr)
+		finish(err)
+		return stats, err
+	}
+	page := j.page
+
+	src, err := crawler.SourceForWebPage(page)
+	if err != nil {
+		log.Printf("网页 %s 数据源配置错误: %v", page.Name, err)
+		err = fmt.Errorf("数据源配置错误: %v", err)
+		finish(err)
+		return stats, err
+	}
+
+	log.Printf("%s: 使用关键词采集 %s (%s): %v", j.label(), page.Name, src.Name(), j.keywords)
+
+	// 采集中途失败时仍保存已拉取的部分，采集记录标记为失败
+	announcements, crawlStats, crawlErr := crawler.Crawl(taskCtx, src, j.keywords, j.startTime, j.endTime)
+	stats.PagesFetched = crawlStats.Pages
+	stats.RecordsFetched = crawlStats.Fetched
+	stats.Matched = crawlStats.Matched
+	if crawlErr != nil {
+		log.Printf("%s 采集失败，保存已获取的 %d 条公告: %v", j.label(), len(announcements), crawlErr)
+	}
+
+	saved, skipped, err := crawler.SaveAnnouncements(announcements, page.ID, runID, j.backfillJobID != 0)
+	stats.Inserted = len(saved)
+	stats.Skipped = skipped
+	if err != nil {
+		log.Printf("%s 保存公告失败: %v", j.label(), err)
+		finish(err)
+		return stats, err
+	}
+
+	if crawlErr == nil {
+		log.Printf("%s 成功采集 %s，获取 %d 条公告", j.label(), page.Name, len(announcements))
+	}
+	// 详情页抓取完成后才结束采集记录，采集结束时公告的正文、字段和类型已经是最终结果
+	if opts, ok := detailOptions(); ok && len(saved) > 0 {
+		opts.Backfill = j.backfillJobID != 0
+		crawler.FetchDetails(taskCtx, saved, page, opts)
+	}
+
+	// 先更新数据源状态再结束采集记录，轮询到采集结束时状态已经是最新的
+	j.checkHealth(runID, src.Name(), stats, crawlErr)
+	finish(crawlErr)
+	return stats, crawlErr
+}
+
+// notifyFailed 向订阅了 crawl.failed 事件的 webhook 推送失败的采集记录
+func (j *crawlJob) notifyFailed(runID int) {
+	run, err := crawlrun.Get(runID)
+	if err == nil {
+		err = webhook.EnqueueCrawlFailed(run)
+	}
+	if err != nil {
+		log.Printf("%s 写入采集失败事件失败: %v", j.label(), err)
+	}
+}
+
+// detailOptions 读取详情页采集配置，未启用时返回 false
+func detailOptions() (crawler.DetailOptions, bool) {
+	cfg := config.GlobalConfig
+	if cfg == nil || !cfg.Crawler.FetchDetail {
+		return crawler.DetailOptions{}, false
+	}
+	return crawler.DetailOptions{
+		DownloadAttachments: cfg.Crawler.DownloadAttachments,
+		AttachmentDir:       cfg.Crawler.AttachmentDir,
+		MaxAttachmentSize:   int64(cfg.Crawler.MaxAttachmentMB) << 20,
+	}, true
+}
+
+// addMonitorJobs 为每个监控配置注册独立的定时采集任务
+func addMonitorJobs() {
+	configs, err := loadMonitorConfigs()
+	if err != nil {
+		log.Printf("读取监控配置失败: %v", err)
+		return
+	}
+
+	for _, mc := range configs {
+		spec, err := CrawlSpec(mc.CrawlFreq, mc.CrawlTime)
+		if err != nil {
+			log.Printf("监控配置 %d 调度规则无效，已跳过: %v", mc.ID, err)
+			continue
+		}
+		// 网页不存在时仍注册任务，由每次采集记录失败原因
+		if page, err := crawler.GetWebPage(mc.WebPageID); err == nil {
+			if _, err := crawler.SourceForWebPage(crawler.WithSourceParams(page, mc.SourceParams)); err != nil {
+				log.Printf("监控配置 %d 数据源参数无效，已跳过: %v", mc.ID, err)
+				continue
+			}
+		}
+
+		id := mc.ID
+		if _, err := c.AddFunc(spec, func() {
+			log.Printf("执行监控配置 %d 的定时采集任务...", id)
+			ExecuteMonitorTask(id, crawlrun.TriggerSchedule)
+		}); err != nil {
+			log.Printf("添加监控配置 %d 的定时采集任务失败: %v", mc.ID, err)
+			continue
+		}
+		log.Printf("已添加监控配置 %d 的定时采集任务: %s", mc.ID, spec)
+	}
+}
@@ -0,0 +1,33 @@
+package scheduler
+
+import (
+	"testing"
+	"time"
+
+	"github.com/ieasydevops/demo-scrapy/internal/models"
+)
+
+func TestCrawlWindowFollowsSchedule(t *testing.T) {
+	// 2025-06-04 为周三
+	at := func(day, hour int) time.Time { return time.Date(2025, 6, day, hour, 0, 5, 0, time.Local) }
+	for _, tc := range []struct {
+		mc   models.MonitorConfig
+		end  time.Time
+		want time.Duration
+	}{
+		{models.MonitorConfig{CrawlFreq: FreqHourly, CrawlTime: "0"}, at(4, 9), minCrawlWindow},
+		{models.MonitorConfig{CrawlFreq: FreqDaily, CrawlTime: "9"}, at(4, 9), 25*time.Hour + 5*time.Second},
+		{models.MonitorConfig{CrawlFreq: FreqWeekly, CrawlTime: "3 9:00"}, at(4, 9), 7*24*time.Hour + time.Hour + 5*time.Second},
+		// 每三天执行一次，窗口覆盖三天
+		{models.MonitorConfig{CrawlFreq: FreqCustom, CrawlTime: "0 9 */3 * *"}, at(4, 9), 3*24*time.Hour + time.Hour + 5*time.Second},
+		// 周一、周四执行：周一的采集需回溯到上周四
+		{models.MonitorConfig{CrawlFreq: FreqCustom, CrawlTime: "0 9 * * 1,4"}, at(2, 9), 4*24*time.Hour + time.Hour + 5*time.Second},
+		{models.MonitorConfig{CrawlFreq: FreqCustom, CrawlTime: "0 9 * * 1,4"}, at(5, 9), 3*24*time.Hour + time.Hour + 5*time.Second},
+		// 按网页直接采集时没有执行计划
+		{models.MonitorConfig{}, at(4, 9), minCrawlWindow},
+	} {
+		if got := crawlWindow(tc.mc, tc.end); got != tc.want {
+			t.Errorf("crawlWindow(%s %q, %s) = %s，期望 %s", tc.mc.CrawlFreq, tc.mc.CrawlTime, tc.end.Format(time.DateTime), got, tc.want)
+		}
+	}
+}
@@ -0,0 +1,224 @@
+package scheduler
+
+import (
+	"context"
+	"flag"
+	"io"
+	"log"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/ieasydevops/demo-scrapy/internal/config"
+	"github.com/ieasydevops/demo-scrapy/internal/crawler/crawlertest"
+	"github.com/ieasydevops/demo-scrapy/internal/crawlrun"
+	"github.com/ieasydevops/demo-scrapy/internal/database"
+	"github.com/ieasydevops/demo-scrapy/internal/digestrun"
+	"github.com/ieasydevops/demo-scrapy/internal/fetch"
+	"github.com/ieasydevops/demo-scrapy/internal/models"
+	"github.com/ieasydevops/demo-scrapy/internal/notify"
+)
+
+func TestMain(m *testing.M) {
+	flag.Parse()
+	if !testing.Verbose() {
+		log.SetOutput(io.Discard)
+	}
+	os.Exit(m.Run())
+}
+
+// notifierFunc 将函数适配为 notify.Notifier
+type notifierFunc func(ctx context.Context, msg notify.Message) error
+
+func (f notifierFunc) Send(ctx context.Context, msg notify.Message) error { return f(ctx, msg) }
+
+// useNotifier 替换 notifierFor，所有渠道的发送都交给 send
+func useNotifier(t *testing.T, send func(channel string, to []string, msg notify.Message) error) {
+	original := notifierFor
+	notifierFor = func(channel string, to []string) (notify.Notifier, error) {
+		return notifierFunc(func(ctx context.Context, msg notify.Message) error {
+			return send(channel, to, msg)
+		}), nil
+	}
+	t.Cleanup(func() { notifierFor = original })
+}
+
+// mailbox 记录每次通过邮件渠道发送的摘要公告
+type mailbox struct{ digests [][]models.Announcement }
+
+func useMailbox(t *testing.T) *mailbox {
+	box := &mailbox{}
+	useNotifier(t, func(channel string, to []string, msg notify.Message) error {
+		if channel == notify.ChannelEmail {
+			box.digests = append(box.digests, msg.Announcements)
+		}
+		return nil
+	})
+	return box
+}
+
+func startScheduler(t *testing.T) {
+	Start()
+	t.Cleanup(func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		Stop(ctx)
+	})
+}
+
+func exec(t *testing.T, query string, args ...interface{}) int {
+	t.Helper()
+	result, err := database.DB.Exec(query, args...)
+	if err != nil {
+		t.Fatalf("%s: %v", query, err)
+	}
+	id, _ := result.LastInsertId()
+	return int(id)
+}
+
+// waitRun 等待采集记录结束
+func waitRun(t *testing.T, id int) models.CrawlRun {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		run, err := crawlrun.Get(id)
+		if err != nil {
+			t.Fatalf("读取采集记录 %d: %v", id, err)
+		}
+		if run.Status != crawlrun.StatusRunning {
+			return run
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("采集记录 %d 超时未结束", id)
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+}
+
+func TestCrawlThenDigest(t *testing.T) {
+	crawlertest.OpenDB(t)
+	crawlertest.ConfigureFetch(t, fetch.FixtureOptions{})
+	box := useMailbox(t)
+
+	now := time.Now()
+	srv := crawlertest.NewSzggzyServer(
+		crawlertest.Record{Title: "深圳市生态环境局监测服务采购公告", Linkurl: "/gsgg/1.html", Webdate: now.Add(-2 * time.Hour)},
+		crawlertest.Record{Title: "深圳市生态环境局执法车辆租赁项目招标公告", Linkurl: "/gsgg/2.html", Webdate: now.Add(-3 * time.Hour)},
+		crawlertest.Record{Title: "深圳市生态环境局监测服务更正公告", Linkurl: "/gsgg/3.html", Webdate: now.Add(-4 * time.Hour)},
+		crawlertest.Record{Title: "深圳市生态环境局上周的公告", Linkurl: "/gsgg/old.html", Webdate: now.Add(-72 * time.Hour)},
+	)
+	defer srv.Close()
+
+	pageID := crawlertest.InsertWebPage(t, "深圳政府采购网", srv.URL, "szggzy", `{"base_url":"`+srv.URL+`"}`)
+	configID := exec(t, "INSERT INTO monitor_config (web_page_id, crawl_time, crawl_freq, keywords) VALUES (?, '9', 'daily', '生态环境局')", pageID)
+	subID := exec(t, "INSERT INTO subscribe_config (email, push_time, keywords) VALUES ('ops@example.com', '8', '生态环境局 -更正')")
+	startScheduler(t)
+
+	runID, coalesced, err := TriggerCrawl(CrawlRequest{MonitorConfigID: configID})
+	if err != nil || coalesced {
+		t.Fatalf("TriggerCrawl: %v coalesced=%v", err, coalesced)
+	}
+	run := waitRun(t, runID)
+	if run.Status != crawlrun.StatusSuccess || run.Inserted != 3 || run.Trigger != crawlrun.TriggerManual {
+		t.Fatalf("采集记录 %+v，期望手动触发成功并新增 3 条", run)
+	}
+
+	ExecuteDigestTask(subID, digestrun.TriggerManual)
+	if len(box.digests) != 1 || len(box.digests[0]) != 2 {
+		t.Fatalf("发送 %d 封摘要，期望 1 封包含 2 条（排除更正公告）", len(box.digests))
+	}
+
+	// 已推送的公告不再重复发送，之后新采集的公告进入下一次摘要
+	ExecuteDigestTask(subID, digestrun.TriggerManual)
+	if len(box.digests) != 1 {
+		t.Fatalf("重复推送了已发送的公告")
+	}
+	srv.Add(crawlertest.Record{Title: "深圳市生态环境局新增采购公告", Linkurl: "/gsgg/4.html", Webdate: now.Add(-time.Hour)})
+	runID, _, err = TriggerCrawl(CrawlRequest{MonitorConfigID: configID})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if run := waitRun(t, runID); run.Inserted != 1 || run.Skipped != 3 {
+		t.Fatalf("第二次采集新增 %d 条、跳过 %d 条，期望新增 1 条、跳过 3 条", run.Inserted, run.Skipped)
+	}
+	ExecuteDigestTask(subID, digestrun.TriggerManual)
+	if len(box.digests) != 2 || len(box.digests[1]) != 1 || box.digests[1][0].URL != srv.URL+"/gsgg/4.html" {
+		t.Fatalf("第二封摘要应只包含新增的公告")
+	}
+
+	runs, total, err := digestrun.List(subID, 1, 10)
+	if err != nil || total != 3 {
+		t.Fatalf("推送记录 %d 条（%v），期望 3 条", total, err)
+	}
+	if runs[0].Sent != 1 || runs[1].Sent != 0 || runs[2].Sent != 2 {
+		t.Errorf("推送记录的发送条数不符: %d %d %d", runs[0].Sent, runs[1].Sent, runs[2].Sent)
+	}
+}
+
+func TestTriggerCrawlCoalesces(t *testing.T) {
+	crawlertest.OpenDB(t)
+	crawlertest.ConfigureFetch(t, fetch.FixtureOptions{})
+	srv := crawlertest.NewSzggzyServer()
+	defer srv.Close()
+	pageID := crawlertest.InsertWebPage(t, "深圳政府采购网", srv.URL, "szggzy", `{"base_url":"`+srv.URL+`"}`)
+	exec(t, "INSERT INTO keywords (keyword) VALUES ('生态环境局')")
+	startScheduler(t)
+
+	// 占用该网页的任务标识，模拟正在执行的采集
+	key := webPageJobKey(pageID)
+	held, _, err := claimJob(key, func() (int, error) { return 42, nil })
+	if err != nil {
+		t.Fatal(err)
+	}
+	runID, coalesced, err := TriggerCrawl(CrawlRequest{WebPageID: pageID})
+	if err != nil || !coalesced || runID != held {
+		t.Fatalf("TriggerCrawl = %d, %v, %v，期望合并到执行中的记录 %d", runID, coalesced, err, held)
+	}
+	releaseJob(key)
+
+	runID, coalesced, err = TriggerCrawl(CrawlRequest{WebPageID: pageID})
+	if err != nil || coalesced {
+		t.Fatalf("TriggerCrawl: %v coalesced=%v", err, coalesced)
+	}
+	if run := waitRun(t, runID); run.Status != crawlrun.StatusSuccess {
+		t.Errorf("采集记录状态 %s，期望 success: %s", run.Status, run.Error)
+	}
+}
+
+func TestCrawlRunFinishesAfterDetails(t *testing.T) {
+	crawlertest.OpenDB(t)
+	crawlertest.ConfigureFetch(t, fetch.FixtureOptions{})
+	originalConfig := config.GlobalConfig
+	config.GlobalConfig = &config.Config{Crawler: config.CrawlerConfig{FetchDetail: true}}
+	t.Cleanup(func() { config.GlobalConfig = originalConfig })
+
+	srv := crawlertest.NewSzggzyServer(crawlertest.Record{
+		Title:   "深圳市生态环境局监测服务采购公告",
+		Linkurl: "/gsgg/1.html",
+		Webdate: time.Now().Add(-time.Hour),
+		Body:    "采购人：深圳市生态环境局 预算金额：120万元",
+	})
+	defer srv.Close()
+	srv.DelayDetails(300 * time.Millisecond)
+	pageID := crawlertest.InsertWebPage(t, "深圳政府采购网", srv.URL, "szggzy", `{"base_url":"`+srv.URL+`"}`)
+	configID := exec(t, "INSERT INTO monitor_config (web_page_id, crawl_time, crawl_freq, keywords) VALUES (?, '9', 'daily', '生态环境局')", pageID)
+	startScheduler(t)
+
+	runID, _, err := TriggerCrawl(CrawlRequest{MonitorConfigID: configID})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if run := waitRun(t, runID); run.Status != crawlrun.StatusSuccess {
+		t.Fatalf("采集记录状态 %s，期望 success: %s", run.Status, run.Error)
+	}
+	// 采集记录结束时详情页已经保存
+	var body string
+	var budget float64
+	if err := database.DB.QueryRow("SELECT body, budget_amount FROM announcements WHERE crawl_run_id = ?", runID).
+		Scan(&body, &budget); err != nil {
+		t.Fatal(err)
+	}
+	if body == "" || budget != 1200000 {
+		t.Errorf("采集结束时正文 %q、预算 %v，期望已保存详情页正文和抽取的预算金额", body, budget)
+	}
+}
@@ -1,108 +1,162 @@
 package scheduler
 
 import (
-	"fmt"
+	"context"
+	"errors"
 	"log"
+	"sync"
 
-	"github.com/ieasydevops/demo-scrapy/internal/crawler"
-	"github.com/ieasydevops/demo-scrapy/internal/database"
-	"github.com/ieasydevops/demo-scrapy/internal/email"
+	"github.com/ieasydevops/demo-scrapy/internal/crawlrun"
 	"github.com/robfig/cron/v3"
 )
 
-var c *cron.Cron
+var (
+	c  *cron.Cron
+	mu sync.Mutex
+
+	// tasks 跟踪正在执行的采集和推送任务，taskCtx 在停止超时后被取消
+	tasks       sync.WaitGroup
+	taskCtx     context.Context
+	cancelTasks context.CancelFunc
+	stopped     bool
+	// stopping 在 Stop 时关闭，通知回填等长时间任务在当前步骤完成后退出
+	stopping chan struct{}
+
+	// active 正在执行的采集和推送，键为任务标识，值为其运行记录 ID
+	activeMu sync.Mutex
+	active   = map[string]int{}
+)
+
+// ErrNotRunning 调度器未启动或已停止
+var ErrNotRunning = errors.New("调度器未运行")
 
 func Start() {
-	c = cron.New()
+	mu.Lock()
+	defer mu.Unlock()
+
+	taskCtx, cancelTasks = context.WithCancel(context.Background())
+	stopped = false
+	stopping = make(chan struct{})
+	c = newCron()
 	c.Start()
 }
 
-func ExecuteCrawlTask() {
-	log.Println("开始执行采集任务...")
+// newCron 创建调度器，同一任务上一次尚未结束时跳过本次触发
+func newCron() *cron.Cron {
+	return cron.New(cron.WithChain(cron.SkipIfStillRunning(cron.DefaultLogger)))
+}
 
-	var keywords []string
-	keywordRows, err := database.DB.Query("SELECT keyword FROM keywords")
-	if err == nil {
-		defer keywordRows.Close()
-		for keywordRows.Next() {
-			var keyword string
-			if err := keywordRows.Scan(&keyword); err == nil {
-				keywords = append(keywords, keyword)
-			}
-		}
+// Stop 停止调度器并等待运行中的任务结束；ctx 到期后取消仍未完成的任务
+func Stop(ctx context.Context) error {
+	mu.Lock()
+	if !stopped && stopping != nil {
+		close(stopping)
 	}
-
-	if len(keywords) == 0 {
-		keywords = []string{"生态环境局"}
+	stopped = true
+	if c != nil {
+		c.Stop()
+	}
+	mu.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		tasks.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		cancelTasks()
+		return nil
+	case <-ctx.Done():
+		log.Println("等待任务结束超时，取消运行中的任务")
+		cancelTasks()
+		return ctx.Err()
 	}
+}
 
-	log.Printf("使用关键词进行API采集: %v", keywords)
+// stopSignal 返回本次运行的停止通知
+func stopSignal() <-chan struct{} {
+	mu.Lock()
+	defer mu.Unlock()
+	return stopping
+}
 
-	announcements, err := crawler.CrawlByAPISearch(keywords, 1)
-	if err != nil {
-		log.Printf("采集失败: %v", err)
-		return
-	}
+// beginTask 登记一个运行中的任务，调度器已停止时返回 false
+func beginTask() bool {
+	mu.Lock()
+	defer mu.Unlock()
 
-	var webPageID int = 1
-	err = database.DB.QueryRow("SELECT id FROM web_pages LIMIT 1").Scan(&webPageID)
-	if err != nil {
-		database.DB.Exec("INSERT OR IGNORE INTO web_pages (url, name) VALUES (?, ?)",
-			"http://zfcg.szggzy.com:8081/gsgg/secondPage.html", "深圳政府采购网")
-		webPageID = 1
+	if c == nil || stopped {
+		return false
 	}
+	tasks.Add(1)
+	return true
+}
 
-	if err := crawler.SaveAnnouncements(announcements, webPageID); err != nil {
-		log.Printf("保存公告失败: %v", err)
-		return
-	}
+// claimJob 登记执行中的任务并调用 start 写入运行记录，返回记录 ID。
+// 同一任务已在执行时不调用 start，返回执行中的记录 ID，claimed 为 false
+func claimJob(key string, start func() (int, error)) (id int, claimed bool, err error) {
+	activeMu.Lock()
+	defer activeMu.Unlock()
 
-	log.Printf("成功采集，获取 %d 条公告", len(announcements))
+	if id, ok := active[key]; ok {
+		return id, false, nil
+	}
+	if id, err = start(); err != nil {
+		return 0, false, err
+	}
+	active[key] = id
+	return id, true, nil
 }
 
-func ReloadTasks() error {
-	c.Stop()
-	c = cron.New()
-	c.Start()
+func releaseJob(key string) {
+	activeMu.Lock()
+	defer activeMu.Unlock()
+	delete(active, key)
+}
 
-	_, err := c.AddFunc("*/10 * * * *", func() {
-		log.Println("执行定时采集任务（每10分钟）...")
-		ExecuteCrawlTask()
-	})
-	if err != nil {
-		log.Printf("添加定时采集任务失败: %v", err)
+// ExecuteCrawlTask 立即按全部监控配置执行一次采集
+func ExecuteCrawlTask() {
+	if !beginTask() {
+		return
 	}
+	defer tasks.Done()
 
-	var pushTime string = "17"
-	var emailAddr string = "403608355@qq.com"
+	log.Println("开始执行采集任务...")
 
-	err = database.DB.QueryRow("SELECT email, push_time FROM push_config LIMIT 1").Scan(&emailAddr, &pushTime)
+	configs, err := loadMonitorConfigs()
 	if err != nil {
-		database.DB.Exec("INSERT OR IGNORE INTO push_config (email, push_time) VALUES (?, ?)", emailAddr, pushTime)
+		log.Printf("读取监控配置失败: %v", err)
+		return
 	}
-
-	hour := pushTime
-	if len(hour) == 1 {
-		hour = "0" + hour
+	if len(configs) == 0 {
+		log.Println("没有监控配置，跳过采集")
+		return
 	}
-	spec := fmt.Sprintf("0 %s * * *", hour)
 
-	_, err = c.AddFunc(spec, func() {
-		log.Println("执行定时邮件推送任务...")
-		newAnnouncements, err := crawler.GetNewAnnouncements()
-		if err != nil {
-			log.Printf("获取新公告失败: %v", err)
+	for _, mc := range configs {
+		if taskCtx.Err() != nil {
 			return
 		}
+		runMonitorConfig(mc, crawlrun.TriggerStartup)
+	}
+}
 
-		if len(newAnnouncements) > 0 {
-			if err := email.SendEmail(emailAddr, newAnnouncements); err != nil {
-				log.Printf("发送邮件失败: %v", err)
-			} else {
-				log.Printf("成功发送 %d 条公告到 %s", len(newAnnouncements), emailAddr)
-			}
-		}
-	})
+func ReloadTasks() error {
+	mu.Lock()
+	defer mu.Unlock()
+
+	if c == nil || stopped {
+		return ErrNotRunning
+	}
+
+	c.Stop()
+	c = newCron()
+	c.Start()
+
+	addMonitorJobs()
+	addDigestJobs()
 
-	return err
+	return nil
 }
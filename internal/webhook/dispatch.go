@@ -0,0 +1,202 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"strconv"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"github.com/ieasydevops/demo-scrapy/internal/database"
+	"github.com/ieasydevops/demo-scrapy/internal/notify"
+	"github.com/ieasydevops/demo-scrapy/internal/outbox"
+)
+
+// 投递请求头，签名与通用 webhook 推送渠道相同：X-Signature 为以密钥对 "<X-Timestamp>.<请求体>" 计算的 HMAC-SHA256
+const (
+	HeaderEvent    = "X-Webhook-Event"
+	HeaderDelivery = "X-Webhook-Delivery"
+)
+
+// Options 投递的超时和重试设置
+type Options struct {
+	Timeout     time.Duration // 单次请求超时
+	MaxAttempts int           // 最大投递次数，用尽后标记为 failed
+	BaseDelay   time.Duration // 第一次重试前的等待，之后每次翻倍
+	MaxDelay    time.Duration // 重试等待的上限
+}
+
+var (
+	optsMu sync.RWMutex
+	opts   = Options{Timeout: 10 * time.Second, MaxAttempts: 8, BaseDelay: 30 * time.Second, MaxDelay: time.Hour}
+
+	poller = outbox.New("投递 webhook 失败", DeliverDue)
+)
+
+// Configure 设置投递的超时和重试，零值字段保留默认值
+func Configure(o Options) {
+	optsMu.Lock()
+	defer optsMu.Unlock()
+	if o.Timeout > 0 {
+		opts.Timeout = o.Timeout
+	}
+	if o.MaxAttempts > 0 {
+		opts.MaxAttempts = o.MaxAttempts
+	}
+	if o.BaseDelay > 0 {
+		opts.BaseDelay = o.BaseDelay
+	}
+	if o.MaxDelay > 0 {
+		opts.MaxDelay = o.MaxDelay
+	}
+}
+
+func options() Options {
+	optsMu.RLock()
+	defer optsMu.RUnlock()
+	return opts
+}
+
+// Wake 通知 Run 立即投递新写入的记录
+func Wake() { poller.Wake() }
+
+// Run 在后台投递到期的记录，直到 ctx 取消。上次退出时未完成的记录在启动后继续投递
+func Run(ctx context.Context) { poller.Run(ctx) }
+
+// due 一条到期的待投递记录
+type due struct {
+	id        int
+	webhookID int
+	event     string
+	payload   string
+	attempts  int
+	url       string
+	secret    string
+	enabled   bool
+}
+
+// DeliverDue 投递一批到期的记录，返回处理的条数
+func DeliverDue(ctx context.Context) (int, error) {
+	rows, err := database.DB.Query(`
+		SELECT d.id, d.webhook_id, d.event, d.payload, d.attempts, w.url, w.secret, w.enabled
+		FROM webhook_deliveries d
+		JOIN webhooks w ON d.webhook_id = w.id
+		WHERE d.status = ? AND d.next_attempt_at <= CURRENT_TIMESTAMP
+		ORDER BY d.next_attempt_at, d.id
+		LIMIT ?
+	`, StatusPending, outbox.BatchSize)
+	if err != nil {
+		return 0, err
+	}
+	var batch []due
+	for rows.Next() {
+		var d due
+		if err := rows.Scan(&d.id, &d.webhookID, &d.event, &d.payload, &d.attempts, &d.url, &d.secret, &d.enabled); err != nil {
+			rows.Close()
+			return 0, err
+		}
+		batch = append(batch, d)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, err
+	}
+
+	o := options()
+	for i, d := range batch {
+		if ctx.Err() != nil {
+			return i, nil
+		}
+		if !d.enabled {
+			// 停用期间到期的记录推迟到重新启用后投递，不计入投递次数
+			if _, err := database.DB.Exec(`UPDATE webhook_deliveries SET next_attempt_at = datetime('now', ?) WHERE id = ?`,
+				outbox.After(o.MaxDelay), d.id); err != nil {
+				return i, err
+			}
+			continue
+		}
+		if err := deliver(ctx, d, o); err != nil {
+			return i, err
+		}
+	}
+	return len(batch), nil
+}
+
+// deliver 发送一次并记录结果，失败时安排下一次重试或标记为 failed
+func deliver(ctx context.Context, d due, o Options) error {
+	status, body, sendErr := send(ctx, d, o.Timeout)
+	if ctx.Err() != nil {
+		// 服务退出时中断的请求不计入投递次数，下次启动后重新投递
+		return nil
+	}
+	attempts := d.attempts + 1
+	if sendErr == nil {
+		_, err := database.DB.Exec(`
+			UPDATE webhook_deliveries SET status = ?, attempts = ?, response_status = ?, response_body = ?, error = '',
+				delivered_at = CURRENT_TIMESTAMP
+			WHERE id = ?
+		`, StatusSuccess, attempts, status, body, d.id)
+		return err
+	}
+
+	if attempts >= o.MaxAttempts {
+		log.Printf("webhook %d 的投递记录 %d 已失败 %d 次，不再重试: %v", d.webhookID, d.id, attempts, sendErr)
+		_, err := database.DB.Exec(`
+			UPDATE webhook_deliveries SET status = ?, attempts = ?, response_status = ?, response_body = ?, error = ?
+			WHERE id = ?
+		`, StatusFailed, attempts, database.NullInt(status), body, sendErr.Error(), d.id)
+		return err
+	}
+	delay := outbox.Backoff(o.BaseDelay, o.MaxDelay, attempts)
+	log.Printf("webhook %d 的投递记录 %d 第 %d 次投递失败，%s 后重试: %v", d.webhookID, d.id, attempts, delay, sendErr)
+	_, err := database.DB.Exec(`
+		UPDATE webhook_deliveries SET attempts = ?, response_status = ?, response_body = ?, error = ?,
+			next_attempt_at = datetime('now', ?)
+		WHERE id = ?
+	`, attempts, database.NullInt(status), body, sendErr.Error(), outbox.After(delay), d.id)
+	return err
+}
+
+// send 发送带签名的请求，非 2xx 响应视为失败，返回状态码和响应的开头部分
+func send(ctx context.Context, d due, timeout time.Duration) (int, string, error) {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	body := []byte(d.payload)
+	req, err := http.NewRequestWithContext(ctx, "POST", d.url, bytes.NewReader(body))
+	if err != nil {
+		return 0, "", err
+	}
+	ts := time.Now().Unix()
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(HeaderEvent, d.event)
+	req.Header.Set(HeaderDelivery, strconv.Itoa(d.id))
+	req.Header.Set(notify.HeaderTimestamp, strconv.FormatInt(ts, 10))
+	req.Header.Set(notify.HeaderSignature, notify.Sign(d.secret, ts, body))
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		var urlErr *url.Error
+		if errors.As(err, &urlErr) {
+			err = urlErr.Err
+		}
+		return 0, "", err
+	}
+	defer resp.Body.Close()
+	data, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+	respBody := string(data)
+	if !utf8.ValidString(respBody) {
+		respBody = ""
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return resp.StatusCode, respBody, fmt.Errorf("HTTP状态码错误: %d", resp.StatusCode)
+	}
+	return resp.StatusCode, respBody, nil
+}
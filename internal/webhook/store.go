@@ -0,0 +1,237 @@
+package webhook
+
+import (
+	"crypto/rand"
+	"database/sql"
+	"encoding/hex"
+	"strings"
+
+	"github.com/ieasydevops/demo-scrapy/internal/database"
+	"github.com/ieasydevops/demo-scrapy/internal/models"
+)
+
+const webhookColumns = "id, name, url, secret, events, types, keywords, enabled, created_at"
+
+// List 返回所有 webhook，包含密钥
+func List() ([]models.Webhook, error) {
+	rows, err := database.DB.Query("SELECT " + webhookColumns + " FROM webhooks ORDER BY id")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var list []models.Webhook
+	for rows.Next() {
+		var w models.Webhook
+		if err := scanWebhook(rows, &w); err != nil {
+			return nil, err
+		}
+		list = append(list, w)
+	}
+	return list, rows.Err()
+}
+
+// Get 按 ID 读取 webhook，包含密钥
+func Get(id int) (models.Webhook, error) {
+	var w models.Webhook
+	err := scanWebhook(database.DB.QueryRow("SELECT "+webhookColumns+" FROM webhooks WHERE id = ?", id), &w)
+	return w, err
+}
+
+// Create 创建 webhook，密钥为空时生成随机密钥，返回新 ID 和使用的密钥
+func Create(w models.Webhook) (int, string, error) {
+	if w.Secret == "" {
+		secret, err := GenerateSecret()
+		if err != nil {
+			return 0, "", err
+		}
+		w.Secret = secret
+	}
+	result, err := database.DB.Exec(`INSERT INTO webhooks (name, url, secret, events, types, keywords, enabled)
+		VALUES (?, ?, ?, ?, ?, ?, ?)`,
+		w.Name, w.URL, w.Secret, strings.Join(w.Events, ","), strings.Join(w.Types, ","), strings.Join(w.Keywords, ","), w.Enabled)
+	if err != nil {
+		return 0, "", err
+	}
+	id, err := result.LastInsertId()
+	return int(id), w.Secret, err
+}
+
+// Update 更新 webhook，密钥为空时保留原密钥
+func Update(w models.Webhook) error {
+	result, err := database.DB.Exec(`UPDATE webhooks SET name = ?, url = ?, secret = COALESCE(NULLIF(?, ''), secret),
+		events = ?, types = ?, keywords = ?, enabled = ? WHERE id = ?`,
+		w.Name, w.URL, w.Secret, strings.Join(w.Events, ","), strings.Join(w.Types, ","), strings.Join(w.Keywords, ","),
+		w.Enabled, w.ID)
+	if err != nil {
+		return err
+	}
+	if n, err := result.RowsAffected(); err == nil && n == 0 {
+		return sql.ErrNoRows
+	}
+	return err
+}
+
+// Delete 删除 webhook 及其投递记录
+func Delete(id int) error {
+	tx, err := database.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec("DELETE FROM webhook_deliveries WHERE webhook_id = ?", id); err != nil {
+		return err
+	}
+	result, err := tx.Exec("DELETE FROM webhooks WHERE id = ?", id)
+	if err != nil {
+		return err
+	}
+	if n, err := result.RowsAffected(); err != nil {
+		return err
+	} else if n == 0 {
+		return sql.ErrNoRows
+	}
+	return tx.Commit()
+}
+
+// GenerateSecret 生成 32 字节的随机签名密钥
+func GenerateSecret() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// Masked 去掉密钥，只保留是否已设置
+func Masked(w models.Webhook) models.Webhook {
+	w.HasSecret = w.Secret != ""
+	w.Secret = ""
+	return w
+}
+
+func scanWebhook(row interface{ Scan(...interface{}) error }, w *models.Webhook) error {
+	var events, types, keywords string
+	if err := row.Scan(&w.ID, &w.Name, &w.URL, &w.Secret, &events, &types, &keywords, &w.Enabled, &w.CreatedAt); err != nil {
+		return err
+	}
+	w.Events = split(events)
+	w.Types = split(types)
+	w.Keywords = split(keywords)
+	return nil
+}
+
+// DeliveryFilter 投递记录的查询条件，零值表示不限制
+type DeliveryFilter struct {
+	WebhookID int
+	Status    string
+	Event     string
+}
+
+const deliveryColumns = `
+	d.id, d.webhook_id, w.name, d.event, d.status, d.attempts, d.next_attempt_at, d.response_status,
+	d.response_body, d.error, d.replay_of, d.created_at, d.delivered_at`
+
+// Deliveries 分页查询投递记录，按创建时间倒序，不包含请求体
+func Deliveries(filter DeliveryFilter, page, pageSize int) ([]models.WebhookDelivery, int, error) {
+	var where []string
+	var args []interface{}
+	if filter.WebhookID > 0 {
+		where = append(where, "d.webhook_id = ?")
+		args = append(args, filter.WebhookID)
+	}
+	if filter.Status != "" {
+		where = append(where, "d.status = ?")
+		args = append(args, filter.Status)
+	}
+	if filter.Event != "" {
+		where = append(where, "d.event = ?")
+		args = append(args, filter.Event)
+	}
+	whereSQL := ""
+	if len(where) > 0 {
+		whereSQL = "WHERE " + strings.Join(where, " AND ")
+	}
+
+	var total int
+	if err := database.DB.QueryRow("SELECT COUNT(*) FROM webhook_deliveries d "+whereSQL, args...).Scan(&total); err != nil {
+		return nil, 0, err
+	}
+
+	rows, err := database.DB.Query("SELECT "+deliveryColumns+`
+		FROM webhook_deliveries d
+		LEFT JOIN webhooks w ON d.webhook_id = w.id
+		`+whereSQL+`
+		ORDER BY d.id DESC
+		LIMIT ? OFFSET ?`, append(args, pageSize, (page-1)*pageSize)...)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	var list []models.WebhookDelivery
+	for rows.Next() {
+		var d models.WebhookDelivery
+		if err := scanDelivery(rows, &d); err != nil {
+			return nil, 0, err
+		}
+		list = append(list, d)
+	}
+	return list, total, rows.Err()
+}
+
+// GetDelivery 按 ID 读取投递记录，包含请求体
+func GetDelivery(id int) (models.WebhookDelivery, error) {
+	var d models.WebhookDelivery
+	var payload string
+	row := database.DB.QueryRow("SELECT "+deliveryColumns+`, d.payload
+		FROM webhook_deliveries d
+		LEFT JOIN webhooks w ON d.webhook_id = w.id
+		WHERE d.id = ?`, id)
+	if err := scanDelivery(row, &d, &payload); err != nil {
+		return d, err
+	}
+	d.Payload = []byte(payload)
+	return d, nil
+}
+
+// Replay 以原请求体重新投递一次，新建一条待投递记录并返回其 ID，签名使用 webhook 当前的密钥
+func Replay(id int) (int, error) {
+	result, err := database.DB.Exec(`
+		INSERT INTO webhook_deliveries (webhook_id, event, payload, replay_of)
+		SELECT webhook_id, event, payload, id FROM webhook_deliveries WHERE id = ?
+	`, id)
+	if err != nil {
+		return 0, err
+	}
+	if n, err := result.RowsAffected(); err != nil {
+		return 0, err
+	} else if n == 0 {
+		return 0, sql.ErrNoRows
+	}
+	newID, err := result.LastInsertId()
+	if err != nil {
+		return 0, err
+	}
+	Wake()
+	return int(newID), nil
+}
+
+func scanDelivery(row interface{ Scan(...interface{}) error }, d *models.WebhookDelivery, extra ...interface{}) error {
+	var name, nextAttemptAt, deliveredAt sql.NullString
+	var responseStatus, replayOf sql.NullInt64
+	dest := append([]interface{}{&d.ID, &d.WebhookID, &name, &d.Event, &d.Status, &d.Attempts, &nextAttemptAt,
+		&responseStatus, &d.ResponseBody, &d.Error, &replayOf, &d.CreatedAt, &deliveredAt}, extra...)
+	if err := row.Scan(dest...); err != nil {
+		return err
+	}
+	d.WebhookName = name.String
+	if d.Status == StatusPending {
+		d.NextAttemptAt = nextAttemptAt.String
+	}
+	d.ResponseStatus = int(responseStatus.Int64)
+	d.ReplayOf = int(replayOf.Int64)
+	d.DeliveredAt = deliveredAt.String
+	return nil
+}
@@ -0,0 +1,254 @@
+// Package webhook 在公告入库、分类、详情页更新、匹配关键词和采集失败时向外部系统推送带签名的事件。
+// 待投递的事件与公告在同一事务中写入 webhook_deliveries 表，由 Run 在后台投递，失败时按指数退避重试
+package webhook
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/ieasydevops/demo-scrapy/internal/database"
+	"github.com/ieasydevops/demo-scrapy/internal/extract"
+	"github.com/ieasydevops/demo-scrapy/internal/keyword"
+	"github.com/ieasydevops/demo-scrapy/internal/models"
+)
+
+// 事件类型
+const (
+	EventAnnouncementCreated    = "announcement.created"
+	EventAnnouncementClassified = "announcement.classified"
+	EventAnnouncementUpdated    = "announcement.updated"
+	EventKeywordMatched         = "keyword.matched"
+	EventCrawlFailed            = "crawl.failed"
+)
+
+// Events 返回可订阅的事件类型
+func Events() []string {
+	return []string{EventAnnouncementCreated, EventAnnouncementClassified, EventAnnouncementUpdated, EventKeywordMatched, EventCrawlFailed}
+}
+
+// 投递状态
+const (
+	StatusPending = "pending"
+	StatusSuccess = "success"
+	StatusFailed  = "failed"
+)
+
+// Payload webhook 的请求体，announcement 事件包含 Announcement，crawl.failed 包含 CrawlRun
+type Payload struct {
+	Event        string               `json:"event"`
+	OccurredAt   string               `json:"occurred_at"`
+	Announcement *models.Announcement `json:"announcement,omitempty"`
+	// Keywords keyword.matched 事件中匹配的关键词表达式
+	Keywords []string         `json:"keywords,omitempty"`
+	CrawlRun *models.CrawlRun `json:"crawl_run,omitempty"`
+}
+
+// Set 已启用的 webhook 及其解析后的关键词，保存一批公告前读取一次
+type Set struct {
+	hooks []hook
+}
+
+type hook struct {
+	models.Webhook
+	exprs []keyword.Expr
+}
+
+// Load 读取已启用的 webhook，关键词无效的 webhook 不参与 keyword.matched 事件
+func Load() (*Set, error) {
+	list, err := List()
+	if err != nil {
+		return nil, err
+	}
+	set := &Set{}
+	for _, w := range list {
+		if !w.Enabled {
+			continue
+		}
+		h := hook{Webhook: w}
+		if exprs, err := keyword.ParseAll(w.Keywords); err == nil {
+			h.exprs = exprs
+		}
+		set.hooks = append(set.hooks, h)
+	}
+	return set, nil
+}
+
+// Empty 判断是否没有启用的 webhook
+func (s *Set) Empty() bool { return s == nil || len(s.hooks) == 0 }
+
+// EnqueueAnnouncement 在保存公告的事务中为订阅了相应事件的 webhook 写入待投递记录，返回写入的条数。
+// doc 为用于匹配关键词的公告内容
+func (s *Set) EnqueueAnnouncement(tx *sql.Tx, ann models.Announcement, doc keyword.Document) (int, error) {
+	if s.Empty() {
+		return 0, nil
+	}
+	now := time.Now().Format(time.RFC3339)
+	count := 0
+	for _, h := range s.hooks {
+		for _, event := range h.Events {
+			payload := Payload{Event: event, OccurredAt: now, Announcement: &ann}
+			switch event {
+			case EventAnnouncementCreated:
+			case EventAnnouncementClassified:
+				if !h.classifies(ann) {
+					continue
+				}
+			case EventKeywordMatched:
+				for i, x := range h.exprs {
+					if x.Match(doc) {
+						payload.Keywords = append(payload.Keywords, h.Keywords[i])
+					}
+				}
+				if len(payload.Keywords) == 0 {
+					continue
+				}
+			default:
+				continue
+			}
+			if err := enqueue(tx, h.ID, payload); err != nil {
+				return count, err
+			}
+			count++
+		}
+	}
+	return count, nil
+}
+
+// EnqueueUpdate 在保存详情页的事务中写入详情页带来的变化，返回写入的条数：类型或抽取字段变化时写入
+// announcement.updated，类型变化且属于 webhook 订阅的类型时重新写入 announcement.classified。
+// previousType 为入库时按标题和摘要判断的类型
+func (s *Set) EnqueueUpdate(tx *sql.Tx, ann models.Announcement, previousType string, fieldsChanged bool) (int, error) {
+	typeChanged := ann.Type != previousType
+	if s.Empty() || (!typeChanged && !fieldsChanged) {
+		return 0, nil
+	}
+	now := time.Now().Format(time.RFC3339)
+	count := 0
+	for _, h := range s.hooks {
+		for _, event := range h.Events {
+			switch event {
+			case EventAnnouncementUpdated:
+			case EventAnnouncementClassified:
+				if !typeChanged || !h.classifies(ann) {
+					continue
+				}
+			default:
+				continue
+			}
+			if err := enqueue(tx, h.ID, Payload{Event: event, OccurredAt: now, Announcement: &ann}); err != nil {
+				return count, err
+			}
+			count++
+		}
+	}
+	return count, nil
+}
+
+// classifies 判断公告是否属于 webhook 订阅 announcement.classified 的类型
+func (h hook) classifies(ann models.Announcement) bool {
+	return ann.Type != "" && (len(h.Types) == 0 || contains(h.Types, ann.Type))
+}
+
+// EnqueueCrawlFailed 为订阅了 crawl.failed 事件的 webhook 写入待投递记录并唤醒投递
+func EnqueueCrawlFailed(run models.CrawlRun) error {
+	set, err := Load()
+	if err != nil || set.Empty() {
+		return err
+	}
+	tx, err := database.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	payload := Payload{Event: EventCrawlFailed, OccurredAt: time.Now().Format(time.RFC3339), CrawlRun: &run}
+	count := 0
+	for _, h := range set.hooks {
+		if !contains(h.Events, EventCrawlFailed) {
+			continue
+		}
+		if err := enqueue(tx, h.ID, payload); err != nil {
+			return err
+		}
+		count++
+	}
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+	if count > 0 {
+		Wake()
+	}
+	return nil
+}
+
+func enqueue(tx *sql.Tx, webhookID int, payload Payload) error {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+	if _, err := tx.Exec("INSERT INTO webhook_deliveries (webhook_id, event, payload) VALUES (?, ?, ?)",
+		webhookID, payload.Event, string(body)); err != nil {
+		return fmt.Errorf("写入 webhook 投递记录失败: %v", err)
+	}
+	return nil
+}
+
+// Validate 校验 webhook 的地址、事件、公告类型和关键词
+func Validate(w models.Webhook) error {
+	if w.Name == "" {
+		return fmt.Errorf("名称不能为空")
+	}
+	if err := validateURL(w.URL); err != nil {
+		return err
+	}
+	if len(w.Events) == 0 {
+		return fmt.Errorf("至少订阅一个事件，可选 %s", strings.Join(Events(), "、"))
+	}
+	for _, event := range w.Events {
+		if !contains(Events(), event) {
+			return fmt.Errorf("未知的事件 %q，可选 %s", event, strings.Join(Events(), "、"))
+		}
+	}
+	for _, t := range w.Types {
+		if !extract.ValidType(t) {
+			return fmt.Errorf("未知的公告类型: %s", t)
+		}
+	}
+	if contains(w.Events, EventKeywordMatched) && len(w.Keywords) == 0 {
+		return fmt.Errorf("订阅 %s 事件时需要设置 keywords", EventKeywordMatched)
+	}
+	_, err := keyword.ParseAll(w.Keywords)
+	return err
+}
+
+func validateURL(s string) error {
+	u, err := url.Parse(s)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("webhook 地址无效: %s", s)
+	}
+	return nil
+}
+
+func contains(values []string, s string) bool {
+	for _, v := range values {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
+// split 解析逗号分隔的列表，忽略空白项
+func split(s string) []string {
+	var result []string
+	for _, part := range strings.Split(s, ",") {
+		if part = strings.TrimSpace(part); part != "" {
+			result = append(result, part)
+		}
+	}
+	return result
+}
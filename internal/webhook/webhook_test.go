@@ -0,0 +1,225 @@
+package webhook_test
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ieasydevops/demo-scrapy/internal/crawler"
+	"github.com/ieasydevops/demo-scrapy/internal/crawler/crawlertest"
+	"github.com/ieasydevops/demo-scrapy/internal/models"
+	"github.com/ieasydevops/demo-scrapy/internal/notify"
+	"github.com/ieasydevops/demo-scrapy/internal/webhook"
+)
+
+// receiver 记录收到的投递，status 为返回的状态码
+type receiver struct {
+	*httptest.Server
+	mu       sync.Mutex
+	status   int
+	requests []received
+}
+
+type received struct {
+	header http.Header
+	body   []byte
+}
+
+func newReceiver(t *testing.T) *receiver {
+	r := &receiver{status: http.StatusOK}
+	r.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		body, _ := io.ReadAll(req.Body)
+		r.mu.Lock()
+		defer r.mu.Unlock()
+		r.requests = append(r.requests, received{req.Header, body})
+		w.WriteHeader(r.status)
+	}))
+	t.Cleanup(r.Close)
+	return r
+}
+
+func (r *receiver) setStatus(status int) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.status = status
+}
+
+func setup(t *testing.T) {
+	crawlertest.OpenDB(t)
+	// 重试等待不足一秒时立即到期
+	webhook.Configure(webhook.Options{Timeout: 5 * time.Second, MaxAttempts: 3, BaseDelay: time.Millisecond, MaxDelay: time.Millisecond})
+}
+
+// drain 投递到没有到期的记录为止
+func drain(t *testing.T) {
+	t.Helper()
+	for i := 0; i < 10; i++ {
+		n, err := webhook.DeliverDue(context.Background())
+		if err != nil {
+			t.Fatalf("DeliverDue: %v", err)
+		}
+		if n == 0 {
+			return
+		}
+	}
+	t.Fatal("投递记录一直未处理完")
+}
+
+func TestAnnouncementEventsAreSigned(t *testing.T) {
+	setup(t)
+	r := newReceiver(t)
+	_, secret, err := webhook.Create(models.Webhook{
+		Name: "crm", URL: r.URL, Enabled: true,
+		Events:   []string{webhook.EventAnnouncementCreated, webhook.EventAnnouncementClassified, webhook.EventKeywordMatched},
+		Types:    []string{"tender"},
+		Keywords: []string{"监测", "车辆"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	pageID := crawlertest.InsertWebPage(t, "深圳政府采购网", "http://example.com", "szggzy", "{}")
+	_, _, err = crawler.SaveAnnouncements([]models.Announcement{
+		{Title: "深圳市生态环境局监测服务招标公告", URL: "http://example.com/1.html", PublishDate: "2025-06-01"},
+		{Title: "深圳市生态环境局会议通知", URL: "http://example.com/2.html", PublishDate: "2025-06-01"},
+	}, pageID, 0, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 历史回填入库的公告不推送
+	_, _, err = crawler.SaveAnnouncements([]models.Announcement{
+		{Title: "深圳市生态环境局监测服务招标公告（回填）", URL: "http://example.com/3.html", PublishDate: "2024-06-01"},
+	}, pageID, 0, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	drain(t)
+
+	// 第一条：入库、招标类型、匹配关键词；第二条只有入库
+	events := map[string]int{}
+	for _, req := range r.requests {
+		ts, _ := strconv.ParseInt(req.header.Get(notify.HeaderTimestamp), 10, 64)
+		if req.header.Get(notify.HeaderSignature) != notify.Sign(secret, ts, req.body) {
+			t.Errorf("投递 %s 的签名不符", req.header.Get(webhook.HeaderDelivery))
+		}
+		var payload webhook.Payload
+		if err := json.Unmarshal(req.body, &payload); err != nil || payload.Announcement == nil {
+			t.Fatalf("请求体 %s 不符", req.body)
+		}
+		if payload.Event != req.header.Get(webhook.HeaderEvent) {
+			t.Errorf("事件 %s 与请求头 %s 不一致", payload.Event, req.header.Get(webhook.HeaderEvent))
+		}
+		if payload.Event == webhook.EventKeywordMatched && (len(payload.Keywords) != 1 || payload.Keywords[0] != "监测") {
+			t.Errorf("匹配的关键词 %v，期望 [监测]", payload.Keywords)
+		}
+		events[payload.Event]++
+	}
+	if events[webhook.EventAnnouncementCreated] != 2 || events[webhook.EventAnnouncementClassified] != 1 || events[webhook.EventKeywordMatched] != 1 {
+		t.Errorf("收到的事件 %v，期望入库 2 次、分类和关键词各 1 次", events)
+	}
+}
+
+func TestDetailChangesAreSent(t *testing.T) {
+	setup(t)
+	r := newReceiver(t)
+	_, _, err := webhook.Create(models.Webhook{
+		Name: "crm", URL: r.URL, Enabled: true,
+		Events: []string{webhook.EventAnnouncementClassified, webhook.EventAnnouncementUpdated},
+		Types:  []string{"award"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	pageID := crawlertest.InsertWebPage(t, "深圳政府采购网", "http://example.com", "szggzy", "{}")
+	saved, _, err := crawler.SaveAnnouncements([]models.Announcement{
+		{Title: "深圳市生态环境局监测服务项目公告", URL: "http://example.com/1.html", PublishDate: "2025-06-01"},
+	}, pageID, 0, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	drain(t)
+	if len(r.requests) != 0 {
+		t.Fatalf("入库时推送了 %d 个事件，期望标题无法归为中标公告时不推送", len(r.requests))
+	}
+
+	// 详情页正文改变了类型并抽取到采购人
+	hooks, err := webhook.Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	ann := saved[0]
+	ann.Body = "成交结果公告 采购人：深圳市生态环境局"
+	ann.Type = "award"
+	ann.Purchaser = "深圳市生态环境局"
+	if n, err := crawler.SaveDetail(ann, nil, hooks); err != nil || n != 2 {
+		t.Fatalf("SaveDetail 写入 %d 个事件（%v），期望分类和更新各 1 个", n, err)
+	}
+	// 再次抓取没有变化时不推送
+	if n, err := crawler.SaveDetail(ann, nil, hooks); err != nil || n != 0 {
+		t.Fatalf("SaveDetail 写入 %d 个事件（%v），期望没有变化时不写入", n, err)
+	}
+	drain(t)
+
+	events := map[string]int{}
+	for _, req := range r.requests {
+		var payload webhook.Payload
+		if err := json.Unmarshal(req.body, &payload); err != nil || payload.Announcement == nil {
+			t.Fatalf("请求体 %s 不符", req.body)
+		}
+		if payload.Announcement.Type != "award" || payload.Announcement.Purchaser != "深圳市生态环境局" {
+			t.Errorf("事件 %s 的公告 %+v，期望为更新后的公告", payload.Event, payload.Announcement)
+		}
+		events[payload.Event]++
+	}
+	if events[webhook.EventAnnouncementClassified] != 1 || events[webhook.EventAnnouncementUpdated] != 1 {
+		t.Errorf("收到的事件 %v，期望分类和更新各 1 次", events)
+	}
+}
+
+func TestDeliveryRetriesThenFailsAndReplays(t *testing.T) {
+	setup(t)
+	r := newReceiver(t)
+	r.setStatus(http.StatusServiceUnavailable)
+	webhookID, _, err := webhook.Create(models.Webhook{Name: "crm", URL: r.URL, Enabled: true, Events: []string{webhook.EventCrawlFailed}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := webhook.EnqueueCrawlFailed(models.CrawlRun{ID: 7, Status: "failed", Error: "响应结构异常"}); err != nil {
+		t.Fatal(err)
+	}
+	drain(t)
+
+	list, total, err := webhook.Deliveries(webhook.DeliveryFilter{WebhookID: webhookID}, 1, 10)
+	if err != nil || total != 1 {
+		t.Fatalf("投递记录 %d 条（%v），期望 1 条", total, err)
+	}
+	failed := list[0]
+	if failed.Status != webhook.StatusFailed || failed.Attempts != 3 || failed.ResponseStatus != 503 || len(r.requests) != 3 {
+		t.Fatalf("投递记录 %+v，收到 %d 次请求，期望重试 3 次后失败", failed, len(r.requests))
+	}
+
+	r.setStatus(http.StatusOK)
+	replayID, err := webhook.Replay(failed.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	drain(t)
+	replay, err := webhook.GetDelivery(replayID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if replay.Status != webhook.StatusSuccess || replay.ReplayOf != failed.ID || replay.Attempts != 1 {
+		t.Errorf("重放记录 %+v，期望一次投递成功", replay)
+	}
+	if string(r.requests[3].body) != string(replay.Payload) {
+		t.Errorf("重放的请求体与原记录不一致")
+	}
+}